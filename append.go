@@ -0,0 +1,65 @@
+package json
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/going/json/internal/encoder"
+)
+
+// appendContext and quoteContext are read only contexts with the options of Marshal,
+// and of an Encoder with SetEscapeHTML(false).
+var (
+	appendContext = &encoder.RuntimeContext{
+		Option: &encoder.Option{Flag: encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option},
+	}
+	quoteContext = &encoder.RuntimeContext{
+		Option: &encoder.Option{Flag: encoder.NormalizeUTF8Option},
+	}
+)
+
+// AppendString appends s as a JSON string to b, escaped as Marshal escapes strings,
+// and returns the extended buffer. It is meant for hand-written MarshalJSON methods:
+//
+//	func (u User) MarshalJSON() ([]byte, error) {
+//		b := []byte(`{"name":`)
+//		b = json.AppendString(b, u.Name)
+//		return append(b, '}'), nil
+//	}
+//
+// The characters <, > and & are escaped as \u003c, \u003e and \u0026, and invalid UTF-8 is replaced by U+FFFD.
+func AppendString(b []byte, s string) []byte {
+	return encoder.AppendString(appendContext, b, s)
+}
+
+// AppendQuote is like AppendString, but does not escape the characters <, > and &,
+// as an Encoder with SetEscapeHTML(false).
+func AppendQuote(b []byte, s string) []byte {
+	return encoder.AppendString(quoteContext, b, s)
+}
+
+// AppendInt appends i as a JSON number to b and returns the extended buffer.
+func AppendInt(b []byte, i int64) []byte {
+	return strconv.AppendInt(b, i, 10)
+}
+
+// AppendFloat appends f as a JSON number to b in the same format as Marshal, and returns the extended buffer.
+// bitSize is 32 for a float32 and 64 for a float64.
+// NaN and infinities are not valid JSON, so they are reported by an *UnsupportedValueError.
+func AppendFloat(b []byte, f float64, bitSize int) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, encoder.ErrUnsupportedFloat(f)
+	}
+	if bitSize == 32 {
+		return encoder.AppendFloat32(appendContext, b, float32(f)), nil
+	}
+	return encoder.AppendFloat64(appendContext, b, f), nil
+}
+
+// AppendTime appends t formatted with layout as a JSON string to b, and returns the extended buffer.
+// layout is the layout of time.Time.Format. Marshal formats a time.Time with time.RFC3339Nano.
+func AppendTime(b []byte, t time.Time, layout string) []byte {
+	var buf [64]byte
+	return encoder.AppendString(appendContext, b, string(t.AppendFormat(buf[:0], layout)))
+}
@@ -0,0 +1,103 @@
+package json_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/going/json"
+)
+
+func TestAppendHelpers(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		s := "a\"b\\c\n<&>\xff "
+		expected, err := json.Marshal(s)
+		assertErr(t, err)
+		assertEq(t, "string", string(expected), string(json.AppendString([]byte("x"), s))[1:])
+	})
+	t.Run("quote", func(t *testing.T) {
+		assertEq(t, "quote", `"<a href=\"x\">&</a>\t"`, string(json.AppendQuote(nil, "<a href=\"x\">&</a>\t")))
+	})
+	t.Run("int", func(t *testing.T) {
+		assertEq(t, "int", "[-42", string(json.AppendInt([]byte("["), -42)))
+	})
+	t.Run("float", func(t *testing.T) {
+		for _, f := range []float64{0, 1.5, -1e-7, 1e21, 123456789} {
+			expected, err := json.Marshal(f)
+			assertErr(t, err)
+			b, err := json.AppendFloat(nil, f, 64)
+			assertErr(t, err)
+			assertEq(t, "float64", string(expected), string(b))
+		}
+		expected, err := json.Marshal(float32(0.1))
+		assertErr(t, err)
+		b, err := json.AppendFloat(nil, float64(float32(0.1)), 32)
+		assertErr(t, err)
+		assertEq(t, "float32", string(expected), string(b))
+
+		_, err = json.AppendFloat(nil, math.NaN(), 64)
+		var verr *json.UnsupportedValueError
+		assertEq(t, "nan", true, errors.As(err, &verr))
+	})
+	t.Run("time", func(t *testing.T) {
+		tm := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+		expected, err := json.Marshal(tm)
+		assertErr(t, err)
+		assertEq(t, "rfc3339", string(expected), string(json.AppendTime(nil, tm, time.RFC3339Nano)))
+		assertEq(t, "layout", `"2024-01-02"`, string(json.AppendTime(nil, tm, "2006-01-02")))
+	})
+}
+
+func TestMarshalAppend(t *testing.T) {
+	type item struct {
+		ID   int      `json:"id"`
+		Tags []string `json:"tags"`
+		Note string   `json:"note,omitempty"`
+	}
+	v := &item{ID: 1, Tags: []string{"a", "<b>"}}
+	expected, err := json.Marshal(v)
+	assertErr(t, err)
+
+	t.Run("append", func(t *testing.T) {
+		got, err := json.MarshalAppend([]byte("prefix:"), v)
+		assertErr(t, err)
+		assertEq(t, "json", "prefix:"+string(expected), string(got))
+
+		got, err = json.MarshalAppend(nil, nil)
+		assertErr(t, err)
+		assertEq(t, "nil", "null", string(got))
+	})
+	t.Run("reuse", func(t *testing.T) {
+		buf := make([]byte, 0, 256)
+		got, err := json.MarshalAppend(buf, v)
+		assertErr(t, err)
+		assertEq(t, "json", string(expected), string(got))
+		assertEq(t, "same buffer", &buf[:1][0], &got[0])
+	})
+	t.Run("option", func(t *testing.T) {
+		got, err := json.MarshalAppend(nil, v, json.ForceIncludeEmpty(), json.DisableHTMLEscape())
+		assertErr(t, err)
+		assertEq(t, "json", `{"id":1,"tags":["a","<b>"],"note":""}`, string(got))
+	})
+	t.Run("error", func(t *testing.T) {
+		dst := []byte("keep")
+		got, err := json.MarshalAppend(dst, math.NaN())
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		assertEq(t, "dst", "keep", string(got))
+	})
+	t.Run("allocs", func(t *testing.T) {
+		buf := make([]byte, 0, 256)
+		appendAllocs := testing.AllocsPerRun(100, func() {
+			buf, _ = json.MarshalAppend(buf[:0], v)
+		})
+		marshalAllocs := testing.AllocsPerRun(100, func() {
+			buf, _ = json.Marshal(v)
+		})
+		if appendAllocs >= marshalAllocs {
+			t.Errorf("expected fewer allocations than Marshal: %v with MarshalAppend, %v with Marshal", appendAllocs, marshalAllocs)
+		}
+	})
+}
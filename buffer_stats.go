@@ -0,0 +1,38 @@
+package json
+
+// BufferStats describes how the encode buffer of an Encoder grew while encoding a value.
+// It is reported by the function set with Encoder.SetBufferStatsFunc.
+type BufferStats struct {
+	// InitialCap is the capacity of the buffer before the value was encoded.
+	InitialCap int
+	// FinalCap is the capacity of the buffer after the value was encoded.
+	FinalCap int
+	// Len is the number of bytes in the buffer after the value was encoded.
+	// It is less than the size of the value if bytes were flushed by SetFlushSize.
+	Len int
+	// Regrows is the number of times the buffer was reallocated.
+	// It is estimated from InitialCap and FinalCap with the growth rule of append,
+	// so it may be a little more than the actual number when long strings were appended at once.
+	Regrows int
+}
+
+// newBufferStats returns the BufferStats of a buffer that has grown from initialCap to b.
+func newBufferStats(initialCap int, b []byte) BufferStats {
+	stats := BufferStats{InitialCap: initialCap, FinalCap: cap(b), Len: len(b)}
+	for c := initialCap; c < stats.FinalCap; stats.Regrows++ {
+		c = growCap(c)
+	}
+	return stats
+}
+
+// growCap returns the capacity that append gives to a byte slice outgrowing c by a few bytes.
+func growCap(c int) int {
+	const threshold = 256
+	if c < threshold {
+		if c == 0 {
+			return 8
+		}
+		return 2 * c
+	}
+	return c + (c+3*threshold)/4
+}
@@ -0,0 +1,121 @@
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+)
+
+// Value is a JSON value assembled by Object and Array.
+// It is encoded as is when it is marshaled or nested in another Object or Array,
+// so that handcrafted payloads are built without intermediate maps, in the order of their fields.
+//
+//	v := json.Object(
+//		json.Field("id", 1),
+//		json.Field("tags", json.Array("a", "b")),
+//	)
+//	b, err := json.Marshal(v) // {"id":1,"tags":["a","b"]}
+//
+// The zero Value is encoded as null.
+type Value struct {
+	raw []byte
+	err error
+}
+
+// Member is an object member built by Field.
+type Member struct {
+	key   string
+	value interface{}
+}
+
+// Field returns the member of an object with key and the value v.
+// v is encoded when the object is built by Object.
+func Field(key string, v interface{}) Member {
+	return Member{key: key, value: v}
+}
+
+// Object returns the object with members, in the given order.
+// Keys are not deduplicated.
+// If a value fails to encode, the error is reported by the Value and by the values it is nested in.
+func Object(members ...Member) Value {
+	ctx := takeBuildContext()
+	defer encoder.ReleaseRuntimeContext(ctx)
+
+	b := make([]byte, 0, 16*len(members)+2)
+	b = append(b, '{')
+	for i, m := range members {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = encoder.AppendString(ctx, b, m.key)
+		b = append(b, ':')
+		var err error
+		if b, err = appendBuildValue(ctx, b, m.value); err != nil {
+			return Value{err: err}
+		}
+	}
+	return Value{raw: append(b, '}')}
+}
+
+// Array returns the array with values, in the given order.
+// If a value fails to encode, the error is reported by the Value and by the values it is nested in.
+func Array(values ...interface{}) Value {
+	ctx := takeBuildContext()
+	defer encoder.ReleaseRuntimeContext(ctx)
+
+	b := make([]byte, 0, 8*len(values)+2)
+	b = append(b, '[')
+	for i, v := range values {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		var err error
+		if b, err = appendBuildValue(ctx, b, v); err != nil {
+			return Value{err: err}
+		}
+	}
+	return Value{raw: append(b, ']')}
+}
+
+// MarshalJSON implements Marshaler. It returns the error of the value that failed to encode, if any.
+func (v Value) MarshalJSON() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	if v.raw == nil {
+		return []byte("null"), nil
+	}
+	return v.raw, nil
+}
+
+// RawMessage returns the encoded value, or the error of the value that failed to encode.
+// The returned RawMessage shares its memory with v.
+func (v Value) RawMessage() (RawMessage, error) {
+	b, err := v.MarshalJSON()
+	return RawMessage(b), err
+}
+
+// takeBuildContext returns a RuntimeContext with the options of Marshal.
+func takeBuildContext() *encoder.RuntimeContext {
+	ctx := encoder.TakeRuntimeContext()
+	ctx.Option.Flag = encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option
+	return ctx
+}
+
+// appendBuildValue appends the encoded v to b, appending nested Values without encoding them again.
+func appendBuildValue(ctx *encoder.RuntimeContext, b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case Value:
+		raw, err := v.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return append(b, raw...), nil
+	case string:
+		return encoder.AppendString(ctx, b, v), nil
+	}
+	buf, err := encode(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	// drop the trailing comma.
+	return append(b, buf[:len(buf)-1]...), nil
+}
@@ -0,0 +1,52 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestValueBuilder(t *testing.T) {
+	t.Run("object", func(t *testing.T) {
+		v := json.Object(
+			json.Field("id", 1),
+			json.Field("name", "<a>"),
+			json.Field("tags", json.Array("a", "b")),
+			json.Field("meta", json.Object()),
+			json.Field("ok", true),
+			json.Field("none", nil),
+			json.Field("point", struct {
+				X int `json:"x"`
+			}{X: 2}),
+		)
+		raw, err := v.RawMessage()
+		assertErr(t, err)
+		assertEq(t, "raw", `{"id":1,"name":"\u003ca\u003e","tags":["a","b"],"meta":{},"ok":true,"none":null,"point":{"x":2}}`, string(raw))
+		assertEq(t, "valid", true, json.Valid(raw))
+	})
+	t.Run("order", func(t *testing.T) {
+		raw, err := json.Object(json.Field("b", 1), json.Field("a", 2)).RawMessage()
+		assertErr(t, err)
+		assertEq(t, "raw", `{"b":1,"a":2}`, string(raw))
+	})
+	t.Run("array", func(t *testing.T) {
+		raw, err := json.Array(1, "x", json.Array(), json.Object(json.Field("k", 1.5))).RawMessage()
+		assertErr(t, err)
+		assertEq(t, "raw", `[1,"x",[],{"k":1.5}]`, string(raw))
+	})
+	t.Run("marshal", func(t *testing.T) {
+		b, err := json.Marshal(map[string]interface{}{
+			"v":    json.Object(json.Field("a", 1)),
+			"zero": json.Value{},
+		})
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"v":{"a":1},"zero":null}`, string(b))
+	})
+	t.Run("error", func(t *testing.T) {
+		v := json.Object(json.Field("outer", json.Array(make(chan int))))
+		_, err := v.RawMessage()
+		assertNeq(t, "error", nil, err)
+		_, err = json.Marshal(v)
+		assertNeq(t, "marshal error", nil, err)
+	})
+}
@@ -0,0 +1,150 @@
+package json
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// MarshalCanonical returns the JSON encoding of v with EncodeOption in the canonical form of RFC 8785 (JCS),
+// whose bytes are stable for signing and hashing:
+//
+//	b, err := json.MarshalCanonical(payload)
+//	sig := ed25519.Sign(key, b)
+//
+// The keys of the objects are sorted by their UTF-16 code units, there is no whitespace,
+// the strings escape only the quote, the backslash and the control characters, and the numbers are
+// serialized as ECMAScript does, in their shortest form of a float64: 1.0 is 1, and 1e21 is 1e+21.
+// An integer that a float64 cannot represent exactly, such as an int64 above 2^53, loses its precision,
+// and a number out of the range of float64 is an error.
+// The options that change the layout, such as indentation or colors, have no effect.
+func MarshalCanonical(v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	b, err := MarshalWithOption(v, optFuncs...)
+	if err != nil {
+		return nil, err
+	}
+	dec := NewDecoderBytes(b)
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return appendCanonical(make([]byte, 0, len(b)), value)
+}
+
+func appendCanonical(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case string:
+		return appendCanonicalString(b, v), nil
+	case Number:
+		return appendCanonicalNumber(b, v)
+	case []interface{}:
+		b = append(b, '[')
+		for i, elem := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendCanonical(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		b = append(b, '{')
+		for i, key := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendCanonicalString(b, key)
+			b = append(b, ':')
+			var err error
+			if b, err = appendCanonical(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, fmt.Errorf("json: unexpected value of type %T in canonical form", v)
+}
+
+// lessUTF16 reports whether a sorts before b by their UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+func appendCanonicalString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			b = utf8.AppendRune(b, r)
+			i += size
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			} else {
+				b = append(b, c)
+			}
+		}
+		i++
+	}
+	return append(b, '"')
+}
+
+// appendCanonicalNumber appends n as ECMAScript's Number.prototype.toString does.
+func appendCanonicalNumber(b []byte, n Number) ([]byte, error) {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("json: number %s is out of the range of the canonical form", n)
+	}
+	if f == 0 {
+		// including -0.
+		return append(b, '0'), nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.AppendFloat(b, f, 'f', -1, 64), nil
+	}
+	start := len(b)
+	b = strconv.AppendFloat(b, f, 'e', -1, 64)
+	// ECMAScript has no leading zero in the exponent, as in 1e-7.
+	if n := len(b); n-start >= 4 && b[n-4] == 'e' && b[n-2] == '0' {
+		b = append(b[:n-2], b[n-1])
+	}
+	return b, nil
+}
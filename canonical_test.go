@@ -0,0 +1,58 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestMarshalCanonical(t *testing.T) {
+	t.Run("numbers", func(t *testing.T) {
+		for _, tc := range []struct {
+			in  string
+			exp string
+		}{
+			{"333333333.33333329", "333333333.3333333"},
+			{"1E30", "1e+30"},
+			{"4.50", "4.5"},
+			{"2e-3", "0.002"},
+			{"0.000000000000000000000000001", "1e-27"},
+			{"0.0000001", "1e-7"},
+			{"0.000001", "0.000001"},
+			{"-0", "0"},
+			{"1e21", "1e+21"},
+			{"1e20", "100000000000000000000"},
+			{"9007199254740993", "9007199254740992"},
+			{"-1.5e-300", "-1.5e-300"},
+		} {
+			got, err := json.MarshalCanonical(json.Number(tc.in))
+			assertErr(t, err)
+			assertEq(t, tc.in, tc.exp, string(got))
+		}
+	})
+	t.Run("keys", func(t *testing.T) {
+		v := map[string]int{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\U0001F600": 5, "\u0080": 6, "\u00f6": 7}
+		got, err := json.MarshalCanonical(v)
+		assertErr(t, err)
+		assertEq(t, "keys", "{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001F600\":5,\"\ufb33\":3}", string(got))
+	})
+	t.Run("struct", func(t *testing.T) {
+		type payload struct {
+			Zeta  string            `json:"zeta"`
+			Alpha []interface{}     `json:"alpha"`
+			Meta  map[string]string `json:"meta"`
+		}
+		got, err := json.MarshalCanonical(payload{
+			Zeta:  "<a & b>\u2028\x0f\"\\\t",
+			Alpha: []interface{}{1.0, true, nil, "x"},
+			Meta:  map[string]string{"b": "2", "a": "1"},
+		}, json.UnorderedMap())
+		assertErr(t, err)
+		assertEq(t, "struct",
+			`{"alpha":[1,true,null,"x"],"meta":{"a":"1","b":"2"},"zeta":"<a & b>`+"\u2028"+`\u000f\"\\\t"}`, string(got))
+	})
+	t.Run("out of range", func(t *testing.T) {
+		_, err := json.MarshalCanonical(json.Number("1e400"))
+		assertNeq(t, "error", nil, err)
+	})
+}
@@ -0,0 +1,27 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/going/json/internal/decoder"
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/runtime"
+)
+
+// RegisterCodec registers marshal and unmarshal as the codec name of T, used for the struct fields of type T
+// with the codec tag option ( e.g. `json:"price,codec=money"` ), so that the same type is encoded differently
+// in different structs without wrapper types.
+// marshal returns the JSON encoding of the field value, which takes precedence over the Marshaler of T.
+// unmarshal is called with the raw JSON value of the field, including null, and takes precedence over the Unmarshaler of T.
+// Encoding or decoding a struct with a field whose codec is not registered or is registered for another type fails.
+// It must be called before a type that uses the codec is encoded or decoded for the first time, typically from an init function.
+func RegisterCodec[T any](name string, marshal func(T) ([]byte, error), unmarshal func(data []byte, v *T) error) {
+	typ := runtime.Type2RType(reflect.TypeOf((*T)(nil)).Elem())
+	encoder.RegisterCodec(name, typ, func(v interface{}) ([]byte, error) {
+		return marshal(v.(T))
+	})
+	decoder.RegisterCodec(name, typ, func(data []byte, p unsafe.Pointer) error {
+		return unmarshal(data, (*T)(p))
+	})
+}
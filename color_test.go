@@ -47,3 +47,24 @@ func TestColorize(t *testing.T) {
 		t.Log("\n" + string(b))
 	})
 }
+
+func TestColorizeMarshaler(t *testing.T) {
+	v := struct {
+		A json.RawMessage
+	}{A: json.RawMessage(`{"b": [1, "c"]}`)}
+	scheme := &json.ColorScheme{
+		Int:       json.ColorFormat{Header: "<i>", Footer: "</i>"},
+		String:    json.ColorFormat{Header: "<s>", Footer: "</s>"},
+		ObjectKey: json.ColorFormat{Header: "<k>", Footer: "</k>"},
+	}
+	t.Run("marshal with color", func(t *testing.T) {
+		b, err := json.MarshalWithOption(v, json.Colorize(scheme))
+		assertErr(t, err)
+		assertEq(t, "colorized", `{<k>"A"</k>:{<k>"b"</k>:[<i>1</i>,<s>"c"</s>]}}`, string(b))
+	})
+	t.Run("marshal indent with color", func(t *testing.T) {
+		b, err := json.MarshalIndentWithOption(v, "", " ", json.Colorize(scheme))
+		assertErr(t, err)
+		assertEq(t, "colorized", "{\n <k>\"A\"</k>: {\n  <k>\"b\"</k>: [\n   <i>1</i>,\n   <s>\"c\"</s>\n  ]\n }\n}", string(b))
+	})
+}
@@ -0,0 +1,95 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// GzipEncoder is an Encoder whose output is compressed with gzip.
+// Close must be called to write the end of the gzip stream.
+type GzipEncoder struct {
+	*Encoder
+	zw *gzip.Writer
+}
+
+// NewGzipEncoder returns a new encoder that writes to w compressed with gzip at level,
+// which is one of the levels of compress/gzip such as gzip.DefaultCompression.
+func NewGzipEncoder(w io.Writer, level int) (*GzipEncoder, error) {
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &GzipEncoder{Encoder: NewEncoder(zw), zw: zw}, nil
+}
+
+// Flush writes the compressed data of the values encoded so far to the underlying writer,
+// so that the reader can decode them before the stream is closed.
+func (e *GzipEncoder) Flush() error {
+	return e.zw.Flush()
+}
+
+// Close writes the end of the gzip stream. It does not close the underlying writer.
+func (e *GzipEncoder) Close() error {
+	return e.zw.Close()
+}
+
+type decompressor struct {
+	magic      []byte
+	decompress func(r io.Reader) (io.Reader, error)
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+var decompressors = []decompressor{
+	{
+		magic: gzipMagic,
+		decompress: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+}
+
+// RegisterDecompressor registers decompress for the input of NewAutoDecoder starting with magic,
+// replacing the one registered for the same magic. decompress is given the input including magic.
+// gzip is registered by default. For zstd, whose magic is "\x28\xb5\x2f\xfd",
+// register the decoder of a zstd package, since the standard library has none.
+// It must be called before NewAutoDecoder, typically from an init function.
+func RegisterDecompressor(magic string, decompress func(r io.Reader) (io.Reader, error)) {
+	for i, d := range decompressors {
+		if string(d.magic) == magic {
+			decompressors[i].decompress = decompress
+			return
+		}
+	}
+	decompressors = append(decompressors, decompressor{magic: []byte(magic), decompress: decompress})
+}
+
+// NewAutoDecoder returns a new decoder that reads from r, decompressing it if it starts with the magic number
+// of a compression format registered by RegisterDecompressor, and reading it as is otherwise.
+// It reads the first bytes of r to detect the format.
+func NewAutoDecoder(r io.Reader) (*Decoder, error) {
+	br := bufio.NewReader(r)
+	for _, d := range decompressors {
+		head, err := br.Peek(len(d.magic))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if bytes.Equal(head, d.magic) {
+			zr, err := d.decompress(br)
+			if err != nil {
+				return nil, err
+			}
+			return NewDecoder(zr), nil
+		}
+	}
+	if head, _ := br.Peek(len(zstdMagic)); bytes.Equal(head, zstdMagic) {
+		return nil, fmt.Errorf("json: input is compressed with zstd, which has no registered decompressor ( see RegisterDecompressor )")
+	}
+	return NewDecoder(br), nil
+}
@@ -0,0 +1,86 @@
+package json_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestGzipEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc, err := json.NewGzipEncoder(&buf, gzip.BestSpeed)
+	assertErr(t, err)
+	assertErr(t, enc.Encode(map[string]int{"a": 1}))
+	assertErr(t, enc.Flush())
+	assertErr(t, enc.Encode([]int{2}))
+	assertErr(t, enc.Close())
+
+	zr, err := gzip.NewReader(&buf)
+	assertErr(t, err)
+	plain, err := io.ReadAll(zr)
+	assertErr(t, err)
+	assertEq(t, "output", "{\"a\":1}\n[2]\n", string(plain))
+
+	_, err = json.NewGzipEncoder(&buf, 100)
+	assertNeq(t, "invalid level", nil, err)
+}
+
+func TestNewAutoDecoder(t *testing.T) {
+	decodeAll := func(t *testing.T, r io.Reader) []interface{} {
+		t.Helper()
+		dec, err := json.NewAutoDecoder(r)
+		assertErr(t, err)
+		var values []interface{}
+		for dec.More() {
+			var v interface{}
+			assertErr(t, dec.Decode(&v))
+			values = append(values, v)
+		}
+		return values
+	}
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc, err := json.NewGzipEncoder(&buf, gzip.DefaultCompression)
+		assertErr(t, err)
+		assertErr(t, enc.Encode("a"))
+		assertErr(t, enc.Encode(1))
+		assertErr(t, enc.Close())
+		values := decodeAll(t, &buf)
+		assertEq(t, "length", 2, len(values))
+		assertEq(t, "first", "a", values[0])
+		assertEq(t, "second", float64(1), values[1])
+	})
+	t.Run("plain", func(t *testing.T) {
+		values := decodeAll(t, strings.NewReader(`{"a":1} 2`))
+		assertEq(t, "length", 2, len(values))
+		assertEq(t, "second", float64(2), values[1])
+	})
+	t.Run("short", func(t *testing.T) {
+		values := decodeAll(t, strings.NewReader(`1`))
+		assertEq(t, "first", float64(1), values[0])
+		assertEq(t, "empty", 0, len(decodeAll(t, strings.NewReader(``))))
+	})
+	t.Run("zstd", func(t *testing.T) {
+		_, err := json.NewAutoDecoder(strings.NewReader("\x28\xb5\x2f\xfd..."))
+		assertNeq(t, "unregistered", nil, err)
+	})
+	t.Run("registered", func(t *testing.T) {
+		magic := "\x00JZ"
+		json.RegisterDecompressor(magic, func(r io.Reader) (io.Reader, error) {
+			// a stand-in for a decompressor, which returns the input after the magic number as is.
+			_, err := io.CopyN(io.Discard, r, int64(len(magic)))
+			return r, err
+		})
+		values := decodeAll(t, strings.NewReader(magic+`[true]`))
+		assertEq(t, "length", 1, len(values))
+		assertEq(t, "value", true, values[0].([]interface{})[0])
+	})
+	t.Run("invalid gzip", func(t *testing.T) {
+		_, err := json.NewAutoDecoder(strings.NewReader("\x1f\x8b"))
+		assertNeq(t, "error", nil, err)
+	})
+}
@@ -1,8 +1,8 @@
 package json
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"io"
 	"reflect"
 	"unsafe"
@@ -13,11 +13,45 @@ import (
 )
 
 type Decoder struct {
-	s *decoder.Stream
+	s          *decoder.Stream
+	values     int64
+	xssiPrefix string
+	arrayMode  arrayModeState
+	// invalidRecord is the function set by SkipInvalidRecords.
+	invalidRecord func(record []byte, err error)
+}
+
+// arrayModeState is the position of a Decoder in ArrayMode.
+type arrayModeState int
+
+const (
+	arrayModeOff arrayModeState = iota
+	arrayModeBegin
+	// arrayModeFirst is after the opening bracket.
+	arrayModeFirst
+	// arrayModeElement is before an element.
+	arrayModeElement
+	// arrayModeNext is after an element.
+	arrayModeNext
+	// arrayModeComma is after the comma following an element.
+	arrayModeComma
+	arrayModeEnd
+)
+
+// DecoderStats holds the throughput statistics of a Decoder.
+type DecoderStats struct {
+	// Values is the number of values decoded by Decode and its variants.
+	Values int64
+	// Bytes is the number of bytes read from the underlying reader, or the size of the data given to NewDecoderBytes.
+	// It includes the bytes that are buffered but not yet decoded.
+	Bytes int64
+	// BufferSize is the high-water mark of the size of the read buffer.
+	BufferSize int64
 }
 
 const (
-	nul = '\000'
+	nul             = '\000'
+	maxNestingDepth = 10000
 )
 
 type emptyInterface struct {
@@ -28,29 +62,85 @@ type emptyInterface struct {
 func unmarshal(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
 	src := make([]byte, len(data)+1) // append nul byte to the end
 	copy(src, data)
+	return unmarshalTerminated(src, v, optFuncs...)
+}
 
+// unmarshalTerminated is like unmarshal but src must already end with a nul byte.
+func unmarshalTerminated(src []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
 	header := (*emptyInterface)(unsafe.Pointer(&v))
 
 	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
 		return err
 	}
-	dec, err := decoder.CompileToGetDecoder(header.typ)
+	ctx := decoder.TakeRuntimeContext()
+	ctx.Buf = src
+	ctx.Option.Flags = 0
+	for _, optFunc := range optFuncs {
+		optFunc(ctx.Option)
+	}
+	decoder.RelaxInput(ctx)
+	if err := decoder.ValidateStrict(ctx); err != nil {
+		decoder.ReleaseRuntimeContext(ctx)
+		return err
+	}
+	decoder.BuildStructuralIndex(ctx)
+	dec, err := decoder.CompileToGetDecoderWithOption(header.typ, ctx.Option)
 	if err != nil {
+		decoder.ReleaseRuntimeContext(ctx)
 		return err
 	}
+	cursor, err := dec.Decode(ctx, 0, 0, header.ptr)
+	if err == nil {
+		err = validateEndBuf(src, cursor)
+	}
+	err = decoder.UnknownFieldsError(ctx, err)
+	err = decoder.RelaxError(ctx, err)
+	decoder.ReleaseRuntimeContext(ctx)
+	return err
+}
+
+func unmarshalFirst(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) ([]byte, error) {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+
+	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
+		return nil, err
+	}
 	ctx := decoder.TakeRuntimeContext()
 	ctx.Buf = src
 	ctx.Option.Flags = 0
 	for _, optFunc := range optFuncs {
 		optFunc(ctx.Option)
 	}
-	cursor, err := dec.Decode(ctx, 0, 0, header.ptr)
+	decoder.RelaxInput(ctx)
+	if err := decoder.ValidateStrict(ctx); err != nil {
+		decoder.ReleaseRuntimeContext(ctx)
+		return nil, err
+	}
+	decoder.BuildStructuralIndex(ctx)
+	dec, err := decoder.CompileToGetDecoderWithOption(header.typ, ctx.Option)
 	if err != nil {
 		decoder.ReleaseRuntimeContext(ctx)
-		return err
+		return nil, err
 	}
+	cursor, err := dec.Decode(ctx, 0, 0, header.ptr)
+	err = decoder.UnknownFieldsError(ctx, err)
+	err = decoder.RelaxError(ctx, err)
 	decoder.ReleaseRuntimeContext(ctx)
-	return validateEndBuf(src, cursor)
+	if err != nil {
+		return nil, err
+	}
+	for cursor < int64(len(data)) {
+		switch data[cursor] {
+		case ' ', '\t', '\n', '\r':
+			cursor++
+			continue
+		}
+		break
+	}
+	return data[cursor:], nil
 }
 
 func unmarshalContext(ctx context.Context, data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
@@ -62,25 +152,36 @@ func unmarshalContext(ctx context.Context, data []byte, v interface{}, optFuncs
 	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
 		return err
 	}
-	dec, err := decoder.CompileToGetDecoder(header.typ)
-	if err != nil {
-		return err
-	}
 	rctx := decoder.TakeRuntimeContext()
 	rctx.Buf = src
 	rctx.Option.Flags = 0
 	rctx.Option.Flags |= decoder.ContextOption
 	rctx.Option.Context = ctx
+	if decoder.DisallowsUnknownFields(ctx) {
+		DisallowUnknownFields()(rctx.Option)
+	}
 	for _, optFunc := range optFuncs {
 		optFunc(rctx.Option)
 	}
-	cursor, err := dec.Decode(rctx, 0, 0, header.ptr)
+	decoder.RelaxInput(rctx)
+	if err := decoder.ValidateStrict(rctx); err != nil {
+		decoder.ReleaseRuntimeContext(rctx)
+		return err
+	}
+	decoder.BuildStructuralIndex(rctx)
+	dec, err := decoder.CompileToGetDecoderWithOption(header.typ, rctx.Option)
 	if err != nil {
 		decoder.ReleaseRuntimeContext(rctx)
 		return err
 	}
+	cursor, err := dec.Decode(rctx, 0, 0, header.ptr)
+	if err == nil {
+		err = validateEndBuf(src, cursor)
+	}
+	err = decoder.UnknownFieldsError(rctx, err)
+	err = decoder.RelaxError(rctx, err)
 	decoder.ReleaseRuntimeContext(rctx)
-	return validateEndBuf(src, cursor)
+	return err
 }
 
 var (
@@ -93,7 +194,14 @@ func extractFromPath(path *Path, data []byte, optFuncs ...DecodeOptionFunc) ([][
 	}
 	src := make([]byte, len(data)+1) // append nul byte to the end
 	copy(src, data)
+	return extractFromPathTerminated(path, src, optFuncs...)
+}
 
+// extractFromPathTerminated is like extractFromPath but src must already end with a nul byte.
+func extractFromPathTerminated(path *Path, src []byte, optFuncs ...DecodeOptionFunc) ([][]byte, error) {
+	if path.path.RootSelectorOnly {
+		return [][]byte{src[:len(src)-1]}, nil
+	}
 	ctx := decoder.TakeRuntimeContext()
 	ctx.Buf = src
 	ctx.Option.Flags = 0
@@ -123,24 +231,31 @@ func unmarshalNoEscape(data []byte, v interface{}, optFuncs ...DecodeOptionFunc)
 	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
 		return err
 	}
-	dec, err := decoder.CompileToGetDecoder(header.typ)
-	if err != nil {
-		return err
-	}
-
 	ctx := decoder.TakeRuntimeContext()
 	ctx.Buf = src
 	ctx.Option.Flags = 0
 	for _, optFunc := range optFuncs {
 		optFunc(ctx.Option)
 	}
-	cursor, err := dec.Decode(ctx, 0, 0, noescape(header.ptr))
+	decoder.RelaxInput(ctx)
+	if err := decoder.ValidateStrict(ctx); err != nil {
+		decoder.ReleaseRuntimeContext(ctx)
+		return err
+	}
+	decoder.BuildStructuralIndex(ctx)
+	dec, err := decoder.CompileToGetDecoderWithOption(header.typ, ctx.Option)
 	if err != nil {
 		decoder.ReleaseRuntimeContext(ctx)
 		return err
 	}
+	cursor, err := dec.Decode(ctx, 0, 0, noescape(header.ptr))
+	if err == nil {
+		err = validateEndBuf(src, cursor)
+	}
+	err = decoder.UnknownFieldsError(ctx, err)
+	err = decoder.RelaxError(ctx, err)
 	decoder.ReleaseRuntimeContext(ctx)
-	return validateEndBuf(src, cursor)
+	return err
 }
 
 func validateEndBuf(src []byte, cursor int64) error {
@@ -152,10 +267,7 @@ func validateEndBuf(src []byte, cursor int64) error {
 		case nul:
 			return nil
 		}
-		return errors.ErrSyntax(
-			fmt.Sprintf("invalid character '%c' after top-level value", src[cursor]),
-			cursor+1,
-		)
+		return errors.ErrAfterTopLevelValue(src[cursor], cursor+1)
 	}
 }
 
@@ -184,6 +296,19 @@ func NewDecoder(r io.Reader) *Decoder {
 	}
 }
 
+// NewDecoderBytes returns a new decoder that reads from data.
+//
+// Unlike NewDecoder, it has no io.Reader to refill its buffer from, and each value is
+// decoded with the same code path as Unmarshal while keeping the streaming Token/Decode API.
+// data is copied once when the decoder is created, so the caller may modify it afterwards.
+// It cannot be used in place: the decoder needs a nul byte after the last value to stop scanning,
+// which would be written into the caller's memory, and it unescapes the strings within its buffer.
+func NewDecoderBytes(data []byte) *Decoder {
+	return &Decoder{
+		s: decoder.NewBytesStream(data),
+	}
+}
+
 // Buffered returns a reader of the data remaining in the Decoder's
 // buffer. The reader is valid until the next call to Decode.
 func (d *Decoder) Buffered() io.Reader {
@@ -201,10 +326,19 @@ func (d *Decoder) Decode(v interface{}) error {
 
 // DecodeContext reads the next JSON-encoded value from its
 // input and stores it in the value pointed to by v with context.Context.
+// ctx is passed only to the UnmarshalerContext implementations of this value, not of the next ones.
 func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	const contextFlags = decoder.ContextOption | decoder.DisallowUnknownOption | decoder.PathModeOption
+	flags, stdctx := d.s.Option.Flags, d.s.Option.Context
 	d.s.Option.Flags |= decoder.ContextOption
 	d.s.Option.Context = ctx
-	return d.DecodeWithOption(v)
+	if decoder.DisallowsUnknownFields(ctx) {
+		DisallowUnknownFields()(d.s.Option)
+	}
+	err := d.DecodeWithOption(v)
+	d.s.Option.Flags = d.s.Option.Flags&^contextFlags | flags&contextFlags
+	d.s.Option.Context = stdctx
+	return err
 }
 
 func (d *Decoder) DecodeWithOption(v interface{}, optFuncs ...DecodeOptionFunc) error {
@@ -219,37 +353,181 @@ func (d *Decoder) DecodeWithOption(v interface{}, optFuncs ...DecodeOptionFunc)
 		return err
 	}
 
-	dec, err := decoder.CompileToGetDecoder(typ)
-	if err != nil {
-		return err
-	}
-	if err := d.s.PrepareForDecode(); err != nil {
-		return err
-	}
+	d.skipXSSIPrefix()
 	s := d.s
 	for _, optFunc := range optFuncs {
 		optFunc(s.Option)
 	}
-	if err := dec.DecodeStream(s, 0, header.ptr); err != nil {
+	if d.invalidRecord != nil {
+		return d.decodeRecord(typ, header.ptr)
+	}
+	if err := d.nextArrayElement(); err != nil {
+		return err
+	}
+	if err := decodeStream(s, typ, header.ptr); err != nil {
 		return err
 	}
+	d.endArrayElement()
 	s.Reset()
+	d.values++
 	return nil
 }
 
+func decodeStream(s *decoder.Stream, typ *runtime.Type, p unsafe.Pointer) error {
+	dec, err := decoder.CompileToGetDecoderWithOption(typ, s.Option)
+	if err != nil {
+		return err
+	}
+	s.RelaxInput()
+	if err := s.PrepareForDecode(); err != nil {
+		return err
+	}
+	if err := s.ValidateStrict(); err != nil {
+		return err
+	}
+	if s.CanDecodeBytes() {
+		err = s.DecodeBytes(dec, p)
+	} else {
+		err = dec.DecodeStream(s, 0, p)
+	}
+	return s.RelaxError(s.UnknownFieldsError(err))
+}
+
+// SkipInvalidRecords makes the Decoder survive the corrupt records of a stream of newline-delimited values,
+// such as NDJSON or the output of Encoder: each line of the input is a record holding a value,
+// and a record that is not valid JSON is passed to report and skipped, instead of failing the whole stream:
+//
+//	dec.SkipInvalidRecords(func(record []byte, err error) {
+//		log.Printf("skipping bad record %q: %v", record, err)
+//	})
+//	for {
+//		var v Record
+//		if err := dec.Decode(&v); err == io.EOF {
+//			break
+//		} else if err != nil { ... }
+//	}
+//
+// err is the *SyntaxError of the record, whose Offset is the offset in the whole input,
+// and record is only valid during the call. Decode returns the next valid record, or io.EOF after the last one.
+// The other errors, such as an *UnmarshalTypeError, are returned by Decode as usual,
+// after which the decoder continues with the next record. The empty lines are skipped.
+// A value spanning several lines is not a record, and ArrayMode has no effect.
+func (d *Decoder) SkipInvalidRecords(report func(record []byte, err error)) {
+	d.invalidRecord = report
+}
+
+// decodeRecord decodes the next valid record with SkipInvalidRecords.
+func (d *Decoder) decodeRecord(typ *runtime.Type, p unsafe.Pointer) error {
+	for {
+		record, offset, err := d.s.ReadLine()
+		if err != nil {
+			return err
+		}
+		d.s.Reset()
+		if i := bytes.IndexByte(record, 0); i >= 0 {
+			// the nul character ends the input of a stream, so the record is not decoded.
+			d.invalidRecord(record, errors.ErrInvalidCharacter(0, "record", offset+int64(i)))
+			continue
+		}
+		s := decoder.NewBytesStream(record)
+		s.Option = d.s.Option
+		s.UseNumber = d.s.UseNumber
+		s.UseRawNumber = d.s.UseRawNumber
+		s.DisallowUnknownFields = d.s.DisallowUnknownFields
+		err = decodeStream(s, typ, p)
+		if err == nil {
+			err = s.ValidateEnd()
+		}
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			if err == nil {
+				d.values++
+			}
+			return err
+		}
+		serr.Offset += offset
+		d.invalidRecord(record, serr)
+	}
+}
+
+// Stats returns the throughput statistics of the values decoded so far.
+func (d *Decoder) Stats() DecoderStats {
+	bytes, bufSize := d.s.Stats()
+	return DecoderStats{
+		Values:     d.values,
+		Bytes:      bytes,
+		BufferSize: bufSize,
+	}
+}
+
 func (d *Decoder) More() bool {
+	d.skipXSSIPrefix()
+	if d.arrayMode != arrayModeOff {
+		// a malformed array has more, so that Decode returns the error.
+		return d.nextArrayElement() != io.EOF
+	}
 	return d.s.More()
 }
 
 func (d *Decoder) Token() (Token, error) {
+	d.skipXSSIPrefix()
 	return d.s.Token()
 }
 
-// DisallowUnknownFields causes the Decoder to return an error when the destination
+// Skip consumes the next value, such as a whole object or array, without decoding it,
+// so that a stream is filtered without allocating for the values that are not needed:
+//
+//	for dec.More() {
+//		if kind, _ := dec.PeekKind(); kind != json.KindObject {
+//			dec.Skip()
+//			continue
+//		}
+//		...
+//	}
+//
+// It follows ArrayMode and may be used between Token calls, where Decode would read a value.
+// The value is only checked to be well nested, not validated.
+// It returns io.EOF at the end of the input.
+func (d *Decoder) Skip() error {
+	d.skipXSSIPrefix()
+	if err := d.nextArrayElement(); err != nil {
+		return err
+	}
+	if err := d.s.SkipValue(); err != nil {
+		return err
+	}
+	d.endArrayElement()
+	d.s.Reset()
+	return nil
+}
+
+// Peek returns the next token without consuming it, so that the following Token or Decode call reads it again.
+// It returns io.EOF at the end of the input.
+func (d *Decoder) Peek() (Token, error) {
+	d.skipXSSIPrefix()
+	return d.s.PeekToken()
+}
+
+// PeekKind returns the kind of the next value without consuming it.
+// It returns KindInvalid at the end of an object or an array, and io.EOF at the end of the input.
+func (d *Decoder) PeekKind() (Kind, error) {
+	d.skipXSSIPrefix()
+	c, err := d.s.PeekChar()
+	if err != nil {
+		return KindInvalid, err
+	}
+	return kindOfChar(c), nil
+}
+
+// DisallowUnknownFields causes the Decoder to return an *UnknownFieldError when the destination
 // is a struct and the input contains object keys which do not match any
 // non-ignored, exported fields in the destination.
+// The value is decoded entirely before the error is returned, so that it reports all the unknown fields:
+// if there are more than one, the error is an *UnknownFieldsError listing them.
 func (d *Decoder) DisallowUnknownFields() {
 	d.s.DisallowUnknownFields = true
+	// the paths of the unknown fields are tracked as with StrictPaths.
+	d.s.Option.Flags |= decoder.PathModeOption
 }
 
 func (d *Decoder) InputOffset() int64 {
@@ -261,3 +539,118 @@ func (d *Decoder) InputOffset() int64 {
 func (d *Decoder) UseNumber() {
 	d.s.UseNumber = true
 }
+
+// UseRawNumber causes the Decoder to unmarshal a number into an interface{} as a
+// RawNumber instead of as a float64. Unlike UseNumber, the number keeps the exact
+// bytes of the input, so it can be re-emitted without any change.
+func (d *Decoder) UseRawNumber() {
+	d.s.UseRawNumber = true
+}
+
+// StripXSSIPrefix makes the Decoder skip prefix, such as XSSIPrefix, if the input begins with it.
+// An input without the prefix is decoded as usual.
+// It must be called before the first value or token is read.
+func (d *Decoder) StripXSSIPrefix(prefix string) {
+	d.xssiPrefix = prefix
+}
+
+// skipXSSIPrefix skips the prefix set by StripXSSIPrefix at the beginning of the input, once.
+func (d *Decoder) skipXSSIPrefix() {
+	if d.xssiPrefix != "" {
+		d.s.SkipPrefix(d.xssiPrefix)
+		d.xssiPrefix = ""
+	}
+}
+
+// ArrayMode causes the Decoder to read the elements of a top-level array as if they were a stream of values.
+// Decode and More consume the opening bracket, the commas and the closing bracket of the array,
+// so that each Decode call reads the next element, and returns io.EOF after the last one:
+//
+//	dec.ArrayMode()
+//	for dec.More() {
+//		var v Record
+//		if err := dec.Decode(&v); err != nil { ... }
+//	}
+//
+// The elements must be separated by exactly one comma, and only whitespace may follow the array.
+// More reports true for a malformed array, so that the next Decode call returns the *SyntaxError.
+// Token and Peek are not affected. It must be called before the first value or token is read.
+func (d *Decoder) ArrayMode() {
+	d.arrayMode = arrayModeBegin
+}
+
+// nextArrayElement moves the stream in ArrayMode to the next element of the array,
+// consuming the brackets and the commas of the array, and returns io.EOF after the last element.
+// The array must have exactly one comma between its elements and only whitespace after it.
+func (d *Decoder) nextArrayElement() error {
+	switch d.arrayMode {
+	case arrayModeBegin:
+		c, err := d.s.NextChar()
+		if err != nil {
+			return err
+		}
+		if c != '[' {
+			return errors.ErrExpected("array in ArrayMode", d.s.TotalOffset())
+		}
+		d.s.SkipPrefix("[")
+		d.arrayMode = arrayModeFirst
+		fallthrough
+	case arrayModeFirst, arrayModeNext, arrayModeComma:
+		c, err := d.nextArrayChar()
+		if err != nil {
+			return err
+		}
+		if d.arrayMode == arrayModeNext && c == ',' {
+			d.s.SkipPrefix(",")
+			d.arrayMode = arrayModeComma
+			if c, err = d.nextArrayChar(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case c == ']' && d.arrayMode != arrayModeComma:
+			d.s.SkipPrefix("]")
+			d.arrayMode = arrayModeEnd
+			return d.nextArrayElement()
+		case c == ']' || c == ',':
+			return errors.ErrInvalidCharacter(c, "array element", d.s.TotalOffset())
+		case d.arrayMode == arrayModeNext:
+			return errors.ErrExpected("comma or end of array in ArrayMode", d.s.TotalOffset())
+		}
+		d.arrayMode = arrayModeElement
+	case arrayModeEnd:
+		// the array is the whole input.
+		if err := d.s.ValidateEnd(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	return nil
+}
+
+// nextArrayChar returns the next character of the array in ArrayMode without consuming it.
+func (d *Decoder) nextArrayChar() (byte, error) {
+	c, err := d.s.NextChar()
+	if err == io.EOF {
+		return 0, errors.ErrUnexpectedEndOfJSON("array", d.s.TotalOffset())
+	}
+	return c, err
+}
+
+// endArrayElement records that the element of the array in ArrayMode is consumed.
+func (d *Decoder) endArrayElement() {
+	if d.arrayMode == arrayModeElement {
+		d.arrayMode = arrayModeNext
+	}
+}
+
+// CacheShapes causes the Decoder to cache the keys of the objects decoded into interface{}
+// across all the values it decodes. See the CacheShapes option for details.
+func (d *Decoder) CacheShapes() {
+	CacheShapes()(d.s.Option)
+}
+
+// UseRegistry causes the Decoder to decode with the decode functions registered in r.
+func (d *Decoder) UseRegistry(r *DecodeRegistry) {
+	WithDecodeRegistry(r)(d.s.Option)
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"io"
 	"math"
 	"math/big"
 	"net"
@@ -15,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 	"unsafe"
 
@@ -274,6 +276,1061 @@ func Test_Decoder_UseNumber(t *testing.T) {
 	assertEq(t, "json.Number", "json.Number", fmt.Sprintf("%T", v["a"]))
 }
 
+func Test_Decoder_UseRawNumber(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"a": 1.50e+10, "b": [12345678901234567890]}`))
+	dec.UseRawNumber()
+	var v map[string]interface{}
+	assertErr(t, dec.Decode(&v))
+	assertEq(t, "json.RawNumber", "decoder.RawNumber", fmt.Sprintf("%T", v["a"]))
+	assertEq(t, "raw bytes", "1.50e+10", v["a"].(json.RawNumber).String())
+	b, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "re-emission", `{"a":1.50e+10,"b":[12345678901234567890]}`, string(b))
+
+	var field struct {
+		N json.RawNumber `json:"n"`
+		M json.RawNumber `json:"m"`
+	}
+	assertErr(t, json.Unmarshal([]byte(`{"n": -0.0100, "m": null}`), &field))
+	assertEq(t, "field", "-0.0100", string(field.N))
+	assertEq(t, "null field", 0, len(field.M))
+	if err := json.Unmarshal([]byte(`{"n": true}`), &field); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// only the grammar of the number is checked, not its range.
+	const large = `{"n": 1e400, "m": 123456789012345678901234567890}`
+	assertErr(t, json.Unmarshal([]byte(large), &field))
+	assertEq(t, "out of float range", "1e400", string(field.N))
+	assertEq(t, "30 digits", "123456789012345678901234567890", string(field.M))
+	dec = json.NewDecoder(strings.NewReader(large))
+	dec.UseRawNumber()
+	assertErr(t, dec.Decode(&v))
+	assertEq(t, "stream out of float range", "1e400", v["n"].(json.RawNumber).String())
+	assertEq(t, "stream 30 digits", "123456789012345678901234567890", v["m"].(json.RawNumber).String())
+	for _, src := range []string{`01`, `1.`, `-`, `1e`, `1e+`, `.5`, `1.e5`} {
+		if err := json.Unmarshal([]byte(`{"n": `+src+`}`), &field); err == nil {
+			t.Errorf("%s: expected error", src)
+		}
+		dec := json.NewDecoder(strings.NewReader(`[` + src + `]`))
+		dec.UseRawNumber()
+		var values []interface{}
+		if err := dec.Decode(&values); err == nil {
+			t.Errorf("%s: expected stream error", src)
+		}
+	}
+}
+
+func Test_Decoder_TrustedInput(t *testing.T) {
+	src := "{\"A\": \"a\xffb\", \"B\": \"\u3042\", \"C\": 12.5}"
+	type T struct {
+		A string
+		B string
+		C float64
+	}
+	t.Run("default", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "replaced", "a\ufffdb", v.A)
+	})
+	t.Run("trusted", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&v, json.TrustedInput()))
+		assertEq(t, "passed through", "a\xffb", v.A)
+		assertEq(t, "multibyte", "\u3042", v.B)
+		assertEq(t, "number", 12.5, v.C)
+	})
+	t.Run("unmarshal", func(t *testing.T) {
+		var v struct {
+			N json.Number
+		}
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"N":1e3}`), &v, json.TrustedInput()))
+		assertEq(t, "number", json.Number("1e3"), v.N)
+	})
+}
+
+func Test_Decoder_SlicePolicy(t *testing.T) {
+	newBuf := func() []*int {
+		buf := make([]*int, 3, 4)
+		for i := range buf[:cap(buf)] {
+			n := i
+			buf[:cap(buf)][i] = &n
+		}
+		return buf
+	}
+	t.Run("reuse", func(t *testing.T) {
+		buf := newBuf()
+		v := buf[:1]
+		assertErr(t, json.Unmarshal([]byte(`[10, 20]`), &v))
+		assertEq(t, "length", 2, len(v))
+		assertEq(t, "shared", &buf[0], &v[0])
+		assertNeq(t, "tail", (*int)(nil), buf[:cap(buf)][3])
+	})
+	t.Run("reuse zero tail", func(t *testing.T) {
+		buf := newBuf()
+		v := buf
+		assertErr(t, json.UnmarshalWithOption([]byte(`[10]`), &v, json.DecodeSlicePolicy(json.SliceReuseZeroTail)))
+		assertEq(t, "length", 1, len(v))
+		assertEq(t, "value", 10, *v[0])
+		assertEq(t, "shared", &buf[0], &v[0])
+		for i, p := range buf[1:cap(buf)] {
+			assertEq(t, fmt.Sprintf("tail %d", i), (*int)(nil), p)
+		}
+	})
+	t.Run("reuse zero tail empty", func(t *testing.T) {
+		buf := newBuf()
+		v := buf
+		assertErr(t, json.NewDecoder(strings.NewReader(`[]`)).DecodeWithOption(&v, json.DecodeSlicePolicy(json.SliceReuseZeroTail)))
+		assertEq(t, "length", 0, len(v))
+		assertEq(t, "tail", (*int)(nil), buf[0])
+	})
+	t.Run("realloc", func(t *testing.T) {
+		buf := newBuf()
+		old := *buf[0]
+		v := buf
+		assertErr(t, json.NewDecoder(strings.NewReader(`[10, 20]`)).DecodeWithOption(&v, json.DecodeSlicePolicy(json.SliceRealloc)))
+		assertEq(t, "length", 2, len(v))
+		assertEq(t, "value", 20, *v[1])
+		assertNeq(t, "not shared", &buf[0], &v[0])
+		assertEq(t, "old element untouched", old, *buf[0])
+	})
+}
+
+func Test_Decoder_MapPolicy(t *testing.T) {
+	type T struct {
+		M map[string]int
+	}
+	t.Run("merge", func(t *testing.T) {
+		v := T{M: map[string]int{"a": 1, "b": 2}}
+		assertErr(t, json.Unmarshal([]byte(`{"M":{"b":3,"c":4}}`), &v))
+		assertEq(t, "map", fmt.Sprint(map[string]int{"a": 1, "b": 3, "c": 4}), fmt.Sprint(v.M))
+	})
+	t.Run("replace", func(t *testing.T) {
+		m := map[string]int{"a": 1, "b": 2}
+		v := T{M: m}
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"M":{"b":3,"c":4}}`), &v, json.DecodeMapPolicy(json.MapReplace)))
+		assertEq(t, "map", fmt.Sprint(map[string]int{"b": 3, "c": 4}), fmt.Sprint(v.M))
+		assertEq(t, "same map", fmt.Sprint(map[string]int{"b": 3, "c": 4}), fmt.Sprint(m))
+	})
+	t.Run("replace empty", func(t *testing.T) {
+		m := map[string]int{"a": 1}
+		assertErr(t, json.NewDecoder(strings.NewReader(`{}`)).DecodeWithOption(&m, json.DecodeMapPolicy(json.MapReplace)))
+		assertEq(t, "length", 0, len(m))
+	})
+}
+
+func Test_Decoder_AcceptSpecialFloats(t *testing.T) {
+	type T struct {
+		A float64
+		B float32
+		C *float64
+		D []float64
+	}
+	src := `{"A":"NaN", "B": "-Infinity" ,"C":Infinity,"D":[NaN,-Infinity,1.5,"Infinity"]}`
+	check := func(t *testing.T, v T) {
+		t.Helper()
+		assertEq(t, "A", true, math.IsNaN(v.A))
+		assertEq(t, "B", true, math.IsInf(float64(v.B), -1))
+		assertEq(t, "C", true, math.IsInf(*v.C, 1))
+		assertEq(t, "D length", 4, len(v.D))
+		assertEq(t, "D[0]", true, math.IsNaN(v.D[0]))
+		assertEq(t, "D[1]", true, math.IsInf(v.D[1], -1))
+		assertEq(t, "D[2]", 1.5, v.D[2])
+		assertEq(t, "D[3]", true, math.IsInf(v.D[3], 1))
+	}
+	t.Run("unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.AcceptSpecialFloats()))
+		check(t, v)
+	})
+	t.Run("stream", func(t *testing.T) {
+		var v T
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(src)))
+		assertErr(t, dec.DecodeWithOption(&v, json.AcceptSpecialFloats()))
+		check(t, v)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		for _, src := range []string{`{"A":"NaN"}`, `{"A":NaN}`, `{"A":-Infinity}`} {
+			var v T
+			if err := json.Unmarshal([]byte(src), &v); err == nil {
+				t.Errorf("expected error for %s", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+				t.Errorf("expected stream error for %s", src)
+			}
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, src := range []string{`{"A":"nan"}`, `{"A":NaNa}`, `{"A":"Infinity }`, `{"A":Inf}`} {
+			var v T
+			if err := json.UnmarshalWithOption([]byte(src), &v, json.AcceptSpecialFloats()); err == nil {
+				t.Errorf("expected error for %s", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&v, json.AcceptSpecialFloats()); err == nil {
+				t.Errorf("expected stream error for %s", src)
+			}
+		}
+	})
+}
+
+func Test_Decoder_AcceptInt64AsString(t *testing.T) {
+	type T struct {
+		A int64
+		B uint64
+		C *int64
+		D []int64
+		E int32
+		F uint32 `json:",bigstring"`
+		G *int64 `json:",bigstring"`
+		H int64  `json:",string"`
+	}
+	src := `{"A":"-9007199254740993", "B": "18446744073709551615" ,"C":"0","D":[1,"2"],"E":3,"F":"4","G":"5","H":"6"}`
+	check := func(t *testing.T, v T) {
+		t.Helper()
+		assertEq(t, "A", int64(-9007199254740993), v.A)
+		assertEq(t, "B", uint64(18446744073709551615), v.B)
+		assertEq(t, "C", int64(0), *v.C)
+		assertEq(t, "D", "[1 2]", fmt.Sprint(v.D))
+		assertEq(t, "E", int32(3), v.E)
+		assertEq(t, "F", uint32(4), v.F)
+		assertEq(t, "G", int64(5), *v.G)
+		assertEq(t, "H", int64(6), v.H)
+	}
+	t.Run("unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.AcceptInt64AsString()))
+		check(t, v)
+	})
+	t.Run("stream", func(t *testing.T) {
+		var v T
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(src)))
+		assertErr(t, dec.DecodeWithOption(&v, json.AcceptInt64AsString()))
+		check(t, v)
+	})
+	t.Run("tag", func(t *testing.T) {
+		for _, src := range []string{`{"F":"4","G":"5"}`, `{"F":4,"G":5}`} {
+			var v T
+			assertErr(t, json.Unmarshal([]byte(src), &v))
+			assertEq(t, "F", uint32(4), v.F)
+			assertEq(t, "G", int64(5), *v.G)
+
+			v = T{}
+			assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+			assertEq(t, "F", uint32(4), v.F)
+			assertEq(t, "G", int64(5), *v.G)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		for _, src := range []string{`{"A":"1"}`, `{"B":"1"}`, `{"D":["1"]}`} {
+			var v T
+			if err := json.Unmarshal([]byte(src), &v); err == nil {
+				t.Errorf("expected error for %s", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+				t.Errorf("expected stream error for %s", src)
+			}
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, src := range []string{`{"A":"1.5"}`, `{"A":"null"}`, `{"A":" 1"}`, `{"A":"1`, `{"B":"-1"}`, `{"E":"3"}`, `{"F":"x"}`} {
+			var v T
+			if err := json.UnmarshalWithOption([]byte(src), &v, json.AcceptInt64AsString()); err == nil {
+				t.Errorf("expected error for %s", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&v, json.AcceptInt64AsString()); err == nil {
+				t.Errorf("expected stream error for %s", src)
+			}
+		}
+	})
+}
+
+func Test_Decoder_CacheShapes(t *testing.T) {
+	srcs := []string{
+		`[{"a":1,"b":"x"},{"a":2,"b":"y"},{"a":3,"b":"z","c":null},{"a":4},{"b":5,"a":6},{"a":7,"b":8}]`,
+		`{"x":{"a":[{"k":1},{"k":2,"l":{}}]},"y":{"a":[]},"z":[{"\u0061":1,"b":2},{"a":3,"b":4}]}`,
+		`[{"a":1,"a":2},{"a":3},{},{"":true}]`,
+		`{"a": 1 , "b" : [ {"c" :2} ] }`,
+	}
+	for _, src := range srcs {
+		var want interface{}
+		assertErr(t, json.Unmarshal([]byte(src), &want))
+
+		var got interface{}
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &got, json.CacheShapes()))
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("unmarshal %s: got %v, want %v", src, got, want)
+		}
+
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(src + src)))
+		dec.CacheShapes()
+		for i := 0; i < 2; i++ {
+			var got interface{}
+			assertErr(t, dec.Decode(&got))
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("stream %s: got %v, want %v", src, got, want)
+			}
+		}
+	}
+	t.Run("invalid", func(t *testing.T) {
+		for _, src := range []string{`[{"a":1},{"a" 1}]`, `[{"a":1},{"a":1,}]`, `[{"a":1},{"a":1 "b":2}]`, `[{"a":1},{1:1}]`, `{"a":`} {
+			var v interface{}
+			if err := json.UnmarshalWithOption([]byte(src), &v, json.CacheShapes()); err == nil {
+				t.Errorf("expected error for %s", src)
+			}
+			dec := json.NewDecoder(strings.NewReader(src))
+			dec.CacheShapes()
+			if err := dec.Decode(&v); err == nil {
+				t.Errorf("expected stream error for %s", src)
+			}
+		}
+	})
+	t.Run("allocs", func(t *testing.T) {
+		var sb strings.Builder
+		sb.WriteString("[")
+		for i := 0; i < 100; i++ {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			fmt.Fprintf(&sb, `{"timestamp":%d,"host":"h","metric":"cpu","value":0.5,"region":"r","service":"s","unit":"%%"}`, i)
+		}
+		sb.WriteString("]")
+		src := []byte(sb.String())
+		allocs := func(optFuncs ...json.DecodeOptionFunc) float64 {
+			return testing.AllocsPerRun(10, func() {
+				var v interface{}
+				if err := json.UnmarshalWithOption(src, &v, optFuncs...); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+		if without, with := allocs(), allocs(json.CacheShapes()); with >= without {
+			t.Errorf("expected fewer allocations with the shape cache: %v without, %v with", without, with)
+		}
+	})
+}
+
+func TestReleaseValue(t *testing.T) {
+	src := `{"a":[1,{"b":"c"},[true,null]],"d":{"e":{}},"f":[]}`
+	var want interface{}
+	assertErr(t, json.Unmarshal([]byte(src), &want))
+	for i := 0; i < 3; i++ {
+		var v interface{}
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		if !reflect.DeepEqual(want, v) {
+			t.Fatalf("unmarshal: got %v, want %v", v, want)
+		}
+		json.ReleaseValue(v)
+		assertEq(t, "released map", 0, len(v.(map[string]interface{})))
+
+		dec := json.NewDecoder(strings.NewReader(src))
+		assertErr(t, dec.Decode(&v))
+		if !reflect.DeepEqual(want, v) {
+			t.Fatalf("stream: got %v, want %v", v, want)
+		}
+		json.ReleaseValue(v)
+
+		assertErr(t, json.UnmarshalWithOption([]byte(`[{"x":1},{"x":2}]`), &v, json.CacheShapes()))
+		assertEq(t, "shaped", "[map[x:1] map[x:2]]", fmt.Sprint(v))
+		json.ReleaseValue(v)
+	}
+	json.ReleaseValue(nil)
+	json.ReleaseValue("x")
+	json.ReleaseValue([]interface{}{})
+
+	allocs := func(release bool) float64 {
+		return testing.AllocsPerRun(10, func() {
+			var v interface{}
+			if err := json.Unmarshal([]byte(src), &v); err != nil {
+				t.Fatal(err)
+			}
+			if release {
+				json.ReleaseValue(v)
+			}
+		})
+	}
+	if without, with := allocs(false), allocs(true); with >= without {
+		t.Errorf("expected fewer allocations with ReleaseValue: %v without, %v with", without, with)
+	}
+}
+
+func Test_Decoder_MaxObjectKeys(t *testing.T) {
+	type T struct {
+		A int
+		M map[string]int
+	}
+	tests := []struct {
+		name        string
+		src         string
+		v           func() interface{}
+		perObject   int
+		perDocument int
+		document    bool
+		limit       int
+	}{
+		{
+			name:      "map",
+			src:       `{"a":1,"b":2,"c":3}`,
+			v:         func() interface{} { return &map[string]int{} },
+			perObject: 2,
+			limit:     2,
+		},
+		{
+			name:      "duplicate keys",
+			src:       `{"a":1,"a":2,"a":3}`,
+			v:         func() interface{} { return &map[string]int{} },
+			perObject: 2,
+			limit:     2,
+		},
+		{
+			name:      "interface",
+			src:       `[{"a":1},{"a":1,"b":2,"c":3}]`,
+			v:         func() interface{} { return new(interface{}) },
+			perObject: 2,
+			limit:     2,
+		},
+		{
+			name:      "struct",
+			src:       `{"A":1,"B":2,"C":3}`,
+			v:         func() interface{} { return &T{} },
+			perObject: 2,
+			limit:     2,
+		},
+		{
+			name:        "document",
+			src:         `{"A":1,"M":{"a":1,"b":2,"c":3}}`,
+			v:           func() interface{} { return &T{} },
+			perObject:   3,
+			perDocument: 4,
+			document:    true,
+			limit:       4,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			check := func(t *testing.T, err error) {
+				t.Helper()
+				var limitErr *json.KeyLimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("expected KeyLimitError, got %v", err)
+				}
+				assertEq(t, "document", tc.document, limitErr.Document)
+				assertEq(t, "limit", tc.limit, limitErr.Limit)
+			}
+			check(t, json.UnmarshalWithOption([]byte(tc.src), tc.v(), json.MaxObjectKeys(tc.perObject, tc.perDocument)))
+			check(t, json.UnmarshalWithOption([]byte(tc.src), tc.v(), json.MaxObjectKeys(tc.perObject, tc.perDocument), json.CacheShapes()))
+			check(t, json.NewDecoder(iotest.OneByteReader(strings.NewReader(tc.src))).
+				DecodeWithOption(tc.v(), json.MaxObjectKeys(tc.perObject, tc.perDocument)))
+
+			assertErr(t, json.UnmarshalWithOption([]byte(tc.src), tc.v(), json.MaxObjectKeys(tc.perObject+1, tc.perDocument*2)))
+			assertErr(t, json.UnmarshalWithOption([]byte(tc.src), tc.v(), json.MaxObjectKeys(0, 0)))
+			assertErr(t, json.Unmarshal([]byte(tc.src), tc.v()))
+		})
+	}
+	t.Run("stream values", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a":1,"b":2} {"c":3,"d":4} {"e":5,"f":6,"g":7}`))
+		for i := 0; i < 2; i++ {
+			var v map[string]int
+			assertErr(t, dec.DecodeWithOption(&v, json.MaxObjectKeys(0, 2)))
+		}
+		var v map[string]int
+		err := dec.Decode(&v)
+		var limitErr *json.KeyLimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("expected KeyLimitError, got %v", err)
+		}
+		assertEq(t, "message", "json: document has more than 2 object keys", err.Error())
+	})
+}
+
+func Test_Decoder_StrictRFC8259(t *testing.T) {
+	deep := strings.Repeat("[", json.StrictMaxDepth+1) + strings.Repeat("]", json.StrictMaxDepth+1)
+	invalid := []struct {
+		name string
+		src  string
+	}{
+		{name: "invalid UTF-8", src: "{\"a\":\"\xff\"}"},
+		{name: "invalid UTF-8 in key", src: "{\"\xc3\":1}"},
+		{name: "control character", src: "[\"a\tb\"]"},
+		{name: "duplicate key", src: `{"a":1,"b":{"a":2},"a":3}`},
+		{name: "escaped duplicate key", src: `{"a":1,"\u0061":2}`},
+		{name: "too deep", src: deep},
+		{name: "NaN", src: `{"f":NaN}`},
+		{name: "trailing data", src: `{"a":1} {}`},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			var v map[string]interface{}
+			err := json.UnmarshalWithOption([]byte(tc.src), &v, json.AcceptSpecialFloats(), json.StrictRFC8259())
+			var syntaxErr *json.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected SyntaxError, got %v", err)
+			}
+			if tc.name != "trailing data" {
+				assertEq(t, "untouched", true, v == nil)
+			}
+		})
+	}
+	t.Run("stream", func(t *testing.T) {
+		for _, tc := range invalid[:6] {
+			var v interface{}
+			err := json.NewDecoder(iotest.OneByteReader(strings.NewReader(tc.src))).DecodeWithOption(&v, json.StrictRFC8259())
+			assertNeq(t, tc.name, nil, err)
+			err = json.NewDecoderBytes([]byte(tc.src)).DecodeWithOption(&v, json.StrictRFC8259())
+			assertNeq(t, tc.name, nil, err)
+		}
+	})
+	t.Run("error message", func(t *testing.T) {
+		var v interface{}
+		err := json.UnmarshalWithOption([]byte(`{"a":1, "a":2}`), &v, json.StrictRFC8259())
+		assertEq(t, "message", `json: duplicate key "a"`, err.Error())
+	})
+	t.Run("valid", func(t *testing.T) {
+		src := `{"a":[1,{"a":"\u00e9\ud83d\ude00"}],"b":{"a":null,"b":true},"\"":"é","c":-1.5e3}`
+		var v, expected interface{}
+		assertErr(t, json.Unmarshal([]byte(src), &expected))
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.StrictRFC8259()))
+		assertEq(t, "value", fmt.Sprint(expected), fmt.Sprint(v))
+
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(src + "\n" + src)))
+		for i := 0; i < 2; i++ {
+			v = nil
+			assertErr(t, dec.DecodeWithOption(&v, json.StrictRFC8259()))
+			assertEq(t, "value", fmt.Sprint(expected), fmt.Sprint(v))
+		}
+	})
+	t.Run("syntax errors left to decoder", func(t *testing.T) {
+		var v interface{}
+		for _, src := range []string{`{"a":`, `{"a" 1}`, `["\u12"]`, `[1,]`} {
+			expected := json.Unmarshal([]byte(src), &v)
+			assertNeq(t, src, nil, expected)
+			err := json.UnmarshalWithOption([]byte(src), &v, json.StrictRFC8259())
+			assertEq(t, src, expected.Error(), err.Error())
+		}
+	})
+}
+
+func TestRejectDuplicateKeys(t *testing.T) {
+	type Inner struct {
+		A int `json:"a"`
+	}
+	type T struct {
+		Items []Inner `json:"items"`
+	}
+	src := `{"items":[{"a":1},{"a":2,"\u0061":3}]}`
+	for name, v := range map[string]interface{}{
+		"struct":    &T{},
+		"map":       &map[string][]map[string]int{},
+		"interface": new(interface{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := json.UnmarshalWithOption([]byte(src), v, json.RejectDuplicateKeys())
+			var dupErr *json.DuplicateKeyError
+			if !errors.As(err, &dupErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertEq(t, "key", "a", dupErr.Key)
+			assertEq(t, "path", "items.1.a", dupErr.Path)
+			assertEq(t, "offset", int64(strings.Index(src, `"\u0061`)), dupErr.Offset)
+			assertEq(t, "class", true, errors.Is(err, json.ErrDuplicateKey))
+			assertEq(t, "message", `json: duplicate key "a"`, err.Error())
+
+			err = json.NewDecoder(iotest.OneByteReader(strings.NewReader(src))).DecodeWithOption(v, json.RejectDuplicateKeys())
+			if !errors.As(err, &dupErr) {
+				t.Fatalf("unexpected stream error: %v", err)
+			}
+			assertEq(t, "stream path", "items.1.a", dupErr.Path)
+		})
+	}
+	t.Run("lenient", func(t *testing.T) {
+		// the keys of different objects and the invalid characters are not rejected.
+		var v interface{}
+		assertErr(t, json.UnmarshalWithOption([]byte("{\"a\":{\"a\":1},\"b\":[{\"a\":\"\t\xff\"}]}"), &v, json.RejectDuplicateKeys()))
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+	})
+}
+
+func Test_Decoder_ErrorClasses(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+	}
+	tests := []struct {
+		name  string
+		src   string
+		opts  []json.DecodeOptionFunc
+		class error
+	}{
+		{name: "trailing data", src: `{"a":1} x`, class: json.ErrTrailingData},
+		{name: "decoder depth", src: strings.Repeat("[", 10001) + strings.Repeat("]", 10001), class: json.ErrDepthExceeded},
+		{name: "duplicate key", src: `{"a":1,"a":2}`, opts: []json.DecodeOptionFunc{json.StrictRFC8259()}, class: json.ErrDuplicateKey},
+		{name: "long string", src: `{"a":"abcde"}`, opts: []json.DecodeOptionFunc{json.MaxStringLength(4)}, class: json.ErrStringTooLong},
+		{name: "long key", src: `{"abcde":1}`, opts: []json.DecodeOptionFunc{json.MaxStringLength(4)}, class: json.ErrStringTooLong},
+		{name: "unknown field", src: `{"b":1}`, opts: []json.DecodeOptionFunc{json.StrictPaths("")}, class: json.ErrUnknownField},
+	}
+	classes := []error{json.ErrTrailingData, json.ErrDepthExceeded, json.ErrDuplicateKey, json.ErrStringTooLong, json.ErrUnknownField}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var v interface{} = &T{}
+			if tc.name == "decoder depth" {
+				v = &[]interface{}{}
+			}
+			err := json.UnmarshalWithOption([]byte(tc.src), v, tc.opts...)
+			for _, class := range classes {
+				assertEq(t, fmt.Sprintf("%v of %v", class, err), class == tc.class, errors.Is(err, class))
+			}
+		})
+	}
+	t.Run("max depth of StrictRFC8259", func(t *testing.T) {
+		var v interface{}
+		deep := strings.Repeat("[", json.StrictMaxDepth+1) + strings.Repeat("]", json.StrictMaxDepth+1)
+		err := json.UnmarshalWithOption([]byte(deep), &v, json.StrictRFC8259())
+		assertEq(t, "depth", true, errors.Is(err, json.ErrDepthExceeded))
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a":1,"b":2}`))
+		dec.DisallowUnknownFields()
+		var v T
+		err := dec.Decode(&v)
+		var fieldErr *json.UnknownFieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("expected UnknownFieldError, got %v", err)
+		}
+		assertEq(t, "field", "b", fieldErr.Field)
+		assertEq(t, "message", `json: unknown field "b"`, err.Error())
+	})
+	t.Run("string length", func(t *testing.T) {
+		var v map[string]string
+		// the length is counted before the escape sequences are decoded.
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"a":"abcd"}`), &v, json.MaxStringLength(4)))
+		err := json.UnmarshalWithOption([]byte(`{"a":"\u0041"}`), &v, json.MaxStringLength(4))
+		assertEq(t, "escaped", true, errors.Is(err, json.ErrStringTooLong))
+		assertErr(t, json.UnmarshalWithOption([]byte("{\"a\":\"\xff\"}"), &v, json.MaxStringLength(4)))
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"a":"abcde"}`), &v, json.MaxStringLength(4), json.MaxStringLength(0)))
+
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(`"abc" "abcde"`)))
+		var s string
+		assertErr(t, dec.DecodeWithOption(&s, json.MaxStringLength(4)))
+		err = dec.DecodeWithOption(&s, json.MaxStringLength(4))
+		assertEq(t, "stream", true, errors.Is(err, json.ErrStringTooLong))
+		assertEq(t, "untouched", "abc", s)
+	})
+}
+
+func Test_Decoder_AllUnknownFields(t *testing.T) {
+	type Item struct {
+		Name string `json:"name"`
+	}
+	type Order struct {
+		ID    int             `json:"id"`
+		Items []Item          `json:"items"`
+		Meta  map[string]Item `json:"meta"`
+	}
+	src := `{"id":1,"note":"x","items":[{"name":"a"},{"name":"b","color":"red"}],"meta":{"k":{"size":2}},"extra":{}}`
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.DisallowUnknownFields()
+	var v Order
+	err := dec.Decode(&v)
+	var fieldsErr *json.UnknownFieldsError
+	if !errors.As(err, &fieldsErr) {
+		t.Fatalf("expected UnknownFieldsError, got %v", err)
+	}
+	paths := make([]string, len(fieldsErr.Fields))
+	for i, f := range fieldsErr.Fields {
+		paths[i] = f.Path
+	}
+	assertEq(t, "paths", "note items.1.color meta.k.size extra", strings.Join(paths, " "))
+	assertEq(t, "key", "color", fieldsErr.Fields[1].Field)
+	assertEq(t, "message", `json: unknown fields "note", "items.1.color", "meta.k.size", "extra"`, err.Error())
+	assertEq(t, "class", true, errors.Is(err, json.ErrUnknownField))
+	var fieldErr *json.UnknownFieldError
+	assertEq(t, "first", true, errors.As(err, &fieldErr) && fieldErr.Field == "note")
+	assertEq(t, "decoded", "b", v.Items[1].Name)
+
+	dec = json.NewDecoder(strings.NewReader(`{"id":1,"x":2} {"id":3}`))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&v)
+	assertEq(t, "single", true, errors.As(err, &fieldErr) && fieldErr.Path == "x")
+	assertErr(t, dec.Decode(&v))
+	assertEq(t, "next value", 3, v.ID)
+
+	err = json.UnmarshalWithOption([]byte(src), &v, json.StrictPaths("items", "meta"))
+	assertEq(t, "strict paths", `json: unknown fields "items.1.color", "meta.k.size"`, fmt.Sprint(err))
+}
+
+func Test_Decoder_UnknownFieldSuggestion(t *testing.T) {
+	type User struct {
+		UserID    int    `json:"user_id"`
+		FirstName string `json:"first_name"`
+		Email     string `json:"email"`
+		ID        int    `json:"id"`
+	}
+	tests := []struct {
+		key        string
+		suggestion string
+	}{
+		{key: "userId", suggestion: "user_id"},
+		{key: "first-name", suggestion: "first_name"},
+		{key: "emial", suggestion: "email"},
+		{key: "FirstNam", suggestion: "first_name"},
+		{key: "ip", suggestion: ""},
+		{key: "address", suggestion: ""},
+		{key: "email_address", suggestion: ""},
+		{key: strings.Repeat("x", 100000), suggestion: ""},
+	}
+	for _, tc := range tests {
+		dec := json.NewDecoder(strings.NewReader(`{"` + tc.key + `":1}`))
+		dec.DisallowUnknownFields()
+		var v User
+		err := dec.Decode(&v)
+		var fieldErr *json.UnknownFieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("expected UnknownFieldError, got %v", err)
+		}
+		assertEq(t, "suggestion of "+tc.key, tc.suggestion, fieldErr.Suggestion)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(`{"userId":1,"emial":"a"}`))
+	dec.DisallowUnknownFields()
+	var v User
+	assertEq(t, "message", `json: unknown fields "userId" (did you mean "user_id"?), "emial" (did you mean "email"?)`, fmt.Sprint(dec.Decode(&v)))
+	dec = json.NewDecoder(strings.NewReader(`{"userId":1}`))
+	dec.DisallowUnknownFields()
+	assertEq(t, "single message", `json: unknown field "userId"; did you mean "user_id"?`, fmt.Sprint(dec.Decode(&v)))
+}
+
+func Test_Decoder_ConfigFiles(t *testing.T) {
+	type Config struct {
+		Name  string   `json:"name"`
+		Ports []int    `json:"ports"`
+		Tags  []string `json:"tags"`
+	}
+	src := "\xef\xbb\xbf// service config\n" +
+		"{\n" +
+		"  \"name\": \"web // not a comment\", /* inline */\n" +
+		"  \"ports\": [80, 443,],\n" +
+		"  /* multi\n     line */\n" +
+		"  \"tags\": [\"a,]\", \"b\"], // trailing\n" +
+		"}\n"
+	expected := Config{Name: "web // not a comment", Ports: []int{80, 443}, Tags: []string{"a,]", "b"}}
+	t.Run("unmarshal", func(t *testing.T) {
+		var v Config
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.ConfigFiles()))
+		assertEq(t, "config", fmt.Sprint(expected), fmt.Sprint(v))
+		assertNeq(t, "standard", nil, json.Unmarshal([]byte(src), &v))
+	})
+	t.Run("decoder", func(t *testing.T) {
+		var v Config
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(src)))
+		assertErr(t, dec.DecodeWithOption(&v, json.ConfigFiles()))
+		assertEq(t, "config", fmt.Sprint(expected), fmt.Sprint(v))
+	})
+	t.Run("position", func(t *testing.T) {
+		bad := "{\n  // comment\n  \"name\": \"x\",\n  \"ports\": [1, \"2\"]\n}"
+		check := func(t *testing.T, err error) {
+			t.Helper()
+			var posErr *json.PositionError
+			if !errors.As(err, &posErr) {
+				t.Fatalf("expected PositionError, got %v", err)
+			}
+			assertEq(t, "line", 4, posErr.Line)
+			var typeErr *json.UnmarshalTypeError
+			assertEq(t, "unwrap", true, errors.As(err, &typeErr))
+		}
+		var v Config
+		err := json.UnmarshalWithOption([]byte(bad), &v, json.ConfigFiles())
+		check(t, err)
+		assertEq(t, "message", true, strings.HasPrefix(err.Error(), "json: line 4, column "))
+		check(t, json.NewDecoder(strings.NewReader(bad)).DecodeWithOption(&v, json.ConfigFiles()))
+
+		err = json.UnmarshalWithOption([]byte("{\n\"a\": 1\n} x"), &v, json.ConfigFiles())
+		var posErr *json.PositionError
+		if !errors.As(err, &posErr) {
+			t.Fatalf("expected PositionError, got %v", err)
+		}
+		assertEq(t, "line", 3, posErr.Line)
+	})
+}
+
+func Test_Decoder_ScalarOrArray(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+	type T struct {
+		Tags  []string `json:"tags,scalarOrArray"`
+		Nums  *[]int   `json:"nums,scalarOrArray"`
+		Items []*item  `json:"items,scalarOrArray"`
+		Plain []int    `json:"plain"`
+	}
+	tests := []struct {
+		input string
+		tags  []string
+		nums  []int
+		items []int
+	}{
+		{input: `{"tags":"a","nums":1,"items":{"id":1}}`, tags: []string{"a"}, nums: []int{1}, items: []int{1}},
+		{input: `{"tags":["a","b"],"nums":[1,2],"items":[{"id":1},{"id":2}]}`, tags: []string{"a", "b"}, nums: []int{1, 2}, items: []int{1, 2}},
+		{input: `{"tags":null,"nums":[],"items":[]}`, nums: []int{}, items: []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			check := func(t *testing.T, v T) {
+				assertEq(t, "tags", fmt.Sprint(test.tags), fmt.Sprint(v.Tags))
+				if v.Nums == nil {
+					t.Fatal("nums is nil")
+				}
+				assertEq(t, "nums", fmt.Sprint(test.nums), fmt.Sprint(*v.Nums))
+				assertEq(t, "items", len(test.items), len(v.Items))
+				for i, id := range test.items {
+					assertEq(t, "item", id, v.Items[i].ID)
+				}
+			}
+			var v T
+			assertErr(t, json.Unmarshal([]byte(test.input), &v))
+			check(t, v)
+			var sv T
+			assertErr(t, json.NewDecoder(strings.NewReader(test.input)).Decode(&sv))
+			check(t, sv)
+		})
+	}
+	t.Run("plain slice", func(t *testing.T) {
+		var v T
+		if err := json.Unmarshal([]byte(`{"plain":1}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("element type error", func(t *testing.T) {
+		var v T
+		err := json.Unmarshal([]byte(`{"tags":1}`), &v)
+		var terr *json.UnmarshalTypeError
+		if !errors.As(err, &terr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func Test_Decoder_NumberSlice(t *testing.T) {
+	tests := []struct {
+		input  string
+		floats []float64
+		ints   []int64
+	}{
+		{input: `[1,-2.5e3, 3e-2 ,4 ]`, floats: []float64{1, -2500, 0.03, 4}},
+		{input: ` [ 7 ] `, floats: []float64{7}, ints: []int64{7}},
+		{input: `[-9223372036854775808,9223372036854775807,0]`, ints: []int64{math.MinInt64, math.MaxInt64, 0}},
+		{input: `[]`, floats: []float64{}, ints: []int64{}},
+		{input: `[1,null,2]`, floats: []float64{1, 0, 2}, ints: []int64{1, 0, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			if test.floats != nil {
+				var v []float64
+				assertErr(t, json.Unmarshal([]byte(test.input), &v))
+				assertEq(t, "floats", fmt.Sprint(test.floats), fmt.Sprint(v))
+			}
+			if test.ints != nil {
+				var v []int64
+				assertErr(t, json.Unmarshal([]byte(test.input), &v))
+				assertEq(t, "ints", fmt.Sprint(test.ints), fmt.Sprint(v))
+			}
+		})
+	}
+	t.Run("reuse", func(t *testing.T) {
+		v := make([]float64, 1, 8)
+		backing := &v[:8][7]
+		assertErr(t, json.Unmarshal([]byte(`[1,2,3]`), &v))
+		assertEq(t, "values", "[1 2 3]", fmt.Sprint(v))
+		assertEq(t, "backing array", true, &v[:8][7] == backing)
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, input := range []string{`[1 2]`, `[1,,2]`, `[1,2,]`, `[1.5]`, `[1,2`, `[--1]`} {
+			v := []int64{42}
+			if err := json.Unmarshal([]byte(input), &v); err == nil {
+				t.Errorf("expected error for %s", input)
+			}
+			assertEq(t, "unchanged on error "+input, "[42]", fmt.Sprint(v))
+		}
+	})
+	t.Run("struct field", func(t *testing.T) {
+		var v struct {
+			Values []float64 `json:"values"`
+			Counts *[]int64  `json:"counts"`
+		}
+		input := `{"values":[0.5,1.5],"counts":[1,2,3]}`
+		assertErr(t, json.Unmarshal([]byte(input), &v))
+		assertEq(t, "values", "[0.5 1.5]", fmt.Sprint(v.Values))
+		assertEq(t, "counts", "[1 2 3]", fmt.Sprint(*v.Counts))
+	})
+}
+
+func Test_Decoder_PathModes(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	}
+	type Order struct {
+		Config   map[string]Item   `json:"config"`
+		Metadata map[string]int    `json:"metadata"`
+		Items    []Item            `json:"items"`
+		Vendor   *Item             `json:"vendor"`
+		Tags     [2]int            `json:"tags"`
+		Extra    map[string]string `json:"extra"`
+	}
+	decode := func(t *testing.T, input string, stream bool, optFuncs ...json.DecodeOptionFunc) (Order, error) {
+		t.Helper()
+		var v Order
+		if stream {
+			dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+			return v, dec.DecodeWithOption(&v, optFuncs...)
+		}
+		return v, json.UnmarshalWithOption([]byte(input), &v, optFuncs...)
+	}
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			t.Run("strict", func(t *testing.T) {
+				_, err := decode(t, `{"config":{"a":{"name":"x","colour":"red"}}}`, stream, json.StrictPaths("config.*"))
+				assertEq(t, "error", `json: unknown field "colour"`, fmt.Sprint(err))
+
+				v, err := decode(t, `{"vendor":{"name":"x","colour":"red"},"unknown":1}`, stream, json.StrictPaths("config.*"))
+				assertErr(t, err)
+				assertEq(t, "vendor", "x", v.Vendor.Name)
+
+				_, err = decode(t, `{"items":[{"name":"x"},{"name":"y","sku":1}]}`, stream, json.StrictPaths("items.*"))
+				assertEq(t, "items", `json: unknown field "sku"`, fmt.Sprint(err))
+			})
+			t.Run("lenient", func(t *testing.T) {
+				input := `{"metadata":{"a":1,"b":"two","c":3},"vendor":{"name":1,"price":2},"items":[{"name":"x","price":"1"},{"name":"y","price":2}],"tags":[1,"2"]}`
+				_, err := decode(t, input, stream)
+				assertNeq(t, "error", nil, err)
+
+				v, err := decode(t, input, stream, json.LenientPaths("metadata", "vendor.name", "items.*.price", "tags.1"))
+				assertErr(t, err)
+				assertEq(t, "metadata", "map[a:1 b:0 c:3]", fmt.Sprint(v.Metadata))
+				assertEq(t, "vendor", "{ 2}", fmt.Sprint(*v.Vendor))
+				assertEq(t, "items", "[{x 0} {y 2}]", fmt.Sprint(v.Items))
+				assertEq(t, "tags", "[1 0]", fmt.Sprint(v.Tags))
+
+				_, err = decode(t, input, stream, json.LenientPaths("metadata", "vendor"))
+				var typeErr *json.UnmarshalTypeError
+				assertEq(t, "items error", true, errors.As(err, &typeErr))
+			})
+			t.Run("nested", func(t *testing.T) {
+				input := `{"config":{"a":{"name":"x","price":"1"},"b":{"name":"y","sku":1}}}`
+				v, err := decode(t, input, stream, json.LenientPaths("config"))
+				assertErr(t, err)
+				assertEq(t, "config", "{y 0}", fmt.Sprint(v.Config["b"]))
+
+				_, err = decode(t, input, stream, json.LenientPaths("config"), json.StrictPaths("config.b"))
+				assertEq(t, "strict in lenient", `json: unknown field "sku"`, fmt.Sprint(err))
+
+				_, err = decode(t, input, stream, json.LenientPaths("config"), json.StrictPaths("config.a.price"))
+				var typeErr *json.UnmarshalTypeError
+				assertEq(t, "type error in strict", true, errors.As(err, &typeErr))
+			})
+		})
+	}
+	t.Run("decoder", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"extra":{"a":"b"},"sku":1} {"vendor":{"sku":1}}`))
+		dec.DisallowUnknownFields()
+		var v Order
+		assertErr(t, dec.DecodeWithOption(&v, json.LenientPaths("sku")))
+		assertEq(t, "extra", "b", v.Extra["a"])
+		assertEq(t, "unknown field", `json: unknown field "sku"`, fmt.Sprint(dec.DecodeWithOption(&v, json.LenientPaths("sku"))))
+	})
+}
+
+func Test_Decoder_ArrayMode(t *testing.T) {
+	type record struct {
+		ID int `json:"id"`
+	}
+	t.Run("elements", func(t *testing.T) {
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(` [ {"id":1} , {"id":2},{"id":3} ] `)))
+		dec.ArrayMode()
+		var ids []int
+		for dec.More() {
+			var v record
+			assertErr(t, dec.Decode(&v))
+			ids = append(ids, v.ID)
+		}
+		assertEq(t, "ids", "[1 2 3]", fmt.Sprint(ids))
+		var v record
+		assertEq(t, "end", io.EOF, dec.Decode(&v))
+		assertEq(t, "more", false, dec.More())
+	})
+	t.Run("decode until EOF", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[1,"a"]`))
+		dec.ArrayMode()
+		var values []interface{}
+		for {
+			var v interface{}
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			assertErr(t, err)
+			values = append(values, v)
+		}
+		assertEq(t, "values", "[1 a]", fmt.Sprint(values))
+	})
+	t.Run("empty", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[]`))
+		dec.ArrayMode()
+		assertEq(t, "more", false, dec.More())
+		var v interface{}
+		assertEq(t, "end", io.EOF, dec.Decode(&v))
+	})
+	t.Run("not array", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"id":1}`))
+		dec.ArrayMode()
+		var v record
+		err := dec.Decode(&v)
+		var syntaxErr *json.SyntaxError
+		assertEq(t, "syntax error", true, errors.As(err, &syntaxErr))
+	})
+	t.Run("unterminated", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[{"id":1}`))
+		dec.ArrayMode()
+		var v record
+		assertErr(t, dec.Decode(&v))
+		err := dec.Decode(&v)
+		var syntaxErr *json.SyntaxError
+		assertEq(t, "syntax error", true, errors.As(err, &syntaxErr))
+	})
+	t.Run("malformed", func(t *testing.T) {
+		for _, src := range []string{
+			`[1 2]`,
+			`[1,]`,
+			`[,1]`,
+			`[1,,2]`,
+			`[1,2] garbage`,
+			`[1,2] [3]`,
+		} {
+			dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(src)))
+			dec.ArrayMode()
+			var err error
+			for err == nil && dec.More() {
+				var v int
+				err = dec.Decode(&v)
+			}
+			var syntaxErr *json.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Errorf("%s: unexpected error: %v", src, err)
+			}
+		}
+	})
+	t.Run("whitespace after array", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader("[1, 2]\n \n"))
+		dec.ArrayMode()
+		var values []int
+		for dec.More() {
+			var v int
+			assertErr(t, dec.Decode(&v))
+			values = append(values, v)
+		}
+		assertEq(t, "values", "[1 2]", fmt.Sprint(values))
+		var v int
+		assertEq(t, "end", io.EOF, dec.Decode(&v))
+	})
+}
+
 func Test_Decoder_DisallowUnknownFields(t *testing.T) {
 	dec := json.NewDecoder(strings.NewReader(`{"x": 1}`))
 	dec.DisallowUnknownFields()
@@ -3696,6 +4753,58 @@ func TestDecodeContextOption(t *testing.T) {
 			t.Fatal("failed to decode with context")
 		}
 	})
+	t.Run("UnmarshalContext with options", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), unmarshalContextKey{}, "hello")
+		var v struct {
+			A unmarshalContextStructType `json:"a"`
+		}
+		err := json.UnmarshalContext(ctx, []byte(`{"a":10,"b":1}`), &v, json.StrictPaths("*"))
+		if !errors.Is(err, json.ErrUnknownField) {
+			t.Fatalf("expected the unknown field error: %v", err)
+		}
+	})
+	t.Run("DecodeContext is not kept for the next value", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), unmarshalContextKey{}, "hello")
+		dec := json.NewDecoder(strings.NewReader("10 10"))
+		var v unmarshalContextStructType
+		assertErr(t, dec.DecodeContext(ctx, &v))
+		assertEq(t, "with context", 100, v.v)
+		if err := dec.Decode(&v); err == nil {
+			t.Fatal("expected the error of the UnmarshalJSON without the context value")
+		}
+	})
+	t.Run("Unmarshal without context", func(t *testing.T) {
+		var v unmarshalContextStructType
+		if err := json.Unmarshal(src, &v); err == nil {
+			t.Fatal("expected the error of the UnmarshalJSON without the context value")
+		}
+	})
+}
+
+func TestUnmarshalFirst(t *testing.T) {
+	data := []byte(` {"a": "x\ny"}  [1, 2]
+"s"`)
+	var m map[string]string
+	rest, err := json.UnmarshalFirst(data, &m)
+	assertErr(t, err)
+	assertEq(t, "first", "x\ny", m["a"])
+	assertEq(t, "rest", "[1, 2]\n\"s\"", string(rest))
+
+	var a []int
+	rest, err = json.UnmarshalFirst(rest, &a)
+	assertErr(t, err)
+	assertEq(t, "second", 2, len(a))
+	assertEq(t, "rest", `"s"`, string(rest))
+
+	var s string
+	rest, err = json.UnmarshalFirst(rest, &s)
+	assertErr(t, err)
+	assertEq(t, "third", "s", s)
+	assertEq(t, "rest", 0, len(rest))
+
+	if _, err := json.UnmarshalFirst([]byte(`{"a":`), &m); err == nil {
+		t.Fatal("expected error")
+	}
 }
 
 func TestIssue251(t *testing.T) {
@@ -4057,3 +5166,312 @@ func TestIssue429(t *testing.T) {
 		}
 	}
 }
+
+func TestAliasRawMessages(t *testing.T) {
+	data := []byte(`{"a":{"x":[1,2]},"b":"A","c":null}`)
+	t.Run("map values", func(t *testing.T) {
+		var v map[string]json.RawMessage
+		assertErr(t, json.UnmarshalWithOption(data, &v, json.AliasRawMessages()))
+		assertEq(t, "a", `{"x":[1,2]}`, string(v["a"]))
+		assertEq(t, "b", `"A"`, string(v["b"]))
+		assertEq(t, "c", `null`, string(v["c"]))
+		// the values are slices of a single copy of the input, so they are as far apart as in the input.
+		distance := uintptr(unsafe.Pointer(&v["b"][0])) - uintptr(unsafe.Pointer(&v["a"][0]))
+		assertEq(t, "shared buffer", uintptr(bytes.Index(data, []byte(`"A"`))-bytes.Index(data, []byte(`{"x"`))), distance)
+		assertEq(t, "no spare capacity", len(v["a"]), cap(v["a"]))
+		a := append(v["a"], 'x')
+		assertEq(t, "append does not overwrite", `"A"`, string(v["b"]))
+		assertEq(t, "appended", `{"x":[1,2]}x`, string(a))
+	})
+	t.Run("decoder", func(t *testing.T) {
+		for _, dec := range []*json.Decoder{
+			json.NewDecoder(bytes.NewReader(append(data, data...))),
+			json.NewDecoder(iotest.OneByteReader(bytes.NewReader(append(data, data...)))),
+		} {
+			var first, second map[string]json.RawMessage
+			assertErr(t, dec.DecodeWithOption(&first, json.AliasRawMessages()))
+			assertErr(t, dec.DecodeWithOption(&second, json.AliasRawMessages()))
+			assertEq(t, "first", `{"x":[1,2]}`, string(first["a"]))
+			assertEq(t, "second", `{"x":[1,2]}`, string(second["a"]))
+		}
+	})
+	t.Run("struct fields", func(t *testing.T) {
+		var v struct {
+			A json.RawMessage  `json:"a"`
+			B *json.RawMessage `json:"b"`
+			C *json.RawMessage `json:"c"`
+		}
+		assertErr(t, json.UnmarshalWithOption(data, &v, json.AliasRawMessages()))
+		assertEq(t, "a", `{"x":[1,2]}`, string(v.A))
+		assertEq(t, "b", `"A"`, string(*v.B))
+		assertEq(t, "c", true, v.C == nil)
+	})
+}
+
+func TestDisallowUnknownFieldsOption(t *testing.T) {
+	type inner struct {
+		B int `json:"b"`
+	}
+	type T struct {
+		A   int   `json:"a"`
+		In  inner `json:"in"`
+		Ext inner `json:"ext"`
+	}
+	src := []byte(`{"a":1,"x":2,"in":{"b":1,"y":3},"ext":{"z":4}}`)
+	opt := json.DecodeOptions(json.DisallowUnknownFields(), json.LenientPaths("ext"))
+
+	var v T
+	err := json.UnmarshalWithOption(src, &v, opt)
+	var fieldsErr *json.UnknownFieldsError
+	if !errors.As(err, &fieldsErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEq(t, "unknown fields", 2, len(fieldsErr.Fields))
+	assertEq(t, "first path", "x", fieldsErr.Fields[0].Path)
+	assertEq(t, "second path", "in.y", fieldsErr.Fields[1].Path)
+	assertEq(t, "decoded", T{A: 1, In: inner{B: 1}}, v)
+
+	var s T
+	err = json.NewDecoder(bytes.NewReader(src)).DecodeWithOption(&s, opt)
+	if !errors.As(err, &fieldsErr) {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	assertEq(t, "stream unknown fields", 2, len(fieldsErr.Fields))
+
+	assertErr(t, json.UnmarshalWithOption([]byte(`{"a":1,"in":{"b":2}}`), &v, opt))
+	assertErr(t, json.Unmarshal(src, &v))
+}
+
+type strictWrapper struct {
+	inner struct {
+		B int `json:"b"`
+	}
+}
+
+func (w *strictWrapper) UnmarshalJSON(ctx context.Context, b []byte) error {
+	return json.UnmarshalContext(ctx, b, &w.inner)
+}
+
+type lenientWrapper struct {
+	inner struct {
+		B int `json:"b"`
+	}
+}
+
+func (w *lenientWrapper) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &w.inner)
+}
+
+func TestDisallowUnknownFieldsNested(t *testing.T) {
+	type T struct {
+		W strictWrapper `json:"w"`
+	}
+	src := []byte(`{"w":{"b":1,"y":2}}`)
+	var v T
+	assertErr(t, json.Unmarshal(src, &v))
+	assertEq(t, "lenient", 1, v.W.inner.B)
+
+	err := json.UnmarshalWithOption(src, &v, json.DisallowUnknownFields())
+	if !errors.Is(err, json.ErrUnknownField) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); !errors.Is(err, json.ErrUnknownField) {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	// the option is added to a context of the caller.
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+	err = json.UnmarshalContext(ctx, src, &v, json.DisallowUnknownFields())
+	if !errors.Is(err, json.ErrUnknownField) {
+		t.Fatalf("unexpected context error: %v", err)
+	}
+	// an Unmarshaler has no context, so its nested decode is not strict.
+	var l struct {
+		W lenientWrapper `json:"w"`
+	}
+	assertErr(t, json.UnmarshalWithOption(src, &l, json.DisallowUnknownFields()))
+	assertEq(t, "unmarshaler", 1, l.W.inner.B)
+}
+
+func TestSizeHints(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`{"items":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"k%d":[%d,%d]}`, i, i, i+1)
+	}
+	b.WriteString(`]}`)
+	src := []byte(b.String())
+
+	type T struct {
+		Items []map[string][]int `json:"items"`
+	}
+	var expected T
+	assertErr(t, json.Unmarshal(src, &expected))
+	for _, opt := range []json.DecodeOptionFunc{
+		json.WithSliceCapacityHint(10),
+		json.WithMapSizeHint(10),
+		json.PresizeContainers(),
+		json.DecodeOptions(json.PresizeContainers(), json.WithSliceCapacityHint(1)),
+	} {
+		var v T
+		assertErr(t, json.UnmarshalWithOption(src, &v, opt))
+		assertEq(t, "decoded", true, reflect.DeepEqual(expected, v))
+		var s T
+		assertErr(t, json.NewDecoder(bytes.NewReader(src)).DecodeWithOption(&s, opt))
+		assertEq(t, "stream decoded", true, reflect.DeepEqual(expected, s))
+	}
+
+	m := []byte(strings.TrimSuffix(strings.Replace(b.String()[len(`{"items":[`):], "},{", ",", -1), "]}"))
+	allocs := func(optFuncs ...json.DecodeOptionFunc) float64 {
+		return testing.AllocsPerRun(10, func() {
+			var v map[string][]int
+			if err := json.UnmarshalWithOption(m, &v, optFuncs...); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	base := allocs()
+	if hinted := allocs(json.WithMapSizeHint(1000)); hinted >= base {
+		t.Errorf("expected fewer allocations with the map size hint: %v >= %v", hinted, base)
+	}
+	if presized := allocs(json.PresizeContainers()); presized >= base {
+		t.Errorf("expected fewer allocations with the presized containers: %v >= %v", presized, base)
+	}
+}
+
+func TestDecodeBackendStructuralIndex(t *testing.T) {
+	type item struct {
+		ID   int             `json:"id"`
+		Raw  json.RawMessage `json:"raw"`
+		Tags []string        `json:"tags"`
+	}
+	// the strings have escaped quotes and backslashes across the 64-byte blocks of the first pass.
+	var b strings.Builder
+	b.WriteString(`[`)
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		pad := strings.Repeat(`\\`, i%7) + strings.Repeat("x", i%64)
+		fmt.Fprintf(&b, `{"id":%d,"skip":{"s":"%s\"}]","n":[[%d],{"a":"{["}]},"raw":{"k":["%s\\"]},"tags":["%s"]}`, i, pad, i, pad, pad)
+	}
+	b.WriteString(`]`)
+	src := []byte(b.String())
+
+	var expected []item
+	assertErr(t, json.Unmarshal(src, &expected))
+	var actual []item
+	assertErr(t, json.UnmarshalWithOption(src, &actual, json.DecodeBackend(json.BackendStructuralIndex)))
+	assertEq(t, "decoded", true, reflect.DeepEqual(expected, actual))
+
+	var presized []map[string]interface{}
+	assertErr(t, json.UnmarshalWithOption(src, &presized, json.DecodeBackend(json.BackendStructuralIndex), json.PresizeContainers()))
+	assertEq(t, "presized", len(expected), len(presized))
+
+	for _, invalid := range []string{
+		`{"id":1,"skip":{"a":[}]}`,
+		`{"id":1,"skip":{"a":"b}`,
+		`{"id":1,"skip":{"a":"\"}`,
+		`{"id":1,"skip":{"a":1}`,
+		`{"id":1,"skip":{"a":1}}}`,
+		"{\"id\":1,\"skip\":{\"a\":\"\x00\"}}",
+		`{"id":1,"skip":{"a":tru}}`,
+	} {
+		var v1, v2 item
+		err1 := json.Unmarshal([]byte(invalid), &v1)
+		err2 := json.UnmarshalWithOption([]byte(invalid), &v2, json.DecodeBackend(json.BackendStructuralIndex))
+		assertEq(t, "error of "+invalid, fmt.Sprint(err1), fmt.Sprint(err2))
+		assertEq(t, "value of "+invalid, true, reflect.DeepEqual(v1, v2))
+	}
+}
+
+func TestRequiredFields(t *testing.T) {
+	type Base struct {
+		ID int `json:"id,required"`
+	}
+	type Item struct {
+		SKU string `json:"sku,required"`
+	}
+	type Order struct {
+		*Base
+		Name  string `json:"name,required"`
+		Email string `json:"email,required"`
+		Note  string `json:"note"`
+		Items []Item `json:"items"`
+	}
+	decoders := map[string]func(src string, v interface{}) error{
+		"bytes": func(src string, v interface{}) error {
+			return json.Unmarshal([]byte(src), v)
+		},
+		"stream": func(src string, v interface{}) error {
+			return json.NewDecoder(strings.NewReader(src)).Decode(v)
+		},
+	}
+	for name, decode := range decoders {
+		decode := decode
+		t.Run(name, func(t *testing.T) {
+			t.Run("present", func(t *testing.T) {
+				var v Order
+				assertErr(t, decode(`{"ID":1,"name":"a","email":null,"items":[{"sku":"x"}]}`, &v))
+				assertEq(t, "id", 1, v.ID)
+				assertEq(t, "name", "a", v.Name)
+			})
+			t.Run("missing", func(t *testing.T) {
+				src := `{"note":"n","email":"e"}`
+				var v Order
+				err := decode(src, &v)
+				var missingErr *json.MissingFieldError
+				if !errors.As(err, &missingErr) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				assertEq(t, "fields", "[id name]", fmt.Sprint(missingErr.Fields))
+				assertEq(t, "type", reflect.TypeOf(Order{}), missingErr.Type)
+				assertEq(t, "offset", int64(len(src)), missingErr.Offset)
+				assertEq(t, "message", `json: missing required fields "id", "name" of Go struct json_test.Order`, err.Error())
+				assertEq(t, "is", true, errors.Is(err, json.ErrMissingField))
+			})
+			t.Run("empty object", func(t *testing.T) {
+				var v Item
+				err := decode(`{}`, &v)
+				assertEq(t, "message", `json: missing required field "sku" of Go struct json_test.Item`, fmt.Sprint(err))
+			})
+			t.Run("nested", func(t *testing.T) {
+				var v Order
+				err := decode(`{"id":1,"name":"a","email":"e","items":[{"sku":"x"},{"note":"y"}]}`, &v)
+				var missingErr *json.MissingFieldError
+				if !errors.As(err, &missingErr) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				assertEq(t, "type", reflect.TypeOf(Item{}), missingErr.Type)
+			})
+			t.Run("null", func(t *testing.T) {
+				var v *Item
+				assertErr(t, decode(`null`, &v))
+				var items []Item
+				assertErr(t, decode(`[null]`, &items))
+			})
+		})
+	}
+	t.Run("first win", func(t *testing.T) {
+		var v Item
+		err := json.UnmarshalWithOption([]byte(`{"note":1}`), &v, json.DecodeFieldPriorityFirstWin())
+		assertEq(t, "is", true, errors.Is(err, json.ErrMissingField))
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"sku":"a","sku":"b"}`), &v, json.DecodeFieldPriorityFirstWin()))
+		assertEq(t, "sku", "a", v.SKU)
+	})
+	t.Run("encode", func(t *testing.T) {
+		b, err := json.Marshal(Item{SKU: "a"})
+		assertErr(t, err)
+		assertEq(t, "encoding", `{"sku":"a"}`, string(b))
+	})
+	t.Run("plan", func(t *testing.T) {
+		if plan := json.Plan(Item{}); !strings.Contains(plan, `field "sku" tagged required`) {
+			t.Fatalf("unexpected plan %s", plan)
+		}
+	})
+}
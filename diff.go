@@ -0,0 +1,73 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/going/json/internal/diff"
+	"github.com/going/json/internal/encoder"
+)
+
+// DiffColorized returns a unified line diff between the JSON values a and b.
+// The values are compared semantically: both are reformatted with sorted object keys and a two-space indentation before diffing,
+// so differences in key order or whitespace are not reported. Numbers are compared by their literal text.
+// Removed lines start with "-" and added lines start with "+".
+// Tokens are colored by scheme and the markers by red and green, unless scheme is nil.
+// It returns an empty string if a and b are equal.
+func DiffColorized(a, b []byte, scheme *ColorScheme) (string, error) {
+	na, err := normalizeForDiff(a)
+	if err != nil {
+		return "", err
+	}
+	nb, err := normalizeForDiff(b)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(na, nb) {
+		return "", nil
+	}
+	edits := diff.Lines(strings.Split(string(na), "\n"), strings.Split(string(nb), "\n"))
+	var buf []byte
+	for _, edit := range edits {
+		if scheme == nil {
+			buf = append(buf, byte(edit.Op), ' ')
+			buf = append(buf, edit.Line...)
+			buf = append(buf, '\n')
+			continue
+		}
+		switch edit.Op {
+		case diff.Delete:
+			buf = append(buf, wrapColor(fgRedColor)...)
+			buf = append(buf, '-')
+			buf = append(buf, resetColor()...)
+		case diff.Insert:
+			buf = append(buf, wrapColor(fgGreenColor)...)
+			buf = append(buf, '+')
+			buf = append(buf, resetColor()...)
+		default:
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, ' ')
+		buf = encoder.AppendColorized(buf, []byte(edit.Line), scheme)
+		buf = append(buf, '\n')
+	}
+	return string(buf), nil
+}
+
+func normalizeForDiff(data []byte) ([]byte, error) {
+	dec := NewDecoderBytes(data)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(new(interface{})); err != io.EOF {
+		if err == nil {
+			return nil, errors.New("json: multiple JSON values in diff input")
+		}
+		return nil, err
+	}
+	return MarshalIndentWithOption(v, "", "  ", DisableHTMLEscape())
+}
@@ -0,0 +1,42 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestDiffColorized(t *testing.T) {
+	t.Run("equal", func(t *testing.T) {
+		got, err := json.DiffColorized([]byte(`{"a":1,"b":[true]}`), []byte(`{ "b": [true], "a": 1 }`), json.DefaultColorScheme)
+		assertErr(t, err)
+		assertEq(t, "diff", "", got)
+	})
+	t.Run("plain", func(t *testing.T) {
+		got, err := json.DiffColorized([]byte(`{"a":1,"b":[1,2]}`), []byte(`{"b":[1,3],"a":1}`), nil)
+		assertErr(t, err)
+		want := `  {
+    "a": 1,
+    "b": [
+      1,
+-     2
++     3
+    ]
+  }
+`
+		assertEq(t, "diff", want, got)
+	})
+	t.Run("colorized", func(t *testing.T) {
+		got, err := json.DiffColorized([]byte(`{"a":"x"}`), []byte(`{"a":null}`), json.DefaultColorScheme)
+		assertErr(t, err)
+		want := "  {\n" +
+			"\x1b[31m-\x1b[0m   \x1b[96m\"a\"\x1b[0m: \x1b[92m\"x\"\x1b[0m\n" +
+			"\x1b[32m+\x1b[0m   \x1b[96m\"a\"\x1b[0m: \x1b[34mnull\x1b[0m\n" +
+			"  }\n"
+		assertEq(t, "diff", want, got)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := json.DiffColorized([]byte(`{`), []byte(`{}`), nil)
+		assertNeq(t, "error", nil, err)
+	})
+}
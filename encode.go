@@ -2,15 +2,18 @@ package json
 
 import (
 	"context"
+	"errors"
+	"hash"
 	"io"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/going/json/internal/encoder"
 	"github.com/going/json/internal/encoder/vm"
-	"github.com/going/json/internal/encoder/vm_color"
-	"github.com/going/json/internal/encoder/vm_color_indent"
-	"github.com/going/json/internal/encoder/vm_indent"
+	"github.com/going/json/internal/runtime"
 )
 
 // An Encoder writes JSON values to an output stream.
@@ -18,8 +21,33 @@ type Encoder struct {
 	w                 io.Writer
 	enabledIndent     bool
 	enabledHTMLEscape bool
+	unorderedMap      bool
 	prefix            string
 	indentStr         string
+	flushSize         int
+	relaxed           bool
+	xssiPrefix        string
+	middleware        []EncodeMiddleware
+	stats             EncoderStats
+	hash              hash.Hash
+	bufferStats       func(BufferStats)
+	tokens            *tokenWriter
+}
+
+// XSSIPrefix is the prefix that Google-style APIs put before their JSON responses
+// to prevent them from being executed as scripts by other sites ( cross-site script inclusion ).
+// See Encoder.SetXSSIPrefix and Decoder.StripXSSIPrefix.
+const XSSIPrefix = ")]}'\n"
+
+// EncoderStats holds the throughput statistics of an Encoder.
+type EncoderStats struct {
+	// Values is the number of values encoded.
+	Values int64
+	// Bytes is the number of bytes written to the underlying writer, including comments.
+	Bytes int64
+	// MaxWrite is the largest number of bytes written to the underlying writer at once,
+	// which is the high-water mark of the encode buffer. It is bounded by SetFlushSize.
+	MaxWrite int
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -59,13 +87,69 @@ func (e *Encoder) EncodeContext(ctx context.Context, v interface{}, optFuncs ...
 }
 
 func (e *Encoder) encodeWithOption(ctx *encoder.RuntimeContext, v interface{}, optFuncs ...EncodeOptionFunc) error {
-	if e.enabledHTMLEscape {
-		ctx.Option.Flag |= encoder.HTMLEscapeOption
+	if e.tokens != nil && len(e.tokens.scopes) > 0 {
+		return errInsideTokens
 	}
-	ctx.Option.Flag |= encoder.NormalizeUTF8Option
-	ctx.Option.DebugOut = os.Stdout
-	for _, optFunc := range optFuncs {
-		optFunc(ctx.Option)
+	initialCap := cap(ctx.Buf)
+	buf, err := e.encodeLine(ctx, v, optFuncs...)
+	if err != nil {
+		return err
+	}
+	if e.bufferStats != nil {
+		e.bufferStats(newBufferStats(initialCap, ctx.Buf))
+	}
+	if _, err := e.write(buf); err != nil {
+		return err
+	}
+	e.stats.Values++
+	return nil
+}
+
+// write writes b to the underlying writer and records it in the statistics.
+// The prefix set by SetXSSIPrefix is written before the first bytes.
+func (e *Encoder) write(b []byte) (int, error) {
+	if e.stats.Bytes == 0 && e.xssiPrefix != "" {
+		n, err := io.WriteString(e.w, e.xssiPrefix)
+		e.stats.Bytes += int64(n)
+		if e.hash != nil {
+			io.WriteString(e.hash, e.xssiPrefix[:n])
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := e.w.Write(b)
+	e.stats.Bytes += int64(n)
+	if e.hash != nil {
+		e.hash.Write(b[:n])
+	}
+	if len(b) > e.stats.MaxWrite {
+		e.stats.MaxWrite = len(b)
+	}
+	return n, err
+}
+
+// encoderWriter is the writer that an Encoder passes to flush the encode buffer.
+type encoderWriter Encoder
+
+func (w *encoderWriter) Write(b []byte) (int, error) {
+	return (*Encoder)(w).write(b)
+}
+
+// Stats returns the throughput statistics of the values written so far.
+func (e *Encoder) Stats() EncoderStats {
+	return e.stats
+}
+
+// encodeLine returns the encoded v followed by a newline character in the buffer of ctx.
+func (e *Encoder) encodeLine(ctx *encoder.RuntimeContext, v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	e.applyOption(ctx, optFuncs)
+	if len(e.middleware) > 0 {
+		return e.encodeLineWithMiddleware(ctx, v)
+	}
+	if e.flushSize > 0 {
+		ctx.FlushWriter = (*encoderWriter)(e)
+		ctx.FlushSize = e.flushSize
 	}
 	var (
 		buf []byte
@@ -77,18 +161,29 @@ func (e *Encoder) encodeWithOption(ctx *encoder.RuntimeContext, v interface{}, o
 		buf, err = encode(ctx, v)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if e.enabledIndent {
 		buf = buf[:len(buf)-2]
 	} else {
 		buf = buf[:len(buf)-1]
 	}
-	buf = append(buf, '\n')
-	if _, err := e.w.Write(buf); err != nil {
-		return err
+	return append(buf, '\n'), nil
+}
+
+// applyOption sets the options of ctx from the settings of the encoder and optFuncs.
+func (e *Encoder) applyOption(ctx *encoder.RuntimeContext, optFuncs []EncodeOptionFunc) {
+	if e.enabledHTMLEscape {
+		ctx.Option.Flag |= encoder.HTMLEscapeOption
+	}
+	if e.unorderedMap {
+		ctx.Option.Flag |= encoder.UnorderedMapOption
+	}
+	ctx.Option.Flag |= encoder.NormalizeUTF8Option
+	ctx.Option.DebugOut = os.Stdout
+	for _, optFunc := range optFuncs {
+		optFunc(ctx.Option)
 	}
-	return nil
 }
 
 // SetEscapeHTML specifies whether problematic HTML characters should be escaped inside JSON quoted strings.
@@ -111,6 +206,212 @@ func (e *Encoder) SetIndent(prefix, indent string) {
 	e.enabledIndent = true
 }
 
+// SetUnorderedMap specifies whether maps are encoded in the iteration order of their entries, as UnorderedMap,
+// instead of the order of their keys. It skips sorting the keys, which dominates the encoding of large maps,
+// at the cost of an output that differs between encodes of the same map.
+func (e *Encoder) SetUnorderedMap(on bool) {
+	e.unorderedMap = on
+}
+
+// SetXSSIPrefix makes the encoder write prefix, such as XSSIPrefix, before the first value,
+// so that the output cannot be executed as a script by other sites.
+// It must be called before anything is written. Calling SetXSSIPrefix("") disables the prefix.
+func (e *Encoder) SetXSSIPrefix(prefix string) {
+	e.xssiPrefix = prefix
+}
+
+// SetRelaxed enables the relaxed ( JSONC ) output mode, which allows WriteComment to annotate the stream.
+// The output is no longer standard JSON once a comment has been written.
+func (e *Encoder) SetRelaxed(on bool) {
+	e.relaxed = on
+}
+
+// WriteComment writes text as a line comment, attached before the next encoded value.
+// Each line of text becomes a separate "//" comment, preceded by the prefix given to SetIndent.
+// It returns an error unless the relaxed mode is enabled by SetRelaxed.
+func (e *Encoder) WriteComment(text string) error {
+	if !e.relaxed {
+		return errors.New("json: WriteComment requires relaxed mode ( see Encoder.SetRelaxed )")
+	}
+	var buf []byte
+	for _, line := range strings.Split(text, "\n") {
+		if e.enabledIndent {
+			buf = append(buf, e.prefix...)
+		}
+		buf = append(buf, "//"...)
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			buf = append(buf, ' ')
+			buf = append(buf, line...)
+		}
+		buf = append(buf, '\n')
+	}
+	_, err := e.write(buf)
+	return err
+}
+
+// SetHash makes the encoder write the bytes written to the underlying writer to h as well,
+// so that the digest of the output, such as for a Digest or Content-MD5 header, is computed while streaming it
+// without buffering or encoding it twice:
+//
+//	h := sha256.New()
+//	enc.SetHash(h)
+//	if err := enc.Encode(v); err != nil { ... }
+//	digest := h.Sum(nil)
+//
+// h receives only the bytes actually written, including the XSSI prefix and the comments.
+// SetHash(nil) detaches the hash.
+func (e *Encoder) SetHash(h hash.Hash) {
+	e.hash = h
+}
+
+// SetBufferStatsFunc makes the encoder call fn with the growth of its encode buffer after each value is encoded,
+// to choose the buffer sizes fitting the values of an application ( see SetMaxRetainedBufferCap ).
+// The buffers are taken from an internal pool, so InitialCap depends on the values encoded before.
+// SetBufferStatsFunc(nil) disables the reports, which is the default.
+func (e *Encoder) SetBufferStatsFunc(fn func(BufferStats)) {
+	e.bufferStats = fn
+}
+
+// SetFlushSize makes the encoder write the encoded bytes to the underlying writer
+// each time about n bytes have accumulated, instead of holding the entire value in memory.
+// It bounds the memory used to encode very large arrays and unordered maps ( see UnorderedMap ).
+// The contents of a sorted map are written only after the whole map has been encoded.
+// If an error occurs, a part of the value may already have been written.
+// n <= 0 disables flushing, which is the default.
+func (e *Encoder) SetFlushSize(n int) {
+	if n > 0 && n < encoder.MinFlushSize {
+		n = encoder.MinFlushSize
+	}
+	e.flushSize = n
+}
+
+// SyncEncoder is an encoder whose methods are safe for concurrent use by multiple goroutines,
+// such as a log sink shared by many goroutines that emit NDJSON records to one writer.
+// Each value is encoded into its own buffer without holding the lock,
+// and written to the underlying writer with a single Write call, so that the values are never interleaved.
+type SyncEncoder struct {
+	mu  sync.Mutex
+	enc Encoder
+}
+
+// NewSyncEncoder returns a new encoder that writes to w and is safe for concurrent use.
+func NewSyncEncoder(w io.Writer) *SyncEncoder {
+	return &SyncEncoder{enc: Encoder{w: w, enabledHTMLEscape: true}}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline character.
+func (e *SyncEncoder) Encode(v interface{}) error {
+	return e.EncodeWithOption(v)
+}
+
+// EncodeWithOption call Encode with EncodeOption.
+func (e *SyncEncoder) EncodeWithOption(v interface{}, optFuncs ...EncodeOptionFunc) error {
+	ctx := encoder.TakeRuntimeContext()
+	ctx.Option.Flag = 0
+
+	err := e.encodeWithOption(ctx, v, optFuncs...)
+
+	encoder.ReleaseRuntimeContext(ctx)
+	return err
+}
+
+// EncodeContext call Encode with context.Context and EncodeOption.
+func (e *SyncEncoder) EncodeContext(ctx context.Context, v interface{}, optFuncs ...EncodeOptionFunc) error {
+	rctx := encoder.TakeRuntimeContext()
+	rctx.Option.Flag = 0
+	rctx.Option.Flag |= encoder.ContextOption
+	rctx.Option.Context = ctx
+
+	err := e.encodeWithOption(rctx, v, optFuncs...) //nolint: contextcheck
+
+	encoder.ReleaseRuntimeContext(rctx)
+	return err
+}
+
+func (e *SyncEncoder) encodeWithOption(ctx *encoder.RuntimeContext, v interface{}, optFuncs ...EncodeOptionFunc) error {
+	e.mu.Lock()
+	enc := e.enc
+	e.mu.Unlock()
+
+	initialCap := cap(ctx.Buf)
+	buf, err := enc.encodeLine(ctx, v, optFuncs...)
+	if err != nil {
+		return err
+	}
+	if enc.bufferStats != nil {
+		enc.bufferStats(newBufferStats(initialCap, ctx.Buf))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.enc.write(buf); err != nil {
+		return err
+	}
+	e.enc.stats.Values++
+	return nil
+}
+
+// Stats returns the throughput statistics of the values written so far.
+func (e *SyncEncoder) Stats() EncoderStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.stats
+}
+
+// SetEscapeHTML is like Encoder.SetEscapeHTML. It applies to the values encoded after it returns.
+func (e *SyncEncoder) SetEscapeHTML(on bool) {
+	e.mu.Lock()
+	e.enc.SetEscapeHTML(on)
+	e.mu.Unlock()
+}
+
+// SetIndent is like Encoder.SetIndent. It applies to the values encoded after it returns.
+func (e *SyncEncoder) SetIndent(prefix, indent string) {
+	e.mu.Lock()
+	e.enc.SetIndent(prefix, indent)
+	e.mu.Unlock()
+}
+
+// SetUnorderedMap is like Encoder.SetUnorderedMap. It applies to the values encoded after it returns.
+func (e *SyncEncoder) SetUnorderedMap(on bool) {
+	e.mu.Lock()
+	e.enc.SetUnorderedMap(on)
+	e.mu.Unlock()
+}
+
+// SetXSSIPrefix is like Encoder.SetXSSIPrefix.
+func (e *SyncEncoder) SetXSSIPrefix(prefix string) {
+	e.mu.Lock()
+	e.enc.SetXSSIPrefix(prefix)
+	e.mu.Unlock()
+}
+
+// SetBufferStatsFunc is like Encoder.SetBufferStatsFunc. fn may be called concurrently.
+func (e *SyncEncoder) SetBufferStatsFunc(fn func(BufferStats)) {
+	e.mu.Lock()
+	e.enc.SetBufferStatsFunc(fn)
+	e.mu.Unlock()
+}
+
+// SetHash is like Encoder.SetHash. h is written while the lock is held.
+func (e *SyncEncoder) SetHash(h hash.Hash) {
+	e.mu.Lock()
+	e.enc.SetHash(h)
+	e.mu.Unlock()
+}
+
+// RegisterZeroChecker registers isZero as the emptiness predicate of T used by the omitempty and omitzero tag options,
+// so that a struct field of type T with either option is omitted if isZero reports true ( e.g. for uuid.Nil or a blank string type ).
+// A field with the omitzero option is also omitted if its value is zero.
+// It applies also to types that omitempty otherwise never omits, such as structs.
+// It must be called before a type that contains T is encoded for the first time, typically from an init function,
+// since the encoding of a type is compiled only once.
+func RegisterZeroChecker[T any](isZero func(T) bool) {
+	typ := runtime.Type2RType(reflect.TypeOf((*T)(nil)).Elem())
+	encoder.RegisterZeroChecker(typ, func(p unsafe.Pointer) bool {
+		return isZero(*(*T)(p))
+	})
+}
+
 func marshalContext(ctx context.Context, v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
 	rctx := encoder.TakeRuntimeContext()
 	rctx.Option.Flag = 0
@@ -165,6 +466,49 @@ func marshal(v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
 	return copied, nil
 }
 
+func marshalAppend(dst []byte, v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	ctx := encoder.TakeRuntimeContext()
+
+	ctx.Option.Flag = 0
+	ctx.Option.Flag |= (encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option)
+	for _, optFunc := range optFuncs {
+		optFunc(ctx.Option)
+	}
+
+	// the value is encoded in the spare capacity of dst, so that it is neither copied nor takes a pooled buffer.
+	buf, err := appendEncode(ctx, dst, v)
+	encoder.ReleaseRuntimeContext(ctx)
+	if err != nil {
+		return dst, err
+	}
+	// remove the trailing comma.
+	return buf[:len(buf)-1], nil
+}
+
+func marshalAll[T any](values []T, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	ctx := encoder.TakeRuntimeContext()
+
+	var dst []byte
+	for i := range values {
+		ctx.Option.Flag = 0
+		ctx.Option.Flag |= (encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option)
+		for _, optFunc := range optFuncs {
+			optFunc(ctx.Option)
+		}
+		buf, err := encode(ctx, values[i])
+		if err != nil {
+			encoder.ReleaseRuntimeContext(ctx)
+			return nil, err
+		}
+		// replace the trailing comma by a newline.
+		dst = append(dst, buf[:len(buf)-1]...)
+		dst = append(dst, '\n')
+	}
+
+	encoder.ReleaseRuntimeContext(ctx)
+	return dst, nil
+}
+
 func marshalNoEscape(v interface{}) ([]byte, error) {
 	ctx := encoder.TakeRuntimeContext()
 
@@ -213,7 +557,16 @@ func marshalIndent(v interface{}, prefix, indent string, optFuncs ...EncodeOptio
 }
 
 func encode(ctx *encoder.RuntimeContext, v interface{}) ([]byte, error) {
-	b := ctx.Buf[:0]
+	buf, err := appendEncode(ctx, ctx.Buf[:0], v)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Buf = buf
+	return buf, nil
+}
+
+// appendEncode appends the JSON encoding of v followed by a comma to b.
+func appendEncode(ctx *encoder.RuntimeContext, b []byte, v interface{}) ([]byte, error) {
 	if v == nil {
 		b = encoder.AppendNull(ctx, b)
 		b = encoder.AppendComma(ctx, b)
@@ -225,7 +578,7 @@ func encode(ctx *encoder.RuntimeContext, v interface{}) ([]byte, error) {
 	typeptr := uintptr(unsafe.Pointer(typ))
 	codeSet, err := encoder.CompileToGetCodeSet(ctx, typeptr)
 	if err != nil {
-		return nil, err
+		return nil, encoder.ErrWithUnsupportedPath(ctx, v, err)
 	}
 
 	p := uintptr(header.ptr)
@@ -234,9 +587,11 @@ func encode(ctx *encoder.RuntimeContext, v interface{}) ([]byte, error) {
 
 	buf, err := encodeRunCode(ctx, b, codeSet)
 	if err != nil {
-		return nil, err
+		return nil, encoder.ErrWithUnsupportedPath(ctx, v, err)
+	}
+	if (ctx.Option.Flag & encoder.AuditOption) != 0 {
+		encoder.Audit(ctx, v)
 	}
-	ctx.Buf = buf
 	return buf, nil
 }
 
@@ -253,14 +608,14 @@ func encodeNoEscape(ctx *encoder.RuntimeContext, v interface{}) ([]byte, error)
 	typeptr := uintptr(unsafe.Pointer(typ))
 	codeSet, err := encoder.CompileToGetCodeSet(ctx, typeptr)
 	if err != nil {
-		return nil, err
+		return nil, encoder.ErrWithUnsupportedPath(ctx, v, err)
 	}
 
 	p := uintptr(header.ptr)
 	ctx.Init(p, codeSet.CodeLength)
 	buf, err := encodeRunCode(ctx, b, codeSet)
 	if err != nil {
-		return nil, err
+		return nil, encoder.ErrWithUnsupportedPath(ctx, v, err)
 	}
 
 	ctx.Buf = buf
@@ -280,7 +635,7 @@ func encodeIndent(ctx *encoder.RuntimeContext, v interface{}, prefix, indent str
 	typeptr := uintptr(unsafe.Pointer(typ))
 	codeSet, err := encoder.CompileToGetCodeSet(ctx, typeptr)
 	if err != nil {
-		return nil, err
+		return nil, encoder.ErrWithUnsupportedPath(ctx, v, err)
 	}
 
 	p := uintptr(header.ptr)
@@ -290,7 +645,10 @@ func encodeIndent(ctx *encoder.RuntimeContext, v interface{}, prefix, indent str
 	ctx.KeepRefs = append(ctx.KeepRefs, header.ptr)
 
 	if err != nil {
-		return nil, err
+		return nil, encoder.ErrWithUnsupportedPath(ctx, v, err)
+	}
+	if (ctx.Option.Flag & encoder.AuditOption) != 0 {
+		encoder.Audit(ctx, v)
 	}
 
 	ctx.Buf = buf
@@ -298,14 +656,29 @@ func encodeIndent(ctx *encoder.RuntimeContext, v interface{}, prefix, indent str
 }
 
 func encodeRunCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
-	if (ctx.Option.Flag & encoder.DebugOption) != 0 {
-		if (ctx.Option.Flag & encoder.ColorizeOption) != 0 {
-			return vm_color.DebugRun(ctx, b, codeSet)
+	if (ctx.Option.Flag & encoder.RedactOption) != 0 {
+		// the result is rewritten as a whole, so it must not be flushed halfway.
+		ctx.FlushWriter = nil
+		start := len(b)
+		buf, err := encodeRunValueCode(ctx, b, codeSet)
+		if err != nil {
+			return nil, err
 		}
-		return vm.DebugRun(ctx, b, codeSet)
+		return redactEncoded(ctx, buf, start)
 	}
+	return encodeRunValueCode(ctx, b, codeSet)
+}
+
+func encodeRunValueCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
 	if (ctx.Option.Flag & encoder.ColorizeOption) != 0 {
-		return vm_color.Run(ctx, b, codeSet)
+		return encodeRunColorCode(ctx, b, codeSet)
+	}
+	return encodeRunPlainCode(ctx, b, codeSet)
+}
+
+func encodeRunPlainCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
+	if (ctx.Option.Flag & encoder.DebugOption) != 0 {
+		return vm.DebugRun(ctx, b, codeSet)
 	}
 	return vm.Run(ctx, b, codeSet)
 }
@@ -313,14 +686,28 @@ func encodeRunCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.Opcod
 func encodeRunIndentCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, prefix, indent string) ([]byte, error) {
 	ctx.Prefix = []byte(prefix)
 	ctx.IndentStr = []byte(indent)
-	if (ctx.Option.Flag & encoder.DebugOption) != 0 {
-		if (ctx.Option.Flag & encoder.ColorizeOption) != 0 {
-			return vm_color_indent.DebugRun(ctx, b, codeSet)
+	if (ctx.Option.Flag & encoder.RedactOption) != 0 {
+		ctx.FlushWriter = nil
+		start := len(b)
+		buf, err := encodeRunValueIndentCode(ctx, b, codeSet)
+		if err != nil {
+			return nil, err
 		}
-		return vm_indent.DebugRun(ctx, b, codeSet)
+		return redactEncoded(ctx, buf, start)
 	}
+	return encodeRunValueIndentCode(ctx, b, codeSet)
+}
+
+func encodeRunValueIndentCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
 	if (ctx.Option.Flag & encoder.ColorizeOption) != 0 {
-		return vm_color_indent.Run(ctx, b, codeSet)
+		return encodeRunColorIndentCode(ctx, b, codeSet)
 	}
-	return vm_indent.Run(ctx, b, codeSet)
+	return encodeRunPlainIndentCode(ctx, b, codeSet)
+}
+
+// redactEncoded replaces the values at the paths of RedactOption in the value encoded from buf[start:].
+func redactEncoded(ctx *encoder.RuntimeContext, buf []byte, start int) ([]byte, error) {
+	encoded := make([]byte, len(buf)-start)
+	copy(encoded, buf[start:])
+	return encoder.Redact(buf[:start], encoded, ctx.Option.RedactPaths)
 }
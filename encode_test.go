@@ -11,6 +11,7 @@ import (
 	"log"
 	"math"
 	"math/big"
+	"math/rand"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -848,6 +849,145 @@ func Test_MarshalerError(t *testing.T) {
 	assertEq(t, "marshaler error", expect, fmt.Sprint(err))
 }
 
+type invalidMarshaler struct{}
+
+func (invalidMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":[1,2}`), nil
+}
+
+func TestInvalidMarshalerOutput(t *testing.T) {
+	v := map[string]interface{}{
+		"x": []interface{}{1, struct{ F invalidMarshaler }{}},
+	}
+	t.Run("marshal", func(t *testing.T) {
+		_, err := json.Marshal(v)
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "path", "$.x[1].F", merr.Path)
+		var serr *json.SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("marshal indent", func(t *testing.T) {
+		_, err := json.MarshalIndent(struct{ A []invalidMarshaler }{A: make([]invalidMarshaler, 3)}, "", "  ")
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "path", "$.A[0]", merr.Path)
+	})
+	t.Run("message", func(t *testing.T) {
+		_, err := json.Marshal(struct{ F invalidMarshaler }{})
+		expect := `json: error calling MarshalJSON for type json_test.invalidMarshaler at $.F: expected comma after array value ( offset 9 of the output )`
+		assertEq(t, "error", expect, fmt.Sprint(err))
+	})
+}
+
+func TestCompactMarshalerOutput(t *testing.T) {
+	v := struct {
+		Raw  json.RawMessage `json:"raw"`
+		List []int           `json:"list"`
+	}{Raw: json.RawMessage(`{"a": [1, 2]}`), List: []int{3}}
+	b, err := json.MarshalIndentWithOption(v, "", "  ", json.CompactMarshalerOutput())
+	assertErr(t, err)
+	if !json.IndentVMsCompiledOut {
+		assertEq(t, "compacted", "{\n  \"raw\": {\"a\":[1,2]},\n  \"list\": [\n    3\n  ]\n}", string(b))
+	}
+
+	b, err = json.MarshalIndent(v, "", "  ")
+	assertErr(t, err)
+	assertEq(t, "re-indented", "{\n  \"raw\": {\n    \"a\": [\n      1,\n      2\n    ]\n  },\n  \"list\": [\n    3\n  ]\n}", string(b))
+
+	b, err = json.MarshalWithOption(v, json.CompactMarshalerOutput())
+	assertErr(t, err)
+	assertEq(t, "compact", `{"raw":{"a":[1,2]},"list":[3]}`, string(b))
+
+	_, err = json.MarshalIndentWithOption(struct{ F invalidMarshaler }{}, "", "  ", json.CompactMarshalerOutput())
+	var serr *json.SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+var errPriceUnavailable = errors.New("price unavailable")
+
+type failingPrice struct{}
+
+func (failingPrice) MarshalJSON() ([]byte, error) {
+	return nil, errPriceUnavailable
+}
+
+type failingText struct{}
+
+func (failingText) MarshalText() ([]byte, error) {
+	return nil, errPriceUnavailable
+}
+
+type wrappingMarshaler struct {
+	V interface{}
+}
+
+func (m wrappingMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.V)
+}
+
+func TestMarshalerErrorPath(t *testing.T) {
+	type item struct {
+		Name  string       `json:"name"`
+		Price failingPrice `json:"price"`
+	}
+	v := map[string]interface{}{
+		"items": []interface{}{1, item{Name: "a"}},
+	}
+	t.Run("marshal", func(t *testing.T) {
+		_, err := json.Marshal(v)
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "path", "$.items[1].price", merr.Path)
+		assertEq(t, "is", true, errors.Is(err, errPriceUnavailable))
+		expect := `json: error calling MarshalJSON for type json_test.failingPrice at $.items[1].price: price unavailable`
+		assertEq(t, "error", expect, err.Error())
+	})
+	t.Run("marshal indent", func(t *testing.T) {
+		_, err := json.MarshalIndent(v, "", "  ")
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "path", "$.items[1].price", merr.Path)
+	})
+	t.Run("marshal text", func(t *testing.T) {
+		_, err := json.Marshal(struct{ T []failingText }{T: make([]failingText, 1)})
+		expect := `json: error calling MarshalText for type json_test.failingText at $.T[0]: price unavailable`
+		assertEq(t, "error", expect, fmt.Sprint(err))
+		assertEq(t, "is", true, errors.Is(err, errPriceUnavailable))
+	})
+	t.Run("nested marshaler", func(t *testing.T) {
+		_, err := json.Marshal(struct{ W wrappingMarshaler }{W: wrappingMarshaler{V: []failingPrice{{}}}})
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "outer path", "$.W", merr.Path)
+		var inner *json.MarshalerError
+		if !errors.As(merr.Err, &inner) {
+			t.Fatalf("unexpected error: %v", merr.Err)
+		}
+		assertEq(t, "inner path", "$[0]", inner.Path)
+		assertEq(t, "is", true, errors.Is(err, errPriceUnavailable))
+	})
+	t.Run("root", func(t *testing.T) {
+		_, err := json.Marshal(failingPrice{})
+		expect := `json: error calling MarshalJSON for type json_test.failingPrice: price unavailable`
+		assertEq(t, "error", expect, fmt.Sprint(err))
+	})
+}
+
 // Ref has Marshaler and Unmarshaler methods with pointer receiver.
 type Ref int
 
@@ -916,6 +1056,192 @@ func TestRefValMarshal(t *testing.T) {
 	}
 }
 
+func TestFloatExponentThreshold(t *testing.T) {
+	type T struct {
+		A float64   `json:"a"`
+		B float32   `json:"b"`
+		C []float64 `json:"c"`
+		D *float64  `json:"d"`
+	}
+	f := -1.5e21
+	v := T{A: 1e21, B: 1e21, C: []float64{123456, 1e7, 1e-7}, D: &f}
+	tests := []struct {
+		name    string
+		optFunc json.EncodeOptionFunc
+		want    string
+	}{
+		{
+			name: "default",
+			want: `{"a":1e+21,"b":1e+21,"c":[123456,10000000,1e-07],"d":-1.5e+21}`,
+		},
+		{
+			name:    "lower",
+			optFunc: json.FloatExponentThreshold(6),
+			want:    `{"a":1e+21,"b":1e+21,"c":[123456,1e+07,1e-07],"d":-1.5e+21}`,
+		},
+		{
+			name:    "higher",
+			optFunc: json.FloatExponentThreshold(22),
+			want:    `{"a":1000000000000000000000,"b":1000000000000000000000,"c":[123456,10000000,1e-07],"d":-1500000000000000000000}`,
+		},
+		{
+			name:    "plain",
+			optFunc: json.FloatExponentThreshold(0),
+			want:    `{"a":1000000000000000000000,"b":1000000000000000000000,"c":[123456,10000000,0.0000001],"d":-1500000000000000000000}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var optFuncs []json.EncodeOptionFunc
+			if tc.optFunc != nil {
+				optFuncs = append(optFuncs, tc.optFunc)
+			}
+			got, err := json.MarshalWithOption(v, optFuncs...)
+			assertErr(t, err)
+			assertEq(t, "struct", tc.want, string(got))
+
+			got, err = json.MarshalWithOption(map[string]interface{}{"a": v.A}, optFuncs...)
+			assertErr(t, err)
+			assertEq(t, "interface", tc.want[:strings.Index(tc.want, ",")]+"}", string(got))
+		})
+	}
+}
+
+func TestInt64AsString(t *testing.T) {
+	type T struct {
+		A int64            `json:"a"`
+		B uint64           `json:"b"`
+		C *int64           `json:"c"`
+		D []int64          `json:"d"`
+		E map[string]int64 `json:"e"`
+		F int32            `json:"f"`
+		G int64            `json:"g,string"`
+		H interface{}      `json:"h"`
+		I int64            `json:"i,bigstring"`
+		J uint64           `json:"j,bigstring"`
+		K *int64           `json:"k,bigstring"`
+		L int64            `json:"l,bigstring,omitempty"`
+	}
+	big := int64(-1 << 53)
+	v := T{
+		A: 1, B: 2, C: &big, D: []int64{3}, E: map[string]int64{"x": 4}, F: 5, G: 6, H: int64(7),
+		I: 1<<53 - 1, J: 1 << 53, K: &big,
+	}
+	tests := []struct {
+		name     string
+		optFuncs []json.EncodeOptionFunc
+		want     string
+	}{
+		{
+			name: "default",
+			want: `{"a":1,"b":2,"c":-9007199254740992,"d":[3],"e":{"x":4},"f":5,"g":"6","h":7,"i":9007199254740991,"j":"9007199254740992","k":"-9007199254740992"}`,
+		},
+		{
+			name:     "Int64AsString",
+			optFuncs: []json.EncodeOptionFunc{json.Int64AsString()},
+			want:     `{"a":"1","b":"2","c":"-9007199254740992","d":["3"],"e":{"x":"4"},"f":5,"g":"6","h":"7","i":"9007199254740991","j":"9007199254740992","k":"-9007199254740992"}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.MarshalWithOption(v, tc.optFuncs...)
+			assertErr(t, err)
+			assertEq(t, "struct", tc.want, string(got))
+
+			got, err = json.MarshalIndentWithOption(&v, "", "", tc.optFuncs...)
+			assertErr(t, err)
+			assertEq(t, "indent", tc.want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(got)))
+
+			var decoded T
+			assertErr(t, json.UnmarshalWithOption([]byte(tc.want), &decoded, json.AcceptInt64AsString()))
+			assertEq(t, "round trip", fmt.Sprint(v.A, v.B, *v.C, v.D, v.E, v.I, v.J, *v.K), fmt.Sprint(decoded.A, decoded.B, *decoded.C, decoded.D, decoded.E, decoded.I, decoded.J, *decoded.K))
+		})
+	}
+}
+
+func TestWithTimeLocation(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tm := time.Date(2024, 1, 2, 9, 4, 5, 0, jst)
+	type T struct {
+		A time.Time            `json:"a"`
+		B *time.Time           `json:"b"`
+		C *time.Time           `json:"c"`
+		D interface{}          `json:"d"`
+		E map[time.Time]string `json:"e"`
+		F []time.Time          `json:"f"`
+	}
+	v := T{A: tm, B: &tm, D: tm, E: map[time.Time]string{tm: "x"}, F: []time.Time{tm}}
+	const utc = `"2024-01-02T00:04:05Z"`
+	want := `{"a":` + utc + `,"b":` + utc + `,"c":null,"d":` + utc + `,"e":{` + utc + `:"x"},"f":[` + utc + `]}`
+
+	got, err := json.MarshalWithOption(v, json.TimesInUTC())
+	assertErr(t, err)
+	assertEq(t, "utc", want, string(got))
+
+	got, err = json.MarshalIndentWithOption(v, "", "", json.TimesInUTC())
+	assertErr(t, err)
+	assertEq(t, "indent", want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(got)))
+
+	got, err = json.MarshalWithOption(tm, json.WithTimeLocation(time.FixedZone("", -5*60*60)))
+	assertErr(t, err)
+	assertEq(t, "location", `"2024-01-01T19:04:05-05:00"`, string(got))
+
+	got, err = json.Marshal(v.A)
+	assertErr(t, err)
+	assertEq(t, "default", `"2024-01-02T09:04:05+09:00"`, string(got))
+	assertEq(t, "original", jst, tm.Location())
+}
+
+func TestUsePointerReceiverMarshalers(t *testing.T) {
+	s := struct {
+		R0 Ref
+		R2 RefText
+		V0 Val
+	}{R0: 12, R2: 14, V0: 13}
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    string
+		wantStd string
+	}{
+		{
+			name:    "struct value",
+			v:       s,
+			want:    `{"R0":"ref","R2":"\"ref\"","V0":"val"}`,
+			wantStd: `{"R0":12,"R2":14,"V0":"val"}`,
+		},
+		{
+			name:    "map value",
+			v:       map[string]Ref{"a": 1},
+			want:    `{"a":"ref"}`,
+			wantStd: `{"a":1}`,
+		},
+		{
+			name:    "interface",
+			v:       []interface{}{Ref(1), RefText(2)},
+			want:    `["ref","\"ref\""]`,
+			wantStd: `[1,2]`,
+		},
+		{
+			name:    "top level",
+			v:       Ref(1),
+			want:    `"ref"`,
+			wantStd: `1`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.MarshalWithOption(tc.v, json.UsePointerReceiverMarshalers())
+			assertErr(t, err)
+			assertEq(t, "with option", tc.want, string(b))
+
+			b, err = json.Marshal(tc.v)
+			assertErr(t, err)
+			assertEq(t, "without option", tc.wantStd, string(b))
+		})
+	}
+}
+
 // C implements Marshaler and returns unescaped JSON.
 type C int
 
@@ -1051,6 +1377,40 @@ func TestTextMarshalerMapKeysAreSorted(t *testing.T) {
 	}
 }
 
+func TestStreamSortedMap(t *testing.T) {
+	type V struct {
+		A []int
+		M map[string]interface{}
+	}
+	for _, v := range []interface{}{
+		map[string]int{"b": 1, "a": 2, "<": 3, "ab": 4, "": 5},
+		map[int]string{10: "a", -1: "b", 2: "c", 100: "d"},
+		map[uint8]bool{255: true, 0: false, 9: true},
+		map[unmarshalerText]int{{"x", "y"}: 1, {"a", "z"}: 3, {"z", "a"}: 4},
+		map[string]V{"z": {A: []int{1}}, "y": {M: map[string]interface{}{"q": 1, "p": map[int]int{2: 1, 1: 2}}}},
+		struct{ M map[string]*int }{M: map[string]*int{"b": nil, "a": nil}},
+		map[string]int{},
+	} {
+		want, err := json.Marshal(v)
+		assertErr(t, err)
+		got, err := json.MarshalWithOption(v, json.StreamSortedMap())
+		assertErr(t, err)
+		assertEq(t, "compact", string(want), string(got))
+
+		want, err = json.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		got, err = json.MarshalIndentWithOption(v, "", "  ", json.StreamSortedMap())
+		assertErr(t, err)
+		assertEq(t, "indent", string(want), string(got))
+
+		want, err = json.MarshalWithOption(v, json.Colorize(json.DefaultColorScheme))
+		assertErr(t, err)
+		got, err = json.MarshalWithOption(v, json.Colorize(json.DefaultColorScheme), json.StreamSortedMap())
+		assertErr(t, err)
+		assertEq(t, "color", string(want), string(got))
+	}
+}
+
 // https://golang.org/issue/33675
 func TestNilMarshalerTextMapKey(t *testing.T) {
 	v := map[*unmarshalerText]int{
@@ -1067,6 +1427,89 @@ func TestNilMarshalerTextMapKey(t *testing.T) {
 	}
 }
 
+type ptrTextMapKey struct {
+	A, B string
+}
+
+func (k *ptrTextMapKey) MarshalText() ([]byte, error) {
+	return []byte(k.A + "/" + k.B), nil
+}
+
+func (k *ptrTextMapKey) UnmarshalText(b []byte) error {
+	pos := bytes.IndexByte(b, '/')
+	if pos == -1 {
+		return errors.New("missing separator")
+	}
+	k.A, k.B = string(b[:pos]), string(b[pos+1:])
+	return nil
+}
+
+type jsonMapKey struct {
+	N int
+}
+
+func (k jsonMapKey) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"n-%d"`, k.N)), nil
+}
+
+func (k *jsonMapKey) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	_, err := fmt.Sscanf(s, "n-%d", &k.N)
+	return err
+}
+
+type invalidJSONMapKey struct{}
+
+func (invalidJSONMapKey) MarshalJSON() ([]byte, error) {
+	return []byte(`1`), nil
+}
+
+func TestMarshalerMapKey(t *testing.T) {
+	t.Run("pointer receiver MarshalText", func(t *testing.T) {
+		v := map[ptrTextMapKey]int{{"x", "<y>"}: 1, {"a", "\"b"}: 2}
+		const want = `{"a/\"b":2,"x/\u003cy\u003e":1}`
+		for _, opt := range []json.EncodeOptionFunc{json.DisableNormalizeUTF8(), json.StreamSortedMap()} {
+			b, err := json.MarshalWithOption(v, opt)
+			assertErr(t, err)
+			assertEq(t, "marshal", want, string(b))
+		}
+		var got map[ptrTextMapKey]int
+		assertErr(t, json.Unmarshal([]byte(want), &got))
+		assertEq(t, "unmarshal", fmt.Sprint(v), fmt.Sprint(got))
+	})
+	t.Run("MarshalJSON", func(t *testing.T) {
+		v := map[jsonMapKey]string{{2}: "b", {10}: "c", {1}: "a"}
+		const want = `{"n-1":"a","n-10":"c","n-2":"b"}`
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", want, string(b))
+
+		b, err = json.MarshalIndent(v, "", "")
+		assertErr(t, err)
+		assertEq(t, "marshal indent", "{\n\"n-1\": \"a\",\n\"n-10\": \"c\",\n\"n-2\": \"b\"\n}", string(b))
+
+		var got map[jsonMapKey]string
+		assertErr(t, json.Unmarshal([]byte(want), &got))
+		assertEq(t, "unmarshal", fmt.Sprint(v), fmt.Sprint(got))
+		got = nil
+		assertErr(t, json.NewDecoder(strings.NewReader(want)).Decode(&got))
+		assertEq(t, "decode", fmt.Sprint(v), fmt.Sprint(got))
+		if err := json.Unmarshal([]byte(`{"x":"a"}`), &got); err == nil {
+			t.Fatal("expected error of UnmarshalJSON")
+		}
+	})
+	t.Run("MarshalJSON not returning string", func(t *testing.T) {
+		_, err := json.Marshal(map[invalidJSONMapKey]int{{}: 1})
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
 var re = regexp.MustCompile
 
 // syntactic checks on form of marshaled floating point numbers.
@@ -1361,6 +1804,65 @@ func TestUnsupportedValues(t *testing.T) {
 	}
 }
 
+func TestUnsupportedErrorPath(t *testing.T) {
+	type Handler struct {
+		Name    string `json:"name"`
+		OnClose func() `json:"onClose"`
+	}
+	type Stats struct {
+		Ratios map[string]float64 `json:"ratios"`
+	}
+	type Report struct {
+		ID       int       `json:"id"`
+		Stats    Stats     `json:"stats"`
+		Handlers []Handler `json:"handlers"`
+	}
+	t.Run("type", func(t *testing.T) {
+		_, err := json.Marshal(Report{})
+		var typeErr *json.UnsupportedTypeError
+		if !errors.As(err, &typeErr) {
+			t.Fatalf("got %T want UnsupportedTypeError", err)
+		}
+		assertEq(t, "path", "$.handlers[*].onClose", typeErr.Path)
+		assertEq(t, "message", "json: unsupported type: func() at $.handlers[*].onClose ( json_test.Report > []json_test.Handler > json_test.Handler )", err.Error())
+
+		_, err = json.Marshal(map[string]chan int{})
+		assertEq(t, "map", "json: unsupported type: chan int at $.* ( map[string]chan int )", err.Error())
+		_, err = json.Marshal(make(chan int))
+		assertEq(t, "root", "json: unsupported type: chan int", err.Error())
+	})
+	t.Run("value", func(t *testing.T) {
+		type Sample struct {
+			Stats Stats `json:"stats"`
+		}
+		type Summary struct {
+			Stats
+			Samples []*Sample `json:"samples"`
+		}
+		v := Summary{
+			Stats:   Stats{Ratios: map[string]float64{"a": 1}},
+			Samples: []*Sample{{}, {Stats: Stats{Ratios: map[string]float64{"b": 1, "c.d": math.Inf(1)}}}},
+		}
+		_, err := json.MarshalIndent(v, "", " ")
+		var valueErr *json.UnsupportedValueError
+		if !errors.As(err, &valueErr) {
+			t.Fatalf("got %T want UnsupportedValueError", err)
+		}
+		assertEq(t, "message", "json: unsupported value: +Inf at $.samples[1].stats.ratios['c.d'] ( json_test.Summary > []*json_test.Sample > json_test.Sample > json_test.Stats > map[string]float64 )", err.Error())
+
+		_, err = json.Marshal(math.NaN())
+		assertEq(t, "root", "json: unsupported value: NaN", err.Error())
+	})
+	t.Run("cycle", func(t *testing.T) {
+		_, err := json.Marshal(map[string]interface{}{"cycle": pointerCycle})
+		var valueErr *json.UnsupportedValueError
+		if !errors.As(err, &valueErr) {
+			t.Fatalf("got %T want UnsupportedValueError", err)
+		}
+		assertEq(t, "path", "$.cycle.Ptr", valueErr.Path)
+	})
+}
+
 func TestIssue10281(t *testing.T) {
 	type Foo struct {
 		N json.Number
@@ -1772,6 +2274,385 @@ func TestOmitEmpty(t *testing.T) {
 	}
 }
 
+func TestForceOmitEmpty(t *testing.T) {
+	type inner struct {
+		S string `json:"s,omitempty"`
+		N int    `json:"n"`
+	}
+	type T struct {
+		A int               `json:"a,omitempty"`
+		B string            `json:"b"`
+		C []int             `json:"c"`
+		D map[string]int    `json:"d,omitempty"`
+		E *inner            `json:"e"`
+		F interface{}       `json:"f"`
+		G inner             `json:"g"`
+		H map[string]string `json:"h"`
+	}
+	v := T{F: inner{}, H: map[string]string{"k": ""}}
+	t.Run("default", func(t *testing.T) {
+		got, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "json", `{"b":"","c":null,"e":null,"f":{"n":0},"g":{"n":0},"h":{"k":""}}`, string(got))
+	})
+	t.Run("include", func(t *testing.T) {
+		got, err := json.MarshalWithOption(v, json.ForceIncludeEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", `{"a":0,"b":"","c":null,"d":null,"e":null,"f":{"s":"","n":0},"g":{"s":"","n":0},"h":{"k":""}}`, string(got))
+	})
+	t.Run("omit", func(t *testing.T) {
+		got, err := json.MarshalWithOption(v, json.ForceOmitEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", `{"f":{},"g":{},"h":{"k":""}}`, string(got))
+	})
+	t.Run("omit indent", func(t *testing.T) {
+		got, err := json.MarshalIndentWithOption(&v, "", " ", json.ForceOmitEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", "{\n \"f\": {},\n \"g\": {},\n \"h\": {\n  \"k\": \"\"\n }\n}", string(got))
+	})
+	t.Run("last option wins", func(t *testing.T) {
+		got, err := json.MarshalWithOption(inner{}, json.ForceOmitEmpty(), json.ForceIncludeEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", `{"s":"","n":0}`, string(got))
+	})
+}
+
+func TestNilAsEmpty(t *testing.T) {
+	type T struct {
+		S  []int            `json:"s"`
+		M  map[string]int   `json:"m"`
+		SP *[]int           `json:"sp"`
+		MP *map[string]int  `json:"mp"`
+		SO []int            `json:"so,omitempty"`
+		I  interface{}      `json:"i"`
+		N  map[string][]int `json:"n"`
+		B  []byte           `json:"b"`
+	}
+	v := T{I: []string(nil), N: map[string][]int{"a": nil}}
+	t.Run("default", func(t *testing.T) {
+		got, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "json", `{"s":null,"m":null,"sp":null,"mp":null,"i":null,"n":{"a":null},"b":null}`, string(got))
+	})
+	t.Run("empty", func(t *testing.T) {
+		got, err := json.MarshalWithOption(v, json.NilSliceAsEmpty(), json.NilMapAsEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", `{"s":[],"m":{},"sp":null,"mp":null,"i":[],"n":{"a":[]},"b":null}`, string(got))
+	})
+	t.Run("indent", func(t *testing.T) {
+		got, err := json.MarshalIndentWithOption(struct {
+			S []int
+			M map[string]int
+		}{}, "", " ", json.NilSliceAsEmpty(), json.NilMapAsEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", "{\n \"S\": [],\n \"M\": {}\n}", string(got))
+	})
+	t.Run("top level", func(t *testing.T) {
+		got, err := json.MarshalWithOption([]int(nil), json.NilSliceAsEmpty())
+		assertErr(t, err)
+		assertEq(t, "slice", `[]`, string(got))
+		got, err = json.MarshalWithOption(map[string]int(nil), json.NilMapAsEmpty())
+		assertErr(t, err)
+		assertEq(t, "map", `{}`, string(got))
+	})
+	t.Run("nested", func(t *testing.T) {
+		var nilMap map[string]int
+		tests := []struct {
+			v    interface{}
+			want string
+		}{
+			{[]map[string]int{nil, {"a": 1}}, `[{},{"a":1}]`},
+			{map[string]map[string]int{"a": nil}, `{"a":{}}`},
+			{[]interface{}{nilMap}, `[{}]`},
+			{map[string]interface{}{"a": nilMap}, `{"a":{}}`},
+			{struct{ I interface{} }{I: nilMap}, `{"I":{}}`},
+			{&nilMap, `{}`},
+			{[]*map[string]int{nil, &nilMap}, `[null,{}]`},
+		}
+		for _, tc := range tests {
+			got, err := json.MarshalWithOption(tc.v, json.NilMapAsEmpty())
+			assertErr(t, err)
+			assertEq(t, "json", tc.want, string(got))
+
+			got, err = json.MarshalIndentWithOption(tc.v, "", "", json.NilMapAsEmpty())
+			assertErr(t, err)
+			assertEq(t, "indent", tc.want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(got)))
+
+			got, err = json.Marshal(tc.v)
+			assertErr(t, err)
+			assertEq(t, "default", strings.ReplaceAll(tc.want, "{}", "null"), string(got))
+		}
+	})
+}
+
+type zeroCheckerID [4]byte
+
+type zeroCheckerName string
+
+type zeroCheckerRange struct {
+	Min, Max int
+}
+
+type zeroCheckerSet map[string]int
+
+func init() {
+	json.RegisterZeroChecker(func(v zeroCheckerID) bool { return v == zeroCheckerID{} })
+	json.RegisterZeroChecker(func(v zeroCheckerName) bool { return strings.TrimSpace(string(v)) == "" })
+	json.RegisterZeroChecker(func(v zeroCheckerRange) bool { return v.Min >= v.Max })
+	json.RegisterZeroChecker(func(v zeroCheckerSet) bool { return v["a"] == 0 })
+}
+
+func TestRegisterZeroChecker(t *testing.T) {
+	type T struct {
+		ID    zeroCheckerID    `json:"id,omitempty"`
+		Name  zeroCheckerName  `json:"name,omitempty"`
+		Range zeroCheckerRange `json:"range,omitempty"`
+		Raw   zeroCheckerName  `json:"raw"`
+	}
+	tests := []struct {
+		name string
+		v    T
+		want string
+	}{
+		{
+			name: "empty",
+			v:    T{Name: "  ", Range: zeroCheckerRange{Min: 2, Max: 1}, Raw: " "},
+			want: `{"raw":" "}`,
+		},
+		{
+			name: "non empty",
+			v:    T{ID: zeroCheckerID{1}, Name: "a", Range: zeroCheckerRange{Max: 1}},
+			want: `{"id":[1,0,0,0],"name":"a","range":{"Min":0,"Max":1},"raw":""}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.v)
+			assertErr(t, err)
+			assertEq(t, "value", tc.want, string(b))
+
+			b, err = json.Marshal(&tc.v)
+			assertErr(t, err)
+			assertEq(t, "pointer", tc.want, string(b))
+
+			b, err = json.MarshalIndent(tc.v, "", "")
+			assertErr(t, err)
+			assertEq(t, "indent", tc.want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(b)))
+		})
+	}
+	t.Run("last field", func(t *testing.T) {
+		v := struct {
+			A    int             `json:"a"`
+			Name zeroCheckerName `json:"name,omitempty"`
+		}{A: 1, Name: " "}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "value", `{"a":1}`, string(b))
+	})
+	t.Run("omitzero", func(t *testing.T) {
+		type T struct {
+			ID    zeroCheckerID    `json:"id,omitzero"`
+			Name  zeroCheckerName  `json:"name,omitzero"`
+			Range zeroCheckerRange `json:"range,omitzero"`
+			Set   zeroCheckerSet   `json:"set,omitzero"`
+			Both  zeroCheckerName  `json:"both,omitempty,omitzero"`
+		}
+		b, err := json.Marshal(T{Name: "  ", Range: zeroCheckerRange{Min: 2, Max: 1}, Set: zeroCheckerSet{"b": 1}, Both: " "})
+		assertErr(t, err)
+		assertEq(t, "empty", `{}`, string(b))
+
+		b, err = json.Marshal(&T{ID: zeroCheckerID{1}, Name: "a", Range: zeroCheckerRange{Max: 1}, Set: zeroCheckerSet{"a": 1}, Both: "b"})
+		assertErr(t, err)
+		assertEq(t, "non empty", `{"id":[1,0,0,0],"name":"a","range":{"Min":0,"Max":1},"set":{"a":1},"both":"b"}`, string(b))
+
+		b, err = json.MarshalIndent(T{Name: " "}, "", "")
+		assertErr(t, err)
+		assertEq(t, "indent", `{}`, string(b))
+	})
+	t.Run("map", func(t *testing.T) {
+		type M struct {
+			Set zeroCheckerSet `json:"set,omitempty"`
+		}
+		type T struct {
+			A   int            `json:"a"`
+			Set zeroCheckerSet `json:"set,omitempty"`
+		}
+		set := zeroCheckerSet{"a": 1, "b": 2}
+		tests := []struct {
+			v    interface{}
+			want string
+		}{
+			{M{}, `{}`},
+			{M{Set: zeroCheckerSet{"b": 2}}, `{}`},
+			{M{Set: set}, `{"set":{"a":1,"b":2}}`},
+			{&M{Set: set}, `{"set":{"a":1,"b":2}}`},
+			{T{Set: zeroCheckerSet{"b": 2}}, `{"a":0}`},
+			{T{Set: set}, `{"a":0,"set":{"a":1,"b":2}}`},
+			{[]T{{Set: set}}, `[{"a":0,"set":{"a":1,"b":2}}]`},
+		}
+		for _, tc := range tests {
+			b, err := json.Marshal(tc.v)
+			assertErr(t, err)
+			assertEq(t, "value", tc.want, string(b))
+
+			b, err = json.MarshalIndent(tc.v, "", "")
+			assertErr(t, err)
+			assertEq(t, "indent", tc.want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(b)))
+		}
+	})
+}
+
+func TestOmitNil(t *testing.T) {
+	type inner struct {
+		A int `json:"a"`
+	}
+	type T struct {
+		P  *int    `json:"p"`
+		PT *int    `json:"pt,omitnil"`
+		S  *inner  `json:"s,omitnil"`
+		Z  int     `json:"z,omitnil"`
+		N  []int   `json:"n"`
+		PS *string `json:"ps"`
+	}
+	s := ""
+	t.Run("tag", func(t *testing.T) {
+		got, err := json.Marshal(T{PS: &s})
+		assertErr(t, err)
+		assertEq(t, "json", `{"p":null,"z":0,"n":null,"ps":""}`, string(got))
+	})
+	t.Run("non nil", func(t *testing.T) {
+		n := 0
+		got, err := json.Marshal(T{PT: &n, S: &inner{}})
+		assertErr(t, err)
+		assertEq(t, "json", `{"p":null,"pt":0,"s":{"a":0},"z":0,"n":null,"ps":null}`, string(got))
+	})
+	t.Run("option", func(t *testing.T) {
+		got, err := json.MarshalWithOption(T{PS: &s}, json.OmitNilPointers())
+		assertErr(t, err)
+		assertEq(t, "json", `{"z":0,"n":null,"ps":""}`, string(got))
+	})
+	t.Run("force include", func(t *testing.T) {
+		got, err := json.MarshalWithOption(T{}, json.OmitNilPointers(), json.ForceIncludeEmpty())
+		assertErr(t, err)
+		assertEq(t, "json", `{"p":null,"pt":null,"s":null,"z":0,"n":null,"ps":null}`, string(got))
+	})
+}
+
+type omitZeroPoint struct {
+	X, Y int
+}
+
+type omitZeroAmount int
+
+func (a omitZeroAmount) IsZero() bool { return a < 0 }
+
+type omitZeroPtrAmount int
+
+func (a *omitZeroPtrAmount) IsZero() bool { return *a < 0 }
+
+func TestOmitZero(t *testing.T) {
+	type T struct {
+		Time   time.Time         `json:"time,omitzero"`
+		Point  omitZeroPoint     `json:"point,omitzero"`
+		Array  [2]int            `json:"array,omitzero"`
+		Slice  []int             `json:"slice,omitzero"`
+		Float  float64           `json:"float,omitzero"`
+		Amount omitZeroAmount    `json:"amount,omitzero"`
+		Ptr    omitZeroPtrAmount `json:"ptr,omitzero"`
+		Both   omitZeroAmount    `json:"both,omitempty,omitzero"`
+		Last   *omitZeroPoint    `json:"last,omitzero"`
+	}
+	tests := []struct {
+		name string
+		v    T
+		want string
+	}{
+		{
+			name: "zero",
+			v:    T{Slice: []int{}, Float: math.Copysign(0, -1), Amount: -1, Ptr: -1, Both: -1},
+			want: `{"slice":[]}`,
+		},
+		{
+			name: "non zero",
+			v: T{
+				Time:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Point:  omitZeroPoint{Y: 1},
+				Array:  [2]int{0, 1},
+				Amount: 0,
+				Both:   0,
+				Last:   &omitZeroPoint{},
+			},
+			want: `{"time":"2024-01-02T03:04:05Z","point":{"X":0,"Y":1},"array":[0,1],"amount":0,"ptr":0,"last":{"X":0,"Y":0}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.v)
+			assertErr(t, err)
+			assertEq(t, "value", tc.want, string(b))
+
+			b, err = json.Marshal(&tc.v)
+			assertErr(t, err)
+			assertEq(t, "pointer", tc.want, string(b))
+
+			b, err = json.MarshalIndent(tc.v, "", "")
+			assertErr(t, err)
+			assertEq(t, "indent", tc.want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(b)))
+		})
+	}
+	t.Run("first field", func(t *testing.T) {
+		v := struct {
+			Time time.Time `json:"time,omitzero"`
+		}{}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "value", `{}`, string(b))
+	})
+	t.Run("force include empty", func(t *testing.T) {
+		v := struct {
+			Point omitZeroPoint `json:"point,omitzero"`
+		}{}
+		b, err := json.MarshalWithOption(v, json.ForceIncludeEmpty())
+		assertErr(t, err)
+		assertEq(t, "value", `{"point":{"X":0,"Y":0}}`, string(b))
+	})
+	t.Run("map", func(t *testing.T) {
+		type M struct {
+			M map[string]int `json:"m,omitzero"`
+		}
+		type T struct {
+			A int             `json:"a"`
+			M map[string]int  `json:"m,omitzero"`
+			P *map[string]int `json:"p,omitzero"`
+		}
+		m := map[string]int{"a": 1}
+		var nilMap map[string]int
+		tests := []struct {
+			v    interface{}
+			want string
+		}{
+			{M{}, `{}`},
+			{M{M: map[string]int{}}, `{"m":{}}`},
+			{M{M: m}, `{"m":{"a":1}}`},
+			{&M{M: m}, `{"m":{"a":1}}`},
+			{[]M{{M: m}, {}}, `[{"m":{"a":1}},{}]`},
+			{T{}, `{"a":0}`},
+			{T{M: map[string]int{}, P: &nilMap}, `{"a":0,"m":{},"p":null}`},
+			{T{M: m, P: &m}, `{"a":0,"m":{"a":1},"p":{"a":1}}`},
+			{&T{M: m}, `{"a":0,"m":{"a":1}}`},
+		}
+		for _, tc := range tests {
+			b, err := json.Marshal(tc.v)
+			assertErr(t, err)
+			assertEq(t, "value", tc.want, string(b))
+
+			b, err = json.MarshalIndent(tc.v, "", "")
+			assertErr(t, err)
+			assertEq(t, "indent", tc.want, strings.NewReplacer("\n", "", ": ", ":").Replace(string(b)))
+		}
+	})
+}
+
 type testNullStr string
 
 func (v *testNullStr) MarshalJSON() ([]byte, error) {
@@ -2036,6 +2917,18 @@ func TestEncodeContextOption(t *testing.T) {
 			t.Fatal("failed to encode with EncodeContext")
 		}
 	})
+	t.Run("Marshal without context", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), marshalContextKey{}, "hello")
+		if _, err := json.MarshalContext(ctx, &marshalContextStructType{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := json.Marshal(&marshalContextStructType{}); err == nil {
+			t.Fatal("expected the error of the MarshalJSON without the context value")
+		}
+		if _, err := json.MarshalIndent(&marshalContextStructType{}, "", " "); err == nil {
+			t.Fatal("expected the error of the MarshalJSON without the context value")
+		}
+	})
 }
 
 func TestInterfaceWithPointer(t *testing.T) {
@@ -2135,6 +3028,160 @@ func TestEmbeddedNotFirstField(t *testing.T) {
 	}
 }
 
+func TestNestAndSquashTagOptions(t *testing.T) {
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Base    `json:",nest"`
+		*Server `json:",nest"`
+		Listen  Server `json:",squash"`
+		Backup  *Base  `json:"backup,squash"`
+		Name    string `json:"name"`
+	}
+	v := Config{
+		Base:   Base{ID: 1},
+		Listen: Server{Host: "localhost", Port: 80},
+		Backup: &Base{ID: 2},
+		Name:   "a",
+	}
+	got, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "marshal", `{"Base":{"id":1},"Server":null,"host":"localhost","port":80,"id":2,"name":"a"}`, string(got))
+
+	var decoded Config
+	assertErr(t, json.Unmarshal([]byte(`{"Base":{"id":1},"Server":{"host":"remote"},"host":"localhost","port":80,"id":2,"name":"a"}`), &decoded))
+	assertEq(t, "base", 1, decoded.Base.ID)
+	assertEq(t, "server", "remote", decoded.Server.Host)
+	assertEq(t, "listen", v.Listen, decoded.Listen)
+	assertEq(t, "backup", 2, decoded.Backup.ID)
+	assertEq(t, "name", "a", decoded.Name)
+
+	got, err = json.Marshal(struct {
+		Base `json:",nest,omitempty"`
+	}{})
+	assertErr(t, err)
+	assertEq(t, "nest omitempty", `{"Base":{"id":0}}`, string(got))
+}
+
+func TestTupleTagOption(t *testing.T) {
+	type Request struct {
+		Method  string
+		Path    string
+		Status  int
+		ignored bool
+		Skip    int `json:"-"`
+		Tags    []string
+	}
+	type Event struct {
+		Req  Request  `json:"req,tuple"`
+		Prev *Request `json:"prev,tuple"`
+		Next *Request `json:"next,tuple"`
+	}
+	v := Event{
+		Req:  Request{Method: "GET", Path: "/a<b", Status: 200, Skip: 1, Tags: []string{"x"}},
+		Prev: &Request{Method: "POST", Path: "/", Status: 201},
+	}
+	expected := `{"req":["GET","/a\u003cb",200,["x"]],"prev":["POST","/",201,null],"next":null}`
+	got, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "marshal", expected, string(got))
+
+	got, err = json.MarshalIndent(Event{Req: Request{Method: "GET"}}, "", " ")
+	assertErr(t, err)
+	assertEq(t, "marshal indent", "{\n \"req\": [\n  \"GET\",\n  \"\",\n  0,\n  null\n ],\n \"prev\": null,\n \"next\": null\n}", string(got))
+
+	for _, stream := range []bool{false, true} {
+		decode := func(input string, v interface{}) error {
+			if stream {
+				return json.NewDecoder(strings.NewReader(input)).Decode(v)
+			}
+			return json.Unmarshal([]byte(input), v)
+		}
+		var decoded Event
+		assertErr(t, decode(expected, &decoded))
+		v.Req.Skip = 0
+		assertEq(t, "req", fmt.Sprint(v.Req), fmt.Sprint(decoded.Req))
+		assertEq(t, "prev", fmt.Sprint(*v.Prev), fmt.Sprint(*decoded.Prev))
+		assertEq(t, "next", true, decoded.Next == nil)
+
+		decoded = Event{Req: Request{Method: "PUT", Status: 500}}
+		assertErr(t, decode(`{"req":[ "GET" , "/x" ],"prev":["DELETE","/y",204,[],"extra",{"a":[1]}]}`, &decoded))
+		assertEq(t, "missing elements", "GET /x 0", fmt.Sprintf("%s %s %d", decoded.Req.Method, decoded.Req.Path, decoded.Req.Status))
+		assertEq(t, "extra elements", "DELETE /y 204", fmt.Sprintf("%s %s %d", decoded.Prev.Method, decoded.Prev.Path, decoded.Prev.Status))
+
+		err := decode(`{"req":{"Method":"GET"}}`, &decoded)
+		var terr *json.UnmarshalTypeError
+		if !errors.As(err, &terr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "type error", "object", terr.Value)
+	}
+}
+
+func TestEncodeNumberSlice(t *testing.T) {
+	type Celsius float64
+	type Series struct {
+		Values []float64  `json:"values"`
+		Counts []int64    `json:"counts,omitempty"`
+		Temps  []Celsius  `json:"temps"`
+		Ptr    *[]float64 `json:"ptr"`
+		Nested [][]int64  `json:"nested"`
+	}
+	values := []float64{0, -1.5, 1e-6, 1e21, 123456.789, math.MaxFloat64, math.SmallestNonzeroFloat64}
+	v := Series{
+		Values: values,
+		Counts: []int64{0, 1, -1, 99, 100, math.MaxInt64, math.MinInt64},
+		Temps:  []Celsius{21.5, -3},
+		Ptr:    &values,
+		Nested: [][]int64{{1, 2}, nil, {}},
+	}
+	for _, indent := range []bool{false, true} {
+		var expected, got []byte
+		var err error
+		if indent {
+			expected, err = stdjson.MarshalIndent(v, "", "  ")
+			assertErr(t, err)
+			got, err = json.MarshalIndent(v, "", "  ")
+		} else {
+			expected, err = stdjson.Marshal(v)
+			assertErr(t, err)
+			got, err = json.Marshal(v)
+		}
+		assertErr(t, err)
+		assertEq(t, "series", string(expected), string(got))
+	}
+	t.Run("empty", func(t *testing.T) {
+		got, err := json.Marshal(Series{Values: []float64{}})
+		assertErr(t, err)
+		assertEq(t, "empty", `{"values":[],"temps":null,"ptr":null,"nested":null}`, string(got))
+		got, err = json.MarshalWithOption(Series{}, json.NilSliceAsEmpty())
+		assertErr(t, err)
+		assertEq(t, "nil as empty", `{"values":[],"temps":[],"ptr":null,"nested":[]}`, string(got))
+	})
+	t.Run("int64 as string", func(t *testing.T) {
+		got, err := json.MarshalWithOption([]int64{1, -2}, json.Int64AsString())
+		assertErr(t, err)
+		assertEq(t, "int64 as string", `["1","-2"]`, string(got))
+	})
+	t.Run("unsupported value", func(t *testing.T) {
+		_, err := json.Marshal([]float64{1, math.Inf(1)})
+		var verr *json.UnsupportedValueError
+		if !errors.As(err, &verr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("interface", func(t *testing.T) {
+		got, err := json.Marshal(map[string]interface{}{"a": []float64{1.25, 2}, "b": []int64{3}})
+		assertErr(t, err)
+		assertEq(t, "interface", `{"a":[1.25,2],"b":[3]}`, string(got))
+	})
+}
+
 type implementedMethodIface interface {
 	M()
 }
@@ -2715,3 +3762,525 @@ func TestIssue441(t *testing.T) {
 	assertErr(t, err)
 	assertEq(t, "unexpected result", "{}", string(b))
 }
+
+type customMethodsEvent struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (e customMethodsEvent) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+func (e *customMethodsEvent) UnmarshalJSON([]byte) error {
+	e.Name = "custom"
+	return nil
+}
+
+type customMethodsLevel int
+
+func (l customMethodsLevel) MarshalText() ([]byte, error) {
+	return []byte("level"), nil
+}
+
+func (l *customMethodsLevel) UnmarshalText([]byte) error {
+	*l = -1
+	return nil
+}
+
+func TestWithoutCustomMethods(t *testing.T) {
+	type wrapper struct {
+		Event  customMethodsEvent            `json:"event"`
+		Ptr    *customMethodsEvent           `json:"ptr"`
+		Level  customMethodsLevel            `json:"level"`
+		Events map[string]customMethodsEvent `json:"events"`
+		Any    interface{}                   `json:"any"`
+	}
+	v := wrapper{
+		Event:  customMethodsEvent{ID: 1, Name: "a"},
+		Ptr:    &customMethodsEvent{ID: 2, Name: "b"},
+		Level:  3,
+		Events: map[string]customMethodsEvent{"x": {ID: 4}},
+		Any:    customMethodsEvent{ID: 5},
+	}
+	b, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "default", `{"event":"custom","ptr":"custom","level":"level","events":{"x":"custom"},"any":"custom"}`, string(b))
+
+	b, err = json.MarshalWithOption(v, json.WithoutCustomMethods(customMethodsEvent{}))
+	assertErr(t, err)
+	expected := `{"event":{"id":1,"name":"a"},"ptr":{"id":2,"name":"b"},"level":"level","events":{"x":{"id":4,"name":""}},"any":{"id":5,"name":""}}`
+	assertEq(t, "without methods", expected, string(b))
+
+	b, err = json.MarshalWithOption(v, json.WithoutCustomMethods(&customMethodsEvent{}, customMethodsLevel(0)), json.UnorderedMap())
+	assertErr(t, err)
+	assertEq(t, "with other options", `{"event":{"id":1,"name":"a"},"ptr":{"id":2,"name":"b"},"level":3,"events":{"x":{"id":4,"name":""}},"any":{"id":5,"name":""}}`, string(b))
+
+	b, err = json.MarshalWithOption(customMethodsEvent{ID: 6}, json.WithoutCustomMethods(customMethodsEvent{}))
+	assertErr(t, err)
+	assertEq(t, "top level", `{"id":6,"name":""}`, string(b))
+
+	b, err = json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "default again", `{"event":"custom","ptr":"custom","level":"level","events":{"x":"custom"},"any":"custom"}`, string(b))
+
+	t.Run("decode", func(t *testing.T) {
+		src := `{"event":{"id":1,"name":"a"},"ptr":{"id":2,"name":"b"},"level":"x"}`
+		var w wrapper
+		assertErr(t, json.Unmarshal([]byte(src), &w))
+		assertEq(t, "default", "custom", w.Event.Name)
+		assertEq(t, "default level", customMethodsLevel(-1), w.Level)
+
+		var got wrapper
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &got, json.DecodeWithoutCustomMethods(customMethodsEvent{})))
+		assertEq(t, "event", customMethodsEvent{ID: 1, Name: "a"}, got.Event)
+		assertEq(t, "ptr", customMethodsEvent{ID: 2, Name: "b"}, *got.Ptr)
+		assertEq(t, "level", customMethodsLevel(-1), got.Level)
+
+		var top customMethodsEvent
+		dec := json.NewDecoder(strings.NewReader(`{"id":7,"name":"c"}`))
+		assertErr(t, dec.DecodeWithOption(&top, json.DecodeWithoutCustomMethods(customMethodsEvent{})))
+		assertEq(t, "top level", customMethodsEvent{ID: 7, Name: "c"}, top)
+
+		r := json.NewDecodeRegistry()
+		json.RegisterDecodeFunc(r, func(data []byte, l *customMethodsLevel) error {
+			*l = 9
+			return nil
+		})
+		got = wrapper{}
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &got, json.WithDecodeRegistry(r), json.DecodeWithoutCustomMethods(customMethodsEvent{})))
+		assertEq(t, "registry event", customMethodsEvent{ID: 1, Name: "a"}, got.Event)
+		assertEq(t, "registry level", customMethodsLevel(9), got.Level)
+	})
+}
+
+func TestWithTagKey(t *testing.T) {
+	type address struct {
+		City string `json:"city" api:"town"`
+	}
+	type user struct {
+		ID      string   `json:"id" api:"user_id"`
+		Secret  string   `json:"secret" api:"-"`
+		Age     int      `json:"age" api:"age,omitempty"`
+		Nick    string   `json:"nick"`
+		Address *address `json:"address" api:"addr"`
+	}
+	v := user{ID: "u1", Secret: "s", Nick: "n", Address: &address{City: "c"}}
+	b, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "default", `{"id":"u1","secret":"s","age":0,"nick":"n","address":{"city":"c"}}`, string(b))
+
+	b, err = json.MarshalWithOption(v, json.WithTagKey("api"))
+	assertErr(t, err)
+	assertEq(t, "api", `{"user_id":"u1","Nick":"n","addr":{"town":"c"}}`, string(b))
+
+	b, err = json.MarshalWithOption(&v, json.WithTagKey("api"), json.ForceIncludeEmpty())
+	assertErr(t, err)
+	assertEq(t, "with other options", `{"user_id":"u1","age":0,"Nick":"n","addr":{"town":"c"}}`, string(b))
+
+	b, err = json.MarshalWithOption(v, json.WithTagKey(""))
+	assertErr(t, err)
+	assertEq(t, "empty key", `{"id":"u1","secret":"s","age":0,"nick":"n","address":{"city":"c"}}`, string(b))
+
+	t.Run("decode", func(t *testing.T) {
+		src := `{"user_id":"u2","secret":"x","age":3,"Nick":"m","id":"ignored","addr":{"town":"t","city":"ignored"}}`
+		var got user
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &got, json.DecodeWithTagKey("api")))
+		assertEq(t, "id", "u2", got.ID)
+		assertEq(t, "secret", "", got.Secret)
+		assertEq(t, "age", 3, got.Age)
+		assertEq(t, "nick", "m", got.Nick)
+		assertEq(t, "city", "t", got.Address.City)
+
+		got = user{}
+		dec := json.NewDecoder(strings.NewReader(src))
+		assertErr(t, dec.DecodeWithOption(&got, json.DecodeWithTagKey("api")))
+		assertEq(t, "stream id", "u2", got.ID)
+		assertEq(t, "stream city", "t", got.Address.City)
+
+		got = user{}
+		assertErr(t, json.Unmarshal([]byte(src), &got))
+		assertEq(t, "default id", "ignored", got.ID)
+		assertEq(t, "default address", (*address)(nil), got.Address)
+	})
+}
+
+func TestFieldNamingStrategy(t *testing.T) {
+	type Profile struct {
+		HTTPServer string
+		Base64Data string
+	}
+	type account struct {
+		UserID    string
+		FirstName string `json:",omitempty"`
+		Email     string `json:"mail"`
+		ID        int
+		Profile
+		Ptr *Profile
+	}
+	v := account{UserID: "u1", Email: "e", ID: 1, Profile: Profile{HTTPServer: "h", Base64Data: "d"}}
+	tests := []struct {
+		naming   json.FieldNaming
+		expected string
+	}{
+		{json.FieldNameAsIs, `{"UserID":"u1","mail":"e","ID":1,"HTTPServer":"h","Base64Data":"d","Ptr":null}`},
+		{json.SnakeCase, `{"user_id":"u1","mail":"e","id":1,"http_server":"h","base64_data":"d","ptr":null}`},
+		{json.CamelCase, `{"userID":"u1","mail":"e","id":1,"httpServer":"h","base64Data":"d","ptr":null}`},
+		{json.KebabCase, `{"user-id":"u1","mail":"e","id":1,"http-server":"h","base64-data":"d","ptr":null}`},
+	}
+	for _, test := range tests {
+		b, err := json.MarshalWithOption(v, json.FieldNamingStrategy(test.naming))
+		assertErr(t, err)
+		assertEq(t, "encode", test.expected, string(b))
+
+		var got account
+		assertErr(t, json.UnmarshalWithOption(b, &got, json.DecodeFieldNamingStrategy(test.naming)))
+		assertEq(t, "decode", v, got)
+	}
+
+	b, err := json.MarshalWithOption(&v, json.FieldNamingStrategy(json.SnakeCase), json.WithTagKey("api"))
+	assertErr(t, err)
+	assertEq(t, "with tag key", `{"user_id":"u1","first_name":"","email":"e","id":1,"http_server":"h","base64_data":"d","ptr":null}`, string(b))
+
+	b, err = json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "default", tests[0].expected, string(b))
+
+	t.Run("decode", func(t *testing.T) {
+		src := `{"user_id":"u2","http_server":"h2","ptr":{"base64_data":"p"}}`
+		var got account
+		dec := json.NewDecoder(strings.NewReader(src))
+		assertErr(t, dec.DecodeWithOption(&got, json.DecodeFieldNamingStrategy(json.SnakeCase)))
+		assertEq(t, "user id", "u2", got.UserID)
+		assertEq(t, "embedded", "h2", got.HTTPServer)
+		assertEq(t, "ptr", "p", got.Ptr.Base64Data)
+
+		got = account{}
+		assertErr(t, json.Unmarshal([]byte(src), &got))
+		assertEq(t, "default", "", got.UserID)
+	})
+}
+
+func TestRedactPaths(t *testing.T) {
+	type card struct {
+		Number string `json:"number"`
+		Expiry string `json:"expiry"`
+	}
+	type user struct {
+		Name string `json:"name"`
+		SSN  string `json:"ssn"`
+	}
+	type account struct {
+		User  user                   `json:"user"`
+		Cards []card                 `json:"cards"`
+		Extra map[string]interface{} `json:"extra"`
+	}
+	v := account{
+		User:  user{Name: "alice", SSN: "123-45-6789"},
+		Cards: []card{{Number: "4111", Expiry: "01/30"}, {Number: "5500", Expiry: "02/31"}},
+		Extra: map[string]interface{}{"a/b": map[string]int{"pin": 1234}, "tags": []string{"x", "y"}, "<k>": "v"},
+	}
+	redact := json.RedactPaths("/user/ssn", "/cards/*/number", "/extra/a~1b", "/extra/tags/1", "/extra/<k>")
+	t.Run("compact", func(t *testing.T) {
+		got, err := json.MarshalWithOption(v, redact)
+		assertErr(t, err)
+		assertEq(t, "redacted",
+			`{"user":{"name":"alice","ssn":"[REDACTED]"},"cards":[{"number":"[REDACTED]","expiry":"01/30"},`+
+				`{"number":"[REDACTED]","expiry":"02/31"}],"extra":{"\u003ck\u003e":"[REDACTED]","a/b":"[REDACTED]","tags":["x","[REDACTED]"]}}`,
+			string(got))
+	})
+	t.Run("indent", func(t *testing.T) {
+		got, err := json.MarshalIndentWithOption(v.User, "", "  ", json.RedactPaths("/ssn"))
+		assertErr(t, err)
+		assertEq(t, "redacted", "{\n  \"name\": \"alice\",\n  \"ssn\": \"[REDACTED]\"\n}", string(got))
+	})
+	t.Run("colorize", func(t *testing.T) {
+		got, err := json.MarshalWithOption(v.User, json.RedactPaths("/ssn"), json.Colorize(json.DefaultColorScheme))
+		assertErr(t, err)
+		plain, err := json.MarshalWithOption(user{Name: "alice"}, json.Colorize(json.DefaultColorScheme))
+		assertErr(t, err)
+		assertEq(t, "redacted", strings.Replace(string(plain), `""`, `"[REDACTED]"`, 1), string(got))
+	})
+	t.Run("root", func(t *testing.T) {
+		got, err := json.MarshalWithOption(v, json.RedactPaths(""))
+		assertErr(t, err)
+		assertEq(t, "redacted", `"[REDACTED]"`, string(got))
+	})
+	t.Run("encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetFlushSize(8)
+		assertErr(t, enc.EncodeWithOption(v.User, json.RedactPaths("/ssn")))
+		assertEq(t, "redacted", "{\"name\":\"alice\",\"ssn\":\"[REDACTED]\"}\n", buf.String())
+	})
+	t.Run("invalid path", func(t *testing.T) {
+		defer func() {
+			assertNeq(t, "panic", nil, recover())
+		}()
+		json.RedactPaths("user")
+	})
+	t.Run("missing index after scalar elements", func(t *testing.T) {
+		got, err := json.MarshalWithOption(json.RawMessage(`{"f":[1,2],"g":3}`), json.RedactPaths("/f/5"))
+		assertErr(t, err)
+		assertEq(t, "unchanged", `{"f":[1,2],"g":3}`, string(got))
+	})
+	t.Run("missing key before a redacted key", func(t *testing.T) {
+		got, err := json.MarshalWithOption(json.RawMessage(`{"a":{"b":1},"ssn":"x"}`), json.RedactPaths("/a/z", "/ssn"))
+		assertErr(t, err)
+		assertEq(t, "redacted", `{"a":{"b":1},"ssn":"[REDACTED]"}`, string(got))
+	})
+}
+
+// randomRedactValue returns a random value of depth at most depth, with its paths appended to paths.
+func randomRedactValue(r *rand.Rand, depth int, path string, paths *[]string) interface{} {
+	*paths = append(*paths, path)
+	n := r.Intn(7)
+	if depth == 0 {
+		n %= 4
+	}
+	switch n {
+	case 0:
+		return r.Intn(100)
+	case 1:
+		return fmt.Sprintf("s%d,:]}", r.Intn(10))
+	case 2:
+		return nil
+	case 3:
+		return r.Intn(2) == 0
+	case 4, 5:
+		m := map[string]interface{}{}
+		for i := r.Intn(4); i > 0; i-- {
+			key := fmt.Sprintf("k%d", r.Intn(5))
+			m[key] = randomRedactValue(r, depth-1, path+"/"+key, paths)
+		}
+		return m
+	}
+	var a []interface{}
+	for i := r.Intn(4); i > 0; i-- {
+		a = append(a, randomRedactValue(r, depth-1, fmt.Sprintf("%s/%d", path, len(a)), paths))
+	}
+	return a
+}
+
+// redactAt replaces the value of v at the JSON Pointer path by the redacted string.
+func redactAt(v interface{}, path string) interface{} {
+	if path == "" {
+		return "[REDACTED]"
+	}
+	token, rest := path[1:], ""
+	if i := strings.IndexByte(token, '/'); i >= 0 {
+		token, rest = token[:i], token[i:]
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		v[token] = redactAt(v[token], rest)
+	case []interface{}:
+		i, _ := strconv.Atoi(token)
+		v[i] = redactAt(v[i], rest)
+	}
+	return v
+}
+
+func TestRedactPathsRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var paths []string
+		v := map[string]interface{}{"r": randomRedactValue(r, 4, "/r", &paths)}
+		plain, err := json.Marshal(v)
+		assertErr(t, err)
+
+		// the paths missing from v leave the encoding unchanged.
+		missing := json.RedactPaths("/k9", "/k0/k9", "/9", "/0/9", "/k1/*/k9", "/*/7")
+		got, err := json.MarshalWithOption(v, missing)
+		assertErr(t, err)
+		assertEq(t, "missing paths", string(plain), string(got))
+		indented, err := json.MarshalIndentWithOption(v, "", "  ", missing)
+		assertErr(t, err)
+		var compact bytes.Buffer
+		assertErr(t, json.Compact(&compact, indented))
+		assertEq(t, "missing paths indented", string(plain), compact.String())
+
+		path := paths[r.Intn(len(paths))]
+		got, err = json.MarshalWithOption(v, json.RedactPaths(path))
+		assertErr(t, err)
+		var expected, actual interface{}
+		assertErr(t, json.Unmarshal(plain, &expected))
+		assertErr(t, json.Unmarshal(got, &actual))
+		if !reflect.DeepEqual(redactAt(expected, path), actual) {
+			t.Fatalf("redacting %q of %s: got %s", path, plain, got)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type user struct {
+		Name    string            `json:"name"`
+		Email   string            `json:"email,omitempty"`
+		Phone   *string           `json:"phone,omitempty"`
+		Since   time.Time         `json:"since,omitzero"`
+		Address address           `json:"address"`
+		Tags    map[string]string `json:"tags,omitempty"`
+		Secret  string            `json:"-"`
+	}
+	collect := func(events *[]string) func(json.AuditEvent) {
+		return func(e json.AuditEvent) {
+			*events = append(*events, e.Kind.String()+" "+e.Path)
+		}
+	}
+	t.Run("encode", func(t *testing.T) {
+		var events []string
+		v := []interface{}{user{Name: "a", Address: address{City: "b"}}, map[string]user{"x/y": {Email: "c", Since: time.Unix(1, 0)}}}
+		_, err := json.MarshalWithOption(v, json.Audit(collect(&events)))
+		assertErr(t, err)
+		assertEq(t, "events", strings.Join([]string{
+			"omitted /0/email", "omitted /0/phone", "omitted /0/since", "omitted /0/address/zip", "omitted /0/tags",
+			"omitted /1/x~1y/phone", "omitted /1/x~1y/address/zip", "omitted /1/x~1y/tags",
+		}, ","), strings.Join(events, ","))
+	})
+	t.Run("encode indent", func(t *testing.T) {
+		var events []string
+		_, err := json.MarshalIndentWithOption(&address{}, "", "  ", json.Audit(collect(&events)))
+		assertErr(t, err)
+		assertEq(t, "events", "omitted /zip", strings.Join(events, ","))
+	})
+	t.Run("force include empty", func(t *testing.T) {
+		var events []string
+		_, err := json.MarshalWithOption(address{}, json.Audit(collect(&events)), json.ForceIncludeEmpty())
+		assertErr(t, err)
+		assertEq(t, "events", 0, len(events))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var events []string
+		var v []user
+		src := `[{"name":"a","nickname":"b","address":{"city":"c","street":"d"},"email":"e","phone":null,"since":"2020-01-01T00:00:00Z","tags":{}},{}]`
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.DecodeAudit(collect(&events))))
+		assertEq(t, "events", strings.Join([]string{
+			"unknown key /0/nickname", "unknown key /0/address/street", "defaulted /0/address/zip",
+			"defaulted /1/name", "defaulted /1/email", "defaulted /1/phone", "defaulted /1/since", "defaulted /1/address", "defaulted /1/tags",
+		}, ","), strings.Join(events, ","))
+	})
+	t.Run("decode stream", func(t *testing.T) {
+		var events []string
+		var v address
+		dec := json.NewDecoder(strings.NewReader(`{"City":"a","country":"b"}`))
+		assertErr(t, dec.DecodeWithOption(&v, json.DecodeAudit(collect(&events))))
+		assertEq(t, "events", "unknown key /country,defaulted /zip", strings.Join(events, ","))
+	})
+	t.Run("disallow unknown fields", func(t *testing.T) {
+		var events []string
+		var v address
+		err := json.UnmarshalWithOption([]byte(`{"country":"b"}`), &v, json.DecodeAudit(collect(&events)), json.DisallowUnknownFields())
+		assertNeq(t, "error", nil, err)
+		assertEq(t, "events", "defaulted /city,defaulted /zip", strings.Join(events, ","))
+	})
+}
+
+type codecMoney int64
+
+func init() {
+	json.RegisterCodec("money-cents", func(m codecMoney) ([]byte, error) {
+		return []byte(strconv.FormatInt(int64(m), 10)), nil
+	}, func(data []byte, m *codecMoney) error {
+		n, err := strconv.ParseInt(string(data), 10, 64)
+		*m = codecMoney(n)
+		return err
+	})
+	json.RegisterCodec("money-decimal", func(m codecMoney) ([]byte, error) {
+		if m < 0 {
+			return nil, fmt.Errorf("negative amount")
+		}
+		return []byte(fmt.Sprintf(`"%d.%02d"`, m/100, m%100)), nil
+	}, func(data []byte, m *codecMoney) error {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		var units, cents int64
+		if _, err := fmt.Sscanf(s, "%d.%d", &units, &cents); err != nil {
+			return err
+		}
+		*m = codecMoney(units*100 + cents)
+		return nil
+	})
+}
+
+func TestCodecTagOption(t *testing.T) {
+	type ledger struct {
+		Amount codecMoney `json:"amount,codec=money-cents"`
+		Note   string     `json:"note"`
+	}
+	type invoice struct {
+		Total codecMoney  `json:"total,codec=money-decimal"`
+		Tax   *codecMoney `json:"tax,omitempty"`
+		Raw   codecMoney  `json:"raw"`
+	}
+	t.Run("encode", func(t *testing.T) {
+		b, err := json.Marshal(ledger{Amount: 1250, Note: "a"})
+		assertErr(t, err)
+		assertEq(t, "ledger", `{"amount":1250,"note":"a"}`, string(b))
+		b, err = json.Marshal(invoice{Total: 1250, Raw: 7})
+		assertErr(t, err)
+		assertEq(t, "invoice", `{"total":"12.50","raw":7}`, string(b))
+		b, err = json.MarshalIndent(invoice{Total: 5}, "", " ")
+		assertErr(t, err)
+		assertEq(t, "indent", "{\n \"total\": \"0.05\",\n \"raw\": 0\n}", string(b))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var l ledger
+		assertErr(t, json.Unmarshal([]byte(`{"amount":1250,"note":"a"}`), &l))
+		assertEq(t, "ledger", ledger{Amount: 1250, Note: "a"}, l)
+		var inv invoice
+		assertErr(t, json.NewDecoder(strings.NewReader(`{"total":"12.50","raw":7}`)).Decode(&inv))
+		assertEq(t, "invoice", invoice{Total: 1250, Raw: 7}, inv)
+	})
+	t.Run("marshal error", func(t *testing.T) {
+		_, err := json.Marshal(invoice{Total: -1})
+		if err == nil || !strings.Contains(err.Error(), "negative amount") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("unknown codec", func(t *testing.T) {
+		type unknown struct {
+			Amount codecMoney `json:"amount,codec=money-unknown"`
+		}
+		if _, err := json.Marshal(unknown{}); err == nil || !strings.Contains(err.Error(), `unknown codec "money-unknown"`) {
+			t.Fatalf("unexpected marshal error: %v", err)
+		}
+		var v unknown
+		if err := json.Unmarshal([]byte(`{"amount":1}`), &v); err == nil || !strings.Contains(err.Error(), `unknown codec "money-unknown"`) {
+			t.Fatalf("unexpected unmarshal error: %v", err)
+		}
+	})
+	t.Run("type mismatch", func(t *testing.T) {
+		type mismatch struct {
+			Amount int64 `json:"amount,codec=money-cents"`
+		}
+		if _, err := json.Marshal(mismatch{}); err == nil {
+			t.Fatal("expected an error")
+		}
+		var v mismatch
+		if err := json.Unmarshal([]byte(`{"amount":1}`), &v); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestEncodeOptions(t *testing.T) {
+	opt := json.EncodeOptions(json.DisableHTMLEscape(), json.NilSliceAsEmpty())
+	v := struct {
+		S string   `json:"s"`
+		L []string `json:"l"`
+	}{S: "<a>"}
+	for i := 0; i < 2; i++ {
+		b, err := json.MarshalWithOption(v, opt)
+		assertErr(t, err)
+		assertEq(t, "bundled options", `{"s":"<a>","l":[]}`, string(b))
+	}
+	b, err := json.MarshalWithOption(v)
+	assertErr(t, err)
+	assertEq(t, "without options", `{"s":"\u003ca\u003e","l":null}`, string(b))
+}
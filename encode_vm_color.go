@@ -0,0 +1,15 @@
+//go:build !json_nocolor
+
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/encoder/vm_color"
+)
+
+func encodeRunColorCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
+	if (ctx.Option.Flag & encoder.DebugOption) != 0 {
+		return vm_color.DebugRun(ctx, b, codeSet)
+	}
+	return vm_color.Run(ctx, b, codeSet)
+}
@@ -0,0 +1,15 @@
+//go:build !json_nocolor && !json_noindentvm
+
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/encoder/vm_color_indent"
+)
+
+func encodeRunColorIndentCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
+	if (ctx.Option.Flag & encoder.DebugOption) != 0 {
+		return vm_color_indent.DebugRun(ctx, b, codeSet)
+	}
+	return vm_color_indent.Run(ctx, b, codeSet)
+}
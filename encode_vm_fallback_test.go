@@ -0,0 +1,87 @@
+//go:build json_nocolor || json_noindentvm
+
+package json
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/encoder/vm_color_indent"
+)
+
+type encodeRunFunc func(*encoder.RuntimeContext, []byte, *encoder.OpcodeSet) ([]byte, error)
+
+// fallbackTestValues are the values encoded by the fallbacks of the VMs compiled out by the build tags.
+// They have no maps and no []byte, which the colorizing fallbacks color differently.
+var fallbackTestValues = []interface{}{
+	nil,
+	"a:b",
+	-1.5e-10,
+	[]int{},
+	struct {
+		A int
+		B uint
+		C float32
+		D string
+		E bool
+		G []int
+		H *struct{}
+		I []interface{}
+		J string `json:"j\"k"`
+		K []struct{}
+		L interface{}
+		M struct {
+			N map[string]int `json:",omitempty"`
+			O float64
+		}
+	}{
+		A: -123,
+		B: 456,
+		C: 3.14,
+		D: "hello \"world\": \\あ",
+		E: true,
+		G: []int{1, 2, 3, 4},
+		I: []interface{}{-10, 1e21, false, nil, "x", []interface{}{}},
+		J: ":",
+		K: []struct{}{{}},
+		L: []interface{}{},
+	},
+}
+
+// runEncode encodes v by run with the options of optFuncs and the indent of Indent("p", "\t").
+func runEncode(t *testing.T, v interface{}, run encodeRunFunc, optFuncs ...EncodeOptionFunc) string {
+	t.Helper()
+	if v == nil {
+		v = (*int)(nil)
+	}
+	ctx := encoder.TakeRuntimeContext()
+	defer encoder.ReleaseRuntimeContext(ctx)
+	ctx.Option.Flag = encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option
+	for _, optFunc := range optFuncs {
+		optFunc(ctx.Option)
+	}
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+	codeSet, err := encoder.CompileToGetCodeSet(ctx, uintptr(unsafe.Pointer(header.typ)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Init(uintptr(header.ptr), codeSet.CodeLength)
+	ctx.Prefix = []byte("p")
+	ctx.IndentStr = []byte("\t")
+	b, err := run(ctx, nil, codeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestColorIndentFallback(t *testing.T) {
+	for _, v := range fallbackTestValues {
+		expected := runEncode(t, v, vm_color_indent.Run, Colorize(DefaultColorScheme))
+		actual := runEncode(t, v, encodeRunColorIndentCode, Colorize(DefaultColorScheme))
+		if expected != actual {
+			t.Errorf("%T: the fallback differs from the VM:\n%q\n%q", v, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build json_nocolor
+
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+)
+
+// encodeRunColorCode is used instead of the color VM when it is compiled out by the json_nocolor build tag.
+// It runs the plain VM and colorizes the result afterwards.
+func encodeRunColorCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
+	// the result is rewritten as a whole, so it must not be flushed halfway.
+	ctx.FlushWriter = nil
+	start := len(b)
+	buf, err := encodeRunPlainCode(ctx, b, codeSet)
+	if err != nil {
+		return nil, err
+	}
+	return colorizeEncoded(ctx, buf, start, ","), nil
+}
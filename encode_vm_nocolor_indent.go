@@ -0,0 +1,29 @@
+//go:build json_nocolor || json_noindentvm
+
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+)
+
+// encodeRunColorIndentCode is used instead of the color indent VM when it is compiled out
+// by the json_nocolor or json_noindentvm build tag.
+// It indents the value by encodeRunPlainIndentCode and colorizes the result afterwards.
+func encodeRunColorIndentCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
+	// the result is rewritten as a whole, so it must not be flushed halfway.
+	ctx.FlushWriter = nil
+	start := len(b)
+	buf, err := encodeRunPlainIndentCode(ctx, b, codeSet)
+	if err != nil {
+		return nil, err
+	}
+	return colorizeEncoded(ctx, buf, start, ",\n"), nil
+}
+
+// colorizeEncoded colorizes buf[start:] which is followed by the separator sep written by the VM.
+func colorizeEncoded(ctx *encoder.RuntimeContext, buf []byte, start int, sep string) []byte {
+	value := make([]byte, len(buf)-start-len(sep))
+	copy(value, buf[start:])
+	buf = encoder.AppendColorized(buf[:start], value, ctx.Option.ColorScheme)
+	return append(buf, sep...)
+}
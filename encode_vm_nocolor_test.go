@@ -0,0 +1,35 @@
+//go:build json_nocolor
+
+package json
+
+import (
+	"testing"
+
+	"github.com/going/json/internal/encoder/vm_color"
+)
+
+func TestColorFallback(t *testing.T) {
+	for _, v := range fallbackTestValues {
+		expected := runEncode(t, v, vm_color.Run, Colorize(DefaultColorScheme))
+		actual := runEncode(t, v, encodeRunColorCode, Colorize(DefaultColorScheme))
+		if expected != actual {
+			t.Errorf("%T: the fallback differs from the VM:\n%q\n%q", v, actual, expected)
+		}
+	}
+}
+
+func TestColorFallbackMapsAndBytes(t *testing.T) {
+	scheme := &ColorScheme{
+		String:    ColorFormat{Header: "<s>", Footer: "</s>"},
+		Binary:    ColorFormat{Header: "<b>", Footer: "</b>"},
+		ObjectKey: ColorFormat{Header: "<k>", Footer: "</k>"},
+	}
+	v := map[string][]byte{"a": []byte("a")}
+	if b := runEncode(t, v, vm_color.Run, Colorize(scheme)); b != `{<s>"a"</s>:<b>"YQ=="</b>},` {
+		t.Errorf("unexpected VM output: %s", b)
+	}
+	// the types of the values are not known to the fallback.
+	if b := runEncode(t, v, encodeRunColorCode, Colorize(scheme)); b != `{<k>"a"</k>:<s>"YQ=="</s>},` {
+		t.Errorf("unexpected fallback output: %s", b)
+	}
+}
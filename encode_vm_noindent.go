@@ -0,0 +1,29 @@
+//go:build json_noindentvm
+
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+)
+
+// encodeRunPlainIndentCode is used instead of the indent VM when it is compiled out by the json_noindentvm build tag.
+// It runs the plain VM and indents the result afterwards.
+func encodeRunPlainIndentCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]byte, error) {
+	// the result is rewritten as a whole, so it must not be flushed halfway.
+	ctx.FlushWriter = nil
+	start := len(b)
+	buf, err := encodeRunPlainCode(ctx, b, codeSet)
+	if err != nil {
+		return nil, err
+	}
+	// replace the trailing comma by the terminator required by the indenter.
+	src := make([]byte, len(buf)-start)
+	copy(src, buf[start:len(buf)-1])
+	src[len(src)-1] = nul
+
+	buf, err = encoder.AppendIndentJSON(buf[:start], src, string(ctx.Prefix), string(ctx.IndentStr))
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, ',', '\n'), nil
+}
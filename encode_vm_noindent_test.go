@@ -0,0 +1,24 @@
+//go:build json_noindentvm
+
+package json
+
+import (
+	"testing"
+
+	"github.com/going/json/internal/encoder/vm_indent"
+)
+
+func init() {
+	IndentVMsCompiledOut = true
+}
+
+func TestIndentFallback(t *testing.T) {
+	values := append(fallbackTestValues, map[string]interface{}{"a": []byte("a"), "b": map[string]int{}})
+	for _, v := range values {
+		expected := runEncode(t, v, vm_indent.Run, UnorderedMap())
+		actual := runEncode(t, v, encodeRunPlainIndentCode, UnorderedMap())
+		if expected != actual {
+			t.Errorf("%T: the fallback differs from the VM:\n%q\n%q", v, actual, expected)
+		}
+	}
+}
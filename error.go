@@ -39,3 +39,59 @@ type UnsupportedTypeError = errors.UnsupportedTypeError
 type UnsupportedValueError = errors.UnsupportedValueError
 
 type PathError = errors.PathError
+
+// An ExprError is returned when an expression compiled by CompileExpr is malformed or fails to evaluate.
+type ExprError = errors.ExprError
+
+// A RefError is returned by ResolveRefs when a reference cannot be resolved.
+type RefError = errors.RefError
+
+// A PositionError wraps a decode error with the line and column where it occurred. It is returned with ConfigFiles.
+type PositionError = errors.PositionError
+
+// A KeyLimitError is returned when an object, or the whole document, has more keys
+// than the limits set by MaxObjectKeys.
+type KeyLimitError = errors.KeyLimitError
+
+// The classes of decode errors, which are matched by errors.Is, so that the failures can be told apart
+// without matching the error messages:
+//
+//	if errors.Is(err, json.ErrUnknownField) {
+//		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+//	}
+var (
+	// ErrTrailingData is matched by the *SyntaxError of Unmarshal for data after the top-level value.
+	ErrTrailingData = errors.ErrTrailingData
+	// ErrDepthExceeded is matched by the *SyntaxError for a value nested too deeply, such as beyond StrictMaxDepth.
+	ErrDepthExceeded = errors.ErrDepthExceeded
+	// ErrDuplicateKey is matched by the *DuplicateKeyError for an object with duplicate keys with RejectDuplicateKeys or StrictRFC8259.
+	ErrDuplicateKey = errors.ErrDuplicateKey
+	// ErrStringTooLong is matched by the *SyntaxError for a string longer than the limit of MaxStringLength.
+	ErrStringTooLong = errors.ErrStringTooLong
+	// ErrUnknownField is matched by the *UnknownFieldError of a Decoder with DisallowUnknownFields or of StrictPaths.
+	ErrUnknownField = errors.ErrUnknownField
+	// ErrMissingField is matched by the *MissingFieldError for an object without the keys of required fields.
+	ErrMissingField = errors.ErrMissingField
+)
+
+// A DuplicateKeyError describes an object key that appears more than once in the object, with RejectDuplicateKeys
+// or StrictRFC8259. It wraps a *SyntaxError, and matches ErrDuplicateKey.
+// Path is the keys and indexes from the root to the key separated by dots, as in UnknownFieldError.
+type DuplicateKeyError = errors.DuplicateKeyError
+
+// An UnknownFieldError describes an object key that matches no field of the struct it is decoded into,
+// with Decoder.DisallowUnknownFields or StrictPaths. It matches ErrUnknownField.
+// Path is the keys and indexes from the root to the key separated by dots, such as "items.0.color",
+// in the syntax of the patterns of StrictPaths.
+// Suggestion is the closest key of the struct, compared without the case, the underscores and the hyphens
+// and allowing a couple of typos, such as "user_id" for "userId", and is mentioned by the error message.
+type UnknownFieldError = errors.UnknownFieldError
+
+// A MissingFieldError lists the fields tagged with the required option, as in `json:"name,required"`,
+// whose keys are absent from the object decoded into their struct, so that they can all be fixed at once.
+// It matches ErrMissingField. A key with a null value is present, and a null object is not checked.
+type MissingFieldError = errors.MissingFieldError
+
+// An UnknownFieldsError lists the unknown fields of a value, when there are more than one,
+// so that they can all be fixed at once. It matches ErrUnknownField, and errors.As finds its first UnknownFieldError.
+type UnknownFieldsError = errors.UnknownFieldsError
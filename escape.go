@@ -0,0 +1,40 @@
+package json
+
+import (
+	"github.com/going/json/internal/encoder"
+)
+
+// NeedsEscape reports whether s is escaped when it is encoded as a JSON string with the options of Marshal
+// and optFuncs, so that a streaming marshaler can write s between quotes as is when it is not:
+//
+//	if !json.NeedsEscape(s) {
+//		b = append(append(append(b, '"'), s...), '"')
+//	} else {
+//		b = json.AppendString(b, s)
+//	}
+//
+// The characters <, > and & are escaped unless DisableHTMLEscape is given,
+// and invalid UTF-8, U+2028 and U+2029 are escaped unless DisableNormalizeUTF8 is given.
+func NeedsEscape(s string, optFuncs ...EncodeOptionFunc) bool {
+	_, escaped := encoder.EscapedLen(escapeFlag(optFuncs), s)
+	return escaped
+}
+
+// EscapedLen returns the length of s encoded as a JSON string with the options of Marshal and optFuncs,
+// including the quotes, so that the buffer for it is allocated in advance.
+func EscapedLen(s string, optFuncs ...EncodeOptionFunc) int {
+	n, _ := encoder.EscapedLen(escapeFlag(optFuncs), s)
+	return n
+}
+
+func escapeFlag(optFuncs []EncodeOptionFunc) encoder.OptionFlag {
+	flag := encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option
+	if len(optFuncs) == 0 {
+		return flag
+	}
+	opt := &EncodeOption{Flag: flag}
+	for _, optFunc := range optFuncs {
+		optFunc(opt)
+	}
+	return opt.Flag
+}
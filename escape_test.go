@@ -0,0 +1,39 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestNeedsEscape(t *testing.T) {
+	tests := []string{
+		"",
+		"plain ascii text",
+		"quote\"",
+		"back\\slash",
+		"tab\tnew\nline\r",
+		"\x00\x1f",
+		"<html>&",
+		"héllo wörld",
+		"invalid\xff\xfe",
+		"sep\u2028\u2029",
+		"truncated\xe2\x80",
+	}
+	options := map[string][]json.EncodeOptionFunc{
+		"default":        nil,
+		"no html":        {json.DisableHTMLEscape()},
+		"no normalize":   {json.DisableNormalizeUTF8()},
+		"no html or utf": {json.DisableHTMLEscape(), json.DisableNormalizeUTF8()},
+	}
+	for name, opts := range options {
+		for _, s := range tests {
+			b, err := json.MarshalWithOption(s, opts...)
+			assertErr(t, err)
+			assertEq(t, name+" length of "+s, len(b), json.EscapedLen(s, opts...))
+			assertEq(t, name+" escape of "+s, string(b) != `"`+s+`"`, json.NeedsEscape(s, opts...))
+		}
+	}
+	assertEq(t, "html", true, json.NeedsEscape("<"))
+	assertEq(t, "no html", false, json.NeedsEscape("<", json.DisableHTMLEscape()))
+}
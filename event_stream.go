@@ -0,0 +1,85 @@
+package json
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// EventStreamEncoder writes JSON values as server-sent events ( the text/event-stream format ),
+// such as to stream the chunks of a response to a browser:
+//
+//	w.Header().Set("Content-Type", "text/event-stream")
+//	enc := json.NewEventStreamEncoder(w)
+//	for chunk := range chunks {
+//		if err := enc.Encode(chunk); err != nil { ... }
+//	}
+//
+// Each event is written by a single Write call and flushed if the writer has a Flush method,
+// as http.ResponseWriter and *bufio.Writer have, so that the client receives it at once.
+// An EventStreamEncoder is safe for concurrent use, so that heartbeats can be sent from another goroutine.
+type EventStreamEncoder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// NewEventStreamEncoder returns a new encoder that writes server-sent events to w.
+func NewEventStreamEncoder(w io.Writer) *EventStreamEncoder {
+	return &EventStreamEncoder{w: w}
+}
+
+// Encode writes the JSON encoding of v as the data of an event, framed as "data: <json>\n\n".
+func (e *EventStreamEncoder) Encode(v interface{}) error {
+	return e.EncodeWithOption(v)
+}
+
+// EncodeWithOption call Encode with EncodeOption.
+func (e *EventStreamEncoder) EncodeWithOption(v interface{}, optFuncs ...EncodeOptionFunc) error {
+	return e.EncodeEvent("", v, optFuncs...)
+}
+
+// EncodeEvent is like Encode but writes an event field with name before the data,
+// so that the client dispatches the event to the listeners of name. An empty name writes no event field.
+func (e *EventStreamEncoder) EncodeEvent(name string, v interface{}, optFuncs ...EncodeOptionFunc) error {
+	if strings.ContainsAny(name, "\r\n") {
+		return fmt.Errorf("json: event name %q contains a line break", name)
+	}
+	data, err := MarshalWithOption(v, optFuncs...)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	buf := e.buf[:0]
+	if name != "" {
+		buf = append(append(append(buf, "event: "...), name...), '\n')
+	}
+	// the encoding has no newline characters, so it is a single data line.
+	buf = append(append(append(buf, "data: "...), data...), "\n\n"...)
+	e.buf = buf
+	return e.write(buf)
+}
+
+// Heartbeat writes a comment line, which the client ignores,
+// to keep the connection from being closed by proxies while no event is sent.
+func (e *EventStreamEncoder) Heartbeat() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.write([]byte(":\n\n"))
+}
+
+// write writes buf and flushes the writer. e.mu must be held.
+func (e *EventStreamEncoder) write(buf []byte) error {
+	if _, err := e.w.Write(buf); err != nil {
+		return err
+	}
+	switch f := e.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
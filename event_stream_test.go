@@ -0,0 +1,48 @@
+package json_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+type flushRecorder struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.String())
+}
+
+func TestEventStreamEncoder(t *testing.T) {
+	t.Run("events", func(t *testing.T) {
+		var w flushRecorder
+		enc := json.NewEventStreamEncoder(&w)
+		assertErr(t, enc.Encode(map[string]string{"delta": "Hel"}))
+		assertErr(t, enc.Heartbeat())
+		assertErr(t, enc.EncodeEvent("done", struct {
+			Tokens int `json:"tokens"`
+		}{3}))
+		assertEq(t, "stream", "data: {\"delta\":\"Hel\"}\n\n:\n\nevent: done\ndata: {\"tokens\":3}\n\n", w.String())
+		assertEq(t, "flushes", 3, len(w.flushed))
+		assertEq(t, "first flush", "data: {\"delta\":\"Hel\"}\n\n", w.flushed[0])
+	})
+	t.Run("bufio writer", func(t *testing.T) {
+		var w bytes.Buffer
+		enc := json.NewEventStreamEncoder(bufio.NewWriter(&w))
+		assertErr(t, enc.Encode("a"))
+		assertEq(t, "stream", "data: \"a\"\n\n", w.String())
+	})
+	t.Run("invalid event name", func(t *testing.T) {
+		var w bytes.Buffer
+		enc := json.NewEventStreamEncoder(&w)
+		if err := enc.EncodeEvent("a\nb", 1); err == nil || !strings.Contains(err.Error(), "line break") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "stream", "", w.String())
+	})
+}
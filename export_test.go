@@ -7,4 +7,8 @@ import (
 var (
 	NewSyntaxError    = errors.ErrSyntax
 	NewMarshalerError = errors.ErrMarshaler
+	NewBufferStats    = newBufferStats
+
+	// IndentVMsCompiledOut reports whether the indent VMs are dropped by the json_noindentvm build tag.
+	IndentVMsCompiledOut bool
 )
@@ -0,0 +1,68 @@
+package json
+
+import (
+	"github.com/going/json/internal/expr"
+)
+
+// Expr is a compiled expression in a subset of the jq language.
+// It can be evaluated any number of times and is safe for concurrent use by multiple goroutines.
+type Expr struct {
+	prog *expr.Program
+}
+
+// CompileExpr compiles an expression in a subset of the jq language.
+//
+// Expression rule
+// .                 : identity. The input value itself.
+// .foo, ."foo"      : the value of a key of an object, or null if the key or the object does not exist.
+// .[n], .[-n]       : an element of an array, counted from the end if negative.
+// .[from:to]        : a sub-array, or a substring. Either bound may be omitted.
+// .[]               : all elements of an array or all values of an object ( wildcard ).
+// ..                : the input value and all values nested in it ( recursive descent ).
+// a | b             : b applied to each output of a.
+// a, b              : the outputs of a followed by the outputs of b.
+// [a]               : an array of all outputs of a.
+// a?                : the outputs of a, or nothing if a fails.
+// ==, !=, <, <=, >, >=, and, or : comparisons and logical operators.
+// "str", 1, true, false, null   : literals.
+//
+// The builtin functions are select(f), map(f), has(key), keys, length, type, not and empty.
+// The values of objects are iterated in key order, since the order of keys is not preserved by decoding.
+func CompileExpr(e string) (*Expr, error) {
+	prog, err := expr.Compile(e)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{prog: prog}, nil
+}
+
+// MustCompileExpr is like CompileExpr but panics if the expression cannot be compiled.
+func MustCompileExpr(e string) *Expr {
+	x, err := CompileExpr(e)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// Run decodes data and returns all outputs of the expression evaluated against it.
+// Objects are returned as map[string]interface{}, arrays as []interface{} and numbers as float64.
+func (e *Expr) Run(data []byte, optFuncs ...DecodeOptionFunc) ([]interface{}, error) {
+	var v interface{}
+	if err := unmarshal(data, &v, optFuncs...); err != nil {
+		return nil, err
+	}
+	return e.prog.Run(v)
+}
+
+// RunValue returns all outputs of the expression evaluated against v,
+// which must be a value made of the types that Unmarshal stores in an interface{}.
+// Numbers may also be of any Go integer or floating-point type.
+func (e *Expr) RunValue(v interface{}) ([]interface{}, error) {
+	return e.prog.Run(v)
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.prog.String()
+}
@@ -0,0 +1,83 @@
+package json_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestExpr(t *testing.T) {
+	data := []byte(`{
+  "users": [
+    {"name": "a", "age": 30, "tags": ["x", "y"]},
+    {"name": "b", "age": 17},
+    {"name": "cé", "age": 45, "admin": true}
+  ],
+  "n": null
+}`)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: `.users[0].name`, want: `[a]`},
+		{expr: `.users[].name`, want: `[a b cé]`},
+		{expr: `.users[-1]."name"`, want: `[cé]`},
+		{expr: `.users[5]`, want: `[<nil>]`},
+		{expr: `.n.x`, want: `[<nil>]`},
+		{expr: `.users[] | select(.age >= 18) | .name`, want: `[a cé]`},
+		{expr: `.users[] | select(.age < 18 or .admin) | .name`, want: `[b cé]`},
+		{expr: `[.users[] | select(.admin and .age > 40).name]`, want: `[[cé]]`},
+		{expr: `.users | map(.age)`, want: `[[30 17 45]]`},
+		{expr: `.users | length`, want: `[3]`},
+		{expr: `.users[0] | keys`, want: `[[age name tags]]`},
+		{expr: `.users[] | has("tags")`, want: `[true false false]`},
+		{expr: `.users[1:] | map(.name)`, want: `[[b cé]]`},
+		{expr: `.users[2].name[1:]`, want: `[é]`},
+		{expr: `.users[0].tags[]`, want: `[x y]`},
+		{expr: `.users[].tags[]?`, want: `[x y]`},
+		{expr: `[..|.age?|select(. != null)]`, want: `[[30 17 45]]`},
+		{expr: `.users[0].name, .users[1].name`, want: `[a b]`},
+		{expr: `.users[0] | .age == 30, .name != "a"`, want: `[true false]`},
+		{expr: `.n | not`, want: `[true]`},
+		{expr: `.users[] | .name | type`, want: `[string string string]`},
+		{expr: `.users[] | empty`, want: `[]`},
+		{expr: `[1, -2.5, "é", true, null]`, want: `[[1 -2.5 é true <nil>]]`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := json.CompileExpr(tc.expr)
+			assertErr(t, err)
+			got, err := e.Run(data)
+			assertErr(t, err)
+			assertEq(t, "outputs", tc.want, fmt.Sprint(got))
+		})
+	}
+	t.Run("value", func(t *testing.T) {
+		e := json.MustCompileExpr(`.[] | select(.id > 1) | .id`)
+		got, err := e.RunValue([]interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": json.Number("2")},
+			map[string]interface{}{"id": 3.5},
+		})
+		assertErr(t, err)
+		assertEq(t, "outputs", `[2 3.5]`, fmt.Sprint(got))
+		assertEq(t, "string", `.[] | select(.id > 1) | .id`, e.String())
+	})
+	t.Run("compile error", func(t *testing.T) {
+		for _, expr := range []string{``, `.a |`, `.[`, `.a ]`, `foo`, `select`, `"a`, `.a = 1`, `.[:]`} {
+			_, err := json.CompileExpr(expr)
+			if _, ok := err.(*json.ExprError); !ok {
+				t.Errorf("expected ExprError for %q but got %v", expr, err)
+			}
+		}
+	})
+	t.Run("eval error", func(t *testing.T) {
+		for _, expr := range []string{`.users.name`, `.users[0].age[]`, `.users[0] | .["a", 0]`, `.users[0].age | keys`} {
+			_, err := json.MustCompileExpr(expr).Run(data)
+			if _, ok := err.(*json.ExprError); !ok {
+				t.Errorf("expected ExprError for %q but got %v", expr, err)
+			}
+		}
+	})
+}
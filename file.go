@@ -0,0 +1,122 @@
+package json
+
+import (
+	"os"
+	"sync"
+
+	"github.com/going/json/internal/decoder"
+)
+
+// File is a JSON document read from a file.
+// On platforms that support it, the contents are memory-mapped instead of being
+// loaded into the heap, so multi-GB documents can be queried by path cheaply.
+//
+// Every call of Bytes, Decoder, Unmarshal and Extract works on its own private
+// copy-on-write view of the file, because the decoder unescapes strings in place.
+// Strings and byte slices obtained from a File may refer to the mapped memory,
+// so they must not be used after Close is called.
+type File struct {
+	file   *os.File
+	size   int64
+	data   []byte // contents of the file when it cannot be memory-mapped
+	mu     sync.Mutex
+	unmaps []func() error
+}
+
+// OpenFile opens the JSON document at path.
+// It falls back to reading the whole file when memory-mapping is not available.
+func OpenFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	size := stat.Size()
+	if canMmapFile(size) {
+		return &File{file: f, size: size}, nil
+	}
+	defer f.Close()
+	data := make([]byte, size)
+	if n, err := f.ReadAt(data, 0); err != nil && int64(n) != size {
+		return nil, err
+	}
+	return &File{size: size, data: data}, nil
+}
+
+// buffer returns a view of the contents terminated by a nul byte.
+func (f *File) buffer() ([]byte, error) {
+	if f.file == nil {
+		buf := make([]byte, len(f.data)+1) // append nul byte to the end
+		copy(buf, f.data)
+		return buf, nil
+	}
+	buf, unmap, err := mmapFile(f.file, f.size)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.unmaps = append(f.unmaps, unmap)
+	f.mu.Unlock()
+	return buf, nil
+}
+
+// Bytes returns the contents of the file.
+func (f *File) Bytes() ([]byte, error) {
+	buf, err := f.buffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf[:f.size], nil
+}
+
+// Decoder returns a new decoder that reads from the contents of the file without copying them.
+func (f *File) Decoder() (*Decoder, error) {
+	buf, err := f.buffer()
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{s: decoder.NewTerminatedBytesStream(buf)}, nil
+}
+
+// Unmarshal decodes the contents of the file into the value pointed to by v.
+func (f *File) Unmarshal(v interface{}, optFuncs ...DecodeOptionFunc) error {
+	buf, err := f.buffer()
+	if err != nil {
+		return err
+	}
+	return unmarshalTerminated(buf, v, optFuncs...)
+}
+
+// Extract extracts the JSON values corresponding to path from the contents of the file.
+// The returned values refer to the contents of the file.
+func (f *File) Extract(path *Path, optFuncs ...DecodeOptionFunc) ([][]byte, error) {
+	buf, err := f.buffer()
+	if err != nil {
+		return nil, err
+	}
+	return extractFromPathTerminated(path, buf, optFuncs...)
+}
+
+// Close releases the resources associated with the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	unmaps := f.unmaps
+	f.unmaps = nil
+	f.mu.Unlock()
+	var err error
+	for _, unmap := range unmaps {
+		if e := unmap(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if f.file != nil {
+		if e := f.file.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+//go:build unix
+// +build unix
+
+package json
+
+import (
+	"os"
+	"syscall"
+)
+
+// canMmapFile reports whether a file of size bytes can be mapped with a trailing nul byte.
+// This is only possible when the last page of the file has room for the terminator,
+// since the rest of that page is zero-filled by the kernel.
+func canMmapFile(size int64) bool {
+	return size > 0 && size%int64(os.Getpagesize()) != 0 && int64(int(size)) == size
+}
+
+// mmapFile maps size+1 bytes of f into memory so that the contents are terminated by a nul byte.
+// The mapping is private and writable because the decoder unescapes strings in place.
+func mmapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	buf, err := syscall.Mmap(int(f.Fd()), 0, int(size)+1, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, func() error { return syscall.Munmap(buf) }, nil
+}
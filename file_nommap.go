@@ -0,0 +1,17 @@
+//go:build !unix
+// +build !unix
+
+package json
+
+import (
+	"errors"
+	"os"
+)
+
+func canMmapFile(size int64) bool {
+	return false
+}
+
+func mmapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	return nil, nil, errors.New("json: memory-mapped files are not supported")
+}
@@ -0,0 +1,51 @@
+package json_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	src := `{"users": [{"name": "a\"b"}, {"name": "c"}]}`
+	for _, size := range []int{len(src), os.Getpagesize()} {
+		data := append([]byte(src), bytes.Repeat([]byte(" "), size-len(src))...)
+		path := filepath.Join(dir, "test.json")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		f, err := json.OpenFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := f.Bytes()
+		assertErr(t, err)
+		assertEq(t, "bytes", string(data), string(b))
+
+		p, err := json.CreatePath("$.users[*].name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		names, err := f.Extract(p)
+		assertErr(t, err)
+		assertEq(t, "extract", 2, len(names))
+		assertEq(t, "extract", `"c"`, string(bytes.TrimSpace(names[1])))
+
+		var v struct {
+			Users []struct {
+				Name string
+			}
+		}
+		dec, err := f.Decoder()
+		assertErr(t, err)
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "decoder", `a"b`, v.Users[0].Name)
+		assertErr(t, f.Unmarshal(&v))
+		assertEq(t, "unmarshal", "c", v.Users[1].Name)
+		assertErr(t, f.Close())
+	}
+}
@@ -0,0 +1,323 @@
+package json
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// FrameFormat is the framing of the JSON values written by FramedEncoder and read by FramedDecoder.
+type FrameFormat int
+
+const (
+	// FrameUint32 prefixes each value with its length as a 4-byte big-endian unsigned integer.
+	FrameUint32 FrameFormat = iota
+	// FrameVarint prefixes each value with its length as an unsigned varint ( see encoding/binary ).
+	FrameVarint
+	// FrameNewline terminates each value with a newline character, as in JSON Lines.
+	// The last value of the input may be unterminated.
+	FrameNewline
+	// FrameSequence prefixes each value with the record separator character (0x1E) and terminates it with
+	// a newline character, as in JSON text sequences ( RFC 7464, application/json-seq ).
+	FrameSequence
+)
+
+// recordSeparator is the character that starts each value of FrameSequence.
+const recordSeparator = 0x1E
+
+// FramedEncoder writes JSON values as frames to an output stream.
+// Each frame is written by a single Write call.
+type FramedEncoder struct {
+	w      io.Writer
+	format FrameFormat
+	buf    []byte
+}
+
+// NewFramedEncoder returns a new encoder that writes FrameUint32 frames to w.
+func NewFramedEncoder(w io.Writer) *FramedEncoder {
+	return &FramedEncoder{w: w, format: FrameUint32}
+}
+
+// SetFormat sets the framing of the subsequent frames.
+func (e *FramedEncoder) SetFormat(format FrameFormat) {
+	e.format = format
+}
+
+// Encode writes the JSON encoding of v as a frame.
+func (e *FramedEncoder) Encode(v interface{}) error {
+	return e.EncodeWithOption(v)
+}
+
+// EncodeWithOption call Encode with EncodeOption.
+func (e *FramedEncoder) EncodeWithOption(v interface{}, optFuncs ...EncodeOptionFunc) error {
+	data, err := MarshalWithOption(v, optFuncs...)
+	if err != nil {
+		return err
+	}
+	return e.WriteFrame(data)
+}
+
+// WriteFrame writes data, which must be a JSON value already encoded, as a frame.
+// With FrameNewline, data must not contain newline characters.
+func (e *FramedEncoder) WriteFrame(data []byte) error {
+	buf := e.buf[:0]
+	switch e.format {
+	case FrameUint32:
+		if uint64(len(data)) > 0xffffffff {
+			return fmt.Errorf("json: frame size %d exceeds the limit of 4-byte length prefix", len(data))
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+		buf = append(buf, data...)
+	case FrameVarint:
+		buf = binary.AppendUvarint(buf, uint64(len(data)))
+		buf = append(buf, data...)
+	case FrameNewline:
+		buf = append(append(buf, data...), '\n')
+	case FrameSequence:
+		buf = append(append(append(buf, recordSeparator), data...), '\n')
+	default:
+		return fmt.Errorf("json: unknown frame format %d", e.format)
+	}
+	e.buf = buf
+	if _, err := e.w.Write(buf); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FramedDecoder reads JSON values as frames from an input stream.
+// Unlike Decoder, it never reads past the end of the current frame,
+// so the input stream can be shared with other readers between frames.
+// Reading byte by byte is needed to find the end of FrameVarint, FrameNewline and FrameSequence frames,
+// which is efficient only if the input stream implements io.ByteReader ( e.g. *bufio.Reader ).
+type FramedDecoder struct {
+	r            io.Reader
+	byteReader   io.ByteReader
+	format       FrameFormat
+	maxFrameSize int
+	head         [4]byte
+}
+
+const maxFrameInt = int(^uint(0) >> 1)
+
+// frameChunkSize is the size of the content of a frame up to which the buffer is allocated at once from its length prefix.
+const frameChunkSize = 64 * 1024
+
+type frameByteReader struct {
+	d *FramedDecoder
+}
+
+func (r frameByteReader) ReadByte() (byte, error) {
+	return r.d.readByte()
+}
+
+// NewFramedDecoder returns a new decoder that reads FrameUint32 frames from r.
+func NewFramedDecoder(r io.Reader) *FramedDecoder {
+	d := &FramedDecoder{r: r, format: FrameUint32}
+	if br, ok := r.(io.ByteReader); ok {
+		d.byteReader = br
+	}
+	return d
+}
+
+// SetFormat sets the framing of the subsequent frames.
+func (d *FramedDecoder) SetFormat(format FrameFormat) {
+	d.format = format
+}
+
+// SetMaxFrameSize sets the maximum size of a frame. A larger frame makes ReadFrame return an error
+// before its content is read. n <= 0 means unlimited, which is the default.
+func (d *FramedDecoder) SetMaxFrameSize(n int) {
+	d.maxFrameSize = n
+}
+
+// Decode reads the next frame and stores its JSON value in the value pointed to by v.
+// It returns io.EOF if the input stream ends at a frame boundary.
+func (d *FramedDecoder) Decode(v interface{}) error {
+	return d.DecodeWithOption(v)
+}
+
+// DecodeWithOption call Decode with DecodeOption.
+func (d *FramedDecoder) DecodeWithOption(v interface{}, optFuncs ...DecodeOptionFunc) error {
+	src, err := d.readFrame(1)
+	if err != nil {
+		return err
+	}
+	src[len(src)-1] = nul
+	return unmarshalTerminated(src, v, optFuncs...)
+}
+
+// ReadFrame reads the next frame and returns its content without decoding it.
+// It returns io.EOF if the input stream ends at a frame boundary.
+func (d *FramedDecoder) ReadFrame() (RawMessage, error) {
+	return d.readFrame(0)
+}
+
+// readFrame reads the next frame into a new buffer which has extra bytes after the content.
+func (d *FramedDecoder) readFrame(extra int) ([]byte, error) {
+	switch d.format {
+	case FrameUint32:
+		if _, err := io.ReadFull(d.r, d.head[:4]); err != nil {
+			return nil, err
+		}
+		return d.readContent(uint64(binary.BigEndian.Uint32(d.head[:4])), extra)
+	case FrameVarint:
+		size, err := binary.ReadUvarint(frameByteReader{d})
+		if err != nil {
+			return nil, err
+		}
+		return d.readContent(size, extra)
+	case FrameNewline:
+		return d.readLine(extra)
+	case FrameSequence:
+		return d.readSequence(extra)
+	}
+	return nil, fmt.Errorf("json: unknown frame format %d", d.format)
+}
+
+func (d *FramedDecoder) readContent(size uint64, extra int) ([]byte, error) {
+	if d.maxFrameSize > 0 && size > uint64(d.maxFrameSize) {
+		return nil, fmt.Errorf("json: frame size %d exceeds the limit %d", size, d.maxFrameSize)
+	}
+	if size > uint64(maxFrameInt-extra) {
+		return nil, fmt.Errorf("json: frame size %d is too large", size)
+	}
+	if size <= frameChunkSize {
+		buf := make([]byte, int(size)+extra)
+		if _, err := io.ReadFull(d.r, buf[:size]); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return buf, nil
+	}
+	// a larger buffer grows as the content is read rather than to the size of the prefix,
+	// which may be corrupt or forged to exhaust the memory.
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(size)); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return append(buf.Bytes(), make([]byte, extra)...), nil
+}
+
+func (d *FramedDecoder) readLine(extra int) ([]byte, error) {
+	var buf []byte
+	for {
+		c, err := d.readByte()
+		if err == io.EOF && len(buf) > 0 {
+			// the last line of the input may have no newline character.
+			c, err = '\n', nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '\n':
+			if len(buf) > 0 && buf[len(buf)-1] == '\r' {
+				buf = buf[:len(buf)-1]
+			}
+			if len(buf) == 0 {
+				// skip empty lines
+				continue
+			}
+			for i := 0; i < extra; i++ {
+				buf = append(buf, 0)
+			}
+			return buf, nil
+		default:
+			if d.maxFrameSize > 0 && len(buf) >= d.maxFrameSize {
+				return nil, fmt.Errorf("json: frame size exceeds the limit %d", d.maxFrameSize)
+			}
+			buf = append(buf, c)
+		}
+	}
+}
+
+// readSequence reads the next value of FrameSequence. The value ends at the newline character that follows
+// a complete JSON text, so that it is returned as soon as it has been read, or else at the next record separator,
+// where the following value starts, or at the end of the input.
+// A corrupt value is returned to be decoded, and fails, but the next value is read from the next record separator,
+// so that the decoder resynchronizes after it. Consecutive record separators are skipped.
+func (d *FramedDecoder) readSequence(extra int) ([]byte, error) {
+	var (
+		buf      []byte
+		started  bool
+		depth    int
+		inString bool
+		escaped  bool
+	)
+	for {
+		c, err := d.readByte()
+		if err == io.EOF && started {
+			return sequenceValue(buf, false, extra)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == recordSeparator {
+			if !started {
+				buf = buf[:0]
+				continue
+			}
+			return sequenceValue(buf, false, extra)
+		}
+		if d.maxFrameSize > 0 && len(buf) >= d.maxFrameSize {
+			return nil, fmt.Errorf("json: frame size exceeds the limit %d", d.maxFrameSize)
+		}
+		buf = append(buf, c)
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString, started = true, true
+		case c == '{' || c == '[':
+			depth++
+			started = true
+		case c == '}' || c == ']':
+			depth--
+		case c == '\n':
+			if started && depth <= 0 {
+				return sequenceValue(buf, true, extra)
+			}
+		case c != ' ' && c != '\t' && c != '\r':
+			started = true
+		}
+	}
+}
+
+// sequenceValue returns the JSON text of a value of FrameSequence with extra bytes after it.
+// A text that is not terminated by a newline character may have been truncated: RFC 7464 requires to drop it
+// unless it ends with a closing bracket or quote, since a truncated number or literal might still be valid.
+func sequenceValue(buf []byte, terminated bool, extra int) ([]byte, error) {
+	text := bytes.TrimSpace(buf)
+	if !terminated {
+		if c := text[len(text)-1]; c != '}' && c != ']' && c != '"' {
+			return nil, fmt.Errorf("json: truncated JSON text sequence value %q", text)
+		}
+	}
+	value := make([]byte, len(text)+extra)
+	copy(value, text)
+	return value, nil
+}
+
+func (d *FramedDecoder) readByte() (byte, error) {
+	if d.byteReader != nil {
+		return d.byteReader.ReadByte()
+	}
+	if _, err := io.ReadFull(d.r, d.head[:1]); err != nil {
+		return 0, err
+	}
+	return d.head[0], nil
+}
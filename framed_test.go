@@ -0,0 +1,132 @@
+package json_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestFramedCodec(t *testing.T) {
+	type T struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	values := []T{{A: 1, B: "x\ny"}, {A: 2}, {A: 3, B: strings.Repeat("z", 300)}}
+	for _, format := range []json.FrameFormat{json.FrameUint32, json.FrameVarint, json.FrameNewline, json.FrameSequence} {
+		var buf bytes.Buffer
+		enc := json.NewFramedEncoder(&buf)
+		enc.SetFormat(format)
+		for _, v := range values {
+			assertErr(t, enc.Encode(v))
+		}
+		buf.WriteString("trailing")
+
+		// a reader without io.ByteReader must not be read past the end of the last frame.
+		r := struct{ io.Reader }{&buf}
+		dec := json.NewFramedDecoder(r)
+		dec.SetFormat(format)
+		for _, want := range values {
+			var got T
+			assertErr(t, dec.Decode(&got))
+			assertEq(t, "value", want, got)
+		}
+		assertEq(t, "rest", "trailing", buf.String())
+	}
+}
+
+func TestFramedDecoderErrors(t *testing.T) {
+	t.Run("eof", func(t *testing.T) {
+		dec := json.NewFramedDecoder(bytes.NewReader(nil))
+		var v interface{}
+		assertEq(t, "error", io.EOF, dec.Decode(&v))
+	})
+	t.Run("truncated", func(t *testing.T) {
+		dec := json.NewFramedDecoder(bytes.NewReader([]byte{0, 0, 0, 5, '1'}))
+		_, err := dec.ReadFrame()
+		assertEq(t, "error", io.ErrUnexpectedEOF, err)
+	})
+	t.Run("forged size", func(t *testing.T) {
+		// the length prefix claims 64 GiB, which must not be allocated before the content arrives.
+		src := binary.AppendUvarint(nil, 1<<36)
+		src = append(src, `{"a":1}`...)
+		dec := json.NewFramedDecoder(bytes.NewReader(src))
+		dec.SetFormat(json.FrameVarint)
+		var v interface{}
+		assertEq(t, "error", io.ErrUnexpectedEOF, dec.Decode(&v))
+	})
+	t.Run("large frame", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewFramedEncoder(&buf)
+		large := strings.Repeat("x", 200*1024)
+		assertErr(t, enc.Encode(large))
+		assertErr(t, enc.Encode(1))
+		dec := json.NewFramedDecoder(&buf)
+		var s string
+		assertErr(t, dec.Decode(&s))
+		assertEq(t, "large", large, s)
+		var n int
+		assertErr(t, dec.Decode(&n))
+		assertEq(t, "next", 1, n)
+	})
+	t.Run("max frame size", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewFramedEncoder(&buf)
+		assertErr(t, enc.Encode("0123456789"))
+		dec := json.NewFramedDecoder(&buf)
+		dec.SetMaxFrameSize(8)
+		if _, err := dec.ReadFrame(); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("newline", func(t *testing.T) {
+		dec := json.NewFramedDecoder(strings.NewReader("\n{\"a\":1}\r\n\n[1]\n"))
+		dec.SetFormat(json.FrameNewline)
+		frame, err := dec.ReadFrame()
+		assertErr(t, err)
+		assertEq(t, "first", `{"a":1}`, string(frame))
+		frame, err = dec.ReadFrame()
+		assertErr(t, err)
+		assertEq(t, "second", `[1]`, string(frame))
+		_, err = dec.ReadFrame()
+		assertEq(t, "end", io.EOF, err)
+	})
+	t.Run("unterminated last line", func(t *testing.T) {
+		dec := json.NewFramedDecoder(strings.NewReader("{\"a\":1}\n{\"a\":2}\r"))
+		dec.SetFormat(json.FrameNewline)
+		var v struct {
+			A int `json:"a"`
+		}
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "first", 1, v.A)
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "last", 2, v.A)
+		assertEq(t, "end", io.EOF, dec.Decode(&v))
+	})
+	t.Run("sequence", func(t *testing.T) {
+		src := "\x1e{\"a\":1}\n\x1e{\"a\":\x1e\x1e{\n  \"a\": [\n    2\n  ]\n}\n\x1e\"s\"\x1e3"
+		dec := json.NewFramedDecoder(strings.NewReader(src))
+		dec.SetFormat(json.FrameSequence)
+		var v struct {
+			A interface{} `json:"a"`
+		}
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "first", float64(1), v.A)
+		// the corrupt value fails, and the decoder resynchronizes at the next record separator.
+		assertNeq(t, "corrupt", nil, dec.Decode(&v))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "multiline", "[2]", fmt.Sprint(v.A))
+		frame, err := dec.ReadFrame()
+		assertErr(t, err)
+		assertEq(t, "unterminated string", `"s"`, string(frame))
+		// a number without the newline character may have been truncated.
+		_, err = dec.ReadFrame()
+		assertNeq(t, "truncated", nil, err)
+		_, err = dec.ReadFrame()
+		assertEq(t, "end", io.EOF, err)
+	})
+}
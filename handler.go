@@ -0,0 +1,179 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize is the maximum size of the request body accepted by Handler, unless HandlerMaxBodySize is given.
+const DefaultMaxBodySize = 1 << 20
+
+// HTTPError is an error with the status of the response, which the function of Handler returns to fail a request
+// with a status other than 500 Internal Server Error. Message is sent to the client, and Err, the cause of the error, is not.
+// A Status that is not a valid HTTP status code, such as 0, is sent as 500 Internal Server Error.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// Error returns Message.
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerOption is the configuration of Handler.
+type HandlerOption struct {
+	maxBodySize   int64
+	decodeOptions []DecodeOptionFunc
+	encodeOptions []EncodeOptionFunc
+	errorFunc     func(err error) (int, interface{})
+}
+
+// HandlerOptionFunc configures Handler.
+type HandlerOptionFunc func(*HandlerOption)
+
+// HandlerMaxBodySize sets the maximum size of the request body, beyond which the request fails with 413 Request Entity Too Large.
+func HandlerMaxBodySize(n int64) HandlerOptionFunc {
+	return func(opt *HandlerOption) {
+		opt.maxBodySize = n
+	}
+}
+
+// HandlerDecodeOptions replaces the options the request bodies are decoded with,
+// which are StrictRFC8259 and DisallowUnknownFields by default.
+func HandlerDecodeOptions(optFuncs ...DecodeOptionFunc) HandlerOptionFunc {
+	return func(opt *HandlerOption) {
+		opt.decodeOptions = optFuncs
+	}
+}
+
+// HandlerEncodeOptions sets the options the responses are encoded with.
+func HandlerEncodeOptions(optFuncs ...EncodeOptionFunc) HandlerOptionFunc {
+	return func(opt *HandlerOption) {
+		opt.encodeOptions = optFuncs
+	}
+}
+
+// HandlerErrorFunc sets the function that shapes the response of a failed request from its error,
+// which returns the status of the response and the value encoded as its body.
+// err is either an *HTTPError for a request that cannot be decoded, wrapping the decode error,
+// or the error returned by the function of Handler.
+// By default, the body is {"error": message}, with the message of an *HTTPError,
+// and a generic message for the other errors, which fail with 500 Internal Server Error.
+func HandlerErrorFunc(fn func(err error) (status int, body interface{})) HandlerOptionFunc {
+	return func(opt *HandlerOption) {
+		opt.errorFunc = fn
+	}
+}
+
+// Handler returns an http.Handler that decodes the JSON body of each request into an In,
+// calls fn with the context of the request, and encodes the returned Out as the JSON body of the response:
+//
+//	http.Handle("/users", json.Handler(func(ctx context.Context, req CreateUser) (*User, error) {
+//		if req.Name == "" {
+//			return nil, &json.HTTPError{Status: http.StatusUnprocessableEntity, Message: "name is required"}
+//		}
+//		return store.Create(ctx, req)
+//	}))
+//
+// The body is decoded with the strict options, StrictRFC8259 and DisallowUnknownFields, unless HandlerDecodeOptions is given,
+// and must not be larger than DefaultMaxBodySize or HandlerMaxBodySize.
+// A request whose body cannot be decoded fails with 400 Bad Request, and fn is not called.
+// A request with a Content-Type other than JSON fails with 415 Unsupported Media Type.
+// An empty body, such as of a GET request, leaves In as its zero value.
+// The failed requests are answered as set by HandlerErrorFunc.
+func Handler[In, Out any](fn func(context.Context, In) (Out, error), optFuncs ...HandlerOptionFunc) http.Handler {
+	opt := HandlerOption{
+		maxBodySize:   DefaultMaxBodySize,
+		decodeOptions: []DecodeOptionFunc{StrictRFC8259(), DisallowUnknownFields()},
+		errorFunc:     defaultHandlerError,
+	}
+	for _, optFunc := range optFuncs {
+		optFunc(&opt)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in In
+		if err := opt.decodeRequest(r, w, &in); err != nil {
+			opt.writeError(w, err)
+			return
+		}
+		out, err := fn(r.Context(), in)
+		if err != nil {
+			opt.writeError(w, err)
+			return
+		}
+		body, err := MarshalWithOption(out, opt.encodeOptions...)
+		if err != nil {
+			opt.writeError(w, err)
+			return
+		}
+		writeHandlerResponse(w, http.StatusOK, body)
+	})
+}
+
+func (opt *HandlerOption) decodeRequest(r *http.Request, w http.ResponseWriter, v interface{}) error {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !isJSONMediaType(contentType) {
+		return &HTTPError{Status: http.StatusUnsupportedMediaType, Message: "unsupported content type " + contentType}
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opt.maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large", Err: err}
+		}
+		return &HTTPError{Status: http.StatusBadRequest, Message: "cannot read request body", Err: err}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := UnmarshalContext(r.Context(), data, v, opt.decodeOptions...); err != nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (opt *HandlerOption) writeError(w http.ResponseWriter, err error) {
+	status, v := opt.errorFunc(err)
+	body, err := MarshalWithOption(v, opt.encodeOptions...)
+	if err != nil {
+		status, body = http.StatusInternalServerError, []byte(`{"error":"internal server error"}`)
+	}
+	writeHandlerResponse(w, status, body)
+}
+
+func defaultHandlerError(err error) (int, interface{}) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status, map[string]string{"error": httpErr.Message}
+	}
+	// the other errors are not sent to the client, since they may reveal the internals of the server.
+	return http.StatusInternalServerError, map[string]string{"error": http.StatusText(http.StatusInternalServerError)}
+}
+
+func writeHandlerResponse(w http.ResponseWriter, status int, body []byte) {
+	if status < 100 || status > 999 {
+		// e.g. an HTTPError without Status, which WriteHeader would panic for.
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
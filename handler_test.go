@@ -0,0 +1,93 @@
+package json_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestHandler(t *testing.T) {
+	type Request struct {
+		Name string `json:"name"`
+	}
+	type Response struct {
+		Greeting string `json:"greeting"`
+	}
+	errNotFound := errors.New("not found")
+	handler := json.Handler(func(ctx context.Context, req Request) (Response, error) {
+		switch req.Name {
+		case "":
+			return Response{}, &json.HTTPError{Status: http.StatusUnprocessableEntity, Message: "name is required"}
+		case "nobody":
+			return Response{}, errNotFound
+		case "unset":
+			return Response{}, &json.HTTPError{Message: "no status"}
+		}
+		return Response{Greeting: "hello " + req.Name}, nil
+	}, json.HandlerMaxBodySize(64))
+
+	serve := func(body, contentType string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	for _, tc := range []struct {
+		name        string
+		body        string
+		contentType string
+		status      int
+		response    string
+	}{
+		{"ok", `{"name": "a"}`, "application/json", http.StatusOK, `{"greeting":"hello a"}`},
+		{"no content type", `{"name": "a"}`, "", http.StatusOK, `{"greeting":"hello a"}`},
+		{"empty body", ``, "", http.StatusUnprocessableEntity, `{"error":"name is required"}`},
+		{"unknown field", `{"name": "a", "nam": 1}`, "", http.StatusBadRequest, ""},
+		{"duplicate key", `{"name": "a", "name": "b"}`, "", http.StatusBadRequest, ""},
+		{"syntax", `{"name": `, "", http.StatusBadRequest, ""},
+		{"too large", `{"name": "` + strings.Repeat("a", 100) + `"}`, "", http.StatusRequestEntityTooLarge, `{"error":"request body too large"}`},
+		{"content type", `{"name": "a"}`, "text/plain", http.StatusUnsupportedMediaType, `{"error":"unsupported content type text/plain"}`},
+		{"internal error", `{"name": "nobody"}`, "", http.StatusInternalServerError, `{"error":"Internal Server Error"}`},
+		{"no status", `{"name": "unset"}`, "", http.StatusInternalServerError, `{"error":"no status"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(tc.body, tc.contentType)
+			assertEq(t, "status", tc.status, w.Code)
+			assertEq(t, "content type", "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+			if tc.response != "" {
+				assertEq(t, "response", tc.response+"\n", w.Body.String())
+			}
+		})
+	}
+	t.Run("error func", func(t *testing.T) {
+		handler := json.Handler(func(ctx context.Context, req Request) (*Response, error) {
+			return nil, errNotFound
+		}, json.HandlerErrorFunc(func(err error) (int, interface{}) {
+			var serr *json.SyntaxError
+			switch {
+			case errors.As(err, &serr):
+				return http.StatusBadRequest, map[string]int64{"offset": serr.Offset}
+			case errors.Is(err, errNotFound):
+				return http.StatusNotFound, map[string]string{"message": err.Error()}
+			}
+			return http.StatusInternalServerError, nil
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assertEq(t, "status", http.StatusNotFound, w.Code)
+		assertEq(t, "response", `{"message":"not found"}`+"\n", w.Body.String())
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name" 1}`)))
+		assertEq(t, "status", http.StatusBadRequest, w.Code)
+		assertEq(t, "response", `{"offset":8}`+"\n", w.Body.String())
+	})
+}
@@ -59,7 +59,11 @@ func (d *arrayDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 				return nil
 			}
 			for {
-				if idx < d.alen {
+				if idx < d.alen && s.Option.Flags&PathModeOption != 0 {
+					if err := s.decodeAtPath(d.valueDecoder, pathIndex(idx), depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size)); err != nil {
+						return err
+					}
+				} else if idx < d.alen {
 					if err := d.valueDecoder.DecodeStream(s, depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size)); err != nil {
 						return err
 					}
@@ -135,14 +139,20 @@ func (d *arrayDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe
 				return cursor, nil
 			}
 			for {
-				if idx < d.alen {
+				if idx < d.alen && ctx.Option.Flags&PathModeOption != 0 {
+					c, err := ctx.decodeAtPath(d.valueDecoder, pathIndex(idx), cursor, depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size))
+					if err != nil {
+						return 0, err
+					}
+					cursor = c
+				} else if idx < d.alen {
 					c, err := d.valueDecoder.Decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size))
 					if err != nil {
 						return 0, err
 					}
 					cursor = c
 				} else {
-					c, err := skipValue(buf, cursor, depth)
+					c, err := ctx.skipValue(cursor, depth)
 					if err != nil {
 						return 0, err
 					}
@@ -0,0 +1,43 @@
+package decoder
+
+import (
+	"sort"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// uniqueFieldSets returns the fields of fieldMap without the aliases of their keys, in the order of their offsets.
+func uniqueFieldSets(fieldMap map[string]*structFieldSet) []*structFieldSet {
+	seen := map[*structFieldSet]struct{}{}
+	sets := make([]*structFieldSet, 0, len(fieldMap))
+	for _, set := range fieldMap {
+		if _, exists := seen[set]; exists {
+			continue
+		}
+		seen[set] = struct{}{}
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].offset != sets[j].offset {
+			return sets[i].offset < sets[j].offset
+		}
+		return sets[i].key < sets[j].key
+	})
+	return sets
+}
+
+// audit reports the key of the object at the path to the collector of AuditOption.
+func (s *pathState) audit(opt *Option, kind runtime.AuditKind, key string) {
+	s.path = append(s.path, key)
+	opt.Audit(runtime.AuditEvent{Kind: kind, Path: runtime.JSONPointer(s.path)})
+	s.path = s.path[:len(s.path)-1]
+}
+
+// auditDefaulted reports the fields of d that are not in seen, the fields decoded from the object at the path.
+func (s *pathState) auditDefaulted(opt *Option, d *structDecoder, seen map[*structFieldSet]struct{}) {
+	for _, set := range d.fieldSets {
+		if _, exists := seen[set]; !exists && set.err == nil {
+			s.audit(opt, runtime.AuditDefaulted, set.key)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package decoder
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/going/json/internal/runtime"
+)
+
+type codec struct {
+	typ *runtime.Type
+	fn  DecodeFunc
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]codec{}
+)
+
+// RegisterCodec registers fn as the codec name of the struct fields of typ with the codec=name tag option.
+func RegisterCodec(name string, typ *runtime.Type, fn DecodeFunc) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs[name] = codec{typ: typ, fn: fn}
+}
+
+// compileCodec returns the decoder of a struct field of typ with the codec=name tag option.
+func compileCodec(name string, typ *runtime.Type) (Decoder, error) {
+	codecMu.RLock()
+	c, exists := codecs[name]
+	codecMu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("json: unknown codec %q ( see RegisterCodec )", name)
+	}
+	if c.typ != typ {
+		return nil, fmt.Errorf("json: codec %q decodes %s, not %s", name, c.typ, typ)
+	}
+	return newAdapterDecoder(typ, c.fn), nil
+}
@@ -15,9 +15,10 @@ import (
 
 var (
 	jsonNumberType   = reflect.TypeOf(json.Number(""))
+	rawNumberType    = reflect.TypeOf(RawNumber(nil))
 	typeAddr         *runtime.TypeAddr
-	cachedDecoderMap unsafe.Pointer // map[uintptr]decoder
-	cachedDecoder    []Decoder
+	cachedDecoderMap [decoderMapShardNum]decoderMapShard
+	cachedDecoder    []unsafe.Pointer // []*Decoder
 	initOnce         sync.Once
 )
 
@@ -27,18 +28,38 @@ func initDecoder() {
 		if typeAddr == nil {
 			typeAddr = &runtime.TypeAddr{}
 		}
-		cachedDecoder = make([]Decoder, typeAddr.AddrRange>>typeAddr.AddrShift+1)
+		cachedDecoder = make([]unsafe.Pointer, typeAddr.AddrRange>>typeAddr.AddrShift+1)
 	})
 }
 
-func loadDecoderMap() map[uintptr]Decoder {
-	initDecoder()
-	p := atomic.LoadPointer(&cachedDecoderMap)
-	return *(*map[uintptr]Decoder)(unsafe.Pointer(&p))
+// decoderMapShardNum is the number of shards of the cache for types out of the range of cachedDecoder.
+// Readers never lock, and writers only lock the shard of the type being stored.
+const decoderMapShardNum = 64
+
+type decoderMapShard struct {
+	mu sync.Mutex
+	m  unsafe.Pointer // map[uintptr]Decoder, replaced with copy-on-write
 }
 
-func storeDecoder(typ uintptr, dec Decoder, m map[uintptr]Decoder) {
-	initDecoder()
+func decoderMapShardOf(typ uintptr) *decoderMapShard {
+	return &cachedDecoderMap[((typ>>4)^(typ>>12))%decoderMapShardNum]
+}
+
+func (s *decoderMapShard) load(typ uintptr) Decoder {
+	p := atomic.LoadPointer(&s.m)
+	return (*(*map[uintptr]Decoder)(unsafe.Pointer(&p)))[typ]
+}
+
+func (s *decoderMapShard) store(typ uintptr, dec Decoder) Decoder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := atomic.LoadPointer(&s.m)
+	m := *(*map[uintptr]Decoder)(unsafe.Pointer(&p))
+	if stored, exists := m[typ]; exists {
+		// another goroutine compiled the same type first.
+		return stored
+	}
 	newDecoderMap := make(map[uintptr]Decoder, len(m)+1)
 	newDecoderMap[typ] = dec
 
@@ -46,56 +67,89 @@ func storeDecoder(typ uintptr, dec Decoder, m map[uintptr]Decoder) {
 		newDecoderMap[k] = v
 	}
 
-	atomic.StorePointer(&cachedDecoderMap, *(*unsafe.Pointer)(unsafe.Pointer(&newDecoderMap)))
+	atomic.StorePointer(&s.m, *(*unsafe.Pointer)(unsafe.Pointer(&newDecoderMap)))
+	return dec
+}
+
+func CompileToGetDecoder(typ *runtime.Type) (Decoder, error) {
+	initDecoder()
+	typeptr := uintptr(unsafe.Pointer(typ))
+	if typeptr > typeAddr.MaxTypeAddr || typeptr < typeAddr.BaseTypeAddr {
+		return compileToGetDecoderSlowPath(typeptr, typ)
+	}
+
+	index := (typeptr - typeAddr.BaseTypeAddr) >> typeAddr.AddrShift
+	if dec := atomic.LoadPointer(&cachedDecoder[index]); dec != nil {
+		return *(*Decoder)(dec), nil
+	}
+
+	dec, err := compileHead(typ, map[uintptr]Decoder{}, runtime.TagConfig{})
+	if err != nil {
+		return nil, err
+	}
+	// Decoder is an interface value of two words, so it is stored by pointer to be replaced atomically.
+	atomic.StorePointer(&cachedDecoder[index], unsafe.Pointer(&dec))
+	return dec, nil
 }
 
 func compileToGetDecoderSlowPath(typeptr uintptr, typ *runtime.Type) (Decoder, error) {
-	decoderMap := loadDecoderMap()
-	if dec, exists := decoderMap[typeptr]; exists {
+	shard := decoderMapShardOf(typeptr)
+	if dec := shard.load(typeptr); dec != nil {
 		return dec, nil
 	}
 
-	dec, err := compileHead(typ, map[uintptr]Decoder{})
+	dec, err := compileHead(typ, map[uintptr]Decoder{}, runtime.TagConfig{})
 	if err != nil {
 		return nil, err
 	}
-	storeDecoder(typeptr, dec, decoderMap)
-	return dec, nil
+	return shard.store(typeptr, dec), nil
 }
 
-func compileHead(typ *runtime.Type, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
+func compileHead(typ *runtime.Type, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
 	switch {
 	case implementsUnmarshalJSONType(runtime.PtrTo(typ)):
 		return newUnmarshalJSONDecoder(runtime.PtrTo(typ), "", ""), nil
 	case runtime.PtrTo(typ).Implements(unmarshalTextType):
 		return newUnmarshalTextDecoder(runtime.PtrTo(typ), "", ""), nil
 	}
-	return compile(typ.Elem(), "", "", structTypeToDecoder)
+	return compile(typ.Elem(), "", "", structTypeToDecoder, tagConfig)
 }
 
-func compile(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
+func compile(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
+	if dec, exists := structTypeToDecoder[uintptr(unsafe.Pointer(typ))]; exists {
+		return dec, nil
+	}
 	switch {
+	case typ == rawMessageType:
+		return newRawMessageDecoder(structName, fieldName), nil
 	case implementsUnmarshalJSONType(runtime.PtrTo(typ)):
 		return newUnmarshalJSONDecoder(runtime.PtrTo(typ), structName, fieldName), nil
 	case runtime.PtrTo(typ).Implements(unmarshalTextType):
 		return newUnmarshalTextDecoder(runtime.PtrTo(typ), structName, fieldName), nil
 	}
+	return compileKind(typ, structName, fieldName, structTypeToDecoder, tagConfig)
+}
 
+// compileKind compiles the decoder of typ by its kind, regardless of its UnmarshalJSON and UnmarshalText methods.
+func compileKind(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
 	switch typ.Kind() {
 	case reflect.Ptr:
-		return compilePtr(typ, structName, fieldName, structTypeToDecoder)
+		return compilePtr(typ, structName, fieldName, structTypeToDecoder, tagConfig)
 	case reflect.Struct:
-		return compileStruct(typ, structName, fieldName, structTypeToDecoder)
+		return compileStruct(typ, structName, fieldName, structTypeToDecoder, tagConfig)
 	case reflect.Slice:
+		if typ == runtime.Type2RType(rawNumberType) {
+			return compileRawNumber(structName, fieldName)
+		}
 		elem := typ.Elem()
 		if elem.Kind() == reflect.Uint8 {
 			return compileBytes(elem, structName, fieldName)
 		}
-		return compileSlice(typ, structName, fieldName, structTypeToDecoder)
+		return compileSlice(typ, structName, fieldName, structTypeToDecoder, tagConfig)
 	case reflect.Array:
-		return compileArray(typ, structName, fieldName, structTypeToDecoder)
+		return compileArray(typ, structName, fieldName, structTypeToDecoder, tagConfig)
 	case reflect.Map:
-		return compileMap(typ, structName, fieldName, structTypeToDecoder)
+		return compileMap(typ, structName, fieldName, structTypeToDecoder, tagConfig)
 	case reflect.Interface:
 		return compileInterface(typ, structName, fieldName)
 	case reflect.Uintptr:
@@ -134,6 +188,19 @@ func compile(typ *runtime.Type, structName, fieldName string, structTypeToDecode
 	return newInvalidDecoder(typ, structName, fieldName), nil
 }
 
+// acceptQuotedInt makes the integer decoder dec, or the one it points to, accept integers quoted as JSON strings.
+// Decoders of other types are left as they are.
+func acceptQuotedInt(dec Decoder) {
+	switch d := dec.(type) {
+	case *intDecoder:
+		d.acceptString = true
+	case *uintDecoder:
+		d.acceptString = true
+	case *ptrDecoder:
+		acceptQuotedInt(d.dec)
+	}
+}
+
 func isStringTagSupportedType(typ *runtime.Type) bool {
 	switch {
 	case implementsUnmarshalJSONType(runtime.PtrTo(typ)):
@@ -156,14 +223,17 @@ func isStringTagSupportedType(typ *runtime.Type) bool {
 	return true
 }
 
-func compileMapKey(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
+func compileMapKey(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
 	if runtime.PtrTo(typ).Implements(unmarshalTextType) {
 		return newUnmarshalTextDecoder(runtime.PtrTo(typ), structName, fieldName), nil
 	}
 	if typ.Kind() == reflect.String {
 		return newStringDecoder(structName, fieldName), nil
 	}
-	dec, err := compile(typ, structName, fieldName, structTypeToDecoder)
+	if isMapKeyUnmarshalJSONType(typ) {
+		return newUnmarshalJSONDecoder(runtime.PtrTo(typ), structName, fieldName), nil
+	}
+	dec, err := compile(typ, structName, fieldName, structTypeToDecoder, tagConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -181,8 +251,26 @@ func compileMapKey(typ *runtime.Type, structName, fieldName string, structTypeTo
 	}
 }
 
-func compilePtr(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
-	dec, err := compile(typ.Elem(), structName, fieldName, structTypeToDecoder)
+// isMapKeyUnmarshalJSONType reports whether the map keys of typ are decoded by UnmarshalJSON, given the key as a JSON string.
+// These are the keys that the encoder encodes by MarshalJSON, of the types that implement it
+// and whose kinds have no encoding as keys of their own.
+func isMapKeyUnmarshalJSONType(typ *runtime.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return false
+	}
+	ptr := runtime.PtrTo(typ)
+	// MarshalJSON with or without a context, as json.Marshaler or json.MarshalerContext.
+	if _, exists := runtime.RType2Type(ptr).MethodByName("MarshalJSON"); !exists {
+		return false
+	}
+	return implementsUnmarshalJSONType(ptr)
+}
+
+func compilePtr(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
+	dec, err := compile(typ.Elem(), structName, fieldName, structTypeToDecoder, tagConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -270,6 +358,12 @@ func compileString(typ *runtime.Type, structName, fieldName string) (Decoder, er
 	return newStringDecoder(structName, fieldName), nil
 }
 
+func compileRawNumber(structName, fieldName string) (Decoder, error) {
+	return newRawNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v RawNumber) {
+		*(*RawNumber)(p) = v
+	}), nil
+}
+
 func compileBool(structName, fieldName string) (Decoder, error) {
 	return newBoolDecoder(structName, fieldName), nil
 }
@@ -278,30 +372,37 @@ func compileBytes(typ *runtime.Type, structName, fieldName string) (Decoder, err
 	return newBytesDecoder(typ, structName, fieldName), nil
 }
 
-func compileSlice(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
+func compileSlice(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
 	elem := typ.Elem()
-	decoder, err := compile(elem, structName, fieldName, structTypeToDecoder)
+	decoder, err := compile(elem, structName, fieldName, structTypeToDecoder, tagConfig)
 	if err != nil {
 		return nil, err
 	}
-	return newSliceDecoder(decoder, elem, elem.Size(), structName, fieldName), nil
+	sliceDecoder := newSliceDecoder(decoder, elem, elem.Size(), structName, fieldName)
+	switch decoder.(type) {
+	case *floatDecoder, *intDecoder:
+		if elem.Kind() == reflect.Float64 || elem.Kind() == reflect.Int64 {
+			return newNumberSliceDecoder(sliceDecoder), nil
+		}
+	}
+	return sliceDecoder, nil
 }
 
-func compileArray(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
+func compileArray(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
 	elem := typ.Elem()
-	decoder, err := compile(elem, structName, fieldName, structTypeToDecoder)
+	decoder, err := compile(elem, structName, fieldName, structTypeToDecoder, tagConfig)
 	if err != nil {
 		return nil, err
 	}
 	return newArrayDecoder(decoder, elem, typ.Len(), structName, fieldName), nil
 }
 
-func compileMap(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
-	keyDec, err := compileMapKey(typ.Key(), structName, fieldName, structTypeToDecoder)
+func compileMap(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
+	keyDec, err := compileMapKey(typ.Key(), structName, fieldName, structTypeToDecoder, tagConfig)
 	if err != nil {
 		return nil, err
 	}
-	valueDec, err := compile(typ.Elem(), structName, fieldName, structTypeToDecoder)
+	valueDec, err := compile(typ.Elem(), structName, fieldName, structTypeToDecoder, tagConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -316,43 +417,43 @@ func compileFunc(typ *runtime.Type, strutName, fieldName string) (Decoder, error
 	return newFuncDecoder(typ, strutName, fieldName), nil
 }
 
-func typeToStructTags(typ *runtime.Type) runtime.StructTags {
+func typeToStructTags(typ *runtime.Type, tagConfig runtime.TagConfig) runtime.StructTags {
 	tags := runtime.StructTags{}
 	fieldNum := typ.NumField()
 	for i := 0; i < fieldNum; i++ {
 		field := typ.Field(i)
-		if runtime.IsIgnoredStructField(field) {
+		if runtime.IsIgnoredStructField(field, tagConfig.Key) {
 			continue
 		}
-		tags = append(tags, runtime.StructTagFromField(field))
+		tags = append(tags, runtime.StructTagFromField(field, tagConfig))
 	}
 	return tags
 }
 
-func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder) (Decoder, error) {
+func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
 	fieldNum := typ.NumField()
 	fieldMap := map[string]*structFieldSet{}
 	typeptr := uintptr(unsafe.Pointer(typ))
 	if dec, exists := structTypeToDecoder[typeptr]; exists {
 		return dec, nil
 	}
-	structDec := newStructDecoder(structName, fieldName, fieldMap)
+	structDec := newStructDecoder(typ, structName, fieldName, fieldMap)
 	structTypeToDecoder[typeptr] = structDec
 	structName = typ.Name()
-	tags := typeToStructTags(typ)
+	tags := typeToStructTags(typ, tagConfig)
 	allFields := []*structFieldSet{}
 	for i := 0; i < fieldNum; i++ {
 		field := typ.Field(i)
-		if runtime.IsIgnoredStructField(field) {
+		if runtime.IsIgnoredStructField(field, tagConfig.Key) {
 			continue
 		}
 		isUnexportedField := unicode.IsLower([]rune(field.Name)[0])
-		tag := runtime.StructTagFromField(field)
-		dec, err := compile(runtime.Type2RType(field.Type), structName, field.Name, structTypeToDecoder)
+		tag := runtime.StructTagFromField(field, tagConfig)
+		dec, err := compile(runtime.Type2RType(field.Type), structName, field.Name, structTypeToDecoder, tagConfig)
 		if err != nil {
 			return nil, err
 		}
-		if field.Anonymous && !tag.IsTaggedKey {
+		if tag.IsFlattened() {
 			if stDec, ok := dec.(*structDecoder); ok {
 				if runtime.Type2RType(field.Type) == typ {
 					// recursive definition
@@ -368,6 +469,7 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 						isTaggedKey: v.isTaggedKey,
 						key:         k,
 						keyLen:      int64(len(k)),
+						required:    v.required,
 					}
 					allFields = append(allFields, fieldSet)
 				}
@@ -396,6 +498,7 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 							key:         k,
 							keyLen:      int64(len(k)),
 							err:         fieldSetErr,
+							required:    v.required,
 						}
 						allFields = append(allFields, fieldSet)
 					}
@@ -404,8 +507,9 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 						dec:         pdec,
 						offset:      field.Offset,
 						isTaggedKey: tag.IsTaggedKey,
-						key:         field.Name,
-						keyLen:      int64(len(field.Name)),
+						key:         tag.Key,
+						keyLen:      int64(len(tag.Key)),
+						required:    newRequiredField(tag, tag.Key),
 					}
 					allFields = append(allFields, fieldSet)
 				}
@@ -414,8 +518,9 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 					dec:         dec,
 					offset:      field.Offset,
 					isTaggedKey: tag.IsTaggedKey,
-					key:         field.Name,
-					keyLen:      int64(len(field.Name)),
+					key:         tag.Key,
+					keyLen:      int64(len(tag.Key)),
+					required:    newRequiredField(tag, tag.Key),
 				}
 				allFields = append(allFields, fieldSet)
 			}
@@ -423,6 +528,25 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 			if tag.IsString && isStringTagSupportedType(runtime.Type2RType(field.Type)) {
 				dec = newWrappedStringDecoder(runtime.Type2RType(field.Type), dec, structName, field.Name)
 			}
+			if tag.IsBigString {
+				acceptQuotedInt(dec)
+			}
+			if tag.IsScalarOrArray {
+				acceptScalar(dec)
+			}
+			if tag.IsTuple {
+				if dec, err = compileTuple(dec, runtime.Type2RType(field.Type), structName, field.Name, structTypeToDecoder, tagConfig); err != nil {
+					return nil, err
+				}
+			}
+			if tag.Codec != "" {
+				if dec, err = compileCodec(tag.Codec, runtime.Type2RType(field.Type)); err != nil {
+					return nil, err
+				}
+			}
+			if tag.IsSealed {
+				dec = newSealedDecoder(dec, structName, field.Name)
+			}
 			var key string
 			if tag.Key != "" {
 				key = tag.Key
@@ -435,6 +559,7 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 				isTaggedKey: tag.IsTaggedKey,
 				key:         key,
 				keyLen:      int64(len(key)),
+				required:    newRequiredField(tag, key),
 			}
 			allFields = append(allFields, fieldSet)
 		}
@@ -447,6 +572,8 @@ func compileStruct(typ *runtime.Type, structName, fieldName string, structTypeTo
 			fieldMap[lower] = set
 		}
 	}
+	structDec.fieldSets = uniqueFieldSets(fieldMap)
+	structDec.requiredFields = indexRequiredFields(structDec.fieldSets)
 	delete(structTypeToDecoder, typeptr)
 	structDec.tryOptimize()
 	return structDec, nil
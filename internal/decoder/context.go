@@ -10,6 +10,25 @@ import (
 type RuntimeContext struct {
 	Buf    []byte
 	Option *Option
+
+	// keys is the number of object keys decoded so far, counted with KeyLimitOption.
+	keys int
+	// newlines are the offsets of the line feeds of Buf, recorded with RelaxedOption.
+	newlines []int64
+	// paths is the position of the value being decoded with PathModeOption.
+	paths pathState
+	// index is the structural index of Buf built with IndexOption if indexed is true.
+	index   structuralIndex
+	indexed bool
+}
+
+// countKey counts the nth key of an object being decoded and checks it against the limits of KeyLimitOption.
+func (ctx *RuntimeContext) countKey(n int, cursor int64) error {
+	if (ctx.Option.Flags & KeyLimitOption) == 0 {
+		return nil
+	}
+	ctx.keys++
+	return ctx.Option.checkKeyLimit(n, ctx.keys, cursor)
 }
 
 var (
@@ -23,10 +42,17 @@ var (
 )
 
 func TakeRuntimeContext() *RuntimeContext {
-	return runtimeContextPool.Get().(*RuntimeContext)
+	ctx := runtimeContextPool.Get().(*RuntimeContext)
+	ctx.keys = 0
+	ctx.paths.reset()
+	return ctx
 }
 
 func ReleaseRuntimeContext(ctx *RuntimeContext) {
+	// the context of UnmarshalContext is request-scoped, so it must not be retained by the pool.
+	ctx.Option.Context = nil
+	ctx.Option.Audit = nil
+	ctx.indexed = false
 	runtimeContextPool.Put(ctx)
 }
 
@@ -1,6 +1,7 @@
 package decoder
 
 import (
+	"math"
 	"strconv"
 	"unsafe"
 
@@ -49,6 +50,51 @@ var (
 	}
 )
 
+// specialFloats are the literals of the float values that JSON cannot represent, accepted with SpecialFloatOption.
+var specialFloats = []struct {
+	literal string
+	value   float64
+}{
+	{literal: `"NaN"`, value: math.NaN()},
+	{literal: `"Infinity"`, value: math.Inf(1)},
+	{literal: `"-Infinity"`, value: math.Inf(-1)},
+	{literal: `NaN`, value: math.NaN()},
+	{literal: `Infinity`, value: math.Inf(1)},
+	{literal: `-Infinity`, value: math.Inf(-1)},
+}
+
+// decodeSpecialFloat decodes one of specialFloats at cursor, and returns the cursor after it.
+func decodeSpecialFloat(buf []byte, cursor int64) (float64, int64, bool) {
+	cursor = skipWhiteSpace(buf, cursor)
+	for _, sf := range specialFloats {
+		end := cursor + int64(len(sf.literal))
+		if end < int64(len(buf)) && string(buf[cursor:end]) == sf.literal && validEndNumberChar[buf[end]] {
+			return sf.value, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// decodeSpecialFloatStream is like decodeSpecialFloat but reads more data from the stream as needed.
+// The cursor is not moved unless a literal is decoded.
+func decodeSpecialFloatStream(s *Stream) (float64, bool) {
+	s.skipWhiteSpace()
+	for _, sf := range specialFloats {
+		if s.hasPrefix(sf.literal) {
+			s.cursor += int64(len(sf.literal))
+			c := s.char()
+			if c == nul && s.read() {
+				c = s.char()
+			}
+			if validEndNumberChar[c] {
+				return sf.value, true
+			}
+			s.cursor -= int64(len(sf.literal))
+		}
+	}
+	return 0, false
+}
+
 func floatBytes(s *Stream) []byte {
 	start := s.cursor
 	for {
@@ -119,6 +165,12 @@ func (d *floatDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, erro
 }
 
 func (d *floatDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	if (s.Option.Flags & SpecialFloatOption) != 0 {
+		if f64, ok := decodeSpecialFloatStream(s); ok {
+			d.op(p, f64)
+			return nil
+		}
+	}
 	bytes, err := d.decodeStreamByte(s)
 	if err != nil {
 		return err
@@ -137,6 +189,12 @@ func (d *floatDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 
 func (d *floatDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
 	buf := ctx.Buf
+	if (ctx.Option.Flags & SpecialFloatOption) != 0 {
+		if f64, c, ok := decodeSpecialFloat(buf, cursor); ok {
+			d.op(p, f64)
+			return c, nil
+		}
+	}
 	bytes, c, err := d.decodeByte(buf, cursor)
 	if err != nil {
 		return 0, err
@@ -12,14 +12,15 @@ import (
 )
 
 type interfaceDecoder struct {
-	typ           *runtime.Type
-	structName    string
-	fieldName     string
-	sliceDecoder  *sliceDecoder
-	mapDecoder    *mapDecoder
-	floatDecoder  *floatDecoder
-	numberDecoder *numberDecoder
-	stringDecoder *stringDecoder
+	typ              *runtime.Type
+	structName       string
+	fieldName        string
+	sliceDecoder     *sliceDecoder
+	mapDecoder       *mapDecoder
+	floatDecoder     *floatDecoder
+	numberDecoder    *numberDecoder
+	rawNumberDecoder *rawNumberDecoder
+	stringDecoder    *stringDecoder
 }
 
 func newEmptyInterfaceDecoder(structName, fieldName string) *interfaceDecoder {
@@ -33,6 +34,9 @@ func newEmptyInterfaceDecoder(structName, fieldName string) *interfaceDecoder {
 		numberDecoder: newNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v json.Number) {
 			*(*interface{})(p) = v
 		}),
+		rawNumberDecoder: newRawNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v RawNumber) {
+			*(*interface{})(p) = v
+		}),
 		stringDecoder: newStringDecoder(structName, fieldName),
 	}
 	ifaceDecoder.sliceDecoder = newSliceDecoder(
@@ -81,11 +85,17 @@ func newInterfaceDecoder(typ *runtime.Type, structName, fieldName string) *inter
 		numberDecoder: newNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v json.Number) {
 			*(*interface{})(p) = v
 		}),
+		rawNumberDecoder: newRawNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v RawNumber) {
+			*(*interface{})(p) = v
+		}),
 		stringDecoder: stringDecoder,
 	}
 }
 
 func (d *interfaceDecoder) numDecoder(s *Stream) Decoder {
+	if s.UseRawNumber {
+		return d.rawNumberDecoder
+	}
 	if s.UseNumber {
 		return d.numberDecoder
 	}
@@ -213,7 +223,10 @@ func (d *interfaceDecoder) decodeStreamEmptyInterface(s *Stream, depth int64, p
 	for {
 		switch c {
 		case '{':
-			var v map[string]interface{}
+			if (s.Option.Flags & ShapeCacheOption) != 0 {
+				return d.decodeStreamShapedMap(s, depth, p)
+			}
+			v := takeMapValue()
 			ptr := unsafe.Pointer(&v)
 			if err := d.mapDecoder.DecodeStream(s, depth, ptr); err != nil {
 				return err
@@ -221,7 +234,7 @@ func (d *interfaceDecoder) decodeStreamEmptyInterface(s *Stream, depth int64, p
 			*(*interface{})(p) = v
 			return nil
 		case '[':
-			var v []interface{}
+			v := takeSliceValue()
 			ptr := unsafe.Pointer(&v)
 			if err := d.sliceDecoder.DecodeStream(s, depth, ptr); err != nil {
 				return err
@@ -328,7 +341,7 @@ func (d *interfaceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer
 		*(*interface{})(p) = nil
 		return nil
 	}
-	decoder, err := CompileToGetDecoder(typ)
+	decoder, err := CompileToGetDecoderWithOption(typ, s.Option)
 	if err != nil {
 		return err
 	}
@@ -393,7 +406,7 @@ func (d *interfaceDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p un
 		**(**interface{})(unsafe.Pointer(&p)) = nil
 		return cursor, nil
 	}
-	decoder, err := CompileToGetDecoder(typ)
+	decoder, err := CompileToGetDecoderWithOption(typ, ctx.Option)
 	if err != nil {
 		return 0, err
 	}
@@ -405,7 +418,10 @@ func (d *interfaceDecoder) decodeEmptyInterface(ctx *RuntimeContext, cursor, dep
 	cursor = skipWhiteSpace(buf, cursor)
 	switch buf[cursor] {
 	case '{':
-		var v map[string]interface{}
+		if (ctx.Option.Flags & ShapeCacheOption) != 0 {
+			return d.decodeShapedMap(ctx, cursor, depth, p)
+		}
+		v := takeMapValue()
 		ptr := unsafe.Pointer(&v)
 		cursor, err := d.mapDecoder.Decode(ctx, cursor, depth, ptr)
 		if err != nil {
@@ -414,7 +430,7 @@ func (d *interfaceDecoder) decodeEmptyInterface(ctx *RuntimeContext, cursor, dep
 		**(**interface{})(unsafe.Pointer(&p)) = v
 		return cursor, nil
 	case '[':
-		var v []interface{}
+		v := takeSliceValue()
 		ptr := unsafe.Pointer(&v)
 		cursor, err := d.sliceDecoder.Decode(ctx, cursor, depth, ptr)
 		if err != nil {
@@ -66,6 +66,30 @@ func (d *mapDecoder) mapassign(t *runtime.Type, m, k, v unsafe.Pointer) {
 	}
 }
 
+// prepareMap returns the map to decode into.
+// An existing map is reused and new keys are merged into it, unless MapClearOption is set.
+// Otherwise, a new map is allocated for size elements.
+func (d *mapDecoder) prepareMap(flags OptionFlags, p unsafe.Pointer, size int) unsafe.Pointer {
+	mapValue := *(*unsafe.Pointer)(p)
+	if mapValue == nil {
+		return makemap(d.mapType, size)
+	}
+	if (flags & MapClearOption) != 0 {
+		d.clearMap(p)
+	}
+	return mapValue
+}
+
+// clearMap deletes all the entries of the map at p.
+// It deletes them one by one since reflect.mapclear is not available before Go 1.21.
+func (d *mapDecoder) clearMap(p unsafe.Pointer) {
+	m := reflect.NewAt(runtime.RType2Type(d.mapType), p).Elem()
+	iter := m.MapRange()
+	for iter.Next() {
+		m.SetMapIndex(iter.Key(), reflect.Value{})
+	}
+}
+
 func (d *mapDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
 	depth++
 	if depth > maxDecodeNestingDepth {
@@ -83,17 +107,25 @@ func (d *mapDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) erro
 	default:
 		return errors.ErrExpected("{ character for map value", s.totalOffset())
 	}
-	mapValue := *(*unsafe.Pointer)(p)
-	if mapValue == nil {
-		mapValue = makemap(d.mapType, 0)
-	}
+	mapValue := d.prepareMap(s.Option.Flags, p, s.Option.sizeHint(depth, s.Option.MapSizeHint))
 	s.cursor++
 	if s.skipWhiteSpace() == '}' {
 		*(*unsafe.Pointer)(p) = mapValue
 		s.cursor++
 		return nil
 	}
-	for {
+	for n := 1; ; n++ {
+		if err := s.countKey(n); err != nil {
+			return err
+		}
+		var key string
+		if s.Option.Flags&PathModeOption != 0 {
+			k, err := s.peekPathKey(depth)
+			if err != nil {
+				return err
+			}
+			key = k
+		}
 		k := unsafe_New(d.keyType)
 		if err := d.keyDecoder.DecodeStream(s, depth, k); err != nil {
 			return err
@@ -104,7 +136,11 @@ func (d *mapDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) erro
 		}
 		s.cursor++
 		v := unsafe_New(d.valueType)
-		if err := d.valueDecoder.DecodeStream(s, depth, v); err != nil {
+		if s.Option.Flags&PathModeOption != 0 {
+			if err := s.decodeAtPath(d.valueDecoder, key, depth, v); err != nil {
+				return err
+			}
+		} else if err := d.valueDecoder.DecodeStream(s, depth, v); err != nil {
 			return err
 		}
 		d.mapassign(d.mapType, mapValue, k, v)
@@ -147,16 +183,24 @@ func (d *mapDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.P
 	}
 	cursor++
 	cursor = skipWhiteSpace(buf, cursor)
-	mapValue := *(*unsafe.Pointer)(p)
-	if mapValue == nil {
-		mapValue = makemap(d.mapType, 0)
-	}
 	if buf[cursor] == '}' {
-		**(**unsafe.Pointer)(unsafe.Pointer(&p)) = mapValue
+		**(**unsafe.Pointer)(unsafe.Pointer(&p)) = d.prepareMap(ctx.Option.Flags, p, 0)
 		cursor++
 		return cursor, nil
 	}
-	for {
+	mapValue := d.prepareMap(ctx.Option.Flags, p, ctx.presize(cursor, depth, true, ctx.Option.MapSizeHint))
+	for n := 1; ; n++ {
+		if err := ctx.countKey(n, cursor); err != nil {
+			return 0, err
+		}
+		var key string
+		if ctx.Option.Flags&PathModeOption != 0 {
+			k, err := peekPathKey(buf, cursor, depth)
+			if err != nil {
+				return 0, err
+			}
+			key = k
+		}
 		k := unsafe_New(d.keyType)
 		keyCursor, err := d.keyDecoder.Decode(ctx, cursor, depth, k)
 		if err != nil {
@@ -168,7 +212,12 @@ func (d *mapDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.P
 		}
 		cursor++
 		v := unsafe_New(d.valueType)
-		valueCursor, err := d.valueDecoder.Decode(ctx, cursor, depth, v)
+		var valueCursor int64
+		if ctx.Option.Flags&PathModeOption != 0 {
+			valueCursor, err = ctx.decodeAtPath(d.valueDecoder, key, cursor, depth, v)
+		} else {
+			valueCursor, err = d.valueDecoder.Decode(ctx, cursor, depth, v)
+		}
 		if err != nil {
 			return 0, err
 		}
@@ -0,0 +1,89 @@
+package decoder
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// noMethodsKey is the key of a decoder compiled with NoMethodsOption without a registry.
+type noMethodsKey struct {
+	types     *runtime.TypeSet
+	tagConfig runtime.TagConfig
+	typeptr   uintptr
+}
+
+var noMethodsDecoders sync.Map // map[noMethodsKey]Decoder
+
+// compileNoMethods compiles the decoder of typ with the types of opt.NoMethodTypes decoded as if they had
+// no UnmarshalJSON and UnmarshalText methods, with the registry of opt if RegistryOption is set,
+// and with the struct tags read as opt.tagConfig returns.
+// The decoders are cached per set of types and tag configuration, except those of a registry, since they change when it does.
+func compileNoMethods(typ *runtime.Type, opt *Option) (Decoder, error) {
+	tagConfig := opt.tagConfig()
+	if (opt.Flags & RegistryOption) != 0 {
+		r := opt.Registry
+		r.mu.RLock()
+		structTypeToDecoder := r.structTypeToDecoder()
+		r.mu.RUnlock()
+		return compileHeadNoMethods(typ, opt.NoMethodTypes, structTypeToDecoder, tagConfig)
+	}
+	key := noMethodsKey{types: opt.NoMethodTypes, tagConfig: tagConfig, typeptr: uintptr(unsafe.Pointer(typ))}
+	if dec, exists := noMethodsDecoders.Load(key); exists {
+		return dec.(Decoder), nil
+	}
+	dec, err := compileHeadNoMethods(typ, opt.NoMethodTypes, map[uintptr]Decoder{}, tagConfig)
+	if err != nil {
+		return nil, err
+	}
+	noMethodsDecoders.Store(key, dec)
+	return dec, nil
+}
+
+func compileHeadNoMethods(typ *runtime.Type, types *runtime.TypeSet, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
+	// each type of the set is compiled by its kind, and looked up by compile as if it was compiled already.
+	// The placeholders are registered first, so that the types of the set referring to each other find them.
+	placeholders := map[uintptr]*noMethodsDecoder{}
+	for _, t := range types.Types() {
+		typeptr := uintptr(unsafe.Pointer(t))
+		if _, exists := structTypeToDecoder[typeptr]; exists {
+			// a decode function of the registry takes precedence.
+			continue
+		}
+		placeholders[typeptr] = &noMethodsDecoder{}
+		structTypeToDecoder[typeptr] = placeholders[typeptr]
+	}
+	for _, t := range types.Types() {
+		typeptr := uintptr(unsafe.Pointer(t))
+		placeholder, exists := placeholders[typeptr]
+		if !exists {
+			continue
+		}
+		delete(structTypeToDecoder, typeptr)
+		dec, err := compileKind(t, "", "", structTypeToDecoder, tagConfig)
+		if err != nil {
+			return nil, err
+		}
+		placeholder.dec = dec
+		structTypeToDecoder[typeptr] = placeholder
+	}
+	return compileHead(typ, structTypeToDecoder, tagConfig)
+}
+
+// noMethodsDecoder decodes a type of NoMethodsOption with the decoder compiled by its kind.
+type noMethodsDecoder struct {
+	dec Decoder
+}
+
+func (d *noMethodsDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	return d.dec.Decode(ctx, cursor, depth, p)
+}
+
+func (d *noMethodsDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	return d.dec.DecodeStream(s, depth, p)
+}
+
+func (d *noMethodsDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return d.dec.DecodePath(ctx, cursor, depth)
+}
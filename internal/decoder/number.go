@@ -29,8 +29,10 @@ func (d *numberDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) e
 	if err != nil {
 		return err
 	}
-	if _, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&bytes)), 64); err != nil {
-		return errors.ErrSyntax(err.Error(), s.totalOffset())
+	if (s.Option.Flags & TrustedInputOption) == 0 {
+		if _, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&bytes)), 64); err != nil {
+			return errors.ErrSyntax(err.Error(), s.totalOffset())
+		}
 	}
 	d.op(p, json.Number(string(bytes)))
 	s.reset()
@@ -42,8 +44,10 @@ func (d *numberDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsaf
 	if err != nil {
 		return 0, err
 	}
-	if _, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&bytes)), 64); err != nil {
-		return 0, errors.ErrSyntax(err.Error(), c)
+	if (ctx.Option.Flags & TrustedInputOption) == 0 {
+		if _, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&bytes)), 64); err != nil {
+			return 0, errors.ErrSyntax(err.Error(), c)
+		}
 	}
 	cursor = c
 	s := *(*string)(unsafe.Pointer(&bytes))
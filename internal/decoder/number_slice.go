@@ -0,0 +1,96 @@
+package decoder
+
+import (
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+)
+
+// numberSliceDecoder decodes a slice of float64 or int64 in bulk: the array is scanned once to count the elements,
+// so that they are decoded into an array allocated at once, without growing it element by element.
+// Arrays containing other than numbers, and the streams, are decoded by the sliceDecoder.
+type numberSliceDecoder struct {
+	slice *sliceDecoder
+}
+
+func newNumberSliceDecoder(slice *sliceDecoder) *numberSliceDecoder {
+	return &numberSliceDecoder{slice: slice}
+}
+
+// numberArrayChars are the characters of an array of numbers except the brackets.
+var numberArrayChars = [256]bool{
+	' ': true, '\t': true, '\n': true, '\r': true, ',': true,
+	'-': true, '+': true, '.': true, 'e': true, 'E': true,
+	'0': true, '1': true, '2': true, '3': true, '4': true,
+	'5': true, '6': true, '7': true, '8': true, '9': true,
+}
+
+// countNumbers returns the number of elements of the array whose elements start at cursor,
+// or false if the array contains other than numbers.
+func countNumbers(buf []byte, cursor int64) (int, bool) {
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] == ']' {
+		return 0, false
+	}
+	n := 1
+	for _, c := range buf[cursor:] {
+		if !numberArrayChars[c] {
+			return n, c == ']'
+		}
+		if c == ',' {
+			n++
+		}
+	}
+	return 0, false
+}
+
+func (d *numberSliceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	return d.slice.DecodeStream(s, depth, p)
+}
+
+func (d *numberSliceDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.Buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '[' || depth+1 > maxDecodeNestingDepth || ctx.Option.Flags&PathModeOption != 0 {
+		return d.slice.Decode(ctx, cursor, depth, p)
+	}
+	n, ok := countNumbers(buf, cursor+1)
+	if !ok {
+		return d.slice.Decode(ctx, cursor, depth, p)
+	}
+	depth++
+
+	// decode into a buffer of the pool as the sliceDecoder does, so that p is not modified on error.
+	slice := d.slice.arrayPool.Get().(*sliceHeader)
+	if slice.cap < n {
+		slice.data = newArray(d.slice.elemType, n)
+		slice.cap = n
+	}
+	dec, size := d.slice.valueDecoder, d.slice.size
+	cursor++
+	for idx := 0; idx < n; idx++ {
+		c, err := dec.Decode(ctx, cursor, depth, unsafe.Pointer(uintptr(slice.data)+uintptr(idx)*size))
+		if err != nil {
+			d.slice.releaseSlice(slice)
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		if idx < n-1 && buf[cursor] == ',' {
+			cursor++
+			continue
+		}
+		if idx == n-1 && buf[cursor] == ']' {
+			break
+		}
+		d.slice.releaseSlice(slice)
+		return 0, errors.ErrInvalidCharacter(buf[cursor], "slice", cursor)
+	}
+	slice.len = n
+	d.slice.storeSlice(ctx.Option.Flags, (*sliceHeader)(p), slice)
+	d.slice.releaseSlice(slice)
+	return cursor + 1, nil
+}
+
+func (d *numberSliceDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return d.slice.DecodePath(ctx, cursor, depth)
+}
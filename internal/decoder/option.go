@@ -1,17 +1,94 @@
 package decoder
 
-import "context"
+import (
+	"context"
 
-type OptionFlags uint8
+	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
+)
+
+type OptionFlags uint32
 
 const (
 	FirstWinOption OptionFlags = 1 << iota
 	ContextOption
 	PathOption
+	TrustedInputOption
+	SliceZeroTailOption
+	SliceReallocOption
+	MapClearOption
+	SpecialFloatOption
+	RegistryOption
+	Int64StringOption
+	ShapeCacheOption
+	KeyLimitOption
+	StrictOption
+	RelaxedOption
+	PathModeOption
+	StringLimitOption
+	NoMethodsOption
+	RawAliasOption
+	DisallowUnknownOption
+	SizeHintOption
+	PrescanOption
+	IndexOption
+	TagKeyOption
+	FieldNamingOption
+	AuditOption
+	DuplicateKeyOption
 )
 
 type Option struct {
 	Flags   OptionFlags
 	Context context.Context
 	Path    *Path
+
+	// Registry is used to compile decoders with RegistryOption.
+	Registry *Registry
+
+	// Shapes caches the keys of the objects decoded into interface{} with ShapeCacheOption.
+	Shapes *ShapeCache
+
+	// MaxObjectKeys and MaxDocumentKeys are the maximum numbers of keys in an object and in the whole document
+	// with KeyLimitOption. Zero means no limit.
+	MaxObjectKeys   int
+	MaxDocumentKeys int
+
+	// MaxDepth is the maximum nesting depth of the values validated with StrictOption. Zero means no limit.
+	MaxDepth int
+
+	// MaxStringLength is the maximum length in bytes of the strings validated with StringLimitOption.
+	MaxStringLength int
+
+	// PathModes are the strictness of the subtrees decoded with PathModeOption.
+	PathModes []PathMode
+
+	// SliceCapacityHint and MapSizeHint are the numbers of elements allocated for the slice or map decoded
+	// with SizeHintOption. Zero means no hint.
+	SliceCapacityHint int
+	MapSizeHint       int
+
+	// NoMethodTypes are the types decoded as if they had no UnmarshalJSON and UnmarshalText methods with NoMethodsOption.
+	NoMethodTypes *runtime.TypeSet
+
+	// TagKey is the key of the struct tags of the fields with TagKeyOption, instead of json.
+	TagKey string
+
+	// FieldNaming is the case of the keys of the fields not named by their tag with FieldNamingOption.
+	FieldNaming runtime.FieldNaming
+
+	// Audit receives the unknown keys ignored and the fields missing from the input with AuditOption.
+	// The paths of the values are tracked as with PathModeOption, which must be set with it.
+	Audit func(runtime.AuditEvent)
+}
+
+// checkKeyLimit returns an error if n keys of an object or total keys of the document exceed the limits.
+func (o *Option) checkKeyLimit(n, total int, offset int64) error {
+	if o.MaxObjectKeys > 0 && n > o.MaxObjectKeys {
+		return errors.ErrObjectKeyLimit(o.MaxObjectKeys, offset)
+	}
+	if o.MaxDocumentKeys > 0 && total > o.MaxDocumentKeys {
+		return errors.ErrDocumentKeyLimit(o.MaxDocumentKeys, offset)
+	}
+	return nil
 }
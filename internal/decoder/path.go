@@ -21,6 +21,7 @@ type PathBuilder struct {
 	node                    PathNode
 	singleQuotePathSelector bool
 	doubleQuotePathSelector bool
+	multipleValues          bool
 }
 
 func (b *PathBuilder) Build(buf []rune) (*Path, error) {
@@ -33,6 +34,7 @@ func (b *PathBuilder) Build(buf []rune) (*Path, error) {
 		RootSelectorOnly:        node == nil,
 		SingleQuotePathSelector: b.singleQuotePathSelector,
 		DoubleQuotePathSelector: b.doubleQuotePathSelector,
+		MultipleValues:          b.multipleValues,
 	}, nil
 }
 
@@ -266,6 +268,7 @@ func (b *PathBuilder) buildIndex(buf []rune) (int, error) {
 }
 
 func (b *PathBuilder) addIndexAllNode() {
+	b.multipleValues = true
 	node := newPathIndexAllNode()
 	if b.root == nil {
 		b.root = node
@@ -276,6 +279,7 @@ func (b *PathBuilder) addIndexAllNode() {
 }
 
 func (b *PathBuilder) addRecursiveNode(selector string) {
+	b.multipleValues = true
 	node := newPathRecursiveNode(selector)
 	if b.root == nil {
 		b.root = node
@@ -317,6 +321,8 @@ type Path struct {
 	RootSelectorOnly        bool
 	SingleQuotePathSelector bool
 	DoubleQuotePathSelector bool
+	// MultipleValues reports whether the path may select several values, with [*] or recursive descent.
+	MultipleValues bool
 }
 
 func (p *Path) Field(sel string) (PathNode, bool, error) {
@@ -413,7 +419,7 @@ func (n *PathSelectorNode) Get(src, dst reflect.Value) error {
 	case reflect.Struct:
 		typ := src.Type()
 		for i := 0; i < typ.Len(); i++ {
-			tag := runtime.StructTagFromField(typ.Field(i))
+			tag := runtime.StructTagFromField(typ.Field(i), runtime.TagConfig{})
 			child, found, err := n.Field(tag.Key)
 			if err != nil {
 				return err
@@ -621,7 +627,7 @@ func (n *PathRecursiveNode) Get(src, dst reflect.Value) error {
 	case reflect.Struct:
 		typ := src.Type()
 		for i := 0; i < typ.Len(); i++ {
-			tag := runtime.StructTagFromField(typ.Field(i))
+			tag := runtime.StructTagFromField(typ.Field(i), runtime.TagConfig{})
 			child, found, err := n.Field(tag.Key)
 			if err != nil {
 				return err
@@ -0,0 +1,221 @@
+package decoder
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+)
+
+// PathMode is the strictness of the values at the paths matching Pattern with PathModeOption.
+type PathMode struct {
+	// Pattern is the keys of the objects and the indexes of the arrays from the root, where "*" matches any of them.
+	Pattern []string
+	// Strict makes the unknown fields errors. Otherwise, the unknown fields and the type errors are tolerated.
+	Strict bool
+}
+
+type pathMode int
+
+const (
+	pathModeDefault pathMode = iota
+	pathModeStrict
+	pathModeLenient
+)
+
+// pathState is the position of the value being decoded with PathModeOption.
+type pathState struct {
+	// path is the keys of the objects and the indexes of the arrays from the root to the value.
+	path []string
+	// strictErr is the type error of a value in a strict subtree, which must not be tolerated by the lenient subtree around it.
+	strictErr error
+	// unknownFields are the unknown fields found so far, which are reported together at the end of the decode.
+	unknownFields []*errors.UnknownFieldError
+}
+
+func (s *pathState) reset() {
+	s.path = s.path[:0]
+	s.strictErr = nil
+	s.unknownFields = nil
+}
+
+// addUnknownField records the unknown field key of the object at the path, and the key suggested for it.
+func (s *pathState) addUnknownField(key, suggestion string) {
+	path := key
+	if len(s.path) > 0 {
+		path = strings.Join(s.path, ".") + "." + key
+	}
+	s.unknownFields = append(s.unknownFields, errors.ErrUnknownStructField(key, path, suggestion))
+}
+
+// unknownFieldsError returns the error for the unknown fields found by the decode, or nil if there are none.
+func (s *pathState) unknownFieldsError() error {
+	switch len(s.unknownFields) {
+	case 0:
+		return nil
+	case 1:
+		return s.unknownFields[0]
+	}
+	return &errors.UnknownFieldsError{Fields: s.unknownFields}
+}
+
+// UnknownFieldsError returns err, or the error for the unknown fields found by the decode if err is nil.
+func UnknownFieldsError(ctx *RuntimeContext, err error) error {
+	if err != nil || ctx.Option.Flags&PathModeOption == 0 {
+		return err
+	}
+	return ctx.paths.unknownFieldsError()
+}
+
+// UnknownFieldsError is like UnknownFieldsError for a stream.
+func (s *Stream) UnknownFieldsError(err error) error {
+	if err != nil || s.Option.Flags&PathModeOption == 0 {
+		return err
+	}
+	return s.paths.unknownFieldsError()
+}
+
+// mode returns the mode of the value at the path followed by key.
+// The longest pattern matching the path wins, and a strict one wins over a lenient one of the same length.
+func (s *pathState) mode(modes []PathMode, key string) pathMode {
+	s.path = append(s.path, key)
+	mode, longest := pathModeDefault, -1
+	for _, m := range modes {
+		if len(m.Pattern) < longest || len(m.Pattern) > len(s.path) || !matchPathPattern(m.Pattern, s.path) {
+			continue
+		}
+		if len(m.Pattern) == longest && mode == pathModeStrict {
+			continue
+		}
+		longest = len(m.Pattern)
+		if m.Strict {
+			mode = pathModeStrict
+		} else {
+			mode = pathModeLenient
+		}
+	}
+	s.path = s.path[:len(s.path)-1]
+	return mode
+}
+
+// matchPathPattern reports whether pattern matches the beginning of path.
+func matchPathPattern(pattern, path []string) bool {
+	for i, p := range pattern {
+		if p != "*" && p != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tolerate reports whether err of the value in mode is tolerated, and records it if it must not be.
+func (s *pathState) tolerate(mode pathMode, err error) bool {
+	if _, ok := err.(*errors.UnmarshalTypeError); !ok {
+		return false
+	}
+	switch mode {
+	case pathModeLenient:
+		return err != s.strictErr
+	case pathModeStrict:
+		s.strictErr = err
+	}
+	return false
+}
+
+// isUnknownFieldError reports whether an unknown field in mode is an error, rather than skipped.
+// disallow is the behavior of the decoder outside of the patterns.
+func isUnknownFieldError(mode pathMode, disallow bool) bool {
+	return mode == pathModeStrict || (mode == pathModeDefault && disallow)
+}
+
+// decodeAtPath decodes the value at cursor, which is the element key of an object or an array, with PathModeOption.
+// The type errors of the value are tolerated in a lenient subtree, skipping the rest of the value.
+func (ctx *RuntimeContext) decodeAtPath(dec Decoder, key string, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	mode := ctx.paths.mode(ctx.Option.PathModes, key)
+	var end int64
+	if mode == pathModeLenient {
+		c, err := ctx.skipValue(cursor, depth)
+		if err != nil {
+			return 0, err
+		}
+		end = c
+	}
+	ctx.paths.path = append(ctx.paths.path, key)
+	c, err := dec.Decode(ctx, cursor, depth, p)
+	ctx.paths.path = ctx.paths.path[:len(ctx.paths.path)-1]
+	if err != nil {
+		if ctx.paths.tolerate(mode, err) {
+			return end, nil
+		}
+		return 0, err
+	}
+	return c, nil
+}
+
+// decodeAtPath is like RuntimeContext.decodeAtPath for a stream.
+func (s *Stream) decodeAtPath(dec Decoder, key string, depth int64, p unsafe.Pointer) error {
+	mode := s.paths.mode(s.Option.PathModes, key)
+	var end int64
+	if mode == pathModeLenient {
+		s.skipWhiteSpace()
+		start := s.cursor
+		if err := s.skipValue(depth); err != nil {
+			return err
+		}
+		end = s.totalOffset()
+		s.cursor = start
+	}
+	s.paths.path = append(s.paths.path, key)
+	err := dec.DecodeStream(s, depth, p)
+	s.paths.path = s.paths.path[:len(s.paths.path)-1]
+	if err != nil {
+		if s.paths.tolerate(mode, err) {
+			// the buffer before the cursor may have been discarded while decoding, but not the rest of the value.
+			s.cursor = end - s.offset
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// pathKey returns the key of an object in the quoted string.
+func pathKey(quoted []byte) string {
+	if len(quoted) < 2 {
+		return ""
+	}
+	key := quoted[1 : len(quoted)-1]
+	if bytes.IndexByte(key, '\\') >= 0 {
+		key = append([]byte{}, key...)
+		key = key[:unescapeString(key)]
+	}
+	return string(key)
+}
+
+// peekPathKey returns the key of an object at cursor without decoding it.
+func peekPathKey(buf []byte, cursor, depth int64) (string, error) {
+	start := skipWhiteSpace(buf, cursor)
+	end, err := skipValue(buf, start, depth)
+	if err != nil {
+		return "", err
+	}
+	return pathKey(buf[start:end]), nil
+}
+
+// peekPathKey returns the key of an object at the cursor without consuming it.
+func (s *Stream) peekPathKey(depth int64) (string, error) {
+	s.skipWhiteSpace()
+	start := s.cursor
+	if err := s.skipValue(depth); err != nil {
+		return "", err
+	}
+	key := pathKey(s.buf[start:s.cursor])
+	s.cursor = start
+	return key, nil
+}
+
+func pathIndex(idx int) string {
+	return strconv.Itoa(idx)
+}
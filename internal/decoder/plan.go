@@ -0,0 +1,151 @@
+package decoder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// Plan returns the tree of the decoders of typ, one per line, indented by depth.
+// It is stable across runs and platforms: it has the kinds of the decoders, the keys of the fields
+// and the types, but neither addresses nor offsets.
+func Plan(typ *runtime.Type) (string, error) {
+	dec, err := compileHead(runtime.PtrTo(typ), map[uintptr]Decoder{}, runtime.TagConfig{})
+	if err != nil {
+		return "", err
+	}
+	p := &planPrinter{path: map[Decoder]struct{}{}}
+	p.print(dec, 0)
+	return p.b.String(), nil
+}
+
+type planPrinter struct {
+	b strings.Builder
+	// path are the decoders from the root to the one being printed, to stop at recursive types.
+	path map[Decoder]struct{}
+}
+
+func (p *planPrinter) line(depth int, format string, args ...interface{}) {
+	p.b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&p.b, format, args...)
+	p.b.WriteByte('\n')
+}
+
+func (p *planPrinter) print(dec Decoder, depth int) {
+	if _, exists := p.path[dec]; exists {
+		if d, ok := dec.(*structDecoder); ok {
+			p.line(depth, "recursive struct %s", d.typ)
+		} else {
+			p.line(depth, "recursive %s", planName(dec))
+		}
+		return
+	}
+	p.path[dec] = struct{}{}
+	defer delete(p.path, dec)
+
+	switch d := dec.(type) {
+	case *ptrDecoder:
+		p.line(depth, "ptr %s", runtime.PtrTo(d.typ))
+		p.print(d.dec, depth+1)
+	case *sliceDecoder:
+		p.line(depth, "slice %s", d.elemType)
+		p.print(d.valueDecoder, depth+1)
+	case *arrayDecoder:
+		p.line(depth, "array [%d]%s", d.alen, d.elemType)
+		p.print(d.valueDecoder, depth+1)
+	case *mapDecoder:
+		p.line(depth, "map %s", d.mapType)
+		p.print(d.keyDecoder, depth+1)
+		p.print(d.valueDecoder, depth+1)
+	case *structDecoder:
+		p.line(depth, "struct %s", d.typ)
+		for _, set := range planFieldSets(d) {
+			if set.err != nil {
+				p.line(depth+1, "field %q error: %s", set.key, set.err)
+				continue
+			}
+			label := fmt.Sprintf("field %q", set.key)
+			if set.isTaggedKey {
+				label += " tagged"
+			}
+			if set.required != nil {
+				label += " required"
+			}
+			p.line(depth+1, "%s", label)
+			p.print(set.dec, depth+2)
+		}
+	case *anonymousFieldDecoder:
+		p.line(depth, "embedded %s", d.structType)
+		p.print(d.dec, depth+1)
+	case *wrappedStringDecoder:
+		p.line(depth, "string-wrapped %s", d.typ)
+		p.print(d.dec, depth+1)
+	case *sealedDecoder:
+		p.line(depth, "sealed")
+		p.print(d.dec, depth+1)
+	case *noMethodsDecoder:
+		p.line(depth, "no-methods")
+		p.print(d.dec, depth+1)
+	case *numberSliceDecoder:
+		p.line(depth, "number-slice")
+		p.print(d.slice, depth+1)
+	case *tupleDecoder:
+		p.line(depth, "tuple %s", d.typ)
+		for _, field := range d.fields {
+			p.print(field.dec, depth+1)
+		}
+	case *intDecoder:
+		p.line(depth, "int %s", d.kind)
+	case *uintDecoder:
+		p.line(depth, "uint %s", d.kind)
+	case *invalidDecoder:
+		p.line(depth, "invalid %s", d.typ)
+	case *unmarshalJSONDecoder:
+		p.line(depth, "unmarshal-json %s", d.typ)
+	case *unmarshalTextDecoder:
+		p.line(depth, "unmarshal-text %s", d.typ)
+	case *adapterDecoder:
+		p.line(depth, "func %s", d.typ)
+	case *interfaceDecoder:
+		p.line(depth, "interface %s", d.typ)
+	default:
+		p.line(depth, "%s", planName(dec))
+	}
+}
+
+// planName returns the kind of dec, as the name of its type without the Decoder suffix.
+func planName(dec Decoder) string {
+	typ := reflect.TypeOf(dec)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return strings.TrimSuffix(typ.Name(), "Decoder")
+}
+
+// planFieldSets returns the fields of d in the order of their keys, without the lower case aliases of the keys.
+// An alias has the same decoder and offset as its key, which sorts before it since upper case letters sort first.
+func planFieldSets(d *structDecoder) []*structFieldSet {
+	all := make([]*structFieldSet, 0, len(d.fieldMap))
+	for _, set := range d.fieldMap {
+		all = append(all, set)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].key < all[j].key })
+	type field struct {
+		dec    Decoder
+		offset uintptr
+	}
+	seen := map[field]struct{}{}
+	sets := all[:0]
+	for _, set := range all {
+		f := field{dec: set.dec, offset: set.offset}
+		if _, exists := seen[f]; exists {
+			continue
+		}
+		seen[f] = struct{}{}
+		sets = append(sets, set)
+	}
+	return sets
+}
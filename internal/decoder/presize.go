@@ -0,0 +1,49 @@
+package decoder
+
+// sizeHint returns the number of elements to allocate for a new slice or map at depth with SizeHintOption,
+// which applies only to the decoded value itself, so that the nested containers are not oversized.
+func (o *Option) sizeHint(depth int64, hint int) int {
+	if (o.Flags&SizeHintOption) == 0 || depth != 1 {
+		return 0
+	}
+	return hint
+}
+
+// presize returns the number of elements to allocate for the array or object whose elements start at cursor.
+// With PrescanOption, the elements are counted by skipping them, otherwise hint is returned.
+func (ctx *RuntimeContext) presize(cursor, depth int64, isObject bool, hint int) int {
+	if (ctx.Option.Flags & PrescanOption) == 0 {
+		return ctx.Option.sizeHint(depth, hint)
+	}
+	return ctx.countElements(cursor, depth, isObject)
+}
+
+// countElements returns the number of elements of the array or object whose elements start at cursor.
+// A syntax error stops the count, and is reported by the decode itself.
+func (ctx *RuntimeContext) countElements(cursor, depth int64, isObject bool) int {
+	buf := ctx.Buf
+	n := 0
+	for {
+		if isObject {
+			end, err := skipValue(buf, cursor, depth)
+			if err != nil {
+				return n
+			}
+			cursor = skipWhiteSpace(buf, end)
+			if buf[cursor] != ':' {
+				return n
+			}
+			cursor++
+		}
+		end, err := ctx.skipValue(cursor, depth)
+		if err != nil {
+			return n
+		}
+		n++
+		cursor = skipWhiteSpace(buf, end)
+		if buf[cursor] != ',' {
+			return n
+		}
+		cursor++
+	}
+}
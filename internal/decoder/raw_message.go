@@ -0,0 +1,61 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+var rawMessageType = runtime.Type2RType(reflect.TypeOf(json.RawMessage(nil)))
+
+// rawMessageDecoder decodes a json.RawMessage without calling its UnmarshalJSON method,
+// which copies the value a second time.
+// With RawAliasOption, the value is sliced out of the input buffer instead of being copied,
+// except in a stream, whose buffer is reused for the next values.
+type rawMessageDecoder struct {
+	structName string
+	fieldName  string
+}
+
+func newRawMessageDecoder(structName, fieldName string) *rawMessageDecoder {
+	return &rawMessageDecoder{structName: structName, fieldName: fieldName}
+}
+
+func (d *rawMessageDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	if err := s.skipValue(depth); err != nil {
+		return err
+	}
+	src := s.buf[start:s.cursor]
+	raw := make(json.RawMessage, len(src))
+	copy(raw, src)
+	*(*json.RawMessage)(p) = raw
+	return nil
+}
+
+func (d *rawMessageDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.Buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := ctx.skipValue(cursor, depth)
+	if err != nil {
+		return 0, err
+	}
+	if (ctx.Option.Flags & RawAliasOption) != 0 {
+		// the capacity is limited so that appending to the value never overwrites the input.
+		*(*json.RawMessage)(p) = json.RawMessage(buf[start:end:end])
+		return end, nil
+	}
+	raw := make(json.RawMessage, end-start)
+	copy(raw, buf[start:end])
+	*(*json.RawMessage)(p) = raw
+	return end, nil
+}
+
+func (d *rawMessageDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return nil, 0, fmt.Errorf("json: raw message decoder does not support decode path")
+}
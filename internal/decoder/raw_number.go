@@ -0,0 +1,129 @@
+package decoder
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+)
+
+// RawNumber is a JSON number literal that keeps the exact bytes read from the input.
+type RawNumber []byte
+
+// String returns the literal text of the number.
+func (n RawNumber) String() string { return string(n) }
+
+// Float64 returns the number as a float64.
+func (n RawNumber) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64.
+func (n RawNumber) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// MarshalJSON returns n as it was read, or null if n is empty.
+func (n RawNumber) MarshalJSON() ([]byte, error) {
+	if len(n) == 0 {
+		return nullbytes, nil
+	}
+	return n, nil
+}
+
+type rawNumberDecoder struct {
+	numberDecoder *numberDecoder
+	op            func(unsafe.Pointer, RawNumber)
+}
+
+func newRawNumberDecoder(structName, fieldName string, op func(unsafe.Pointer, RawNumber)) *rawNumberDecoder {
+	return &rawNumberDecoder{
+		numberDecoder: newNumberDecoder(structName, fieldName, nil),
+		op:            op,
+	}
+}
+
+func (d *rawNumberDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	bytes, err := d.numberDecoder.decodeStreamByte(s)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		s.reset()
+		return nil
+	}
+	if (s.Option.Flags&TrustedInputOption) == 0 && !isNumberLiteral(bytes) {
+		return errors.ErrSyntax(fmt.Sprintf("invalid number literal %q", bytes), s.totalOffset())
+	}
+	num := make(RawNumber, len(bytes))
+	copy(num, bytes)
+	d.op(p, num)
+	s.reset()
+	return nil
+}
+
+func (d *rawNumberDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	bytes, c, err := d.numberDecoder.decodeByte(ctx.Buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	if bytes == nil {
+		return c, nil
+	}
+	if (ctx.Option.Flags&TrustedInputOption) == 0 && !isNumberLiteral(bytes) {
+		return 0, errors.ErrSyntax(fmt.Sprintf("invalid number literal %q", bytes), c)
+	}
+	num := make(RawNumber, len(bytes))
+	copy(num, bytes)
+	d.op(p, num)
+	return c, nil
+}
+
+func (d *rawNumberDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return d.numberDecoder.DecodePath(ctx, cursor, depth)
+}
+
+// isNumberLiteral reports whether b is a number literal of the JSON grammar.
+// Unlike strconv.ParseFloat, it does not check the range of the number, which a RawNumber keeps as it is,
+// and rejects the forms of Go, such as "Inf" or hexadecimal numbers.
+func isNumberLiteral(b []byte) bool {
+	if len(b) > 0 && b[0] == '-' {
+		b = b[1:]
+	}
+	switch {
+	case len(b) == 0:
+		return false
+	case b[0] == '0':
+		b = b[1:]
+	case '1' <= b[0] && b[0] <= '9':
+		b = skipDigits(b[1:])
+	default:
+		return false
+	}
+	if len(b) >= 2 && b[0] == '.' && isDigit(b[1]) {
+		b = skipDigits(b[2:])
+	}
+	if len(b) >= 2 && (b[0] == 'e' || b[0] == 'E') {
+		b = b[1:]
+		if b[0] == '+' || b[0] == '-' {
+			b = b[1:]
+		}
+		if len(b) == 0 || !isDigit(b[0]) {
+			return false
+		}
+		b = skipDigits(b[1:])
+	}
+	return len(b) == 0
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func skipDigits(b []byte) []byte {
+	for len(b) > 0 && isDigit(b[0]) {
+		b = b[1:]
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+package decoder
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
+)
+
+// DecodeFunc decodes the JSON value in data into the value p points to.
+type DecodeFunc func(data []byte, p unsafe.Pointer) error
+
+// Registry holds decode functions that override the decoding of their types,
+// for the decoders that use the registry with RegistryOption instead of the process-global cache.
+type Registry struct {
+	mu       sync.RWMutex
+	adapters map[uintptr]Decoder
+	cache    map[uintptr]Decoder
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		adapters: map[uintptr]Decoder{},
+		cache:    map[uintptr]Decoder{},
+	}
+}
+
+// Register makes the decoders compiled by the registry decode typ with fn.
+// Decoders compiled before it are discarded.
+func (r *Registry) Register(typ *runtime.Type, fn DecodeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adapters[uintptr(unsafe.Pointer(typ))] = newAdapterDecoder(typ, fn)
+	r.cache = map[uintptr]Decoder{}
+}
+
+// CompileToGetDecoder is like the package-level CompileToGetDecoder but uses the decode functions of the registry.
+func (r *Registry) CompileToGetDecoder(typ *runtime.Type) (Decoder, error) {
+	typeptr := uintptr(unsafe.Pointer(typ))
+	r.mu.RLock()
+	dec, exists := r.cache[typeptr]
+	r.mu.RUnlock()
+	if exists {
+		return dec, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if dec, exists := r.cache[typeptr]; exists {
+		return dec, nil
+	}
+	dec, err := compileHead(typ, r.structTypeToDecoder(), runtime.TagConfig{})
+	if err != nil {
+		return nil, err
+	}
+	r.cache[typeptr] = dec
+	return dec, nil
+}
+
+// structTypeToDecoder returns a map for compile with the adapters of the registry.
+// The adapters are looked up before anything else by compile, as if they were compiled already.
+// r.mu must be held.
+func (r *Registry) structTypeToDecoder() map[uintptr]Decoder {
+	structTypeToDecoder := make(map[uintptr]Decoder, len(r.adapters))
+	for k, v := range r.adapters {
+		structTypeToDecoder[k] = v
+	}
+	return structTypeToDecoder
+}
+
+// CompileToGetDecoderWithOption returns the decoder of typ compiled with the registry of opt if RegistryOption is set,
+// without the methods of the types of opt with NoMethodsOption, with the struct tags of opt.TagKey with TagKeyOption
+// and the field names of opt.FieldNaming with FieldNamingOption,
+// and the decoder of the process-global cache otherwise.
+func CompileToGetDecoderWithOption(typ *runtime.Type, opt *Option) (Decoder, error) {
+	if (opt.Flags & NoMethodsOption) != 0 {
+		return compileNoMethods(typ, opt)
+	}
+	if (opt.Flags & (TagKeyOption | FieldNamingOption)) != 0 {
+		return compileTagConfig(typ, opt)
+	}
+	if (opt.Flags & RegistryOption) != 0 {
+		return opt.Registry.CompileToGetDecoder(typ)
+	}
+	return CompileToGetDecoder(typ)
+}
+
+type adapterDecoder struct {
+	typ *runtime.Type
+	fn  DecodeFunc
+}
+
+func newAdapterDecoder(typ *runtime.Type, fn DecodeFunc) *adapterDecoder {
+	return &adapterDecoder{typ: typ, fn: fn}
+}
+
+func (d *adapterDecoder) annotateError(cursor int64, err error) error {
+	if e, ok := err.(*errors.SyntaxError); ok {
+		e.Offset = cursor
+	}
+	return err
+}
+
+func (d *adapterDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	if err := s.skipValue(depth); err != nil {
+		return err
+	}
+	src := s.buf[start:s.cursor]
+	dst := make([]byte, len(src))
+	copy(dst, src)
+	if err := d.fn(dst, p); err != nil {
+		return d.annotateError(s.cursor, err)
+	}
+	return nil
+}
+
+func (d *adapterDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.Buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := ctx.skipValue(cursor, depth)
+	if err != nil {
+		return 0, err
+	}
+	src := buf[start:end]
+	dst := make([]byte, len(src))
+	copy(dst, src)
+	if err := d.fn(dst, p); err != nil {
+		return 0, d.annotateError(cursor, err)
+	}
+	return end, nil
+}
+
+func (d *adapterDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return nil, 0, fmt.Errorf("json: decode function of %s does not support decode path", d.typ)
+}
@@ -0,0 +1,125 @@
+package decoder
+
+import (
+	"bytes"
+
+	"github.com/going/json/internal/errors"
+)
+
+// RelaxInput rewrites the byte order mark, the comments and the trailing commas of the nul-terminated ctx.Buf
+// into whitespace with RelaxedOption, so that it is decoded as standard JSON with the same offsets.
+func RelaxInput(ctx *RuntimeContext) {
+	if ctx.Option.Flags&RelaxedOption == 0 {
+		return
+	}
+	buf := ctx.Buf[:len(ctx.Buf)-1]
+	relax(buf, true)
+	ctx.newlines = appendNewlines(ctx.newlines[:0], buf, 0)
+}
+
+// RelaxError returns err with the line and column of its offset in ctx.Buf with RelaxedOption.
+func RelaxError(ctx *RuntimeContext, err error) error {
+	if err == nil || ctx.Option.Flags&RelaxedOption == 0 {
+		return err
+	}
+	return errors.ErrWithPosition(err, ctx.newlines)
+}
+
+// RelaxInput reads the rest of the input and rewrites it like RelaxInput with RelaxedOption.
+// It is done once, since the whole input is in the buffer afterwards.
+func (s *Stream) RelaxInput() {
+	if s.Option.Flags&RelaxedOption == 0 || s.relaxed {
+		return
+	}
+	for s.read() {
+	}
+	buf := s.buf[s.cursor:s.length]
+	relax(buf, s.totalOffset() == 0)
+	s.newlines = appendNewlines(nil, buf, s.totalOffset())
+	s.relaxed = true
+}
+
+// RelaxError returns err with the line and column of its offset in the input once RelaxInput has been done.
+// The lines read before are not counted.
+func (s *Stream) RelaxError(err error) error {
+	if err == nil || !s.relaxed {
+		return err
+	}
+	return errors.ErrWithPosition(err, s.newlines)
+}
+
+// appendNewlines appends the offsets of the line feeds of buf to dst. base is the offset of buf in the input.
+func appendNewlines(dst []int64, buf []byte, base int64) []int64 {
+	for i := 0; ; {
+		n := bytes.IndexByte(buf[i:], '\n')
+		if n < 0 {
+			return dst
+		}
+		i += n
+		dst = append(dst, base+int64(i))
+		i++
+	}
+}
+
+// relax rewrites the comments and the trailing commas of buf, and its byte order mark if bom is true, into whitespace.
+// Line breaks in block comments are kept, so that the lines of the input are not changed.
+func relax(buf []byte, bom bool) {
+	cursor := 0
+	if bom && len(buf) >= 3 && buf[0] == 0xEF && buf[1] == 0xBB && buf[2] == 0xBF {
+		buf[0], buf[1], buf[2] = ' ', ' ', ' '
+		cursor = 3
+	}
+	// comma is the position of the last comma that may be a trailing comma.
+	comma := -1
+	for cursor < len(buf) {
+		switch buf[cursor] {
+		case ' ', '\n', '\t', '\r':
+			cursor++
+		case ',':
+			comma = cursor
+			cursor++
+		case '}', ']':
+			if comma >= 0 {
+				buf[comma] = ' '
+				comma = -1
+			}
+			cursor++
+		case '"':
+			comma = -1
+			for cursor++; cursor < len(buf); cursor++ {
+				if buf[cursor] == '\\' {
+					cursor++
+				} else if buf[cursor] == '"' {
+					break
+				}
+			}
+			cursor++
+		case '/':
+			if cursor+1 < len(buf) && buf[cursor+1] == '/' {
+				for ; cursor < len(buf) && buf[cursor] != '\n'; cursor++ {
+					buf[cursor] = ' '
+				}
+				continue
+			}
+			if cursor+1 < len(buf) && buf[cursor+1] == '*' {
+				buf[cursor], buf[cursor+1] = ' ', ' '
+				for cursor += 2; cursor < len(buf); cursor++ {
+					if buf[cursor] == '*' && cursor+1 < len(buf) && buf[cursor+1] == '/' {
+						buf[cursor], buf[cursor+1] = ' ', ' '
+						cursor += 2
+						break
+					}
+					if buf[cursor] != '\n' && buf[cursor] != '\r' {
+						buf[cursor] = ' '
+					}
+				}
+				continue
+			}
+			comma = -1
+			cursor++
+		default:
+			comma = -1
+			cursor++
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package decoder
+
+import (
+	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
+)
+
+// requiredField is a field with the required tag option. The fieldSets of its key share it,
+// including the ones of the structs it is flattened into, so that it is present if any of them is decoded.
+type requiredField struct {
+	key string
+}
+
+// newRequiredField returns the required field of the key of tag, or nil if it does not have the required option.
+func newRequiredField(tag *runtime.StructTag, key string) *requiredField {
+	if !tag.IsRequired {
+		return nil
+	}
+	return &requiredField{key: key}
+}
+
+// indexRequiredFields returns the required fields of sets in their order, and sets the index of each in sets.
+func indexRequiredFields(sets []*structFieldSet) []*requiredField {
+	var (
+		fields  []*requiredField
+		indexes map[*requiredField]int
+	)
+	for _, set := range sets {
+		if set.required == nil {
+			continue
+		}
+		idx, exists := indexes[set.required]
+		if !exists {
+			if indexes == nil {
+				indexes = map[*requiredField]int{}
+			}
+			idx = len(fields)
+			indexes[set.required] = idx
+			fields = append(fields, set.required)
+		}
+		set.requiredIdx = idx
+	}
+	return fields
+}
+
+// missingFields returns the error of the required fields of d that are not in present, indexed as requiredFields,
+// for the object ending at cursor, or nil if they are all present.
+func (d *structDecoder) missingFields(present []bool, cursor int64) error {
+	var keys []string
+	for i, field := range d.requiredFields {
+		if present == nil || !present[i] {
+			keys = append(keys, field.key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return errors.ErrMissingRequiredFields(runtime.RType2Type(d.typ), keys, cursor)
+}
@@ -0,0 +1,70 @@
+package decoder
+
+import (
+	"unsafe"
+)
+
+// acceptScalar makes the slice decoded by dec accept a single value as the slice of the value.
+func acceptScalar(dec Decoder) {
+	switch d := dec.(type) {
+	case *sliceDecoder:
+		d.acceptScalar = true
+	case *numberSliceDecoder:
+		d.slice.acceptScalar = true
+	case *ptrDecoder:
+		acceptScalar(d.dec)
+	}
+}
+
+// newScalarSlice returns a slice of one element initialized to the zero value.
+func (d *sliceDecoder) newScalarSlice(flags OptionFlags) (*sliceHeader, unsafe.Pointer) {
+	slice := d.newSlice(flags, (*sliceHeader)(nilSlice), 1)
+	ep := slice.data
+	if d.isElemPointerType {
+		**(**unsafe.Pointer)(unsafe.Pointer(&ep)) = nil
+	} else {
+		typedmemmove(d.elemType, ep, unsafe_New(d.elemType))
+	}
+	return slice, ep
+}
+
+// decodeScalar decodes the value at cursor as the only element of the slice.
+func (d *sliceDecoder) decodeScalar(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	slice, ep := d.newScalarSlice(ctx.Option.Flags)
+	var (
+		c   int64
+		err error
+	)
+	if ctx.Option.Flags&PathModeOption != 0 {
+		c, err = ctx.decodeAtPath(d.valueDecoder, pathIndex(0), cursor, depth, ep)
+	} else {
+		c, err = d.valueDecoder.Decode(ctx, cursor, depth, ep)
+	}
+	if err != nil {
+		d.releaseSlice(slice)
+		return 0, err
+	}
+	slice.len = 1
+	d.storeSlice(ctx.Option.Flags, (*sliceHeader)(p), slice)
+	d.releaseSlice(slice)
+	return c, nil
+}
+
+// decodeScalarStream is like decodeScalar for a stream.
+func (d *sliceDecoder) decodeScalarStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	slice, ep := d.newScalarSlice(s.Option.Flags)
+	var err error
+	if s.Option.Flags&PathModeOption != 0 {
+		err = s.decodeAtPath(d.valueDecoder, pathIndex(0), depth, ep)
+	} else {
+		err = d.valueDecoder.DecodeStream(s, depth, ep)
+	}
+	if err != nil {
+		d.releaseSlice(slice)
+		return err
+	}
+	slice.len = 1
+	d.storeSlice(s.Option.Flags, (*sliceHeader)(p), slice)
+	d.releaseSlice(slice)
+	return nil
+}
@@ -0,0 +1,134 @@
+package decoder
+
+import (
+	"github.com/going/json/internal/errors"
+)
+
+// ScanArray calls fn with the offsets of each element of the array that begins at cursor, without decoding them.
+// buf must end with a nul byte. Scanning stops when fn returns false.
+// It returns the cursor after the array, or after the last scanned element if fn stopped the scan.
+func ScanArray(buf []byte, cursor int64, fn func(start, end int64) bool) (int64, error) {
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '[' {
+		return 0, errors.ErrExpected("[ character for array", cursor)
+	}
+	cursor++
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] == ']' {
+		return cursor + 1, nil
+	}
+	for {
+		cursor = skipWhiteSpace(buf, cursor)
+		start := cursor
+		end, err := skipValue(buf, cursor, 1)
+		if err != nil {
+			return 0, err
+		}
+		if !fn(start, end) {
+			return end, nil
+		}
+		cursor = skipWhiteSpace(buf, end)
+		switch buf[cursor] {
+		case ']':
+			return cursor + 1, nil
+		case ',':
+			cursor++
+		default:
+			return 0, errors.ErrInvalidCharacter(buf[cursor], "array", cursor)
+		}
+	}
+}
+
+// ScanObject calls fn with the offsets of each key and value of the object that begins at cursor, without decoding them.
+// The key offsets include the quotes. buf must end with a nul byte. Scanning stops when fn returns false.
+// It returns the cursor after the object, or after the last scanned value if fn stopped the scan.
+func ScanObject(buf []byte, cursor int64, fn func(keyStart, keyEnd, start, end int64) bool) (int64, error) {
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '{' {
+		return 0, errors.ErrExpected("{ character for object", cursor)
+	}
+	cursor++
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] == '}' {
+		return cursor + 1, nil
+	}
+	for {
+		cursor = skipWhiteSpace(buf, cursor)
+		if buf[cursor] != '"' {
+			return 0, errors.ErrExpected("object key", cursor)
+		}
+		keyStart := cursor
+		keyEnd, err := skipValue(buf, cursor, 1)
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, keyEnd)
+		if buf[cursor] != ':' {
+			return 0, errors.ErrExpected("colon after object key", cursor)
+		}
+		cursor = skipWhiteSpace(buf, cursor+1)
+		start := cursor
+		end, err := skipValue(buf, cursor, 1)
+		if err != nil {
+			return 0, err
+		}
+		if !fn(keyStart, keyEnd, start, end) {
+			return end, nil
+		}
+		cursor = skipWhiteSpace(buf, end)
+		switch buf[cursor] {
+		case '}':
+			return cursor + 1, nil
+		case ',':
+			cursor++
+		default:
+			return 0, errors.ErrExpected("comma after object value", cursor)
+		}
+	}
+}
+
+// ArrayLen returns the number of elements of the array that begins at cursor and the cursor after it.
+// buf must end with a nul byte.
+func ArrayLen(buf []byte, cursor int64) (int, int64, error) {
+	n := 0
+	cursor, err := ScanArray(buf, cursor, func(_, _ int64) bool {
+		n++
+		return true
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, cursor, nil
+}
+
+// ObjectKeys returns the unescaped keys of the object that begins at cursor in order of appearance and the cursor after it.
+// buf must end with a nul byte. Escaped keys are unescaped in place, so buf is modified.
+func ObjectKeys(buf []byte, cursor int64) ([]string, int64, error) {
+	var (
+		keys   []string
+		keyErr error
+	)
+	dec := newStringDecoder("", "")
+	cursor, err := ScanObject(buf, cursor, func(keyStart, _, _, _ int64) bool {
+		key, _, err := dec.decodeByte(buf, keyStart)
+		if err != nil {
+			keyErr = err
+			return false
+		}
+		keys = append(keys, string(key))
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if keyErr != nil {
+		return nil, 0, keyErr
+	}
+	return keys, cursor, nil
+}
+
+// ScanValue returns the cursor after the value that begins at cursor, without decoding it.
+// buf must end with a nul byte.
+func ScanValue(buf []byte, cursor int64) (int64, error) {
+	return skipValue(buf, cursor, 0)
+}
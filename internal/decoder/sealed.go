@@ -0,0 +1,83 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unsafe"
+)
+
+// Open decrypts the values of the struct fields with the sealed tag option. It is set by RegisterSealer.
+var Open func(ciphertext []byte) ([]byte, error)
+
+// sealedDecoder decodes the base64 string written for a struct field with the sealed tag option,
+// opening it with Open and decoding the plaintext with dec.
+type sealedDecoder struct {
+	dec           Decoder
+	stringDecoder *stringDecoder
+	structName    string
+	fieldName     string
+}
+
+func newSealedDecoder(dec Decoder, structName, fieldName string) *sealedDecoder {
+	return &sealedDecoder{
+		dec:           dec,
+		stringDecoder: newStringDecoder(structName, fieldName),
+		structName:    structName,
+		fieldName:     fieldName,
+	}
+}
+
+// sealedNull is the plaintext decoded for null, which is not sealed.
+var sealedNull = []byte{'n', 'u', 'l', 'l', nul}
+
+// open returns the plaintext of the base64 ciphertext b, terminated by a nul character, or sealedNull if b is nil.
+func (d *sealedDecoder) open(b []byte) ([]byte, error) {
+	if b == nil {
+		return sealedNull, nil
+	}
+	if Open == nil {
+		return nil, fmt.Errorf("json: cannot open sealed field %s.%s: no Sealer is registered ( see RegisterSealer )", d.structName, d.fieldName)
+	}
+	ciphertext := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
+	n, err := base64.StdEncoding.Decode(ciphertext, b)
+	if err != nil {
+		return nil, fmt.Errorf("json: cannot open sealed field %s.%s: %w", d.structName, d.fieldName, err)
+	}
+	plaintext, err := Open(ciphertext[:n])
+	if err != nil {
+		return nil, fmt.Errorf("json: cannot open sealed field %s.%s: %w", d.structName, d.fieldName, err)
+	}
+	return append(plaintext[:len(plaintext):len(plaintext)], nul), nil
+}
+
+func (d *sealedDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	bytes, err := d.stringDecoder.decodeStreamByte(s)
+	if err != nil {
+		return err
+	}
+	buf, err := d.open(bytes)
+	if err != nil {
+		return err
+	}
+	_, err = d.dec.Decode(&RuntimeContext{Buf: buf, Option: s.Option}, 0, depth, p)
+	return err
+}
+
+func (d *sealedDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	bytes, c, err := d.stringDecoder.decodeByte(ctx.Buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := d.open(bytes)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := d.dec.Decode(&RuntimeContext{Buf: buf, Option: ctx.Option}, 0, depth, p); err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+func (d *sealedDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return nil, 0, fmt.Errorf("json: sealed decoder does not support decode path")
+}
@@ -0,0 +1,174 @@
+package decoder
+
+import (
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+)
+
+// maxShapes is the maximum number of shapes a ShapeCache holds, so that it doesn't grow with the input.
+const maxShapes = 256
+
+// ShapeCache caches the keys of the objects decoded into interface{} with ShapeCacheOption, indexed by their first key.
+// The maps of objects with the cached keys are allocated with their final size,
+// and share the key strings instead of allocating them for every object.
+// A ShapeCache is not safe for concurrent use.
+type ShapeCache struct {
+	shapes map[string][]string
+}
+
+func NewShapeCache() *ShapeCache {
+	return &ShapeCache{shapes: map[string][]string{}}
+}
+
+func (c *ShapeCache) lookup(first []byte) []string {
+	return c.shapes[string(first)]
+}
+
+func (c *ShapeCache) store(keys []string) {
+	if _, exists := c.shapes[keys[0]]; !exists && len(c.shapes) >= maxShapes {
+		return
+	}
+	c.shapes[keys[0]] = keys
+}
+
+// shapeBuilder tracks the keys of an object as it is decoded, against the cached shape of the object.
+type shapeBuilder struct {
+	cache *ShapeCache
+	shape []string
+	// keys are the keys of the object, set once a key differs from shape.
+	keys []string
+	n    int
+}
+
+// key returns the key string of the raw key, shared with the cached shape if it is the same.
+func (b *shapeBuilder) key(raw []byte) string {
+	i := b.n
+	b.n++
+	if i == 0 {
+		b.shape = b.cache.lookup(raw)
+	}
+	if b.keys == nil && i < len(b.shape) && b.shape[i] == string(raw) {
+		return b.shape[i]
+	}
+	if b.keys == nil {
+		b.keys = make([]string, i, i+1)
+		copy(b.keys, b.shape)
+	}
+	k := string(raw)
+	b.keys = append(b.keys, k)
+	return k
+}
+
+// size returns the size hint for the map of the object.
+func (b *shapeBuilder) size() int {
+	return len(b.shape)
+}
+
+// finish stores the keys of the object as its shape if they differ from the cached one.
+func (b *shapeBuilder) finish() {
+	if b.keys != nil {
+		b.cache.store(b.keys)
+	}
+}
+
+func (d *interfaceDecoder) decodeStreamShapedMap(s *Stream, depth int64, p unsafe.Pointer) error {
+	depth++
+	if depth > maxDecodeNestingDepth {
+		return errors.ErrExceededMaxDepth(s.char(), s.cursor)
+	}
+	s.cursor++ // skip '{'
+	if s.skipWhiteSpace() == '}' {
+		s.cursor++
+		*(*interface{})(p) = map[string]interface{}{}
+		return nil
+	}
+	b := shapeBuilder{cache: s.Option.Shapes}
+	var m map[string]interface{}
+	for n := 1; ; n++ {
+		if err := s.countKey(n); err != nil {
+			return err
+		}
+		raw, err := d.stringDecoder.decodeStreamByte(s)
+		if err != nil {
+			return err
+		}
+		key := b.key(raw)
+		if m == nil {
+			if m = takeMapValue(); m == nil {
+				m = make(map[string]interface{}, b.size())
+			}
+		}
+		s.skipWhiteSpace()
+		if !s.equalChar(':') {
+			return errors.ErrExpected("colon after object key", s.totalOffset())
+		}
+		s.cursor++
+		var v interface{}
+		if err := d.mapDecoder.valueDecoder.DecodeStream(s, depth, unsafe.Pointer(&v)); err != nil {
+			return err
+		}
+		m[key] = v
+		s.skipWhiteSpace()
+		if s.equalChar('}') {
+			s.cursor++
+			b.finish()
+			*(*interface{})(p) = m
+			return nil
+		}
+		if !s.equalChar(',') {
+			return errors.ErrExpected("comma after object value", s.totalOffset())
+		}
+		s.cursor++
+	}
+}
+
+func (d *interfaceDecoder) decodeShapedMap(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.Buf
+	depth++
+	if depth > maxDecodeNestingDepth {
+		return 0, errors.ErrExceededMaxDepth(buf[cursor], cursor)
+	}
+	cursor = skipWhiteSpace(buf, cursor+1) // skip '{'
+	if buf[cursor] == '}' {
+		**(**interface{})(unsafe.Pointer(&p)) = map[string]interface{}{}
+		return cursor + 1, nil
+	}
+	b := shapeBuilder{cache: ctx.Option.Shapes}
+	var m map[string]interface{}
+	for n := 1; ; n++ {
+		if err := ctx.countKey(n, cursor); err != nil {
+			return 0, err
+		}
+		raw, keyCursor, err := d.stringDecoder.decodeByte(buf, cursor)
+		if err != nil {
+			return 0, err
+		}
+		key := b.key(raw)
+		if m == nil {
+			if m = takeMapValue(); m == nil {
+				m = make(map[string]interface{}, b.size())
+			}
+		}
+		cursor = skipWhiteSpace(buf, keyCursor)
+		if buf[cursor] != ':' {
+			return 0, errors.ErrExpected("colon after object key", cursor)
+		}
+		var v interface{}
+		valueCursor, err := d.mapDecoder.valueDecoder.Decode(ctx, cursor+1, depth, unsafe.Pointer(&v))
+		if err != nil {
+			return 0, err
+		}
+		m[key] = v
+		cursor = skipWhiteSpace(buf, valueCursor)
+		if buf[cursor] == '}' {
+			b.finish()
+			**(**interface{})(unsafe.Pointer(&p)) = m
+			return cursor + 1, nil
+		}
+		if buf[cursor] != ',' {
+			return 0, errors.ErrExpected("comma after object value", cursor)
+		}
+		cursor++
+	}
+}
@@ -24,6 +24,8 @@ type sliceDecoder struct {
 	arrayPool         sync.Pool
 	structName        string
 	fieldName         string
+	// acceptScalar decodes a value other than an array and null as the array of the value, for the scalarOrArray tag option.
+	acceptScalar bool
 }
 
 // If use reflect.SliceHeader, data type is uintptr.
@@ -59,9 +61,13 @@ func newSliceDecoder(dec Decoder, elemType *runtime.Type, size uintptr, structNa
 	}
 }
 
-func (d *sliceDecoder) newSlice(src *sliceHeader) *sliceHeader {
+// newSlice returns the buffer the elements are decoded into, with a capacity of at least size elements.
+func (d *sliceDecoder) newSlice(flags OptionFlags, src *sliceHeader, size int) *sliceHeader {
 	slice := d.arrayPool.Get().(*sliceHeader)
-	if src.len > 0 {
+	if slice.cap < size {
+		slice = &sliceHeader{data: newArray(d.elemType, size), cap: size}
+	}
+	if src.len > 0 && (flags&SliceReallocOption) == 0 {
 		// copy original elem
 		if slice.cap < src.cap {
 			data := newArray(d.elemType, src.cap)
@@ -80,6 +86,22 @@ func (d *sliceDecoder) releaseSlice(p *sliceHeader) {
 	d.arrayPool.Put(p)
 }
 
+// storeSlice copies the decoded elements of src to dst.
+// The backing array of dst is reused if it is large enough, unless SliceReallocOption is set.
+// If SliceZeroTailOption is set, the reused backing array is zeroed between the new length and its capacity.
+func (d *sliceDecoder) storeSlice(flags OptionFlags, dst, src *sliceHeader) {
+	if dst.data == nil || src.len > dst.cap || (flags&SliceReallocOption) != 0 {
+		dst.data = newArray(d.elemType, src.len)
+		dst.cap = src.len
+	} else if (flags & SliceZeroTailOption) != 0 {
+		for i := src.len; i < dst.cap; i++ {
+			typedmemclr(d.elemType, unsafe.Pointer(uintptr(dst.data)+uintptr(i)*d.size))
+		}
+	}
+	dst.len = src.len
+	copySlice(d.elemType, *dst, *src)
+}
+
 //go:linkname copySlice reflect.typedslicecopy
 func copySlice(elemType *runtime.Type, dst, src sliceHeader) int
 
@@ -89,6 +111,9 @@ func newArray(*runtime.Type, int) unsafe.Pointer
 //go:linkname typedmemmove reflect.typedmemmove
 func typedmemmove(t *runtime.Type, dst, src unsafe.Pointer)
 
+//go:linkname typedmemclr reflect.typedmemclr
+func typedmemclr(t *runtime.Type, ptr unsafe.Pointer)
+
 func (d *sliceDecoder) errNumber(offset int64) *errors.UnmarshalTypeError {
 	return &errors.UnmarshalTypeError{
 		Value:  "number",
@@ -119,17 +144,12 @@ func (d *sliceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 		case '[':
 			s.cursor++
 			if s.skipWhiteSpace() == ']' {
-				dst := (*sliceHeader)(p)
-				if dst.data == nil {
-					dst.data = newArray(d.elemType, 0)
-				} else {
-					dst.len = 0
-				}
+				d.storeSlice(s.Option.Flags, (*sliceHeader)(p), (*sliceHeader)(nilSlice))
 				s.cursor++
 				return nil
 			}
 			idx := 0
-			slice := d.newSlice((*sliceHeader)(p))
+			slice := d.newSlice(s.Option.Flags, (*sliceHeader)(p), s.Option.sizeHint(depth, s.Option.SliceCapacityHint))
 			srcLen := slice.len
 			capacity := slice.cap
 			data := slice.data
@@ -153,7 +173,11 @@ func (d *sliceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 					}
 				}
 
-				if err := d.valueDecoder.DecodeStream(s, depth, ep); err != nil {
+				if s.Option.Flags&PathModeOption != 0 {
+					if err := s.decodeAtPath(d.valueDecoder, pathIndex(idx), depth, ep); err != nil {
+						return err
+					}
+				} else if err := d.valueDecoder.DecodeStream(s, depth, ep); err != nil {
 					return err
 				}
 				s.skipWhiteSpace()
@@ -163,13 +187,7 @@ func (d *sliceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 					slice.cap = capacity
 					slice.len = idx + 1
 					slice.data = data
-					dst := (*sliceHeader)(p)
-					dst.len = idx + 1
-					if dst.len > dst.cap {
-						dst.data = newArray(d.elemType, dst.len)
-						dst.cap = dst.len
-					}
-					copySlice(d.elemType, *dst, *slice)
+					d.storeSlice(s.Option.Flags, (*sliceHeader)(p), slice)
 					d.releaseSlice(slice)
 					s.cursor++
 					return nil
@@ -192,6 +210,9 @@ func (d *sliceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 				s.cursor++
 			}
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			if d.acceptScalar {
+				return d.decodeScalarStream(s, depth, p)
+			}
 			return d.errNumber(s.totalOffset())
 		case nul:
 			if s.read() {
@@ -199,6 +220,9 @@ func (d *sliceDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) er
 			}
 			goto ERROR
 		default:
+			if d.acceptScalar {
+				return d.decodeScalarStream(s, depth, p)
+			}
 			goto ERROR
 		}
 	}
@@ -229,17 +253,12 @@ func (d *sliceDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe
 			cursor++
 			cursor = skipWhiteSpace(buf, cursor)
 			if buf[cursor] == ']' {
-				dst := (*sliceHeader)(p)
-				if dst.data == nil {
-					dst.data = newArray(d.elemType, 0)
-				} else {
-					dst.len = 0
-				}
+				d.storeSlice(ctx.Option.Flags, (*sliceHeader)(p), (*sliceHeader)(nilSlice))
 				cursor++
 				return cursor, nil
 			}
 			idx := 0
-			slice := d.newSlice((*sliceHeader)(p))
+			slice := d.newSlice(ctx.Option.Flags, (*sliceHeader)(p), ctx.presize(cursor, depth, false, ctx.Option.SliceCapacityHint))
 			srcLen := slice.len
 			capacity := slice.cap
 			data := slice.data
@@ -261,7 +280,15 @@ func (d *sliceDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe
 						typedmemmove(d.elemType, ep, unsafe_New(d.elemType))
 					}
 				}
-				c, err := d.valueDecoder.Decode(ctx, cursor, depth, ep)
+				var (
+					c   int64
+					err error
+				)
+				if ctx.Option.Flags&PathModeOption != 0 {
+					c, err = ctx.decodeAtPath(d.valueDecoder, pathIndex(idx), cursor, depth, ep)
+				} else {
+					c, err = d.valueDecoder.Decode(ctx, cursor, depth, ep)
+				}
 				if err != nil {
 					return 0, err
 				}
@@ -272,13 +299,7 @@ func (d *sliceDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe
 					slice.cap = capacity
 					slice.len = idx + 1
 					slice.data = data
-					dst := (*sliceHeader)(p)
-					dst.len = idx + 1
-					if dst.len > dst.cap {
-						dst.data = newArray(d.elemType, dst.len)
-						dst.cap = dst.len
-					}
-					copySlice(d.elemType, *dst, *slice)
+					d.storeSlice(ctx.Option.Flags, (*sliceHeader)(p), slice)
 					d.releaseSlice(slice)
 					cursor++
 					return cursor, nil
@@ -293,8 +314,14 @@ func (d *sliceDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe
 				cursor++
 			}
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			if d.acceptScalar {
+				return d.decodeScalar(ctx, cursor, depth, p)
+			}
 			return 0, d.errNumber(cursor)
 		default:
+			if d.acceptScalar && buf[cursor] != nul {
+				return d.decodeScalar(ctx, cursor, depth, p)
+			}
 			return 0, errors.ErrUnexpectedEndOfJSON("slice", cursor)
 		}
 	}
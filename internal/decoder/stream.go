@@ -17,6 +17,7 @@ const (
 type Stream struct {
 	buf                   []byte
 	bufSize               int64
+	maxBufSize            int64
 	length                int64
 	r                     io.Reader
 	offset                int64
@@ -24,23 +25,94 @@ type Stream struct {
 	filledBuffer          bool
 	allRead               bool
 	UseNumber             bool
+	UseRawNumber          bool
 	DisallowUnknownFields bool
 	Option                *Option
+
+	// keys is the number of object keys of the current value decoded so far, counted with KeyLimitOption.
+	keys int
+	// relaxed reports whether the rest of the input has been read and relaxed with RelaxedOption,
+	// and newlines are the offsets of its line feeds.
+	relaxed  bool
+	newlines []int64
+	// paths is the position of the value being decoded with PathModeOption.
+	paths pathState
 }
 
 func NewStream(r io.Reader) *Stream {
 	return &Stream{
-		r:       r,
-		bufSize: initBufSize,
-		buf:     make([]byte, initBufSize),
-		Option:  &Option{},
+		r:          r,
+		bufSize:    initBufSize,
+		maxBufSize: initBufSize,
+		buf:        make([]byte, initBufSize),
+		Option:     &Option{},
+	}
+}
+
+// NewBytesStream returns a stream over in-memory data.
+// data is copied once to terminate it with a nul character, as Unmarshal does,
+// and the stream never refills its buffer.
+func NewBytesStream(data []byte) *Stream {
+	buf := make([]byte, len(data)+1) // append nul byte to the end
+	copy(buf, data)
+	return NewTerminatedBytesStream(buf)
+}
+
+// NewTerminatedBytesStream is like NewBytesStream but uses buf without copying it.
+// buf must end with a nul character, and it is modified in place while decoding escaped strings.
+func NewTerminatedBytesStream(buf []byte) *Stream {
+	return &Stream{
+		buf:        buf,
+		bufSize:    int64(len(buf)),
+		maxBufSize: int64(len(buf)),
+		length:     int64(len(buf) - 1),
+		allRead:    true,
+		Option:     &Option{},
+	}
+}
+
+// CanDecodeBytes reports whether the next value can be decoded by Decoder.Decode
+// over the whole buffer instead of Decoder.DecodeStream.
+// The paths of PathModeOption, which the unknown fields are reported and the strictness is chosen with,
+// are tracked by the stream only.
+func (s *Stream) CanDecodeBytes() bool {
+	return s.r == nil && !s.UseNumber && !s.UseRawNumber && !s.DisallowUnknownFields &&
+		(s.Option.Flags&(PathModeOption|DisallowUnknownOption)) == 0
+}
+
+// DecodeBytes decodes the next value with the same path as Unmarshal.
+// The caller must check CanDecodeBytes before calling it.
+func (s *Stream) DecodeBytes(dec Decoder, p unsafe.Pointer) error {
+	ctx := TakeRuntimeContext()
+	ctx.Buf = s.buf
+	*ctx.Option = *s.Option
+	cursor, err := dec.Decode(ctx, s.cursor, 0, p)
+	ReleaseRuntimeContext(ctx)
+	if err != nil {
+		return err
 	}
+	s.cursor = cursor
+	return nil
+}
+
+// countKey is like RuntimeContext.countKey.
+func (s *Stream) countKey(n int) error {
+	if (s.Option.Flags & KeyLimitOption) == 0 {
+		return nil
+	}
+	s.keys++
+	return s.Option.checkKeyLimit(n, s.keys, s.totalOffset())
 }
 
 func (s *Stream) TotalOffset() int64 {
 	return s.totalOffset()
 }
 
+// Stats returns the number of bytes read into the buffer so far and the largest size of the buffer.
+func (s *Stream) Stats() (int64, int64) {
+	return s.offset + s.length, s.maxBufSize
+}
+
 func (s *Stream) Buffered() io.Reader {
 	buflen := int64(len(s.buf))
 	for i := s.cursor; i < buflen; i++ {
@@ -52,6 +124,8 @@ func (s *Stream) Buffered() io.Reader {
 }
 
 func (s *Stream) PrepareForDecode() error {
+	s.keys = 0
+	s.paths.reset()
 	for {
 		switch s.char() {
 		case ' ', '\t', '\r', '\n':
@@ -125,6 +199,152 @@ func (s *Stream) More() bool {
 	return true
 }
 
+// peekCursor returns the position of the first character of the next token, skipping whitespace and separators.
+// It reads more data if needed but doesn't advance the cursor.
+func (s *Stream) peekCursor() (int64, error) {
+	cursor := s.cursor
+	for {
+		switch s.buf[cursor] {
+		case ' ', '\n', '\r', '\t', ',', ':':
+			cursor++
+		case nul:
+			// read appends data after the nul character without moving the data before it, so cursor stays valid.
+			if !s.read() {
+				return 0, io.EOF
+			}
+		default:
+			return cursor, nil
+		}
+	}
+}
+
+// PeekChar returns the first character of the next token without advancing the stream.
+func (s *Stream) PeekChar() (byte, error) {
+	cursor, err := s.peekCursor()
+	if err != nil {
+		return 0, err
+	}
+	return s.buf[cursor], nil
+}
+
+// NextChar skips the whitespace and returns the next character without consuming it.
+// Unlike PeekChar, it does not skip the commas and colons. It returns io.EOF at the end of the input.
+func (s *Stream) NextChar() (byte, error) {
+	if c := s.skipWhiteSpace(); c != nul {
+		return c, nil
+	}
+	return 0, io.EOF
+}
+
+// PeekToken returns the next token without advancing the stream.
+func (s *Stream) PeekToken() (interface{}, error) {
+	cursor, err := s.peekCursor()
+	if err != nil {
+		return nil, err
+	}
+	if s.buf[cursor] == '"' {
+		// decoding a string rewrites escape sequences in place, so a copy of the string is decoded instead.
+		end, err := s.peekStringEnd(cursor)
+		if err != nil {
+			return nil, err
+		}
+		tmp := NewBytesStream(s.buf[cursor:end])
+		tmp.Option = s.Option
+		return tmp.Token()
+	}
+	saved := s.cursor
+	s.cursor = cursor
+	tok, err := s.Token()
+	s.cursor = saved
+	return tok, err
+}
+
+// ReadLine consumes the next line of the input that is not empty, and returns it without the line feed
+// with its offset in the input. The line is only valid until the stream is read again.
+// A nul character of the line is kept as data: only the one at the end of the data read so far ends the buffer.
+// It returns io.EOF at the end of the input.
+func (s *Stream) ReadLine() ([]byte, int64, error) {
+	for {
+		switch s.buf[s.cursor] {
+		case ' ', '\n', '\t', '\r':
+			s.cursor++
+			continue
+		case nul:
+			if s.cursor == s.length {
+				if s.read() {
+					continue
+				}
+				return nil, 0, io.EOF
+			}
+		}
+		break
+	}
+	start := s.cursor
+	for cursor := start; ; cursor++ {
+		switch s.buf[cursor] {
+		case '\n':
+			s.cursor = cursor + 1
+			return s.buf[start:cursor], s.offset + start, nil
+		case nul:
+			if cursor < s.length {
+				continue
+			}
+			// read appends data after the cursor without moving the data before it, so start stays valid.
+			s.cursor = cursor
+			if s.read() {
+				cursor--
+				continue
+			}
+			return s.buf[start:cursor], s.offset + start, nil
+		}
+	}
+}
+
+// ValidateEnd returns an error if anything other than whitespace follows the value decoded from the stream.
+func (s *Stream) ValidateEnd() error {
+	if c := s.skipWhiteSpace(); c != nul {
+		return errors.ErrAfterTopLevelValue(c, s.totalOffset())
+	}
+	return nil
+}
+
+// SkipValue consumes the next value without decoding it.
+// It returns io.EOF at the end of the input.
+func (s *Stream) SkipValue() error {
+	if err := s.PrepareForDecode(); err != nil {
+		return err
+	}
+	switch c := s.skipWhiteSpace(); c {
+	case '{', '[', '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 't', 'f', 'n':
+		return s.skipValue(0)
+	case nul:
+		return io.EOF
+	default:
+		return errors.ErrInvalidBeginningOfValue(c, s.totalOffset())
+	}
+}
+
+// peekStringEnd returns the position next to the closing quote of the string starting at cursor.
+func (s *Stream) peekStringEnd(cursor int64) (int64, error) {
+	start := cursor
+	for cursor++; ; cursor++ {
+		switch s.buf[cursor] {
+		case '\\':
+			cursor++
+			if s.buf[cursor] == nul && !s.read() {
+				return 0, errors.ErrUnexpectedEndOfJSON("string", s.offset+start)
+			}
+		case '"':
+			return cursor + 1, nil
+		case nul:
+			if !s.read() {
+				return 0, errors.ErrUnexpectedEndOfJSON("string", s.offset+start)
+			}
+			cursor--
+		}
+	}
+}
+
 func (s *Stream) Token() (interface{}, error) {
 	for {
 		c := s.char()
@@ -138,6 +358,11 @@ func (s *Stream) Token() (interface{}, error) {
 			s.cursor++
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 			bytes := floatBytes(s)
+			if s.UseRawNumber {
+				num := make(RawNumber, len(bytes))
+				copy(num, bytes)
+				return num, nil
+			}
 			str := *(*string)(unsafe.Pointer(&bytes))
 			if s.UseNumber {
 				return json.Number(str), nil
@@ -193,6 +418,9 @@ func (s *Stream) readBuf() []byte {
 		s.bufSize *= 2
 		remainBuf := s.buf
 		s.buf = make([]byte, s.bufSize)
+		if s.bufSize > s.maxBufSize {
+			s.maxBufSize = s.bufSize
+		}
 		copy(s.buf, remainBuf)
 	}
 	remainLen := s.length - s.cursor
@@ -456,6 +684,32 @@ func (s *Stream) skipValue(depth int64) error {
 	}
 }
 
+// SkipPrefix skips prefix if the data at the cursor begins with it, reading more data as needed.
+// It reports whether prefix is skipped.
+func (s *Stream) SkipPrefix(prefix string) bool {
+	if !s.hasPrefix(prefix) {
+		return false
+	}
+	s.cursor += int64(len(prefix))
+	return true
+}
+
+// hasPrefix reports whether the buffer has prefix at the cursor, reading more data as needed.
+func (s *Stream) hasPrefix(prefix string) bool {
+	for i := 0; i < len(prefix); i++ {
+		cursor := s.cursor + int64(i)
+		for s.buf[cursor] == nul && cursor >= s.length {
+			if !s.read() {
+				return false
+			}
+		}
+		if s.buf[cursor] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func nullBytes(s *Stream) error {
 	// current cursor's character is 'n'
 	s.cursor++
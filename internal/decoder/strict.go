@@ -0,0 +1,219 @@
+package decoder
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/going/json/internal/errors"
+)
+
+// ValidateStrict checks the first value of the nul-terminated ctx.Buf with StrictOption, StringLimitOption and DuplicateKeyOption.
+func ValidateStrict(ctx *RuntimeContext) error {
+	if ctx.Option.Flags&(StrictOption|StringLimitOption|DuplicateKeyOption) == 0 {
+		return nil
+	}
+	buf := ctx.Buf
+	return validateStrict(buf[:len(buf)-1], ctx.Option, 0)
+}
+
+// ValidateStrict checks the next value of the stream with StrictOption, StringLimitOption and DuplicateKeyOption,
+// reading it entirely without consuming it.
+func (s *Stream) ValidateStrict() error {
+	if s.Option.Flags&(StrictOption|StringLimitOption|DuplicateKeyOption) == 0 {
+		return nil
+	}
+	s.skipWhiteSpace()
+	start := s.cursor
+	err := s.skipValue(0)
+	end := s.cursor
+	s.cursor = start
+	if err != nil {
+		// the syntax error is reported by the decoder.
+		return nil
+	}
+	return validateStrict(s.buf[start:end], s.Option, s.offset+start)
+}
+
+// validateStrict returns an error if the first value of buf has strings longer than opt.MaxStringLength with StringLimitOption,
+// objects with duplicate keys with DuplicateKeyOption, or with StrictOption, has strings with invalid UTF-8
+// or unescaped control characters, objects with duplicate keys, or is nested deeper than opt.MaxDepth ( if opt.MaxDepth > 0 ).
+// offset is the position of buf in the input.
+// Syntax errors are left to the decoder: the validation stops at them without an error.
+func validateStrict(buf []byte, opt *Option, offset int64) error {
+	strict := opt.Flags&StrictOption != 0
+	uniqueKeys := strict || opt.Flags&DuplicateKeyOption != 0
+	maxDepth, maxStringLen := 0, 0
+	if strict {
+		maxDepth = opt.MaxDepth
+	}
+	if opt.Flags&StringLimitOption != 0 {
+		maxStringLen = opt.MaxStringLength
+	}
+	var (
+		// objects holds the objects and the arrays being validated, from the outermost one.
+		objects []strictScope
+		// pool holds the maps of the objects validated so far, to be reused.
+		pool      []map[string]struct{}
+		expectKey bool
+	)
+	end := int64(len(buf))
+	for cursor := int64(0); cursor < end; {
+		switch c := buf[cursor]; c {
+		case ' ', '\n', '\t', '\r', ':':
+			cursor++
+		case ',':
+			if n := len(objects); n > 0 {
+				expectKey = objects[n-1].keys != nil
+				objects[n-1].index++
+			}
+			cursor++
+		case '{', '[':
+			if maxDepth > 0 && len(objects) >= maxDepth {
+				return errors.ErrExceededMaxDepth(c, offset+cursor)
+			}
+			var keys map[string]struct{}
+			if c == '{' {
+				if n := len(pool); n > 0 {
+					keys = pool[n-1]
+					pool = pool[:n-1]
+				} else {
+					keys = map[string]struct{}{}
+				}
+			}
+			objects = append(objects, strictScope{keys: keys})
+			expectKey = c == '{'
+			cursor++
+		case '}', ']':
+			if len(objects) == 0 {
+				return nil
+			}
+			if keys := objects[len(objects)-1].keys; keys != nil {
+				for k := range keys {
+					delete(keys, k)
+				}
+				pool = append(pool, keys)
+			}
+			objects = objects[:len(objects)-1]
+			expectKey = false
+			cursor++
+			if len(objects) == 0 {
+				return nil
+			}
+		case '"':
+			start := cursor
+			next, escaped, err := validateStrictString(buf, cursor, offset, strict)
+			if err != nil || next < 0 {
+				return err
+			}
+			if maxStringLen > 0 && next-start-2 > int64(maxStringLen) {
+				return errors.ErrStringLimit(maxStringLen, offset+start)
+			}
+			cursor = next
+			if expectKey {
+				if uniqueKeys {
+					key := buf[start+1 : cursor-1]
+					if escaped {
+						key = append([]byte{}, key...)
+						key = key[:unescapeString(key)]
+					}
+					scope := &objects[len(objects)-1]
+					if _, exists := scope.keys[string(key)]; exists {
+						return errors.ErrDuplicateObjectKey(string(key), strictPath(objects, string(key)), offset+start)
+					}
+					scope.key = string(key)
+					scope.keys[scope.key] = struct{}{}
+				}
+				expectKey = false
+			} else if len(objects) == 0 {
+				return nil
+			}
+		case nul:
+			return nil
+		default:
+			// numbers and literals, which are validated by the decoder.
+		SCALAR:
+			for cursor++; cursor < end; cursor++ {
+				switch buf[cursor] {
+				case ' ', '\n', '\t', '\r', ',', ':', '}', ']', '{', '[', '"', nul:
+					break SCALAR
+				}
+			}
+			if len(objects) == 0 {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// strictScope is an object or an array validated by validateStrict.
+type strictScope struct {
+	// keys holds the keys of an object, and is nil for an array.
+	keys map[string]struct{}
+	// key is the last key of an object, and index is the index of the current element of an array.
+	key   string
+	index int
+}
+
+// strictPath returns the keys and the indexes from the root to key of the innermost of objects, separated by dots.
+func strictPath(objects []strictScope, key string) string {
+	var b strings.Builder
+	for _, scope := range objects[:len(objects)-1] {
+		if scope.keys != nil {
+			b.WriteString(scope.key)
+		} else {
+			b.WriteString(strconv.Itoa(scope.index))
+		}
+		b.WriteByte('.')
+	}
+	b.WriteString(key)
+	return b.String()
+}
+
+// validateStrictString validates the string starting at cursor, and returns the position next to its closing quote,
+// or -1 if the string is not terminated or has a malformed escape sequence, and whether it has escape sequences.
+// The characters are validated only if strict is true.
+func validateStrictString(buf []byte, cursor, offset int64, strict bool) (int64, bool, error) {
+	end := int64(len(buf))
+	escaped := false
+	for cursor++; cursor < end; {
+		c := buf[cursor]
+		switch {
+		case c == '"':
+			return cursor + 1, escaped, nil
+		case c == '\\':
+			escaped = true
+			if cursor+1 < end && buf[cursor+1] == 'u' {
+				if cursor+6 > end {
+					return -1, false, nil
+				}
+				for _, h := range buf[cursor+2 : cursor+6] {
+					if !isHex(h) {
+						return -1, false, nil
+					}
+				}
+				cursor += 6
+			} else {
+				cursor += 2
+			}
+		case !strict:
+			cursor++
+		case c < 0x20:
+			return 0, false, errors.ErrSyntax("json: invalid control character in string", offset+cursor)
+		case c < utf8.RuneSelf:
+			cursor++
+		default:
+			r, size := utf8.DecodeRune(buf[cursor:])
+			if r == utf8.RuneError && size == 1 {
+				return 0, false, errors.ErrSyntax("json: invalid UTF-8 in string", offset+cursor)
+			}
+			cursor += int64(size)
+		}
+	}
+	return -1, false, nil
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
@@ -200,6 +200,7 @@ var (
 )
 
 func stringBytes(s *Stream) ([]byte, error) {
+	trusted := (s.Option.Flags & TrustedInputOption) != 0
 	_, cursor, p := s.stat()
 	cursor++ // skip double quote char
 	start := cursor
@@ -236,6 +237,9 @@ func stringBytes(s *Stream) ([]byte, error) {
 			0xB0, 0xB1, 0xB2, 0xB3, 0xB4, 0xB5, 0xB6, 0xB7, 0xB8, 0xB9, 0xBA, 0xBB, 0xBC, 0xBD, 0xBE, 0xBF, // 0xB0-0xBF
 			0xC0, 0xC1, // 0xC0-0xC1
 			0xF5, 0xF6, 0xF7, 0xF8, 0xF9, 0xFA, 0xFB, 0xFC, 0xFD, 0xFE, 0xFF: // 0xF5-0xFE
+			if trusted {
+				break
+			}
 			// character is invalid
 			s.buf = append(append(append([]byte{}, s.buf[:cursor]...), runeErrBytes...), s.buf[cursor+1:]...)
 			_, _, p = s.stat()
@@ -258,6 +262,10 @@ func stringBytes(s *Stream) ([]byte, error) {
 			}
 			fallthrough
 		default:
+			if trusted {
+				// input is known to be valid UTF-8: skip rune validation
+				break
+			}
 			// multi bytes character
 			if !utf8.FullRune(s.buf[cursor : len(s.buf)-1]) {
 				s.cursor = cursor
@@ -11,6 +11,7 @@ import (
 	"unsafe"
 
 	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
 )
 
 type structFieldSet struct {
@@ -21,9 +22,14 @@ type structFieldSet struct {
 	key         string
 	keyLen      int64
 	err         error
+	// required is the field with the required tag option that the key is of, or nil,
+	// and requiredIdx is its index in the requiredFields of the struct decoder.
+	required    *requiredField
+	requiredIdx int
 }
 
 type structDecoder struct {
+	typ                *runtime.Type
 	fieldMap           map[string]*structFieldSet
 	fieldUniqueNameNum int
 	stringDecoder      *stringDecoder
@@ -35,6 +41,11 @@ type structDecoder struct {
 	sortedFieldSets    []*structFieldSet
 	keyDecoder         func(*structDecoder, []byte, int64) (int64, *structFieldSet, error)
 	keyStreamDecoder   func(*structDecoder, *Stream) (*structFieldSet, string, error)
+
+	// fieldSets are the fields of fieldMap without the aliases of their keys, in the order of their offsets.
+	fieldSets []*structFieldSet
+	// requiredFields are the fields with the required tag option, including the ones of the flattened structs.
+	requiredFields []*requiredField
 }
 
 var (
@@ -59,8 +70,9 @@ func toASCIILower(s string) string {
 	return string(b)
 }
 
-func newStructDecoder(structName, fieldName string, fieldMap map[string]*structFieldSet) *structDecoder {
+func newStructDecoder(typ *runtime.Type, structName, fieldName string, fieldMap map[string]*structFieldSet) *structDecoder {
 	return &structDecoder{
+		typ:              typ,
 		fieldMap:         fieldMap,
 		stringDecoder:    newStringDecoder(structName, fieldName),
 		structName:       structName,
@@ -681,17 +693,36 @@ func (d *structDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) e
 	s.cursor++
 	if s.skipWhiteSpace() == '}' {
 		s.cursor++
+		if s.Option.Flags&AuditOption != 0 {
+			s.paths.auditDefaulted(s.Option, d, nil)
+		}
+		if len(d.requiredFields) > 0 {
+			return d.missingFields(nil, s.totalOffset())
+		}
 		return nil
 	}
 	var (
 		seenFields   map[int]struct{}
 		seenFieldNum int
+		// auditedFields are the fields decoded so far with AuditOption.
+		auditedFields map[*structFieldSet]struct{}
+		// presentFields are the required fields decoded so far, indexed as requiredFields.
+		presentFields []bool
 	)
 	firstWin := (s.Option.Flags & FirstWinOption) != 0
 	if firstWin {
 		seenFields = make(map[int]struct{}, d.fieldUniqueNameNum)
 	}
-	for {
+	if s.Option.Flags&AuditOption != 0 {
+		auditedFields = make(map[*structFieldSet]struct{}, len(d.fieldSets))
+	}
+	if len(d.requiredFields) > 0 {
+		presentFields = make([]bool, len(d.requiredFields))
+	}
+	for n := 1; ; n++ {
+		if err := s.countKey(n); err != nil {
+			return err
+		}
 		s.reset()
 		field, key, err := d.keyStreamDecoder(d, s)
 		if err != nil {
@@ -705,28 +736,44 @@ func (d *structDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) e
 			if field.err != nil {
 				return field.err
 			}
+			if auditedFields != nil {
+				auditedFields[field] = struct{}{}
+			}
+			if field.required != nil {
+				presentFields[field.requiredIdx] = true
+			}
 			if firstWin {
 				if _, exists := seenFields[field.fieldIdx]; exists {
 					if err := s.skipValue(depth); err != nil {
 						return err
 					}
 				} else {
-					if err := field.dec.DecodeStream(s, depth, unsafe.Pointer(uintptr(p)+field.offset)); err != nil {
+					if err := d.decodeFieldStream(s, field, depth, p); err != nil {
 						return err
 					}
 					seenFieldNum++
-					if d.fieldUniqueNameNum <= seenFieldNum {
+					if d.fieldUniqueNameNum <= seenFieldNum && auditedFields == nil {
 						return s.skipObject(depth)
 					}
 					seenFields[field.fieldIdx] = struct{}{}
 				}
 			} else {
-				if err := field.dec.DecodeStream(s, depth, unsafe.Pointer(uintptr(p)+field.offset)); err != nil {
+				if err := d.decodeFieldStream(s, field, depth, p); err != nil {
 					return err
 				}
 			}
+		} else if s.Option.Flags&PathModeOption != 0 {
+			disallow := s.DisallowUnknownFields || s.Option.Flags&DisallowUnknownOption != 0
+			if isUnknownFieldError(s.paths.mode(s.Option.PathModes, key), disallow) {
+				s.paths.addUnknownField(key, d.suggestKey(key))
+			} else if s.Option.Flags&AuditOption != 0 {
+				s.paths.audit(s.Option, runtime.AuditUnknownKey, key)
+			}
+			if err := s.skipValue(depth); err != nil {
+				return err
+			}
 		} else if s.DisallowUnknownFields {
-			return fmt.Errorf("json: unknown field %q", key)
+			return errors.ErrUnknownStructField(key, key, d.suggestKey(key))
 		} else {
 			if err := s.skipValue(depth); err != nil {
 				return err
@@ -735,6 +782,12 @@ func (d *structDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) e
 		c := s.skipWhiteSpace()
 		if c == '}' {
 			s.cursor++
+			if auditedFields != nil {
+				s.paths.auditDefaulted(s.Option, d, auditedFields)
+			}
+			if presentFields != nil {
+				return d.missingFields(presentFields, s.totalOffset())
+			}
 			return nil
 		}
 		if c != ',' {
@@ -768,21 +821,44 @@ func (d *structDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsaf
 	cursor = skipWhiteSpace(buf, cursor)
 	if buf[cursor] == '}' {
 		cursor++
+		if ctx.Option.Flags&AuditOption != 0 {
+			ctx.paths.auditDefaulted(ctx.Option, d, nil)
+		}
+		if len(d.requiredFields) > 0 {
+			if err := d.missingFields(nil, cursor); err != nil {
+				return 0, err
+			}
+		}
 		return cursor, nil
 	}
 	var (
 		seenFields   map[int]struct{}
 		seenFieldNum int
+		// auditedFields are the fields decoded so far with AuditOption.
+		auditedFields map[*structFieldSet]struct{}
+		// presentFields are the required fields decoded so far, indexed as requiredFields.
+		presentFields []bool
 	)
 	firstWin := (ctx.Option.Flags & FirstWinOption) != 0
 	if firstWin {
 		seenFields = make(map[int]struct{}, d.fieldUniqueNameNum)
 	}
-	for {
+	if ctx.Option.Flags&AuditOption != 0 {
+		auditedFields = make(map[*structFieldSet]struct{}, len(d.fieldSets))
+	}
+	if len(d.requiredFields) > 0 {
+		presentFields = make([]bool, len(d.requiredFields))
+	}
+	for n := 1; ; n++ {
+		if err := ctx.countKey(n, cursor); err != nil {
+			return 0, err
+		}
+		keyStart := skipWhiteSpace(buf, cursor)
 		c, field, err := d.keyDecoder(d, buf, cursor)
 		if err != nil {
 			return 0, err
 		}
+		keyEnd := c
 		cursor = skipWhiteSpace(buf, c)
 		if char(b, cursor) != ':' {
 			return 0, errors.ErrExpected("colon after object key", cursor)
@@ -795,34 +871,48 @@ func (d *structDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsaf
 			if field.err != nil {
 				return 0, field.err
 			}
+			if auditedFields != nil {
+				auditedFields[field] = struct{}{}
+			}
+			if field.required != nil {
+				presentFields[field.requiredIdx] = true
+			}
 			if firstWin {
 				if _, exists := seenFields[field.fieldIdx]; exists {
-					c, err := skipValue(buf, cursor, depth)
+					c, err := ctx.skipValue(cursor, depth)
 					if err != nil {
 						return 0, err
 					}
 					cursor = c
 				} else {
-					c, err := field.dec.Decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+field.offset))
+					c, err := d.decodeField(ctx, field, cursor, depth, p)
 					if err != nil {
 						return 0, err
 					}
 					cursor = c
 					seenFieldNum++
-					if d.fieldUniqueNameNum <= seenFieldNum {
+					if d.fieldUniqueNameNum <= seenFieldNum && auditedFields == nil {
 						return skipObject(buf, cursor, depth)
 					}
 					seenFields[field.fieldIdx] = struct{}{}
 				}
 			} else {
-				c, err := field.dec.Decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+field.offset))
+				c, err := d.decodeField(ctx, field, cursor, depth, p)
 				if err != nil {
 					return 0, err
 				}
 				cursor = c
 			}
 		} else {
-			c, err := skipValue(buf, cursor, depth)
+			if ctx.Option.Flags&PathModeOption != 0 {
+				key := pathKey(buf[keyStart:keyEnd])
+				if isUnknownFieldError(ctx.paths.mode(ctx.Option.PathModes, key), ctx.Option.Flags&DisallowUnknownOption != 0) {
+					ctx.paths.addUnknownField(key, d.suggestKey(key))
+				} else if ctx.Option.Flags&AuditOption != 0 {
+					ctx.paths.audit(ctx.Option, runtime.AuditUnknownKey, key)
+				}
+			}
+			c, err := ctx.skipValue(cursor, depth)
 			if err != nil {
 				return 0, err
 			}
@@ -831,6 +921,14 @@ func (d *structDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsaf
 		cursor = skipWhiteSpace(buf, cursor)
 		if char(b, cursor) == '}' {
 			cursor++
+			if auditedFields != nil {
+				ctx.paths.auditDefaulted(ctx.Option, d, auditedFields)
+			}
+			if presentFields != nil {
+				if err := d.missingFields(presentFields, cursor); err != nil {
+					return 0, err
+				}
+			}
 			return cursor, nil
 		}
 		if char(b, cursor) != ',' {
@@ -840,6 +938,22 @@ func (d *structDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsaf
 	}
 }
 
+// decodeField decodes the value of field of the struct at p.
+func (d *structDecoder) decodeField(ctx *RuntimeContext, field *structFieldSet, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	if ctx.Option.Flags&PathModeOption != 0 {
+		return ctx.decodeAtPath(field.dec, field.key, cursor, depth, unsafe.Pointer(uintptr(p)+field.offset))
+	}
+	return field.dec.Decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+field.offset))
+}
+
+// decodeFieldStream is like decodeField for a stream.
+func (d *structDecoder) decodeFieldStream(s *Stream, field *structFieldSet, depth int64, p unsafe.Pointer) error {
+	if s.Option.Flags&PathModeOption != 0 {
+		return s.decodeAtPath(field.dec, field.key, depth, unsafe.Pointer(uintptr(p)+field.offset))
+	}
+	return field.dec.DecodeStream(s, depth, unsafe.Pointer(uintptr(p)+field.offset))
+}
+
 func (d *structDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
 	return nil, 0, fmt.Errorf("json: struct decoder does not support decode path")
 }
@@ -0,0 +1,181 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// structuralIndex is the position of the closing bracket of every object and array of a document,
+// built in a first pass over the whole input with IndexOption, so that skipping a value is a lookup
+// instead of a scan of its bytes.
+// The first pass classifies the input 64 bytes at a time, as simdjson does: the quotes, backslashes and brackets
+// of a block are found as bit masks with word-at-a-time arithmetic, and the masks of the strings are derived from them.
+type structuralIndex struct {
+	// containers are the objects and arrays in the order of their opening brackets.
+	containers []container
+	// stack is the indexes in containers of the objects and arrays being indexed.
+	stack []int32
+	// maxDepth is the deepest nesting of the document.
+	maxDepth int64
+}
+
+// container is the positions of the opening and the closing brackets of an object or an array.
+type container struct {
+	open, close uint32
+}
+
+const (
+	lsb = 0x0101010101010101
+	msb = 0x8080808080808080
+	// gatherMagic moves the most significant bits of the 8 bytes of a word, shifted to the least significant bits,
+	// to the 8 bits of the highest byte of the product.
+	gatherMagic = 0x0102040810204080
+	evenBits    = 0x5555555555555555
+)
+
+// zeroBytes returns the most significant bit of each zero byte of x.
+func zeroBytes(x uint64) uint64 {
+	return ^(((x &^ msb) + (msb - lsb)) | x) & msb
+}
+
+// gather returns the most significant bits of the 8 bytes of x as a mask of one bit per byte.
+func gather(x uint64) uint64 {
+	return ((x >> 7) * gatherMagic) >> 56
+}
+
+// blockMasks returns the masks of the quotes, the backslashes, the opening and the closing brackets
+// of the 64 bytes of block.
+func blockMasks(block []byte) (quote, backslash, open, close uint64) {
+	_ = block[63]
+	for i := 0; i < 8; i++ {
+		w := binary.LittleEndian.Uint64(block[i*8:])
+		// '{' and '[', and '}' and ']', differ only by the 0x20 bit.
+		folded := w | (lsb * 0x20)
+		q, b, o, c := zeroBytes(w^(lsb*'"')), zeroBytes(w^(lsb*'\\')), zeroBytes(folded^(lsb*'{')), zeroBytes(folded^(lsb*'}'))
+		if q|b|o|c == 0 {
+			continue
+		}
+		shift := uint(i * 8)
+		quote |= gather(q) << shift
+		backslash |= gather(b) << shift
+		open |= gather(o) << shift
+		close |= gather(c) << shift
+	}
+	return
+}
+
+// escapedMask returns the mask of the characters escaped by a backslash in a block,
+// carrying the escape of the first character of the next block in prevEscaped.
+func escapedMask(backslash uint64, prevEscaped *uint64) uint64 {
+	backslash &^= *prevEscaped
+	followsEscape := backslash<<1 | *prevEscaped
+	oddSequenceStarts := backslash &^ evenBits &^ followsEscape
+	sequencesStartingOnEvenBits, overflow := bits.Add64(oddSequenceStarts, backslash, 0)
+	*prevEscaped = overflow
+	return (evenBits ^ sequencesStartingOnEvenBits<<1) & followsEscape
+}
+
+// prefixXor returns the mask whose bit i is the parity of the bits 0 to i of x.
+func prefixXor(x uint64) uint64 {
+	x ^= x << 1
+	x ^= x << 2
+	x ^= x << 4
+	x ^= x << 8
+	x ^= x << 16
+	x ^= x << 32
+	return x
+}
+
+// build indexes buf, which ends with a nul byte. It reports false if the brackets or the strings of buf
+// are not balanced, or buf has another nul byte or a backslash outside of the strings,
+// in which case the values are skipped by scanning them, which reports the errors.
+// The storage of the previous index is reused.
+func (idx *structuralIndex) build(buf []byte) bool {
+	src := buf[:len(buf)-1]
+	idx.containers, idx.stack, idx.maxDepth = idx.containers[:0], idx.stack[:0], 0
+	if len(src) >= math.MaxUint32 || bytes.IndexByte(src, nul) >= 0 {
+		return false
+	}
+	var (
+		prevEscaped uint64
+		prevInStr   uint64
+		tail        [64]byte
+	)
+	for base := 0; base < len(src); base += 64 {
+		block := src[base:]
+		if len(block) < 64 {
+			copy(tail[:], block)
+			block = tail[:]
+		}
+		quote, backslash, open, close := blockMasks(block)
+		if quote|backslash|open|close == 0 && prevInStr == 0 {
+			continue
+		}
+		quote &^= escapedMask(backslash, &prevEscaped)
+		// the mask of a string covers its opening quote and its content, but not its closing quote.
+		inString := prefixXor(quote) ^ prevInStr
+		prevInStr = uint64(int64(inString) >> 63)
+		if backslash&^inString != 0 {
+			// a backslash outside of the strings is invalid, and escapes nothing for the scan.
+			return false
+		}
+		for structurals := (open | close) &^ inString; structurals != 0; structurals &= structurals - 1 {
+			pos := base + bits.TrailingZeros64(structurals)
+			switch c := src[pos]; c {
+			case '{', '[':
+				idx.stack = append(idx.stack, int32(len(idx.containers)))
+				idx.containers = append(idx.containers, container{open: uint32(pos)})
+				if depth := int64(len(idx.stack)); depth > idx.maxDepth {
+					idx.maxDepth = depth
+				}
+			default:
+				if len(idx.stack) == 0 {
+					return false
+				}
+				top := &idx.containers[idx.stack[len(idx.stack)-1]]
+				if src[top.open] != c-2 {
+					// '}' - 2 is '{' and ']' - 2 is '['.
+					return false
+				}
+				top.close = uint32(pos)
+				idx.stack = idx.stack[:len(idx.stack)-1]
+			}
+		}
+	}
+	return len(idx.stack) == 0 && prevInStr == 0 && prevEscaped == 0
+}
+
+// closeOf returns the position of the closing bracket of the object or array opening at cursor.
+func (idx *structuralIndex) closeOf(cursor int64) (int64, bool) {
+	containers := idx.containers
+	i := sort.Search(len(containers), func(i int) bool { return int64(containers[i].open) >= cursor })
+	if i == len(containers) || int64(containers[i].open) != cursor {
+		return 0, false
+	}
+	return int64(containers[i].close), true
+}
+
+// BuildStructuralIndex builds the index of the input of ctx with IndexOption.
+// It must be called after the input is rewritten by RelaxInput.
+func BuildStructuralIndex(ctx *RuntimeContext) {
+	if (ctx.Option.Flags & IndexOption) != 0 {
+		ctx.indexed = ctx.index.build(ctx.Buf)
+	}
+}
+
+// skipValue is like skipValue for the input of ctx, looking up the end of an object or array in the index if there is one.
+func (ctx *RuntimeContext) skipValue(cursor, depth int64) (int64, error) {
+	buf := ctx.Buf
+	if ctx.indexed {
+		cursor = skipWhiteSpace(buf, cursor)
+		if c := buf[cursor]; (c == '{' || c == '[') && depth+ctx.index.maxDepth <= maxDecodeNestingDepth {
+			if end, ok := ctx.index.closeOf(cursor); ok {
+				return end + 1, nil
+			}
+		}
+	}
+	return skipValue(buf, cursor, depth)
+}
@@ -0,0 +1,90 @@
+package decoder
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestDistance is the largest edit distance between an unknown key and the key suggested for it.
+const maxSuggestDistance = 2
+
+// maxSuggestKeyLen is the length of the longest unknown key that a key is suggested for,
+// which bounds the cost of the edit distances of a key from the input.
+const maxSuggestKeyLen = 64
+
+// suggestKey returns the key of the struct closest to the unknown key, ignoring the case, the underscores and the hyphens,
+// or "" if none is close enough to be a likely misspelling.
+func (d *structDecoder) suggestKey(key string) string {
+	if len(key) > maxSuggestKeyLen {
+		return ""
+	}
+	keys := make([]string, 0, len(d.fieldMap))
+	seen := make(map[string]struct{}, len(d.fieldMap))
+	for _, set := range d.fieldMap {
+		if set.err != nil {
+			continue
+		}
+		if _, exists := seen[set.key]; exists {
+			continue
+		}
+		seen[set.key] = struct{}{}
+		keys = append(keys, set.key)
+	}
+	// the suggestion doesn't depend on the order of the map when keys are as close.
+	sort.Strings(keys)
+
+	target := normalizeSuggestKey(key)
+	suggestion, best := "", maxSuggestDistance+1
+	for _, k := range keys {
+		normalized := normalizeSuggestKey(k)
+		if diff := len(target) - len(normalized); diff > maxSuggestDistance || -diff > maxSuggestDistance {
+			// the edit distance is at least the difference of the lengths.
+			continue
+		}
+		dist := editDistance(target, normalized)
+		if dist < best && 2*dist < len(target) {
+			suggestion, best = k, dist
+		}
+	}
+	return suggestion
+}
+
+func normalizeSuggestKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+// editDistance returns the Levenshtein distance between a and b in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,52 @@
+package decoder
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// tagConfigDecoderKey is the key of a decoder compiled with TagKeyOption or FieldNamingOption without a registry.
+type tagConfigDecoderKey struct {
+	tagConfig runtime.TagConfig
+	typeptr   uintptr
+}
+
+var tagConfigDecoders sync.Map // map[tagConfigDecoderKey]Decoder
+
+// tagConfig returns how the struct tags of the decoders compiled with o are read.
+func (o *Option) tagConfig() runtime.TagConfig {
+	var config runtime.TagConfig
+	if (o.Flags & TagKeyOption) != 0 {
+		config.Key = o.TagKey
+	}
+	if (o.Flags & FieldNamingOption) != 0 {
+		config.Naming = o.FieldNaming
+	}
+	return config
+}
+
+// compileTagConfig compiles the decoder of typ with the struct tags of opt.TagKey and the field names of opt.FieldNaming,
+// and with the registry of opt if RegistryOption is set.
+// The decoders are cached per tag configuration, except those of a registry, since they change when it does.
+func compileTagConfig(typ *runtime.Type, opt *Option) (Decoder, error) {
+	tagConfig := opt.tagConfig()
+	if (opt.Flags & RegistryOption) != 0 {
+		r := opt.Registry
+		r.mu.RLock()
+		structTypeToDecoder := r.structTypeToDecoder()
+		r.mu.RUnlock()
+		return compileHead(typ, structTypeToDecoder, tagConfig)
+	}
+	key := tagConfigDecoderKey{tagConfig: tagConfig, typeptr: uintptr(unsafe.Pointer(typ))}
+	if dec, exists := tagConfigDecoders.Load(key); exists {
+		return dec.(Decoder), nil
+	}
+	dec, err := compileHead(typ, map[uintptr]Decoder{}, tagConfig)
+	if err != nil {
+		return nil, err
+	}
+	tagConfigDecoders.Store(key, dec)
+	return dec, nil
+}
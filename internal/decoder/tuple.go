@@ -0,0 +1,190 @@
+package decoder
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
+)
+
+// tupleDecoder decodes the array written for a struct field with the tuple tag option,
+// decoding the elements into the fields of the struct by position.
+type tupleDecoder struct {
+	typ        *runtime.Type
+	fields     []tupleField
+	structName string
+	fieldName  string
+}
+
+type tupleField struct {
+	dec    Decoder
+	typ    *runtime.Type
+	offset uintptr
+}
+
+// compileTuple returns the decoder of typ for the tuple tag option, or dec if typ is not a struct or a pointer to it.
+func compileTuple(dec Decoder, typ *runtime.Type, structName, fieldName string, structTypeToDecoder map[uintptr]Decoder, tagConfig runtime.TagConfig) (Decoder, error) {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elemDec, err := compileTuple(nil, typ.Elem(), structName, fieldName, structTypeToDecoder, tagConfig)
+		if err != nil || elemDec == nil {
+			return dec, err
+		}
+		return newPtrDecoder(elemDec, typ.Elem(), structName, fieldName), nil
+	case reflect.Struct:
+		d := &tupleDecoder{typ: typ, structName: structName, fieldName: fieldName}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !runtime.IsTupleElemField(field, tagConfig.Key) {
+				continue
+			}
+			fieldType := runtime.Type2RType(field.Type)
+			fieldDec, err := compile(fieldType, typ.Name(), field.Name, structTypeToDecoder, tagConfig)
+			if err != nil {
+				return nil, err
+			}
+			d.fields = append(d.fields, tupleField{dec: fieldDec, typ: fieldType, offset: field.Offset})
+		}
+		return d, nil
+	}
+	return dec, nil
+}
+
+// clearFields sets the fields from idx to the zero value, as the elements missing in the array.
+func (d *tupleDecoder) clearFields(idx int, p unsafe.Pointer) {
+	for _, f := range d.fields[idx:] {
+		typedmemclr(f.typ, unsafe.Pointer(uintptr(p)+f.offset))
+	}
+}
+
+func (d *tupleDecoder) errUnexpectedValue(c byte, offset int64) *errors.UnmarshalTypeError {
+	value := "object"
+	switch c {
+	case '"':
+		value = "string"
+	case 't', 'f':
+		value = "bool"
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		value = "number"
+	}
+	return &errors.UnmarshalTypeError{
+		Value:  value,
+		Type:   runtime.RType2Type(d.typ),
+		Struct: d.structName,
+		Field:  d.fieldName,
+		Offset: offset,
+	}
+}
+
+func (d *tupleDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	depth++
+	if depth > maxDecodeNestingDepth {
+		return errors.ErrExceededMaxDepth(s.char(), s.cursor)
+	}
+
+	switch s.skipWhiteSpace() {
+	case 'n':
+		return nullBytes(s)
+	case '[':
+	case nul:
+		return errors.ErrUnexpectedEndOfJSON("tuple", s.totalOffset())
+	default:
+		return d.errUnexpectedValue(s.char(), s.totalOffset())
+	}
+	s.cursor++
+	if s.skipWhiteSpace() == ']' {
+		d.clearFields(0, p)
+		s.cursor++
+		return nil
+	}
+	for idx := 0; ; {
+		if idx < len(d.fields) {
+			f := d.fields[idx]
+			if err := f.dec.DecodeStream(s, depth, unsafe.Pointer(uintptr(p)+f.offset)); err != nil {
+				return err
+			}
+		} else if err := s.skipValue(depth); err != nil {
+			return err
+		}
+		idx++
+	RETRY:
+		switch s.skipWhiteSpace() {
+		case ']':
+			if idx < len(d.fields) {
+				d.clearFields(idx, p)
+			}
+			s.cursor++
+			return nil
+		case ',':
+			s.cursor++
+		case nul:
+			if s.read() {
+				goto RETRY
+			}
+			return errors.ErrUnexpectedEndOfJSON("tuple", s.totalOffset())
+		default:
+			return errors.ErrExpected("comma after array element", s.totalOffset())
+		}
+	}
+}
+
+func (d *tupleDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.Buf
+	depth++
+	if depth > maxDecodeNestingDepth {
+		return 0, errors.ErrExceededMaxDepth(buf[cursor], cursor)
+	}
+
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case 'n':
+		if err := validateNull(buf, cursor); err != nil {
+			return 0, err
+		}
+		return cursor + 4, nil
+	case '[':
+	case nul:
+		return 0, errors.ErrUnexpectedEndOfJSON("tuple", cursor)
+	default:
+		return 0, d.errUnexpectedValue(buf[cursor], cursor)
+	}
+	cursor = skipWhiteSpace(buf, cursor+1)
+	if buf[cursor] == ']' {
+		d.clearFields(0, p)
+		return cursor + 1, nil
+	}
+	for idx := 0; ; {
+		var (
+			c   int64
+			err error
+		)
+		if idx < len(d.fields) {
+			f := d.fields[idx]
+			c, err = f.dec.Decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+f.offset))
+		} else {
+			c, err = ctx.skipValue(cursor, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		idx++
+		cursor = skipWhiteSpace(buf, c)
+		switch buf[cursor] {
+		case ']':
+			if idx < len(d.fields) {
+				d.clearFields(idx, p)
+			}
+			return cursor + 1, nil
+		case ',':
+			cursor++
+		default:
+			return 0, errors.ErrInvalidCharacter(buf[cursor], "tuple", cursor)
+		}
+	}
+}
+
+func (d *tupleDecoder) DecodePath(ctx *RuntimeContext, cursor, depth int64) ([][]byte, int64, error) {
+	return nil, 0, fmt.Errorf("json: tuple decoder does not support decode path")
+}
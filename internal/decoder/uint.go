@@ -15,6 +15,8 @@ type uintDecoder struct {
 	op         func(unsafe.Pointer, uint64)
 	structName string
 	fieldName  string
+	// acceptString makes the decoder accept integers quoted as JSON strings, as well as Int64StringOption does for 64-bit integers.
+	acceptString bool
 }
 
 func newUintDecoder(typ *runtime.Type, structName, fieldName string, op func(unsafe.Pointer, uint64)) *uintDecoder {
@@ -128,8 +130,69 @@ func (d *uintDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error
 	}
 }
 
-func (d *uintDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+// acceptsString reports whether the decoder accepts integers quoted as JSON strings with the flags.
+func (d *uintDecoder) acceptsString(flags OptionFlags) bool {
+	return d.acceptString || ((flags&Int64StringOption) != 0 && d.typ.Size() == 8)
+}
+
+// decodeQuotedStreamByte is like decodeStreamByte but also accepts the integer quoted as a JSON string.
+func (d *uintDecoder) decodeQuotedStreamByte(s *Stream) ([]byte, error) {
+	if s.skipWhiteSpace() != '"' {
+		return d.decodeStreamByte(s)
+	}
+	s.cursor++
+	c := s.char()
+	if c == nul && s.read() {
+		c = s.char()
+	}
+	if c != '-' && (c < '0' || '9' < c) {
+		return nil, d.typeError([]byte{c}, s.totalOffset())
+	}
 	bytes, err := d.decodeStreamByte(s)
+	if err != nil {
+		return nil, err
+	}
+	c = s.char()
+	if c == nul && s.read() {
+		c = s.char()
+	}
+	if c != '"' {
+		return nil, d.typeError(bytes, s.totalOffset())
+	}
+	s.cursor++
+	return bytes, nil
+}
+
+// decodeQuotedByte is like decodeByte but also accepts the integer quoted as a JSON string.
+func (d *uintDecoder) decodeQuotedByte(buf []byte, cursor int64) ([]byte, int64, error) {
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '"' {
+		return d.decodeByte(buf, cursor)
+	}
+	cursor++
+	if c := buf[cursor]; c != '-' && (c < '0' || '9' < c) {
+		return nil, 0, d.typeError([]byte{c}, cursor)
+	}
+	bytes, c, err := d.decodeByte(buf, cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+	if buf[c] != '"' {
+		return nil, 0, d.typeError(bytes, c)
+	}
+	return bytes, c + 1, nil
+}
+
+func (d *uintDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) error {
+	var (
+		bytes []byte
+		err   error
+	)
+	if d.acceptsString(s.Option.Flags) {
+		bytes, err = d.decodeQuotedStreamByte(s)
+	} else {
+		bytes, err = d.decodeStreamByte(s)
+	}
 	if err != nil {
 		return err
 	}
@@ -159,7 +222,16 @@ func (d *uintDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Pointer) err
 }
 
 func (d *uintDecoder) Decode(ctx *RuntimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
-	bytes, c, err := d.decodeByte(ctx.Buf, cursor)
+	var (
+		bytes []byte
+		c     int64
+		err   error
+	)
+	if d.acceptsString(ctx.Option.Flags) {
+		bytes, c, err = d.decodeQuotedByte(ctx.Buf, cursor)
+	} else {
+		bytes, c, err = d.decodeByte(ctx.Buf, cursor)
+	}
 	if err != nil {
 		return 0, err
 	}
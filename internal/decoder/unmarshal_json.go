@@ -24,6 +24,28 @@ func newUnmarshalJSONDecoder(typ *runtime.Type, structName, fieldName string) *u
 	}
 }
 
+// disallowUnknownFieldsKey is the key of the context given to UnmarshalerContext by a decode disallowing the unknown fields,
+// so that the values decoded with this context by the implementation disallow them too.
+type disallowUnknownFieldsKey struct{}
+
+// unmarshalerContextOf returns the context given to UnmarshalerContext with opt.
+func unmarshalerContextOf(opt *Option, disallowUnknown bool) context.Context {
+	ctx := context.Background()
+	if (opt.Flags & ContextOption) != 0 {
+		ctx = opt.Context
+	}
+	if disallowUnknown {
+		ctx = context.WithValue(ctx, disallowUnknownFieldsKey{}, true)
+	}
+	return ctx
+}
+
+// DisallowsUnknownFields reports whether ctx is given to UnmarshalerContext by a decode disallowing the unknown fields.
+func DisallowsUnknownFields(ctx context.Context) bool {
+	disallow, _ := ctx.Value(disallowUnknownFieldsKey{}).(bool)
+	return disallow
+}
+
 func (d *unmarshalJSONDecoder) annotateError(cursor int64, err error) {
 	switch e := err.(type) {
 	case *errors.UnmarshalTypeError:
@@ -50,13 +72,8 @@ func (d *unmarshalJSONDecoder) DecodeStream(s *Stream, depth int64, p unsafe.Poi
 	}))
 	switch v := v.(type) {
 	case unmarshalerContext:
-		var ctx context.Context
-		if (s.Option.Flags & ContextOption) != 0 {
-			ctx = s.Option.Context
-		} else {
-			ctx = context.Background()
-		}
-		if err := v.UnmarshalJSON(ctx, dst); err != nil {
+		disallowUnknown := s.DisallowUnknownFields || (s.Option.Flags&DisallowUnknownOption) != 0
+		if err := v.UnmarshalJSON(unmarshalerContextOf(s.Option, disallowUnknown), dst); err != nil {
 			d.annotateError(s.cursor, err)
 			return err
 		}
@@ -73,7 +90,7 @@ func (d *unmarshalJSONDecoder) Decode(ctx *RuntimeContext, cursor, depth int64,
 	buf := ctx.Buf
 	cursor = skipWhiteSpace(buf, cursor)
 	start := cursor
-	end, err := skipValue(buf, cursor, depth)
+	end, err := ctx.skipValue(cursor, depth)
 	if err != nil {
 		return 0, err
 	}
@@ -85,13 +102,15 @@ func (d *unmarshalJSONDecoder) Decode(ctx *RuntimeContext, cursor, depth int64,
 		typ: d.typ,
 		ptr: p,
 	}))
-	if (ctx.Option.Flags & ContextOption) != 0 {
-		if err := v.(unmarshalerContext).UnmarshalJSON(ctx.Option.Context, dst); err != nil {
+	switch v := v.(type) {
+	case unmarshalerContext:
+		disallowUnknown := (ctx.Option.Flags & DisallowUnknownOption) != 0
+		if err := v.UnmarshalJSON(unmarshalerContextOf(ctx.Option, disallowUnknown), dst); err != nil {
 			d.annotateError(cursor, err)
 			return 0, err
 		}
-	} else {
-		if err := v.(json.Unmarshaler).UnmarshalJSON(dst); err != nil {
+	case json.Unmarshaler:
+		if err := v.UnmarshalJSON(dst); err != nil {
 			d.annotateError(cursor, err)
 			return 0, err
 		}
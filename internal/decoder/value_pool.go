@@ -0,0 +1,65 @@
+package decoder
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// valuePoolUsed is set by the first call to ReleaseValue,
+	// so that the decodes of the programs that never release values don't look up the pools.
+	valuePoolUsed  uint32
+	mapValuePool   sync.Pool
+	sliceValuePool sync.Pool
+)
+
+// takeMapValue returns an empty map released by ReleaseValue, or nil if there is none.
+func takeMapValue() map[string]interface{} {
+	if atomic.LoadUint32(&valuePoolUsed) == 0 {
+		return nil
+	}
+	if v := mapValuePool.Get(); v != nil {
+		return v.(map[string]interface{})
+	}
+	return nil
+}
+
+// takeSliceValue returns an empty slice with the backing array of a slice released by ReleaseValue, or nil if there is none.
+func takeSliceValue() []interface{} {
+	if atomic.LoadUint32(&valuePoolUsed) == 0 {
+		return nil
+	}
+	if v := sliceValuePool.Get(); v != nil {
+		return *(v.(*[]interface{}))
+	}
+	return nil
+}
+
+// ReleaseValue puts the maps and slices of v, decoded into interface{}, to the pools that the decoders take them from.
+// The maps are cleared and the elements of the slices are zeroed before they are pooled.
+// The slices are pooled with their capacity, so they must not share the backing array with the slices in use.
+func ReleaseValue(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return
+		}
+		for k, elem := range v {
+			ReleaseValue(elem)
+			delete(v, k)
+		}
+		atomic.StoreUint32(&valuePoolUsed, 1)
+		mapValuePool.Put(v)
+	case []interface{}:
+		if cap(v) == 0 {
+			return
+		}
+		for i, elem := range v {
+			ReleaseValue(elem)
+			v[i] = nil
+		}
+		v = v[:0]
+		atomic.StoreUint32(&valuePoolUsed, 1)
+		sliceValuePool.Put(&v)
+	}
+}
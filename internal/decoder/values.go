@@ -0,0 +1,329 @@
+package decoder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// valuesNode is a dotted key of URL query parameters or form values, such as a in a.b=1.
+type valuesNode struct {
+	values   []string
+	children map[string]*valuesNode
+}
+
+func (n *valuesNode) child(key string) *valuesNode {
+	if n.children == nil {
+		n.children = map[string]*valuesNode{}
+	}
+	c, exists := n.children[key]
+	if !exists {
+		c = &valuesNode{}
+		n.children[key] = c
+	}
+	return c
+}
+
+// ValuesToJSON returns the JSON document of the URL query parameters or form values, to be decoded into typ with opt.
+// The dotted keys are nested objects, or arrays by their indexes, the repeated keys are arrays,
+// and the values are strings, or numbers and booleans for the fields of these types.
+func ValuesToJSON(values map[string][]string, typ reflect.Type, opt *Option) ([]byte, error) {
+	root := &valuesNode{}
+	for key, vals := range values {
+		node := root
+		for _, name := range strings.Split(key, ".") {
+			node = node.child(name)
+		}
+		node.values = append(node.values, vals...)
+	}
+	c := valuesConverter{tagConfig: opt.tagConfig()}
+	return c.appendNode(nil, root, typ, "", false)
+}
+
+type valuesConverter struct {
+	tagConfig runtime.TagConfig
+}
+
+// appendNode appends the JSON value of node to be decoded into typ, or into any value if typ is nil.
+// path is the dotted key of node, and quoted reports whether the value is quoted for the string option of its field.
+func (c *valuesConverter) appendNode(b []byte, node *valuesNode, typ reflect.Type, path string, quoted bool) ([]byte, error) {
+	typ = valuesContentType(typ)
+	if len(node.children) == 0 {
+		return c.appendLeaf(b, node.values, typ, quoted), nil
+	}
+	if len(node.values) > 0 {
+		return nil, fmt.Errorf("json: parameter %s has both a value and nested parameters", path)
+	}
+	keys := make([]string, 0, len(node.children))
+	for key := range node.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	kind := reflect.Interface
+	if typ != nil && !isValuesUnmarshaler(typ) {
+		kind = typ.Kind()
+	}
+	switch kind {
+	case reflect.Slice, reflect.Array:
+		return c.appendIndexes(b, node, typ, path, keys)
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		b = append(b, '{')
+		for i, key := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendValuesString(b, key)
+			b = append(b, ':')
+			var (
+				elem       reflect.Type
+				elemQuoted bool
+			)
+			switch kind {
+			case reflect.Struct:
+				if tag := c.field(typ, key, 0); tag != nil {
+					elem, elemQuoted = tag.Field.Type, tag.IsString
+				}
+			case reflect.Map:
+				elem = typ.Elem()
+			}
+			var err error
+			if b, err = c.appendNode(b, node.children[key], elem, joinValuesPath(path, key), elemQuoted); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, fmt.Errorf("json: parameter %s cannot have nested parameters for a value of type %s", path, typ)
+}
+
+// appendIndexes appends the array of the children of node, whose keys are the indexes of the elements.
+func (c *valuesConverter) appendIndexes(b []byte, node *valuesNode, typ reflect.Type, path string, keys []string) ([]byte, error) {
+	elems := make([]*valuesNode, 0, len(keys))
+	for _, key := range keys {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(keys) {
+			return nil, fmt.Errorf("json: parameter %s has an invalid index %q, expected from 0 to %d", path, key, len(keys)-1)
+		}
+		for len(elems) <= i {
+			elems = append(elems, nil)
+		}
+		elems[i] = node.children[key]
+	}
+	b = append(b, '[')
+	for i, elem := range elems {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if elem == nil {
+			b = append(b, "null"...)
+			continue
+		}
+		var err error
+		if b, err = c.appendNode(b, elem, typ.Elem(), joinValuesPath(path, strconv.Itoa(i)), false); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, ']'), nil
+}
+
+// appendLeaf appends the values of a key without nested parameters: an array for a slice, or for any value
+// if there are more than one, or else the first value.
+func (c *valuesConverter) appendLeaf(b []byte, values []string, typ reflect.Type, quoted bool) []byte {
+	if typ != nil && !isValuesUnmarshaler(typ) && typ.Kind() != reflect.String {
+		switch typ.Kind() {
+		case reflect.Slice, reflect.Array:
+			if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+				// a []byte is a base64 string.
+				break
+			}
+			elem := valuesContentType(typ.Elem())
+			b = append(b, '[')
+			n := 0
+			for _, value := range values {
+				if value == "" && isValuesEmptyNull(elem) {
+					// an empty value, as in ids=, is no element.
+					continue
+				}
+				if n > 0 {
+					b = append(b, ',')
+				}
+				b = appendValuesScalar(b, value, elem)
+				n++
+			}
+			return append(b, ']')
+		}
+	}
+	if len(values) == 0 {
+		return append(b, "null"...)
+	}
+	if len(values) > 1 && (typ == nil || typ.Kind() == reflect.Interface) {
+		b = append(b, '[')
+		for i, value := range values {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendValuesString(b, value)
+		}
+		return append(b, ']')
+	}
+	if quoted {
+		// the string option of the field expects the JSON encoding of the value in a string.
+		return appendValuesString(b, string(appendValuesScalar(nil, values[0], typ)))
+	}
+	return appendValuesScalar(b, values[0], typ)
+}
+
+// appendValuesScalar appends value as a number or a boolean for typ of these kinds, if it is one, or as a string.
+// An empty value for a number or a boolean is null, which leaves it unchanged.
+func appendValuesScalar(b []byte, value string, typ reflect.Type) []byte {
+	if typ == nil || isValuesUnmarshaler(typ) {
+		return appendValuesString(b, value)
+	}
+	if value == "" && isValuesEmptyNull(typ) {
+		return append(b, "null"...)
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		if value == "on" {
+			// the value of a checked checkbox.
+			return append(b, "true"...)
+		}
+		if v, err := strconv.ParseBool(value); err == nil {
+			return strconv.AppendBool(b, v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if isValuesNumber(value) {
+			return append(b, value...)
+		}
+	}
+	// the decoder reports a value that is not of the type.
+	return appendValuesString(b, value)
+}
+
+// isValuesEmptyNull reports whether an empty value is null for typ, which is a number or a boolean.
+func isValuesEmptyNull(typ reflect.Type) bool {
+	if typ == nil || isValuesUnmarshaler(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// field returns the tag of the field of the struct typ for key, matched as the decoder does, or nil if there is none.
+func (c *valuesConverter) field(typ reflect.Type, key string, depth int) *runtime.StructTag {
+	var folded *runtime.StructTag
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if runtime.IsIgnoredStructField(field, c.tagConfig.Key) {
+			continue
+		}
+		tag := runtime.StructTagFromField(field, c.tagConfig)
+		if tag.IsFlattened() {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded != typ && depth < maxDecodeNestingDepth {
+				if t := c.field(embedded, key, depth+1); t != nil && (t.Key == key || folded == nil) {
+					if t.Key == key {
+						return t
+					}
+					folded = t
+				}
+			}
+			continue
+		}
+		if tag.Key == key {
+			return tag
+		}
+		if folded == nil && strings.EqualFold(tag.Key, key) {
+			folded = tag
+		}
+	}
+	return folded
+}
+
+// valuesContentType returns the type pointed to by typ, which is decoded from the same value.
+func valuesContentType(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Ptr && !isValuesUnmarshaler(typ) {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// isValuesUnmarshaler reports whether typ decodes itself, from a string as a value of a parameter is.
+func isValuesUnmarshaler(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(unmarshalJSONType) || ptr.Implements(unmarshalJSONContextType) || ptr.Implements(unmarshalTextType)
+}
+
+// isValuesNumber reports whether s is a number in the syntax of JSON.
+func isValuesNumber(s string) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if i == len(s) || s[i] == '0' || digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+func joinValuesPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func appendValuesString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,123 @@
+package encoder
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// Audit reports the struct fields of v omitted from its encoding with the options of ctx to the collector of AuditOption,
+// in the order they are encoded. It searches v after it has been encoded, so that it costs nothing otherwise.
+func Audit(ctx *RuntimeContext, v interface{}) {
+	a := &auditor{option: ctx.Option.Flag, tagConfig: ctx.Option.tagConfig(), collect: ctx.Option.Audit}
+	a.value(reflect.ValueOf(v))
+}
+
+type auditor struct {
+	option    OptionFlag
+	tagConfig runtime.TagConfig
+	collect   func(runtime.AuditEvent)
+	// path is the keys of the objects and the indexes of the arrays from the root to the value.
+	path []string
+}
+
+func (a *auditor) value(v reflect.Value) {
+	if !v.IsValid() {
+		return
+	}
+	if v.Kind() == reflect.Interface {
+		a.value(v.Elem())
+		return
+	}
+	if isMarshalerType(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			a.value(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			a.path = append(a.path, strconv.Itoa(i))
+			a.value(v.Index(i))
+			a.path = a.path[:len(a.path)-1]
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = mapKeyName(key)
+		}
+		sort.Sort(mapKeysByName{keys: keys, names: names})
+		for i, key := range keys {
+			a.path = append(a.path, names[i])
+			a.value(v.MapIndex(key))
+			a.path = a.path[:len(a.path)-1]
+		}
+	case reflect.Struct:
+		a.structFields(v)
+	}
+}
+
+func (a *auditor) structFields(v reflect.Value) {
+	if !v.CanAddr() {
+		if !v.CanInterface() {
+			// a value read through an unexported embedded field cannot be copied.
+			return
+		}
+		// the emptiness of the fields is checked through their address, as the encoder does.
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if runtime.IsIgnoredStructField(field, a.tagConfig.Key) {
+			continue
+		}
+		tag := runtime.StructTagFromField(field, a.tagConfig)
+		if isEmbeddedField(field, tag) {
+			a.value(v.Field(i))
+			continue
+		}
+		a.path = append(a.path, tag.Key)
+		if a.isOmitted(tag, v.Field(i)) {
+			a.collect(runtime.AuditEvent{Kind: runtime.AuditOmitted, Path: runtime.JSONPointer(a.path)})
+		} else {
+			a.value(v.Field(i))
+		}
+		a.path = a.path[:len(a.path)-1]
+	}
+}
+
+// isOmitted reports whether the field of tag, whose value is v, is omitted by the encoder.
+func (a *auditor) isOmitted(tag *runtime.StructTag, v reflect.Value) bool {
+	if (a.option & ForceIncludeEmptyOption) != 0 {
+		return false
+	}
+	var omitEmpty bool
+	switch {
+	case (a.option&ForceOmitEmptyOption) != 0 || tag.IsOmitEmpty:
+		omitEmpty = true
+	case tag.Field.Type.Kind() == reflect.Ptr:
+		omitEmpty = tag.IsOmitNil || (a.option&OmitNilPointerOption) != 0
+	}
+	typ := runtime.Type2RType(v.Type())
+	p := unsafe.Pointer(v.UnsafeAddr())
+	if tag.IsOmitZero && isZeroValue(typ, p) {
+		return true
+	}
+	if !omitEmpty {
+		return false
+	}
+	m, _ := zeroCheckers.Load().(map[*runtime.Type]ZeroChecker)
+	if checker, exists := m[typ]; exists {
+		return checker(p)
+	}
+	return isEmptyValue(v)
+}
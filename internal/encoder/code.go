@@ -80,6 +80,9 @@ func (c *IntCode) ToOpcode(ctx *compileContext) Opcodes {
 		code = newOpCode(ctx, c.typ, OpInt)
 	}
 	code.NumBitSize = c.bitSize
+	if c.bitSize == 64 && !c.isString && (ctx.option&Int64AsStringOption) != 0 {
+		code.Flags |= QuotedIntFlags
+	}
 	ctx.incIndex()
 	return Opcodes{code}
 }
@@ -110,6 +113,9 @@ func (c *UintCode) ToOpcode(ctx *compileContext) Opcodes {
 		code = newOpCode(ctx, c.typ, OpUint)
 	}
 	code.NumBitSize = c.bitSize
+	if c.bitSize == 64 && !c.isString && (ctx.option&Int64AsStringOption) != 0 {
+		code.Flags |= QuotedIntFlags
+	}
 	ctx.incIndex()
 	return Opcodes{code}
 }
@@ -631,32 +637,111 @@ func (c *StructFieldCode) getAnonymousStruct() *StructCode {
 	return c.getStruct()
 }
 
-func optimizeStructHeader(code *Opcode, tag *runtime.StructTag) OpType {
+func isOmitEmpty(ctx *compileContext, tag *runtime.StructTag) bool {
+	switch {
+	case (ctx.option & ForceIncludeEmptyOption) != 0:
+		return false
+	case (ctx.option & ForceOmitEmptyOption) != 0:
+		return true
+	case tag.IsOmitEmpty:
+		return true
+	}
+	// omitempty of a pointer field omits only nil, so it is reused for omitnil.
+	if tag.Field.Type.Kind() != reflect.Ptr || tag.IsFlattened() {
+		return false
+	}
+	return tag.IsOmitNil || (ctx.option&OmitNilPointerOption) != 0
+}
+
+func optimizeStructHeader(ctx *compileContext, code *Opcode, tag *runtime.StructTag) OpType {
 	headType := code.ToHeaderType(tag.IsString)
-	if tag.IsOmitEmpty {
+	if isOmitEmpty(ctx, tag) {
 		headType = headType.HeadToOmitEmptyHead()
 	}
 	return headType
 }
 
-func optimizeStructField(code *Opcode, tag *runtime.StructTag) OpType {
+func optimizeStructField(ctx *compileContext, code *Opcode, tag *runtime.StructTag) OpType {
 	fieldType := code.ToFieldType(tag.IsString)
-	if tag.IsOmitEmpty {
+	if isOmitEmpty(ctx, tag) {
 		fieldType = fieldType.FieldToOmitEmptyField()
 	}
 	return fieldType
 }
 
+// quotedIntFlags returns the flags that make the integer of the field quoted by Int64AsStringOption or the bigstring tag option.
+// The integers of the string tag option are quoted by their opcodes instead.
+func quotedIntFlags(value *Opcode, tag *runtime.StructTag) OpFlags {
+	if tag.IsString {
+		return 0
+	}
+	switch value.Op {
+	case OpInt, OpIntPtr, OpUint, OpUintPtr:
+		flags := value.Flags & QuotedIntFlags
+		if tag.IsBigString {
+			flags |= QuotedBigIntFlags
+		}
+		return flags
+	}
+	return 0
+}
+
+// zeroCheckerFlags returns the flags of a field omitted by the checker registered by RegisterZeroChecker,
+// or by the omitzero tag option, or 0 if the opcode of the field checks its emptiness itself.
+// Such a field uses the generic omitempty opcode, which calls IsZeroByChecker.
+func (c *StructFieldCode) zeroCheckerFlags(ctx *compileContext) OpFlags {
+	if c.isAnonymous {
+		return 0
+	}
+	omitEmpty := isOmitEmpty(ctx, c.tag)
+	if c.tag.IsOmitZero && (ctx.option&ForceIncludeEmptyOption) == 0 && !(omitEmpty && isZeroImpliedByEmpty(c.typ)) {
+		flags := ZeroCheckerFlags | OmitZeroFlags
+		if omitEmpty {
+			flags |= OmitEmptyFlags
+		}
+		return flags
+	}
+	if omitEmpty && hasZeroChecker(c.typ) {
+		return ZeroCheckerFlags
+	}
+	return 0
+}
+
+// derefZeroCheckedMap makes the map opcode value of a field with the generic omitempty opcode of zeroCheckerFlags
+// load the map from the address of the field, which the generic opcode stores instead of the map itself
+// as the map-specific opcodes do.
+func derefZeroCheckedMap(value *Opcode) {
+	switch value.Op {
+	case OpMap, OpMapPtr:
+		// the map opcode of a pointer field is OpMap with the number of pointers, as converted for the map-specific opcodes.
+		value.Op = OpMapPtr
+		value.PtrNum++
+	}
+}
+
 func (c *StructFieldCode) headerOpcodes(ctx *compileContext, field *Opcode, valueCodes Opcodes) Opcodes {
 	value := valueCodes.First()
-	op := optimizeStructHeader(value, c.tag)
+	op := optimizeStructHeader(ctx, value, c.tag)
+	zeroChecked := false
+	if flags := c.zeroCheckerFlags(ctx); flags != 0 {
+		op = OpStructHeadOmitEmpty
+		field.Flags |= flags
+		zeroChecked = true
+	}
 	field.Op = op
 	if value.Flags&MarshalerContextFlags != 0 {
 		field.Flags |= MarshalerContextFlags
 	}
+	field.Flags |= quotedIntFlags(value, c.tag)
 	field.NumBitSize = value.NumBitSize
 	field.PtrNum = value.PtrNum
 	field.FieldQuery = value.FieldQuery
+	if zeroChecked {
+		derefZeroCheckedMap(value)
+	}
+	if value.Flags&CodecFlags != 0 {
+		field.Size = value.Size
+	}
 	fieldCodes := Opcodes{field}
 	if op.IsMultipleOpHead() {
 		field.Next = value
@@ -669,14 +754,27 @@ func (c *StructFieldCode) headerOpcodes(ctx *compileContext, field *Opcode, valu
 
 func (c *StructFieldCode) fieldOpcodes(ctx *compileContext, field *Opcode, valueCodes Opcodes) Opcodes {
 	value := valueCodes.First()
-	op := optimizeStructField(value, c.tag)
+	op := optimizeStructField(ctx, value, c.tag)
+	zeroChecked := false
+	if flags := c.zeroCheckerFlags(ctx); flags != 0 {
+		op = OpStructFieldOmitEmpty
+		field.Flags |= flags
+		zeroChecked = true
+	}
 	field.Op = op
 	if value.Flags&MarshalerContextFlags != 0 {
 		field.Flags |= MarshalerContextFlags
 	}
+	field.Flags |= quotedIntFlags(value, c.tag)
 	field.NumBitSize = value.NumBitSize
 	field.PtrNum = value.PtrNum
 	field.FieldQuery = value.FieldQuery
+	if zeroChecked {
+		derefZeroCheckedMap(value)
+	}
+	if value.Flags&CodecFlags != 0 {
+		field.Size = value.Size
+	}
 
 	fieldCodes := Opcodes{field}
 	if op.IsMultipleOpField() {
@@ -741,6 +839,18 @@ func (c *StructFieldCode) flags() OpFlags {
 	if c.isMarshalerContext {
 		flags |= MarshalerContextFlags
 	}
+	if c.tag != nil && c.tag.IsSealed {
+		flags |= SealedFlags
+	}
+	if c.tag != nil && c.tag.IsTuple && isTupleType(c.typ) {
+		flags |= TupleFlags
+	}
+	if isNumberSliceCode(c.value) {
+		flags |= NumberSliceFlags
+	}
+	if c.tag != nil && c.tag.Codec != "" {
+		flags |= CodecFlags
+	}
 	return flags
 }
 
@@ -776,7 +886,7 @@ func (c *StructFieldCode) ToOpcode(ctx *compileContext, isFirstField, isEndField
 	}
 	codes := c.fieldOpcodes(ctx, field, valueCodes)
 	if isEndField {
-		if isEnableStructEndOptimization(c.value) {
+		if isEnableStructEndOptimization(c.value) && (field.Flags&ZeroCheckerFlags) == 0 {
 			field.Op = field.Op.FieldToEnd()
 		} else {
 			codes = c.addStructEndCode(ctx, codes)
@@ -860,6 +970,12 @@ type MarshalJSONCode struct {
 	isAddrForMarshaler bool
 	isNilableType      bool
 	isMarshalerContext bool
+	isMapKey           bool
+	isSealed           bool
+	isTuple            bool
+	isNumberSlice      bool
+	// codec is the id of the codec of the field with the codec tag option, or zero.
+	codec uint32
 }
 
 func (c *MarshalJSONCode) Kind() CodeKind {
@@ -880,6 +996,22 @@ func (c *MarshalJSONCode) ToOpcode(ctx *compileContext) Opcodes {
 	} else {
 		code.Flags &= ^IsNilableTypeFlags
 	}
+	if c.isMapKey {
+		code.Flags |= MapKeyFlags
+	}
+	if c.isSealed {
+		code.Flags |= SealedFlags
+	}
+	if c.isTuple {
+		code.Flags |= TupleFlags
+	}
+	if c.isNumberSlice {
+		code.Flags |= NumberSliceFlags
+	}
+	if c.codec != 0 {
+		code.Flags |= CodecFlags
+		code.Size = c.codec
+	}
 	ctx.incIndex()
 	return Opcodes{code}
 }
@@ -891,6 +1023,11 @@ func (c *MarshalJSONCode) Filter(query *FieldQuery) Code {
 		isAddrForMarshaler: c.isAddrForMarshaler,
 		isNilableType:      c.isNilableType,
 		isMarshalerContext: c.isMarshalerContext,
+		isMapKey:           c.isMapKey,
+		isSealed:           c.isSealed,
+		isTuple:            c.isTuple,
+		isNumberSlice:      c.isNumberSlice,
+		codec:              c.codec,
 	}
 }
 
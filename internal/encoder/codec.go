@@ -0,0 +1,57 @@
+package encoder
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// CodecFunc returns the JSON encoding of v, the value of a struct field with the codec tag option.
+type CodecFunc func(v interface{}) ([]byte, error)
+
+type codec struct {
+	typ *runtime.Type
+	fn  CodecFunc
+}
+
+var (
+	codecMu sync.RWMutex
+	// codecIDs are the ids of the codecs by name. The id of a codec is its index in codecs plus one,
+	// and is stored in Opcode.Size of the MarshalJSON opcodes with CodecFlags.
+	codecIDs = map[string]uint32{}
+	codecs   []codec
+)
+
+// RegisterCodec registers fn as the codec name of the struct fields of typ with the codec=name tag option.
+// An existing codec of the same name is replaced, including in the opcodes compiled with it.
+func RegisterCodec(name string, typ *runtime.Type, fn CodecFunc) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	if id, exists := codecIDs[name]; exists {
+		codecs[id-1] = codec{typ: typ, fn: fn}
+		return
+	}
+	codecs = append(codecs, codec{typ: typ, fn: fn})
+	codecIDs[name] = uint32(len(codecs))
+}
+
+// codecID returns the id of the codec name for a field of typ.
+func codecID(name string, typ *runtime.Type) (uint32, error) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	id, exists := codecIDs[name]
+	if !exists {
+		return 0, fmt.Errorf("json: unknown codec %q ( see RegisterCodec )", name)
+	}
+	if c := codecs[id-1]; c.typ != typ {
+		return 0, fmt.Errorf("json: codec %q encodes %s, not %s", name, c.typ, typ)
+	}
+	return id, nil
+}
+
+func codecByID(id uint32) CodecFunc {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	return codecs[id-1].fn
+}
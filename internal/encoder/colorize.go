@@ -0,0 +1,80 @@
+package encoder
+
+// AppendColorized appends src to dst with each scalar token wrapped by the format of scheme.
+// src must be valid JSON produced by the encoder, so the kind of each token is decided from its first byte.
+// Numbers are treated as Float if they contain a fraction or an exponent, otherwise as Int.
+// Unlike the color VMs, it colors the keys of maps as ObjectKey and the []byte values as String.
+// It is used instead of the color VMs when they are compiled out by the json_nocolor or json_noindentvm build tag.
+func AppendColorized(dst, src []byte, scheme *ColorScheme) []byte {
+	for cursor := 0; cursor < len(src); {
+		switch c := src[cursor]; c {
+		case '"':
+			end := colorizeStringEnd(src, cursor)
+			format := scheme.String
+			if colorizeIsObjectKey(src, end) {
+				format = scheme.ObjectKey
+			}
+			dst = appendColorFormat(dst, format, src[cursor:end])
+			cursor = end
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			end := cursor + 1
+			format := scheme.Int
+		NUMBER:
+			for ; end < len(src); end++ {
+				switch src[end] {
+				case '.', 'e', 'E':
+					format = scheme.Float
+				case '+', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				default:
+					break NUMBER
+				}
+			}
+			dst = appendColorFormat(dst, format, src[cursor:end])
+			cursor = end
+		case 't':
+			dst = appendColorFormat(dst, scheme.Bool, src[cursor:cursor+4])
+			cursor += 4
+		case 'f':
+			dst = appendColorFormat(dst, scheme.Bool, src[cursor:cursor+5])
+			cursor += 5
+		case 'n':
+			dst = appendColorFormat(dst, scheme.Null, src[cursor:cursor+4])
+			cursor += 4
+		default:
+			dst = append(dst, c)
+			cursor++
+		}
+	}
+	return dst
+}
+
+func appendColorFormat(dst []byte, format ColorFormat, v []byte) []byte {
+	dst = append(dst, format.Header...)
+	dst = append(dst, v...)
+	return append(dst, format.Footer...)
+}
+
+func colorizeStringEnd(src []byte, cursor int) int {
+	for cursor++; cursor < len(src); cursor++ {
+		switch src[cursor] {
+		case '\\':
+			cursor++
+		case '"':
+			return cursor + 1
+		}
+	}
+	return len(src)
+}
+
+func colorizeIsObjectKey(src []byte, cursor int) bool {
+	for ; cursor < len(src); cursor++ {
+		switch src[cursor] {
+		case ' ', '\t', '\n', '\r':
+		case ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
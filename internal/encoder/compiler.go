@@ -22,8 +22,8 @@ var (
 	marshalJSONContextType = reflect.TypeOf((*marshalerContext)(nil)).Elem()
 	marshalTextType        = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
 	jsonNumberType         = reflect.TypeOf(json.Number(""))
-	cachedOpcodeSets       []*OpcodeSet
-	cachedOpcodeMap        unsafe.Pointer // map[uintptr]*OpcodeSet
+	cachedOpcodeSets       []unsafe.Pointer // []*OpcodeSet
+	cachedOpcodeMap        [opcodeMapShardNum]opcodeMapShard
 	typeAddr               *runtime.TypeAddr
 	initEncoderOnce        sync.Once
 )
@@ -34,16 +34,39 @@ func initEncoder() {
 		if typeAddr == nil {
 			typeAddr = &runtime.TypeAddr{}
 		}
-		cachedOpcodeSets = make([]*OpcodeSet, typeAddr.AddrRange>>typeAddr.AddrShift+1)
+		cachedOpcodeSets = make([]unsafe.Pointer, typeAddr.AddrRange>>typeAddr.AddrShift+1)
 	})
 }
 
-func loadOpcodeMap() map[uintptr]*OpcodeSet {
-	p := atomic.LoadPointer(&cachedOpcodeMap)
-	return *(*map[uintptr]*OpcodeSet)(unsafe.Pointer(&p))
+// opcodeMapShardNum is the number of shards of the cache for types out of the range of cachedOpcodeSets.
+// Readers never lock, and writers only lock the shard of the type being stored,
+// so concurrent first use of many distinct types doesn't serialize on one lock.
+const opcodeMapShardNum = 64
+
+type opcodeMapShard struct {
+	mu sync.Mutex
+	m  unsafe.Pointer // map[uintptr]*OpcodeSet, replaced with copy-on-write
+}
+
+func opcodeMapShardOf(typ uintptr) *opcodeMapShard {
+	return &cachedOpcodeMap[((typ>>4)^(typ>>12))%opcodeMapShardNum]
 }
 
-func storeOpcodeSet(typ uintptr, set *OpcodeSet, m map[uintptr]*OpcodeSet) {
+func (s *opcodeMapShard) load(typ uintptr) *OpcodeSet {
+	p := atomic.LoadPointer(&s.m)
+	return (*(*map[uintptr]*OpcodeSet)(unsafe.Pointer(&p)))[typ]
+}
+
+func (s *opcodeMapShard) store(typ uintptr, set *OpcodeSet) *OpcodeSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := atomic.LoadPointer(&s.m)
+	m := *(*map[uintptr]*OpcodeSet)(unsafe.Pointer(&p))
+	if stored, exists := m[typ]; exists {
+		// another goroutine compiled the same type first.
+		return stored
+	}
 	newOpcodeMap := make(map[uintptr]*OpcodeSet, len(m)+1)
 	newOpcodeMap[typ] = set
 
@@ -51,20 +74,147 @@ func storeOpcodeSet(typ uintptr, set *OpcodeSet, m map[uintptr]*OpcodeSet) {
 		newOpcodeMap[k] = v
 	}
 
-	atomic.StorePointer(&cachedOpcodeMap, *(*unsafe.Pointer)(unsafe.Pointer(&newOpcodeMap)))
+	atomic.StorePointer(&s.m, *(*unsafe.Pointer)(unsafe.Pointer(&newOpcodeMap)))
+	return set
+}
+
+func CompileToGetCodeSet(ctx *RuntimeContext, typeptr uintptr) (*OpcodeSet, error) {
+	initEncoder()
+	if typeptr > typeAddr.MaxTypeAddr || typeptr < typeAddr.BaseTypeAddr {
+		codeSet, err := compileToGetCodeSetSlowPath(typeptr)
+		if err != nil {
+			return nil, err
+		}
+		return getCodeSetForOption(ctx, codeSet)
+	}
+	index := (typeptr - typeAddr.BaseTypeAddr) >> typeAddr.AddrShift
+	if codeSet := (*OpcodeSet)(atomic.LoadPointer(&cachedOpcodeSets[index])); codeSet != nil {
+		return getCodeSetForOption(ctx, codeSet)
+	}
+	codeSet, err := newCompiler().compile(typeptr)
+	if err != nil {
+		return nil, err
+	}
+	atomic.StorePointer(&cachedOpcodeSets[index], unsafe.Pointer(codeSet))
+	return getCodeSetForOption(ctx, codeSet)
 }
 
 func compileToGetCodeSetSlowPath(typeptr uintptr) (*OpcodeSet, error) {
-	opcodeMap := loadOpcodeMap()
-	if codeSet, exists := opcodeMap[typeptr]; exists {
+	shard := opcodeMapShardOf(typeptr)
+	if codeSet := shard.load(typeptr); codeSet != nil {
 		return codeSet, nil
 	}
 	codeSet, err := newCompiler().compile(typeptr)
 	if err != nil {
 		return nil, err
 	}
-	storeOpcodeSet(typeptr, codeSet, opcodeMap)
-	return codeSet, nil
+	return shard.store(typeptr, codeSet), nil
+}
+
+func getCodeSetForOption(ctx *RuntimeContext, codeSet *OpcodeSet) (*OpcodeSet, error) {
+	codeSet, err := getTagConfigCodeSetIfNeeded(ctx, codeSet)
+	if err != nil {
+		return nil, err
+	}
+	codeSet, err = getNoMethodsCodeSetIfNeeded(ctx, codeSet)
+	if err != nil {
+		return nil, err
+	}
+	codeSet, err = getFilteredCodeSetIfNeeded(ctx, codeSet)
+	if err != nil {
+		return nil, err
+	}
+	return getVariantCodeSetIfNeeded(ctx, codeSet)
+}
+
+// getTagConfigCodeSetIfNeeded returns the OpcodeSet of codeSet.Type compiled with the struct tags of TagKeyOption
+// and the field names of FieldNamingOption.
+// It is cached per configuration in codeSet, and the other options are applied on top of it.
+func getTagConfigCodeSetIfNeeded(ctx *RuntimeContext, codeSet *OpcodeSet) (*OpcodeSet, error) {
+	tagConfig := ctx.Option.tagConfig()
+	if tagConfig == (runtime.TagConfig{}) {
+		return codeSet, nil
+	}
+	if cacheCodeSet := codeSet.getTagConfigCache(tagConfig); cacheCodeSet != nil {
+		return cacheCodeSet, nil
+	}
+	compiler := newCompiler()
+	compiler.tagConfig = tagConfig
+	code, err := compiler.typeToCode(codeSet.Type)
+	if err != nil {
+		return nil, err
+	}
+	tagConfigCodeSet, err := compiler.codeToOpcodeSet(codeSet.Type, code, 0)
+	if err != nil {
+		return nil, err
+	}
+	codeSet.setTagConfigCache(tagConfig, tagConfigCodeSet)
+	return tagConfigCodeSet, nil
+}
+
+// getNoMethodsCodeSetIfNeeded returns the OpcodeSet of codeSet.Type compiled without the methods of the types of NoMethodsOption.
+// It is cached per set of types in codeSet, and the other options are applied on top of it.
+func getNoMethodsCodeSetIfNeeded(ctx *RuntimeContext, codeSet *OpcodeSet) (*OpcodeSet, error) {
+	if (ctx.Option.Flag & NoMethodsOption) == 0 {
+		return codeSet, nil
+	}
+	types := ctx.Option.NoMethodTypes
+	if cacheCodeSet := codeSet.getNoMethodsCache(types); cacheCodeSet != nil {
+		return cacheCodeSet, nil
+	}
+	compiler := newCompiler()
+	compiler.noMethodTypes = types
+	compiler.tagConfig = ctx.Option.tagConfig()
+	code, err := compiler.typeToCode(codeSet.Type)
+	if err != nil {
+		return nil, err
+	}
+	noMethodsCodeSet, err := compiler.codeToOpcodeSet(codeSet.Type, code, 0)
+	if err != nil {
+		return nil, err
+	}
+	codeSet.setNoMethodsCache(types, noMethodsCodeSet)
+	return noMethodsCodeSet, nil
+}
+
+func getVariantCodeSetIfNeeded(ctx *RuntimeContext, codeSet *OpcodeSet) (*OpcodeSet, error) {
+	option := ctx.Option.Flag & compileOption
+	if option == 0 {
+		return codeSet, nil
+	}
+	if cacheCodeSet := codeSet.getVariantCache(option); cacheCodeSet != nil {
+		return cacheCodeSet, nil
+	}
+	var code Code
+	if (option & PtrMarshalerOption) != 0 {
+		// PtrMarshalerOption changes the choice of the codes, so they are built again instead of reusing codeSet.Code.
+		compiler := newCompiler()
+		compiler.isPtrMarshalerEnabled = true
+		compiler.tagConfig = ctx.Option.tagConfig()
+		if (ctx.Option.Flag & NoMethodsOption) != 0 {
+			compiler.noMethodTypes = ctx.Option.NoMethodTypes
+		}
+		rebuilt, err := compiler.typeToCode(codeSet.Type)
+		if err != nil {
+			return nil, err
+		}
+		if (ctx.Option.Flag & FieldQueryOption) != 0 {
+			rebuilt = rebuilt.Filter(FieldQueryFromContext(ctx.Option.Context))
+		}
+		code = rebuilt
+	} else {
+		setCode, err := codeSet.code()
+		if err != nil {
+			return nil, err
+		}
+		code = setCode
+	}
+	variantCodeSet, err := newCompiler().codeToOpcodeSet(codeSet.Type, code, option)
+	if err != nil {
+		return nil, err
+	}
+	codeSet.setVariantCache(option, variantCodeSet)
+	return variantCodeSet, nil
 }
 
 func getFilteredCodeSetIfNeeded(ctx *RuntimeContext, codeSet *OpcodeSet) (*OpcodeSet, error) {
@@ -80,7 +230,11 @@ func getFilteredCodeSetIfNeeded(ctx *RuntimeContext, codeSet *OpcodeSet) (*Opcod
 	if cacheCodeSet != nil {
 		return cacheCodeSet, nil
 	}
-	queryCodeSet, err := newCompiler().codeToOpcodeSet(codeSet.Type, codeSet.Code.Filter(query))
+	code, err := codeSet.code()
+	if err != nil {
+		return nil, err
+	}
+	queryCodeSet, err := newCompiler().codeToOpcodeSet(codeSet.Type, code.Filter(query), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +244,12 @@ func getFilteredCodeSetIfNeeded(ctx *RuntimeContext, codeSet *OpcodeSet) (*Opcod
 
 type Compiler struct {
 	structTypeToCode map[uintptr]*StructCode
+	// isPtrMarshalerEnabled makes unaddressable values use the MarshalJSON and MarshalText methods with pointer receiver, by copying them.
+	isPtrMarshalerEnabled bool
+	// noMethodTypes are the types encoded as if they had no MarshalJSON and MarshalText methods with NoMethodsOption.
+	noMethodTypes *runtime.TypeSet
+	// tagConfig is how the struct tags of the fields are read with TagKeyOption and FieldNamingOption.
+	tagConfig runtime.TagConfig
 }
 
 func newCompiler() *Compiler {
@@ -105,13 +265,14 @@ func (c *Compiler) compile(typeptr uintptr) (*OpcodeSet, error) {
 	if err != nil {
 		return nil, err
 	}
-	return c.codeToOpcodeSet(typ, code)
+	return c.codeToOpcodeSet(typ, code, 0)
 }
 
-func (c *Compiler) codeToOpcodeSet(typ *runtime.Type, code Code) (*OpcodeSet, error) {
+func (c *Compiler) codeToOpcodeSet(typ *runtime.Type, code Code, option OptionFlag) (*OpcodeSet, error) {
 	noescapeKeyCode := c.codeToOpcode(&compileContext{
 		structTypeToCodes: map[uintptr]Opcodes{},
 		recursiveCodes:    &Opcodes{},
+		option:            option,
 	}, typ, code)
 	if err := noescapeKeyCode.Validate(); err != nil {
 		return nil, err
@@ -120,6 +281,7 @@ func (c *Compiler) codeToOpcodeSet(typ *runtime.Type, code Code) (*OpcodeSet, er
 		structTypeToCodes: map[uintptr]Opcodes{},
 		recursiveCodes:    &Opcodes{},
 		escapeKey:         true,
+		option:            option,
 	}, typ, code)
 	noescapeKeyCode = copyOpcode(noescapeKeyCode)
 	escapeKeyCode = copyOpcode(escapeKeyCode)
@@ -138,6 +300,9 @@ func (c *Compiler) codeToOpcodeSet(typ *runtime.Type, code Code) (*OpcodeSet, er
 		EndCode:                  ToEndCode(interfaceNoescapeKeyCode),
 		Code:                     code,
 		QueryCache:               map[string]*OpcodeSet{},
+		VariantCache:             map[OptionFlag]*OpcodeSet{},
+		NoMethodsCache:           map[*runtime.TypeSet]*OpcodeSet{},
+		TagConfigCache:           map[runtime.TagConfig]*OpcodeSet{},
 	}, nil
 }
 
@@ -166,10 +331,13 @@ func (c *Compiler) typeToCode(typ *runtime.Type) (Code, error) {
 		elem := typ.Elem()
 		if elem.Kind() == reflect.Uint8 {
 			p := runtime.PtrTo(elem)
-			if !c.implementsMarshalJSONType(p) && !p.Implements(marshalTextType) {
+			if !c.implementsMarshalJSONType(p) && !c.implementsMarshalTextType(p) {
 				return c.bytesCode(typ, isPtr)
 			}
 		}
+		if c.isNumberSliceType(typ) {
+			return c.numberSliceCode(typ), nil
+		}
 		return c.sliceCode(typ)
 	case reflect.Map:
 		if isPtr {
@@ -209,7 +377,7 @@ func (c *Compiler) typeToCode(typ *runtime.Type) (Code, error) {
 	case reflect.Interface:
 		return c.interfaceCode(typ, isPtr)
 	default:
-		if isPtr && typ.Implements(marshalTextType) {
+		if isPtr && c.implementsMarshalTextType(typ) {
 			typ = orgType
 		}
 		return c.typeToCodeWithPtr(typ, isPtr)
@@ -230,10 +398,13 @@ func (c *Compiler) typeToCodeWithPtr(typ *runtime.Type, isPtr bool) (Code, error
 		elem := typ.Elem()
 		if elem.Kind() == reflect.Uint8 {
 			p := runtime.PtrTo(elem)
-			if !c.implementsMarshalJSONType(p) && !p.Implements(marshalTextType) {
+			if !c.implementsMarshalJSONType(p) && !c.implementsMarshalTextType(p) {
 				return c.bytesCode(typ, false)
 			}
 		}
+		if c.isNumberSliceType(typ) {
+			return c.numberSliceCode(typ), nil
+		}
 		return c.sliceCode(typ)
 	case reflect.Array:
 		return c.arrayCode(typ)
@@ -486,7 +657,7 @@ func (c *Compiler) listElemCode(typ *runtime.Type) (Code, error) {
 	switch {
 	case c.implementsMarshalJSONType(typ) || c.implementsMarshalJSONType(runtime.PtrTo(typ)):
 		return c.marshalJSONCode(typ)
-	case !typ.Implements(marshalTextType) && runtime.PtrTo(typ).Implements(marshalTextType):
+	case c.isPtrMarshalTextType(typ):
 		return c.marshalTextCode(typ)
 	case typ.Kind() == reflect.Map:
 		return c.ptrCode(runtime.PtrTo(typ))
@@ -508,9 +679,13 @@ func (c *Compiler) listElemCode(typ *runtime.Type) (Code, error) {
 	}
 }
 
+// mapKeyCode returns the code of map keys of typ.
+// Keys are encoded by MarshalText, as strings or integers by their kind, or by MarshalJSON in this order of priority.
+// MarshalJSON is used only for keys of other kinds, and its result must be a JSON string.
+// The methods with pointer receiver are also used, since the encoder copies the key.
 func (c *Compiler) mapKeyCode(typ *runtime.Type) (Code, error) {
 	switch {
-	case c.implementsMarshalText(typ):
+	case c.implementsMarshalText(typ), c.isPtrMarshalTextType(typ):
 		return c.marshalTextCode(typ)
 	}
 	switch typ.Kind() {
@@ -541,6 +716,14 @@ func (c *Compiler) mapKeyCode(typ *runtime.Type) (Code, error) {
 	case reflect.Uintptr:
 		return c.uintStringCode(typ)
 	}
+	if c.implementsMarshalJSONType(typ) || c.isPtrMarshalJSONType(typ) {
+		code, err := c.marshalJSONCode(typ)
+		if err != nil {
+			return nil, err
+		}
+		code.isMapKey = true
+		return code, nil
+	}
 	return nil, &errors.UnsupportedTypeError{Type: runtime.RType2Type(typ)}
 }
 
@@ -632,17 +815,29 @@ func (c *Compiler) structFieldCode(structCode *StructCode, tag *runtime.StructTa
 	field := tag.Field
 	fieldType := runtime.Type2RType(field.Type)
 	isIndirectSpecialCase := isPtr && isOnlyOneFirstField
+	// the key of a field flattened by the squash option is not written even if it is tagged.
+	isAnonymous := tag.IsFlattened() && toElemType(fieldType).Kind() == reflect.Struct
 	fieldCode := &StructFieldCode{
 		typ:           fieldType,
 		key:           tag.Key,
 		tag:           tag,
 		offset:        field.Offset,
-		isAnonymous:   field.Anonymous && !tag.IsTaggedKey && toElemType(fieldType).Kind() == reflect.Struct,
-		isTaggedKey:   tag.IsTaggedKey,
+		isAnonymous:   isAnonymous,
+		isTaggedKey:   tag.IsTaggedKey && !isAnonymous,
 		isNilableType: c.isNilableType(fieldType),
 		isNilCheck:    true,
 	}
 	switch {
+	case tag.Codec != "":
+		id, err := codecID(tag.Codec, fieldType)
+		if err != nil {
+			return nil, err
+		}
+		fieldCode.value = &MarshalJSONCode{typ: fieldType, isNilableType: c.isNilableType(fieldType), codec: id}
+	case tag.IsSealed:
+		fieldCode.value = &MarshalJSONCode{typ: fieldType, isNilableType: c.isNilableType(fieldType), isSealed: true}
+	case tag.IsTuple && isTupleType(fieldType):
+		fieldCode.value = &MarshalJSONCode{typ: fieldType, isNilableType: c.isNilableType(fieldType), isTuple: true}
 	case c.isMovePointerPositionFromHeadToFirstMarshalJSONFieldCase(fieldType, isIndirectSpecialCase):
 		code, err := c.marshalJSONCode(fieldType)
 		if err != nil {
@@ -663,7 +858,7 @@ func (c *Compiler) structFieldCode(structCode *StructCode, tag *runtime.StructTa
 		fieldCode.isNilCheck = false
 		structCode.isIndirect = false
 		structCode.disableIndirectConversion = true
-	case isPtr && c.isPtrMarshalJSONType(fieldType):
+	case (isPtr || c.isPtrMarshalerEnabled) && c.isPtrMarshalJSONType(fieldType):
 		// *struct{ field T }
 		// func (*T) MarshalJSON() ([]byte, error)
 		code, err := c.marshalJSONCode(fieldType)
@@ -673,7 +868,7 @@ func (c *Compiler) structFieldCode(structCode *StructCode, tag *runtime.StructTa
 		fieldCode.value = code
 		fieldCode.isAddrForMarshaler = true
 		fieldCode.isNilCheck = false
-	case isPtr && c.isPtrMarshalTextType(fieldType):
+	case (isPtr || c.isPtrMarshalerEnabled) && c.isPtrMarshalTextType(fieldType):
 		// *struct{ field T }
 		// func (*T) MarshalText() ([]byte, error)
 		code, err := c.marshalTextCode(fieldType)
@@ -814,10 +1009,10 @@ func (c *Compiler) typeToStructTags(typ *runtime.Type) runtime.StructTags {
 	fieldNum := typ.NumField()
 	for i := 0; i < fieldNum; i++ {
 		field := typ.Field(i)
-		if runtime.IsIgnoredStructField(field) {
+		if runtime.IsIgnoredStructField(field, c.tagConfig.Key) {
 			continue
 		}
-		tags = append(tags, runtime.StructTagFromField(field))
+		tags = append(tags, runtime.StructTagFromField(field, c.tagConfig))
 	}
 	return tags
 }
@@ -836,7 +1031,7 @@ func (c *Compiler) isMovePointerPositionFromHeadToFirstMarshalTextFieldCase(typ
 
 func (c *Compiler) implementsMarshalJSON(typ *runtime.Type) bool {
 	if !c.implementsMarshalJSONType(typ) {
-		return false
+		return c.isPtrMarshalerEnabled && typ.Kind() != reflect.Ptr && c.isPtrMarshalJSONType(typ)
 	}
 	if typ.Kind() != reflect.Ptr {
 		return true
@@ -850,14 +1045,14 @@ func (c *Compiler) implementsMarshalJSON(typ *runtime.Type) bool {
 }
 
 func (c *Compiler) implementsMarshalText(typ *runtime.Type) bool {
-	if !typ.Implements(marshalTextType) {
-		return false
+	if !c.implementsMarshalTextType(typ) {
+		return c.isPtrMarshalerEnabled && typ.Kind() != reflect.Ptr && c.isPtrMarshalTextType(typ)
 	}
 	if typ.Kind() != reflect.Ptr {
 		return true
 	}
 	// type kind is reflect.Ptr
-	if !typ.Elem().Implements(marshalTextType) {
+	if !c.implementsMarshalTextType(typ.Elem()) {
 		return true
 	}
 	// needs to dereference
@@ -881,15 +1076,25 @@ func (c *Compiler) isNilableType(typ *runtime.Type) bool {
 }
 
 func (c *Compiler) implementsMarshalJSONType(typ *runtime.Type) bool {
+	if c.noMethodTypes.Has(typ) {
+		return false
+	}
 	return typ.Implements(marshalJSONType) || typ.Implements(marshalJSONContextType)
 }
 
+func (c *Compiler) implementsMarshalTextType(typ *runtime.Type) bool {
+	if c.noMethodTypes.Has(typ) {
+		return false
+	}
+	return typ.Implements(marshalTextType)
+}
+
 func (c *Compiler) isPtrMarshalJSONType(typ *runtime.Type) bool {
 	return !c.implementsMarshalJSONType(typ) && c.implementsMarshalJSONType(runtime.PtrTo(typ))
 }
 
 func (c *Compiler) isPtrMarshalTextType(typ *runtime.Type) bool {
-	return !typ.Implements(marshalTextType) && runtime.PtrTo(typ).Implements(marshalTextType)
+	return !c.implementsMarshalTextType(typ) && c.implementsMarshalTextType(runtime.PtrTo(typ))
 }
 
 func (c *Compiler) codeToOpcode(ctx *compileContext, typ *runtime.Type, code Code) *Opcode {
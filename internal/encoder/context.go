@@ -2,7 +2,9 @@ package encoder
 
 import (
 	"context"
+	"io"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/going/json/internal/runtime"
@@ -13,6 +15,7 @@ type compileContext struct {
 	ptrIndex          int
 	indent            uint32
 	escapeKey         bool
+	option            OptionFlag
 	structTypeToCodes map[uintptr]Opcodes
 	recursiveCodes    *Opcodes
 }
@@ -79,6 +82,13 @@ type RuntimeContext struct {
 	Prefix     []byte
 	IndentStr  []byte
 	Option     *Option
+
+	// FlushWriter receives the head of the buffer once it has grown to FlushSize during encoding.
+	// nil disables flushing.
+	FlushWriter io.Writer
+	FlushSize   int
+	// SortedMaps is the number of sorted maps being encoded.
+	SortedMaps int
 }
 
 func (c *RuntimeContext) Init(p uintptr, codelen int) {
@@ -89,6 +99,27 @@ func (c *RuntimeContext) Init(p uintptr, codelen int) {
 	c.KeepRefs = c.KeepRefs[:0]
 	c.SeenPtr = c.SeenPtr[:0]
 	c.BaseIndent = 0
+	c.SortedMaps = 0
+}
+
+// flushTailSize is the number of trailing bytes kept in the buffer by FlushBuffer,
+// since the VM may rewrite the last bytes it has written ( e.g. replace the trailing comma by a closing bracket ).
+const flushTailSize = 16
+
+// MinFlushSize is the smallest FlushSize.
+const MinFlushSize = flushTailSize * 4
+
+// FlushBuffer writes the head of b to FlushWriter if b has grown to FlushSize, and returns the remaining tail.
+// It does nothing while a sorted map is being encoded, since its entries refer to positions of b.
+func (c *RuntimeContext) FlushBuffer(b []byte) ([]byte, error) {
+	if len(b) < c.FlushSize || c.SortedMaps > 0 {
+		return b, nil
+	}
+	n := len(b) - flushTailSize
+	if _, err := c.FlushWriter.Write(b[:n]); err != nil {
+		return nil, err
+	}
+	return b[:copy(b, b[n:])], nil
 }
 
 func (c *RuntimeContext) Ptr() uintptr {
@@ -100,6 +131,31 @@ func TakeRuntimeContext() *RuntimeContext {
 	return runtimeContextPool.Get().(*RuntimeContext)
 }
 
+// maxRetainedBufCap is the maximum capacity of buffers returned to runtimeContextPool.
+// zero means unlimited.
+var maxRetainedBufCap int64
+
+func SetMaxRetainedBufferCap(n int) {
+	atomic.StoreInt64(&maxRetainedBufCap, int64(n))
+}
+
 func ReleaseRuntimeContext(ctx *RuntimeContext) {
+	if max := atomic.LoadInt64(&maxRetainedBufCap); max > 0 {
+		if int64(cap(ctx.Buf)) > max {
+			// trim oversized buffer so that an occasional large encoding doesn't stay in the pool.
+			size := int64(bufSize)
+			if size > max {
+				size = max
+			}
+			ctx.Buf = make([]byte, 0, size)
+		}
+		if int64(cap(ctx.MarshalBuf)) > max {
+			ctx.MarshalBuf = nil
+		}
+	}
+	ctx.FlushWriter = nil
+	// the context of MarshalContext is request-scoped, so it must not be retained by the pool.
+	ctx.Option.Context = nil
+	ctx.Option.Audit = nil
 	runtimeContextPool.Put(ctx)
 }
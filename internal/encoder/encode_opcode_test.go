@@ -18,3 +18,49 @@ func TestDumpOpcode(t *testing.T) {
 	}
 	codeSet.EscapeKeyCode.Dump()
 }
+
+func TestReleaseRuntimeContextTrimsBuffer(t *testing.T) {
+	SetMaxRetainedBufferCap(4096)
+	defer SetMaxRetainedBufferCap(0)
+
+	ctx := TakeRuntimeContext()
+	ctx.Buf = make([]byte, 0, 1<<20)
+	ctx.MarshalBuf = make([]byte, 0, 1<<20)
+	ReleaseRuntimeContext(ctx)
+	if cap(ctx.Buf) != bufSize {
+		t.Fatalf("expected trimmed buffer but got cap %d", cap(ctx.Buf))
+	}
+	if ctx.MarshalBuf != nil {
+		t.Fatal("expected marshal buffer to be dropped")
+	}
+}
+
+func TestValidateSnapshotCodes(t *testing.T) {
+	codes := make([]Opcode, 3)
+	reset := func() {
+		codes[0] = Opcode{Op: OpStructHeadInt, Idx: 0, Next: &codes[1]}
+		codes[1] = Opcode{Op: OpStructEndInt, Idx: uintptrSize, Next: &codes[2]}
+		codes[2] = Opcode{Op: OpEnd}
+	}
+	reset()
+	codeLength, err := validateSnapshotCodes(codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codeLength != 2 {
+		t.Fatalf("unexpected code length %d", codeLength)
+	}
+	for name, corrupt := range map[string]func(){
+		"unknown opcode":  func() { codes[1].Op = OpType(len(opTypeStrings)) },
+		"unaligned index": func() { codes[1].Idx = 1 },
+		"too large index": func() { codes[1].ElemIdx = 1 << 20 },
+		"loop":            func() { codes[1].Next = &codes[0] },
+		"loop to itself":  func() { codes[2] = Opcode{Op: OpInt, Next: &codes[2]} },
+	} {
+		reset()
+		corrupt()
+		if _, err := validateSnapshotCodes(codes); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
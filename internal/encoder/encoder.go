@@ -2,6 +2,7 @@ package encoder
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	"encoding/base64"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/going/json/internal/errors"
@@ -103,9 +105,34 @@ type OpcodeSet struct {
 	EndCode                  *Opcode
 	Code                     Code
 	QueryCache               map[string]*OpcodeSet
+	VariantCache             map[OptionFlag]*OpcodeSet
+	NoMethodsCache           map[*runtime.TypeSet]*OpcodeSet
+	TagConfigCache           map[runtime.TagConfig]*OpcodeSet
 	cacheMu                  sync.RWMutex
 }
 
+// code returns the Code the opcodes of the set are compiled from.
+// It is compiled again on first use for a set loaded from a snapshot, which has only the opcodes.
+func (s *OpcodeSet) code() (Code, error) {
+	s.cacheMu.RLock()
+	code := s.Code
+	s.cacheMu.RUnlock()
+	if code != nil {
+		return code, nil
+	}
+	code, err := newCompiler().typeToCode(s.Type)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheMu.Lock()
+	if s.Code == nil {
+		s.Code = code
+	}
+	code = s.Code
+	s.cacheMu.Unlock()
+	return code, nil
+}
+
 func (s *OpcodeSet) getQueryCache(hash string) *OpcodeSet {
 	s.cacheMu.RLock()
 	codeSet := s.QueryCache[hash]
@@ -119,6 +146,45 @@ func (s *OpcodeSet) setQueryCache(hash string, codeSet *OpcodeSet) {
 	s.cacheMu.Unlock()
 }
 
+func (s *OpcodeSet) getVariantCache(option OptionFlag) *OpcodeSet {
+	s.cacheMu.RLock()
+	codeSet := s.VariantCache[option]
+	s.cacheMu.RUnlock()
+	return codeSet
+}
+
+func (s *OpcodeSet) setVariantCache(option OptionFlag, codeSet *OpcodeSet) {
+	s.cacheMu.Lock()
+	s.VariantCache[option] = codeSet
+	s.cacheMu.Unlock()
+}
+
+func (s *OpcodeSet) getNoMethodsCache(types *runtime.TypeSet) *OpcodeSet {
+	s.cacheMu.RLock()
+	codeSet := s.NoMethodsCache[types]
+	s.cacheMu.RUnlock()
+	return codeSet
+}
+
+func (s *OpcodeSet) setNoMethodsCache(types *runtime.TypeSet, codeSet *OpcodeSet) {
+	s.cacheMu.Lock()
+	s.NoMethodsCache[types] = codeSet
+	s.cacheMu.Unlock()
+}
+
+func (s *OpcodeSet) getTagConfigCache(tagConfig runtime.TagConfig) *OpcodeSet {
+	s.cacheMu.RLock()
+	codeSet := s.TagConfigCache[tagConfig]
+	s.cacheMu.RUnlock()
+	return codeSet
+}
+
+func (s *OpcodeSet) setTagConfigCache(tagConfig runtime.TagConfig, codeSet *OpcodeSet) {
+	s.cacheMu.Lock()
+	s.TagConfigCache[tagConfig] = codeSet
+	s.cacheMu.Unlock()
+}
+
 type CompiledCode struct {
 	Code    *Opcode
 	Linked  bool // whether recursive code already have linked
@@ -258,13 +324,15 @@ type mapIter struct {
 }
 
 type MapContext struct {
-	Start int
-	First int
-	Idx   int
-	Slice *Mapslice
-	Buf   []byte
-	Len   int
-	Iter  mapIter
+	Start     int
+	First     int
+	Idx       int
+	Slice     *Mapslice
+	Buf       []byte
+	Len       int
+	Iter      mapIter
+	Entries   []MapEntry
+	Presorted bool
 }
 
 var mapContextPool = sync.Pool{
@@ -288,10 +356,17 @@ func NewMapContext(mapLen int, unorderedMap bool) *MapContext {
 	ctx.Iter = mapIter{}
 	ctx.Idx = 0
 	ctx.Len = mapLen
+	ctx.Presorted = false
 	return ctx
 }
 
 func ReleaseMapContext(c *MapContext) {
+	if c.Presorted {
+		// don't keep the map alive from the pool.
+		for i := range c.Entries {
+			c.Entries[i] = MapEntry{}
+		}
+	}
 	mapContextPool.Put(c)
 }
 
@@ -329,26 +404,34 @@ func AppendByteSlice(_ *RuntimeContext, b []byte, src []byte) []byte {
 	return append(append(b, buf...), '"')
 }
 
-func AppendFloat32(_ *RuntimeContext, b []byte, v float32) []byte {
+func AppendFloat32(ctx *RuntimeContext, b []byte, v float32) []byte {
 	f64 := float64(v)
 	abs := math.Abs(f64)
 	fmt := byte('f')
 	// Note: Must use float32 comparisons for underlying float32 value to get precise cutoffs right.
 	if abs != 0 {
 		f32 := float32(abs)
-		if f32 < 1e-6 || f32 >= 1e21 {
+		if (ctx.Option.Flag & FloatExpThresholdOption) != 0 {
+			if threshold := ctx.Option.FloatExpThreshold; !math.IsInf(threshold, 1) && (f32 < 1e-6 || f32 >= float32(threshold)) {
+				fmt = 'e'
+			}
+		} else if f32 < 1e-6 || f32 >= 1e21 {
 			fmt = 'e'
 		}
 	}
 	return strconv.AppendFloat(b, f64, fmt, -1, 32)
 }
 
-func AppendFloat64(_ *RuntimeContext, b []byte, v float64) []byte {
+func AppendFloat64(ctx *RuntimeContext, b []byte, v float64) []byte {
 	abs := math.Abs(v)
 	fmt := byte('f')
 	// Note: Must use float32 comparisons for underlying float32 value to get precise cutoffs right.
 	if abs != 0 {
-		if abs < 1e-6 || abs >= 1e21 {
+		if (ctx.Option.Flag & FloatExpThresholdOption) != 0 {
+			if threshold := ctx.Option.FloatExpThreshold; !math.IsInf(threshold, 1) && (abs < 1e-6 || abs >= threshold) {
+				fmt = 'e'
+			}
+		} else if abs < 1e-6 || abs >= 1e21 {
 			fmt = 'e'
 		}
 	}
@@ -395,7 +478,36 @@ func AppendNumber(_ *RuntimeContext, b []byte, n json.Number) ([]byte, error) {
 	return b, nil
 }
 
+// inTimeLocation returns v converted to loc if it is a time.Time or a non-nil *time.Time, and v otherwise.
+func inTimeLocation(loc *time.Location, v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return t.In(loc)
+	case *time.Time:
+		if t != nil {
+			tt := t.In(loc)
+			return &tt
+		}
+	}
+	return v
+}
+
+// stdContext returns the context passed to the MarshalerContext implementations,
+// which is context.Background() unless the encode has ContextOption.
+func (c *RuntimeContext) stdContext() context.Context {
+	if c.Option.Flag&ContextOption != 0 {
+		return c.Option.Context
+	}
+	return context.Background()
+}
+
 func AppendMarshalJSON(ctx *RuntimeContext, code *Opcode, b []byte, v interface{}) ([]byte, error) {
+	if (code.Flags & SealedFlags) != 0 {
+		return appendSealed(ctx, b, v)
+	}
+	if (code.Flags & NumberSliceFlags) != 0 {
+		return appendNumberSlice(ctx, b, v)
+	}
 	rv := reflect.ValueOf(v) // convert by dynamic interface type
 	if (code.Flags & AddrForMarshalerFlags) != 0 {
 		if rv.CanAddr() {
@@ -412,43 +524,67 @@ func AppendMarshalJSON(ctx *RuntimeContext, code *Opcode, b []byte, v interface{
 	}
 
 	v = rv.Interface()
+	if ctx.Option.Flag&TimeLocationOption != 0 {
+		v = inTimeLocation(ctx.Option.TimeLocation, v)
+	}
 	var bb []byte
-	if (code.Flags & MarshalerContextFlags) != 0 {
+	if (code.Flags & CodecFlags) != 0 {
+		out, err := codecByID(code.Size)(v)
+		if err != nil {
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "codec")
+		}
+		bb = out
+	} else if (code.Flags & TupleFlags) != 0 {
+		out, err := marshalTuple(v, ctx.Option.tagConfig().Key)
+		if err != nil {
+			return nil, err
+		}
+		bb = out
+	} else if (code.Flags & MarshalerContextFlags) != 0 {
 		marshaler, ok := v.(marshalerContext)
 		if !ok {
 			return AppendNull(ctx, b), nil
 		}
-		stdctx := ctx.Option.Context
+		stdctx := ctx.stdContext()
 		if ctx.Option.Flag&FieldQueryOption != 0 {
 			stdctx = SetFieldQueryToContext(stdctx, code.FieldQuery)
 		}
-		b, err := marshaler.MarshalJSON(stdctx)
+		out, err := marshaler.MarshalJSON(stdctx)
 		if err != nil {
-			return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
 		}
-		bb = b
+		bb = out
 	} else {
 		marshaler, ok := v.(json.Marshaler)
 		if !ok {
 			return AppendNull(ctx, b), nil
 		}
-		b, err := marshaler.MarshalJSON()
+		out, err := marshaler.MarshalJSON()
 		if err != nil {
-			return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
+		}
+		bb = out
+	}
+	if (code.Flags&MapKeyFlags) != 0 && !isJSONString(bb) {
+		return nil, &errors.MarshalerError{
+			Type: reflect.TypeOf(v),
+			Err:  fmt.Errorf("map key must be encoded as a JSON string, but got %s", bb),
 		}
-		bb = b
 	}
 	marshalBuf := ctx.MarshalBuf[:0]
 	marshalBuf = append(append(marshalBuf, bb...), nul)
 	compactedBuf, err := compact(b, marshalBuf, (ctx.Option.Flag&HTMLEscapeOption) != 0)
 	if err != nil {
-		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
 	}
 	ctx.MarshalBuf = marshalBuf
 	return compactedBuf, nil
 }
 
 func AppendMarshalJSONIndent(ctx *RuntimeContext, code *Opcode, b []byte, v interface{}) ([]byte, error) {
+	if (code.Flags & SealedFlags) != 0 {
+		return appendSealed(ctx, b, v)
+	}
 	rv := reflect.ValueOf(v) // convert by dynamic interface type
 	if (code.Flags & AddrForMarshalerFlags) != 0 {
 		if rv.CanAddr() {
@@ -460,30 +596,66 @@ func AppendMarshalJSONIndent(ctx *RuntimeContext, code *Opcode, b []byte, v inte
 		}
 	}
 	v = rv.Interface()
+	if ctx.Option.Flag&TimeLocationOption != 0 {
+		v = inTimeLocation(ctx.Option.TimeLocation, v)
+	}
 	var bb []byte
-	if (code.Flags & MarshalerContextFlags) != 0 {
+	if (code.Flags & CodecFlags) != 0 {
+		out, err := codecByID(code.Size)(v)
+		if err != nil {
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "codec")
+		}
+		bb = out
+	} else if (code.Flags & NumberSliceFlags) != 0 {
+		// the compact array is indented below like the output of MarshalJSON.
+		out, err := appendNumberSlice(ctx, nil, v)
+		if err != nil {
+			return nil, err
+		}
+		bb = out
+	} else if (code.Flags & TupleFlags) != 0 {
+		out, err := marshalTuple(v, ctx.Option.tagConfig().Key)
+		if err != nil {
+			return nil, err
+		}
+		bb = out
+	} else if (code.Flags & MarshalerContextFlags) != 0 {
 		marshaler, ok := v.(marshalerContext)
 		if !ok {
 			return AppendNull(ctx, b), nil
 		}
-		b, err := marshaler.MarshalJSON(ctx.Option.Context)
+		out, err := marshaler.MarshalJSON(ctx.stdContext())
 		if err != nil {
-			return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
 		}
-		bb = b
+		bb = out
 	} else {
 		marshaler, ok := v.(json.Marshaler)
 		if !ok {
 			return AppendNull(ctx, b), nil
 		}
-		b, err := marshaler.MarshalJSON()
+		out, err := marshaler.MarshalJSON()
 		if err != nil {
-			return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
+		}
+		bb = out
+	}
+	if (code.Flags&MapKeyFlags) != 0 && !isJSONString(bb) {
+		return nil, &errors.MarshalerError{
+			Type: reflect.TypeOf(v),
+			Err:  fmt.Errorf("map key must be encoded as a JSON string, but got %s", bb),
 		}
-		bb = b
 	}
 	marshalBuf := ctx.MarshalBuf[:0]
 	marshalBuf = append(append(marshalBuf, bb...), nul)
+	if (ctx.Option.Flag&CompactMarshalerOption) != 0 && (code.Flags&(NumberSliceFlags|TupleFlags)) == 0 {
+		compactedBuf, err := compact(b, marshalBuf, (ctx.Option.Flag&HTMLEscapeOption) != 0)
+		if err != nil {
+			return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
+		}
+		ctx.MarshalBuf = marshalBuf
+		return compactedBuf, nil
+	}
 	indentedBuf, err := doIndent(
 		b,
 		marshalBuf,
@@ -492,12 +664,19 @@ func AppendMarshalJSONIndent(ctx *RuntimeContext, code *Opcode, b []byte, v inte
 		(ctx.Option.Flag&HTMLEscapeOption) != 0,
 	)
 	if err != nil {
-		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalJSON")
 	}
 	ctx.MarshalBuf = marshalBuf
 	return indentedBuf, nil
 }
 
+// isJSONString reports whether b, the output of MarshalJSON, looks like a JSON string.
+// The syntax of the string itself is validated later by compacting or indenting it.
+func isJSONString(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"'
+}
+
 func AppendMarshalText(ctx *RuntimeContext, code *Opcode, b []byte, v interface{}) ([]byte, error) {
 	rv := reflect.ValueOf(v) // convert by dynamic interface type
 	if (code.Flags & AddrForMarshalerFlags) != 0 {
@@ -510,13 +689,16 @@ func AppendMarshalText(ctx *RuntimeContext, code *Opcode, b []byte, v interface{
 		}
 	}
 	v = rv.Interface()
+	if ctx.Option.Flag&TimeLocationOption != 0 {
+		v = inTimeLocation(ctx.Option.TimeLocation, v)
+	}
 	marshaler, ok := v.(encoding.TextMarshaler)
 	if !ok {
 		return AppendNull(ctx, b), nil
 	}
 	bytes, err := marshaler.MarshalText()
 	if err != nil {
-		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalText")
 	}
 	return AppendString(ctx, b, *(*string)(unsafe.Pointer(&bytes))), nil
 }
@@ -533,13 +715,16 @@ func AppendMarshalTextIndent(ctx *RuntimeContext, code *Opcode, b []byte, v inte
 		}
 	}
 	v = rv.Interface()
+	if ctx.Option.Flag&TimeLocationOption != 0 {
+		v = inTimeLocation(ctx.Option.TimeLocation, v)
+	}
 	marshaler, ok := v.(encoding.TextMarshaler)
 	if !ok {
 		return AppendNull(ctx, b), nil
 	}
 	bytes, err := marshaler.MarshalText()
 	if err != nil {
-		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		return nil, errMarshaler(ctx, b, reflect.TypeOf(v), err, "MarshalText")
 	}
 	return AppendString(ctx, b, *(*string)(unsafe.Pointer(&bytes))), nil
 }
@@ -209,3 +209,9 @@ func indentArray(
 		cursor++
 	}
 }
+
+// AppendIndentJSON appends the indented form of src to dst.
+// src must be terminated by nul.
+func AppendIndentJSON(dst, src []byte, prefix, indentStr string) ([]byte, error) {
+	return doIndent(dst, src, prefix, indentStr, false)
+}
@@ -77,7 +77,42 @@ func numMask(numBitSize uint8) uint64 {
 	return 1<<numBitSize - 1
 }
 
+// maxSafeInteger is the largest integer that JavaScript numbers represent exactly along with all the smaller ones.
+const maxSafeInteger = 1<<53 - 1
+
+// isQuotedInt reports whether the 64-bit integer p points to is encoded as a JSON string,
+// always with QuotedIntFlags and only if its magnitude exceeds maxSafeInteger with QuotedBigIntFlags.
+func isQuotedInt(p uintptr, code *Opcode) bool {
+	if code.NumBitSize != 64 {
+		return false
+	}
+	if (code.Flags & QuotedIntFlags) != 0 {
+		return true
+	}
+	v := **(**int64)(unsafe.Pointer(&p))
+	return v < -maxSafeInteger || maxSafeInteger < v
+}
+
+// isQuotedUint is like isQuotedInt but for unsigned integers.
+func isQuotedUint(p uintptr, code *Opcode) bool {
+	if code.NumBitSize != 64 {
+		return false
+	}
+	if (code.Flags & QuotedIntFlags) != 0 {
+		return true
+	}
+	return **(**uint64)(unsafe.Pointer(&p)) > maxSafeInteger
+}
+
 func AppendInt(_ *RuntimeContext, out []byte, p uintptr, code *Opcode) []byte {
+	if (code.Flags&(QuotedIntFlags|QuotedBigIntFlags)) != 0 && isQuotedInt(p, code) {
+		out = append(out, '"')
+		return append(appendInt(out, p, code), '"')
+	}
+	return appendInt(out, p, code)
+}
+
+func appendInt(out []byte, p uintptr, code *Opcode) []byte {
 	var u64 uint64
 	switch code.NumBitSize {
 	case 8:
@@ -132,6 +167,14 @@ func AppendInt(_ *RuntimeContext, out []byte, p uintptr, code *Opcode) []byte {
 }
 
 func AppendUint(_ *RuntimeContext, out []byte, p uintptr, code *Opcode) []byte {
+	if (code.Flags&(QuotedIntFlags|QuotedBigIntFlags)) != 0 && isQuotedUint(p, code) {
+		out = append(out, '"')
+		return append(appendUint(out, p, code), '"')
+	}
+	return appendUint(out, p, code)
+}
+
+func appendUint(out []byte, p uintptr, code *Opcode) []byte {
 	var u64 uint64
 	switch code.NumBitSize {
 	case 8:
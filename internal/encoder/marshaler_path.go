@@ -0,0 +1,84 @@
+package encoder
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/going/json/internal/errors"
+)
+
+// errMarshaler returns the error for a failed or invalid call of the method sourceFunc,
+// recording the location of the value in the document being encoded.
+// b is the buffer that the value is appended to.
+func errMarshaler(ctx *RuntimeContext, b []byte, typ reflect.Type, err error, sourceFunc string) *errors.MarshalerError {
+	merr := errors.ErrMarshaler(typ, err, sourceFunc)
+	if ctx.FlushWriter == nil {
+		// the head of the document is no longer in b once it has been flushed.
+		merr.Path = encodedPath(b)
+	}
+	return merr
+}
+
+type encodedPathFrame struct {
+	isArray   bool
+	index     int
+	key       string
+	expectKey bool
+}
+
+// encodedPath returns the location of the next value appended to b, the part of the document encoded so far.
+// The location has the form of $.a[0].b, the same as Path.
+func encodedPath(b []byte) string {
+	var stack []encodedPathFrame
+	for cursor := 0; cursor < len(b); cursor++ {
+		switch b[cursor] {
+		case '\x1b':
+			// skip the escape sequence of ColorScheme.
+			for cursor < len(b) && b[cursor] != 'm' {
+				cursor++
+			}
+		case '"':
+			end := colorizeStringEnd(b, cursor)
+			// the keys of a sorted map are not followed by ':' until the map is completed,
+			// so a key is detected by its position instead.
+			if len(stack) > 0 && stack[len(stack)-1].expectKey {
+				stack[len(stack)-1].key = unquoteEncodedKey(b[cursor:end])
+				stack[len(stack)-1].expectKey = false
+			}
+			cursor = end - 1
+		case ',':
+			if len(stack) > 0 {
+				if frame := &stack[len(stack)-1]; frame.isArray {
+					frame.index++
+				} else {
+					frame.expectKey = true
+				}
+			}
+		case '{':
+			stack = append(stack, encodedPathFrame{expectKey: true})
+		case '[':
+			stack = append(stack, encodedPathFrame{isArray: true})
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	var path pathBuilder
+	for _, frame := range stack {
+		if frame.isArray {
+			path.WriteIndex(frame.index)
+		} else {
+			path.WriteKey(frame.key)
+		}
+	}
+	return path.String()
+}
+
+func unquoteEncodedKey(s []byte) string {
+	if key, err := strconv.Unquote(string(s)); err == nil {
+		return key
+	}
+	return strings.Trim(string(s), `"`)
+}
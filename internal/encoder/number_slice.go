@@ -0,0 +1,91 @@
+package encoder
+
+import (
+	"math"
+	"reflect"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// isNumberSliceType reports whether typ is a slice of float64 or int64 without marshalers,
+// which is encoded in bulk by appendNumberSlice instead of element by element by the VM.
+func (c *Compiler) isNumberSliceType(typ *runtime.Type) bool {
+	elem := typ.Elem()
+	switch elem.Kind() {
+	case reflect.Float64, reflect.Int64:
+	default:
+		return false
+	}
+	p := runtime.PtrTo(elem)
+	return !c.implementsMarshalJSONType(elem) && !c.implementsMarshalJSONType(p) &&
+		!c.implementsMarshalTextType(elem) && !c.implementsMarshalTextType(p)
+}
+
+func (c *Compiler) numberSliceCode(typ *runtime.Type) *MarshalJSONCode {
+	return &MarshalJSONCode{typ: typ, isNilableType: c.isNilableType(typ), isNumberSlice: true}
+}
+
+// isNumberSliceCode reports whether code is the code of a slice encoded by appendNumberSlice, or of a pointer to it.
+func isNumberSliceCode(code Code) bool {
+	switch code := code.(type) {
+	case *MarshalJSONCode:
+		return code.isNumberSlice
+	case *PtrCode:
+		return isNumberSliceCode(code.value)
+	}
+	return false
+}
+
+// int64Code is the opcode of an int64 for appendInt.
+var int64Code = &Opcode{NumBitSize: 64}
+
+// numberSliceElemSize is the size of an encoded element reserved in advance, enough for most of the elements.
+const numberSliceElemSize = 24
+
+// appendNumberSlice appends the []float64 or []int64 v, or a pointer to it.
+// The buffer is grown once for all the elements, and the elements are formatted without the dispatch of the VM.
+func appendNumberSlice(ctx *RuntimeContext, b []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return AppendNull(ctx, b), nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.IsNil() && (ctx.Option.Flag&NilSliceAsEmptyOption) == 0 {
+		return AppendNull(ctx, b), nil
+	}
+	n := rv.Len()
+	if n == 0 {
+		return append(b, '[', ']'), nil
+	}
+	if size := len(b) + n*numberSliceElemSize + 2; cap(b) < size {
+		grown := make([]byte, len(b), size)
+		copy(grown, b)
+		b = grown
+	}
+	b = append(b, '[')
+	data := unsafe.Pointer(rv.Pointer())
+	if rv.Type().Elem().Kind() == reflect.Float64 {
+		for _, f := range unsafe.Slice((*float64)(data), n) {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, ErrUnsupportedFloat(f)
+			}
+			b = append(AppendFloat64(ctx, b, f), ',')
+		}
+	} else {
+		quoted := (ctx.Option.Flag & Int64AsStringOption) != 0
+		ints := unsafe.Slice((*int64)(data), n)
+		for i := range ints {
+			p := uintptr(unsafe.Pointer(&ints[i]))
+			if quoted {
+				b = append(append(appendInt(append(b, '"'), p, int64Code), '"'), ',')
+			} else {
+				b = append(appendInt(b, p, int64Code), ',')
+			}
+		}
+	}
+	b[len(b)-1] = ']'
+	return b, nil
+}
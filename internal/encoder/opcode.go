@@ -12,7 +12,7 @@ import (
 
 const uintptrSize = 4 << (^uintptr(0) >> 63)
 
-type OpFlags uint16
+type OpFlags uint32
 
 const (
 	AnonymousHeadFlags     OpFlags = 1 << 0
@@ -25,6 +25,16 @@ const (
 	IsNilableTypeFlags     OpFlags = 1 << 7
 	MarshalerContextFlags  OpFlags = 1 << 8
 	NonEmptyInterfaceFlags OpFlags = 1 << 9
+	ZeroCheckerFlags       OpFlags = 1 << 10
+	MapKeyFlags            OpFlags = 1 << 11
+	QuotedIntFlags         OpFlags = 1 << 12
+	QuotedBigIntFlags      OpFlags = 1 << 13
+	SealedFlags            OpFlags = 1 << 14
+	TupleFlags             OpFlags = 1 << 15
+	NumberSliceFlags       OpFlags = 1 << 16
+	CodecFlags             OpFlags = 1 << 17
+	OmitZeroFlags          OpFlags = 1 << 18
+	OmitEmptyFlags         OpFlags = 1 << 19
 )
 
 type Opcode struct {
@@ -37,7 +47,6 @@ type Opcode struct {
 	Offset     uint32  // offset size from struct header
 	PtrNum     uint8   // pointer number: e.g. double pointer is 2.
 	NumBitSize uint8
-	Flags      OpFlags
 
 	Type       *runtime.Type // go type
 	Jmp        *CompiledCode // for recursive call
@@ -45,8 +54,9 @@ type Opcode struct {
 	ElemIdx    uint32        // offset to access array/slice elem
 	Length     uint32        // offset to access slice length or array length
 	Indent     uint32        // indent number
-	Size       uint32        // array/slice elem size
+	Size       uint32        // array/slice elem size, or the id of the codec with CodecFlags
 	DisplayIdx uint32        // opcode index
+	Flags      OpFlags       // next to DisplayIdx to fill the padding before DisplayKey
 	DisplayKey string        // key text to display
 }
 
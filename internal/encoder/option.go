@@ -3,9 +3,12 @@ package encoder
 import (
 	"context"
 	"io"
+	"time"
+
+	"github.com/going/json/internal/runtime"
 )
 
-type OptionFlag uint8
+type OptionFlag uint32
 
 const (
 	HTMLEscapeOption OptionFlag = 1 << iota
@@ -16,14 +19,68 @@ const (
 	ContextOption
 	NormalizeUTF8Option
 	FieldQueryOption
+	ForceIncludeEmptyOption
+	ForceOmitEmptyOption
+	NilSliceAsEmptyOption
+	NilMapAsEmptyOption
+	OmitNilPointerOption
+	PresortMapOption
+	PtrMarshalerOption
+	TimeLocationOption
+	FloatExpThresholdOption
+	Int64AsStringOption
+	NoMethodsOption
+	TagKeyOption
+	FieldNamingOption
+	RedactOption
+	AuditOption
+	CompactMarshalerOption
 )
 
+// compileOption is the set of options that change the compiled opcodes.
+// An OpcodeSet compiled with them is cached per combination in the OpcodeSet compiled without them.
+const compileOption = ForceIncludeEmptyOption | ForceOmitEmptyOption | OmitNilPointerOption | PtrMarshalerOption | Int64AsStringOption
+
 type Option struct {
 	Flag        OptionFlag
 	ColorScheme *ColorScheme
 	Context     context.Context
 	DebugOut    io.Writer
 	DebugDOTOut io.WriteCloser
+
+	// TimeLocation is the location time.Time values are converted to with TimeLocationOption.
+	TimeLocation *time.Location
+
+	// FloatExpThreshold is the lowest absolute value of the floats encoded in exponent form with FloatExpThresholdOption.
+	// If it is +Inf, all floats are encoded in plain decimal form.
+	FloatExpThreshold float64
+
+	// NoMethodTypes are the types encoded as if they had no MarshalJSON and MarshalText methods with NoMethodsOption.
+	NoMethodTypes *runtime.TypeSet
+
+	// TagKey is the key of the struct tags of the fields with TagKeyOption, instead of json.
+	TagKey string
+
+	// FieldNaming is the case of the keys of the fields not named by their tag with FieldNamingOption.
+	FieldNaming runtime.FieldNaming
+
+	// RedactPaths are the paths of the values replaced by RedactedValue with RedactOption.
+	RedactPaths *RedactPaths
+
+	// Audit receives the struct fields omitted from the output with AuditOption.
+	Audit func(runtime.AuditEvent)
+}
+
+// tagConfig returns how the struct tags of the codes compiled with o are read.
+func (o *Option) tagConfig() runtime.TagConfig {
+	var config runtime.TagConfig
+	if (o.Flag & TagKeyOption) != 0 {
+		config.Key = o.TagKey
+	}
+	if (o.Flag & FieldNamingOption) != 0 {
+		config.Naming = o.FieldNaming
+	}
+	return config
 }
 
 type EncodeFormat struct {
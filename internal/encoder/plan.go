@@ -0,0 +1,17 @@
+package encoder
+
+import (
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// Plan returns the opcodes of typ compiled without options, one per line as DebugOption prints them.
+// It is stable across runs of the same version of the encoder on the same architecture.
+func Plan(typ *runtime.Type) (string, error) {
+	codeSet, err := newCompiler().compile(uintptr(unsafe.Pointer(typ)))
+	if err != nil {
+		return "", err
+	}
+	return codeSet.NoescapeKeyCode.Dump() + "\n", nil
+}
@@ -0,0 +1,286 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/going/json/internal/errors"
+)
+
+// RedactedValue is the JSON value that replaces the values at the paths of RedactOption.
+const RedactedValue = `"[REDACTED]"`
+
+// RedactPaths are the JSON Pointers of the values redacted with RedactOption, where "*" matches any key or index.
+type RedactPaths struct {
+	// patterns are the unescaped reference tokens of the pointers.
+	patterns [][]string
+}
+
+// NewRedactPaths parses the JSON Pointers of pointers.
+func NewRedactPaths(pointers []string) (*RedactPaths, error) {
+	r := &RedactPaths{patterns: make([][]string, 0, len(pointers))}
+	for _, pointer := range pointers {
+		if pointer == "" {
+			r.patterns = append(r.patterns, nil)
+			continue
+		}
+		if pointer[0] != '/' {
+			return nil, fmt.Errorf("json: invalid JSON Pointer %q: it must be empty or start with /", pointer)
+		}
+		tokens := strings.Split(pointer[1:], "/")
+		for i, token := range tokens {
+			tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		}
+		r.patterns = append(r.patterns, tokens)
+	}
+	return r, nil
+}
+
+// Alive returns the indexes of all the patterns, which are alive at the top-level value.
+func (r *RedactPaths) Alive() []int {
+	alive := make([]int, len(r.patterns))
+	for i := range alive {
+		alive[i] = i
+	}
+	return alive
+}
+
+// Matches reports whether one of the patterns of alive, which matched the depth reference tokens of the path
+// of a value, ends at the value.
+func (r *RedactPaths) Matches(alive []int, depth int) bool {
+	for _, i := range alive {
+		if len(r.patterns[i]) == depth {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the patterns of alive that still match after the reference token key of the child of a value at depth.
+func (r *RedactPaths) Next(alive []int, depth int, key string) []int {
+	var next []int
+	for _, i := range alive {
+		pattern := r.patterns[i]
+		if len(pattern) > depth && (pattern[depth] == "*" || pattern[depth] == key) {
+			next = append(next, i)
+		}
+	}
+	return next
+}
+
+// Redact appends src, a JSON value followed by any bytes, to dst, with the values at the paths of r
+// replaced by RedactedValue. src is the output of the encoder, possibly indented or colorized.
+// It returns a *SyntaxError if src is not a JSON value.
+func Redact(dst, src []byte, r *RedactPaths) ([]byte, error) {
+	rd := &redactor{src: src, dst: dst, paths: r}
+	rd.value(r.Alive(), 0)
+	if rd.err != nil {
+		return nil, rd.err
+	}
+	return append(rd.dst, rd.src[rd.cursor:]...), nil
+}
+
+type redactor struct {
+	src    []byte
+	dst    []byte
+	cursor int
+	paths  *RedactPaths
+	err    error
+}
+
+// space copies the whitespace and the color escape sequences at the cursor.
+func (rd *redactor) space() {
+	start := rd.cursor
+	for rd.cursor < len(rd.src) {
+		switch rd.src[rd.cursor] {
+		case ' ', '\n', '\t', '\r':
+			rd.cursor++
+			continue
+		case '\x1b':
+			for rd.cursor < len(rd.src) && rd.src[rd.cursor] != 'm' {
+				rd.cursor++
+			}
+			rd.cursor++
+			continue
+		}
+		break
+	}
+	if rd.cursor > len(rd.src) {
+		rd.cursor = len(rd.src)
+	}
+	rd.dst = append(rd.dst, rd.src[start:rd.cursor]...)
+}
+
+// value copies the value at the cursor, whose path matched the patterns of alive up to depth.
+// Each loop either moves the cursor or stops with rd.err, so that it ends on any input.
+func (rd *redactor) value(alive []int, depth int) {
+	rd.space()
+	if rd.cursor >= len(rd.src) {
+		return
+	}
+	if rd.paths.Matches(alive, depth) {
+		if rd.skip() {
+			rd.dst = append(rd.dst, RedactedValue...)
+		}
+		return
+	}
+	if len(alive) == 0 {
+		rd.copyValue()
+		return
+	}
+	switch rd.src[rd.cursor] {
+	case '{':
+		rd.dst = append(rd.dst, '{')
+		rd.cursor++
+		for rd.err == nil {
+			rd.space()
+			if rd.cursor >= len(rd.src) {
+				return
+			}
+			switch rd.src[rd.cursor] {
+			case '}':
+				rd.dst = append(rd.dst, '}')
+				rd.cursor++
+				return
+			case ',':
+				rd.dst = append(rd.dst, ',')
+				rd.cursor++
+				continue
+			}
+			start := rd.cursor
+			if rd.src[start] != '"' || !rd.skip() {
+				rd.fail()
+				return
+			}
+			quoted := rd.src[start:rd.cursor]
+			rd.dst = append(rd.dst, quoted...)
+			rd.space()
+			if rd.cursor >= len(rd.src) || rd.src[rd.cursor] != ':' {
+				rd.fail()
+				return
+			}
+			rd.dst = append(rd.dst, ':')
+			rd.cursor++
+			rd.value(rd.paths.Next(alive, depth, unquoteKey(quoted)), depth+1)
+		}
+	case '[':
+		rd.dst = append(rd.dst, '[')
+		rd.cursor++
+		for idx := 0; rd.err == nil; idx++ {
+			rd.space()
+			if rd.cursor >= len(rd.src) {
+				return
+			}
+			switch rd.src[rd.cursor] {
+			case ']':
+				rd.dst = append(rd.dst, ']')
+				rd.cursor++
+				return
+			case ',':
+				rd.dst = append(rd.dst, ',')
+				rd.cursor++
+				idx--
+				continue
+			}
+			rd.value(rd.paths.Next(alive, depth, strconv.Itoa(idx)), depth+1)
+		}
+	default:
+		rd.copyValue()
+	}
+}
+
+// copyValue copies the value at the cursor as it is.
+func (rd *redactor) copyValue() {
+	start := rd.cursor
+	if rd.skip() {
+		rd.dst = append(rd.dst, rd.src[start:rd.cursor]...)
+	}
+}
+
+// skip moves the cursor past the value at the cursor, and stops at the end of the value
+// before the separator or the closing bracket of its parent.
+// It reports false with rd.err if there is no value at the cursor.
+func (rd *redactor) skip() bool {
+	start := rd.cursor
+	nesting := 0
+LOOP:
+	for rd.cursor < len(rd.src) {
+		switch rd.src[rd.cursor] {
+		case '"':
+			rd.cursor++
+			for rd.cursor < len(rd.src) && rd.src[rd.cursor] != '"' {
+				if rd.src[rd.cursor] == '\\' {
+					rd.cursor++
+				}
+				rd.cursor++
+			}
+			rd.cursor++
+			if nesting == 0 {
+				break LOOP
+			}
+		case '{', '[':
+			nesting++
+			rd.cursor++
+		case '}', ']':
+			if nesting == 0 {
+				// the closing bracket of the parent.
+				break LOOP
+			}
+			nesting--
+			rd.cursor++
+			if nesting == 0 {
+				break LOOP
+			}
+		case '\x1b':
+			if nesting == 0 {
+				break LOOP
+			}
+			// the escape sequences of the colors contain a bracket.
+			for rd.cursor < len(rd.src) && rd.src[rd.cursor] != 'm' {
+				rd.cursor++
+			}
+			rd.cursor++
+		case ',', ':', ' ', '\n', '\t', '\r':
+			if nesting == 0 {
+				break LOOP
+			}
+			rd.cursor++
+		default:
+			rd.cursor++
+		}
+	}
+	if rd.cursor > len(rd.src) {
+		rd.cursor = len(rd.src)
+	}
+	if rd.cursor == start {
+		rd.fail()
+		return false
+	}
+	return true
+}
+
+// fail stops the redactor on the invalid character at the cursor.
+func (rd *redactor) fail() {
+	if rd.err != nil {
+		return
+	}
+	if rd.cursor >= len(rd.src) {
+		rd.err = errors.ErrUnexpectedEndOfJSON("value to redact", int64(rd.cursor))
+		return
+	}
+	rd.err = errors.ErrInvalidCharacter(rd.src[rd.cursor], "value to redact", int64(rd.cursor))
+}
+
+// unquoteKey returns the key of an object in the quoted string encoded by the encoder.
+func unquoteKey(quoted []byte) string {
+	if bytes.IndexByte(quoted, '\\') < 0 && len(quoted) >= 2 {
+		return string(quoted[1 : len(quoted)-1])
+	}
+	key, err := strconv.Unquote(string(quoted))
+	if err != nil {
+		return string(quoted)
+	}
+	return key
+}
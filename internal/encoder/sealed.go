@@ -0,0 +1,37 @@
+package encoder
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/going/json/internal/errors"
+)
+
+// Seal encrypts the encoding of the values of the struct fields with the sealed tag option.
+// It is set by RegisterSealer.
+var Seal func(plaintext []byte) ([]byte, error)
+
+var errNoSealer = fmt.Errorf("no Sealer is registered ( see RegisterSealer )")
+
+// appendSealed appends v, encoded with Marshal and sealed by Seal, as a base64 string.
+// Nil pointers, maps and slices are appended as null without being sealed.
+func appendSealed(ctx *RuntimeContext, b []byte, v interface{}) ([]byte, error) {
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return AppendNull(ctx, b), nil
+		}
+	}
+	if Seal == nil {
+		return nil, errors.ErrMarshaler(reflect.TypeOf(v), errNoSealer, "Seal")
+	}
+	plaintext, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := Seal(plaintext)
+	if err != nil {
+		return nil, errors.ErrMarshaler(reflect.TypeOf(v), err, "Seal")
+	}
+	return AppendByteSlice(ctx, b, sealed), nil
+}
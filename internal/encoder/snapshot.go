@@ -0,0 +1,571 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// snapshotMagic starts a snapshot, followed by the fingerprint of the encoder that made it.
+const snapshotMagic = "going/json snapshot 1\n"
+
+// snapshotFingerprint identifies the opcodes of this version of the encoder on this platform,
+// so that a snapshot is only loaded by the encoder that made it.
+var snapshotFingerprint = func() uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d %d\n", uintptrSize, unsafe.Sizeof(Opcode{}))
+	for _, s := range opTypeStrings {
+		fmt.Fprintln(h, s)
+	}
+	return h.Sum64()
+}()
+
+// typeGraph is the types reachable from a root type through the elements, the keys and the fields of the types,
+// in breadth-first order. The types of the opcodes of a snapshot are stored as their indexes in the graph of the root type.
+type typeGraph struct {
+	types []reflect.Type
+	index map[reflect.Type]int
+}
+
+func newTypeGraph(root reflect.Type) *typeGraph {
+	g := &typeGraph{index: map[reflect.Type]int{}}
+	g.add(root)
+	for i := 0; i < len(g.types); i++ {
+		t := g.types[i]
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Chan:
+			g.add(t.Elem())
+		case reflect.Map:
+			g.add(t.Key())
+			g.add(t.Elem())
+		case reflect.Struct:
+			for j := 0; j < t.NumField(); j++ {
+				g.add(t.Field(j).Type)
+			}
+		}
+	}
+	return g
+}
+
+func (g *typeGraph) add(t reflect.Type) {
+	if _, exists := g.index[t]; !exists {
+		g.index[t] = len(g.types)
+		g.types = append(g.types, t)
+	}
+}
+
+// ref returns the reference of typ in a snapshot: 0 for nil, and the index of typ in the graph, or of the type
+// it points to, otherwise. It reports false if typ is not in the graph.
+func (g *typeGraph) ref(typ *runtime.Type) (uint64, bool) {
+	if typ == nil {
+		return 0, true
+	}
+	t := runtime.RType2Type(typ)
+	if i, exists := g.index[t]; exists {
+		return uint64(i)<<1 + 1, true
+	}
+	if t.Kind() == reflect.Ptr {
+		if i, exists := g.index[t.Elem()]; exists {
+			return uint64(i)<<1 | 1 + 1, true
+		}
+	}
+	return 0, false
+}
+
+func (g *typeGraph) typeOf(ref uint64) (*runtime.Type, bool) {
+	if ref == 0 {
+		return nil, true
+	}
+	ref--
+	i := ref >> 1
+	if i >= uint64(len(g.types)) {
+		return nil, false
+	}
+	t := g.types[i]
+	if ref&1 != 0 {
+		t = reflect.PtrTo(t)
+	}
+	return runtime.Type2RType(t), true
+}
+
+// hash returns the hash of the layout of the types of the graph, of their struct tags and methods,
+// and of the zero checkers registered for them, which are all the compile of the root type depends on.
+func (g *typeGraph) hash() uint64 {
+	var b []byte
+	for _, t := range g.types {
+		b = binary.AppendUvarint(b, uint64(t.Kind()))
+		b = binary.AppendUvarint(b, uint64(t.Size()))
+		b = appendSnapshotString(b, t.PkgPath())
+		b = appendSnapshotString(b, t.String())
+		ptr := reflect.PtrTo(t)
+		var methods uint64
+		for i, implements := range []bool{
+			t.Implements(marshalJSONType), t.Implements(marshalJSONContextType), t.Implements(marshalTextType), t.Implements(isZeroerType),
+			ptr.Implements(marshalJSONType), ptr.Implements(marshalJSONContextType), ptr.Implements(marshalTextType), ptr.Implements(isZeroerType),
+			hasZeroChecker(runtime.Type2RType(t)),
+		} {
+			if implements {
+				methods |= 1 << i
+			}
+		}
+		b = binary.AppendUvarint(b, methods)
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Chan:
+			b = binary.AppendUvarint(b, uint64(g.index[t.Elem()]))
+		case reflect.Array:
+			b = binary.AppendUvarint(b, uint64(g.index[t.Elem()]))
+			b = binary.AppendUvarint(b, uint64(t.Len()))
+		case reflect.Map:
+			b = binary.AppendUvarint(b, uint64(g.index[t.Key()]))
+			b = binary.AppendUvarint(b, uint64(g.index[t.Elem()]))
+		case reflect.Struct:
+			b = binary.AppendUvarint(b, uint64(t.NumField()))
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				b = appendSnapshotString(b, f.Name)
+				b = appendSnapshotString(b, f.PkgPath)
+				b = binary.AppendUvarint(b, uint64(f.Offset))
+				b = appendSnapshotString(b, string(f.Tag))
+				b = appendSnapshotBool(b, f.Anonymous)
+				b = binary.AppendUvarint(b, uint64(g.index[f.Type]))
+			}
+		}
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// snapshotName is the name of a type in a snapshot. Types of the same name are told apart by the hash of their graph.
+func snapshotName(t reflect.Type) string {
+	if t.Name() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// AppendSnapshot compiles types and appends the snapshot of their opcodes to b.
+func AppendSnapshot(b []byte, types []*runtime.Type) ([]byte, error) {
+	b = append(b, snapshotMagic...)
+	b = binary.LittleEndian.AppendUint64(b, snapshotFingerprint)
+	b = binary.AppendUvarint(b, uint64(len(types)))
+	for _, typ := range types {
+		codeSet, err := newCompiler().compile(uintptr(unsafe.Pointer(typ)))
+		if err != nil {
+			return nil, err
+		}
+		t := runtime.RType2Type(typ)
+		g := newTypeGraph(t)
+		body, err := appendSnapshotBody(nil, g, codeSet)
+		if err != nil {
+			return nil, fmt.Errorf("json: cannot snapshot %s: %w", t, err)
+		}
+		b = appendSnapshotString(b, snapshotName(t))
+		b = binary.LittleEndian.AppendUint64(b, g.hash())
+		b = binary.AppendUvarint(b, uint64(len(body)))
+		b = append(b, body...)
+	}
+	return b, nil
+}
+
+// appendSnapshotBody appends the opcodes of codeSet with the key escaping and without it, and the recursive opcodes
+// they jump to. The opcodes are numbered in the order of their sequences, and the pointers between them are
+// stored as the numbers plus one, zero being nil.
+func appendSnapshotBody(b []byte, g *typeGraph, codeSet *OpcodeSet) ([]byte, error) {
+	var (
+		codes   []*Opcode
+		codeIDs = map[*Opcode]uint64{}
+		jmps    []*CompiledCode
+		jmpIDs  = map[*CompiledCode]uint64{}
+	)
+	addSequence := func(head *Opcode) {
+		for c := head; c != nil; c = c.IterNext() {
+			if _, exists := codeIDs[c]; exists {
+				return
+			}
+			codeIDs[c] = uint64(len(codes)) + 1
+			codes = append(codes, c)
+			if c.IsEnd() {
+				return
+			}
+		}
+	}
+	addSequence(codeSet.NoescapeKeyCode)
+	addSequence(codeSet.EscapeKeyCode)
+	for i := 0; i < len(codes); i++ {
+		c := codes[i]
+		for _, p := range []*Opcode{c.Next, c.End, c.NextField} {
+			if _, exists := codeIDs[p]; p != nil && !exists {
+				addSequence(p)
+			}
+		}
+		if c.Jmp != nil {
+			if _, exists := jmpIDs[c.Jmp]; !exists {
+				jmpIDs[c.Jmp] = uint64(len(jmps)) + 1
+				jmps = append(jmps, c.Jmp)
+				addSequence(c.Jmp.Code)
+			}
+		}
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(jmps)))
+	for _, jmp := range jmps {
+		b = binary.AppendUvarint(b, codeIDs[jmp.Code])
+		b = appendSnapshotBool(b, jmp.Linked)
+		b = binary.AppendUvarint(b, uint64(jmp.CurLen))
+		b = binary.AppendUvarint(b, uint64(jmp.NextLen))
+	}
+	b = binary.AppendUvarint(b, uint64(len(codes)))
+	for _, c := range codes {
+		if c.FieldQuery != nil {
+			return nil, fmt.Errorf("field query")
+		}
+		typeRef, ok := g.ref(c.Type)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not reachable", c.Type)
+		}
+		b = binary.AppendUvarint(b, uint64(c.Op))
+		b = binary.AppendUvarint(b, uint64(c.Idx))
+		b = binary.AppendUvarint(b, codeIDs[c.Next])
+		b = binary.AppendUvarint(b, codeIDs[c.End])
+		b = binary.AppendUvarint(b, codeIDs[c.NextField])
+		b = appendSnapshotString(b, c.Key)
+		b = binary.AppendUvarint(b, uint64(c.Offset))
+		b = append(b, c.PtrNum, c.NumBitSize)
+		b = binary.AppendUvarint(b, typeRef)
+		b = binary.AppendUvarint(b, jmpIDs[c.Jmp])
+		b = binary.AppendUvarint(b, uint64(c.ElemIdx))
+		b = binary.AppendUvarint(b, uint64(c.Length))
+		b = binary.AppendUvarint(b, uint64(c.Indent))
+		b = binary.AppendUvarint(b, uint64(c.DisplayIdx))
+		b = binary.AppendUvarint(b, uint64(c.Flags))
+		b = appendSnapshotString(b, c.DisplayKey)
+		if (c.Flags & CodecFlags) != 0 {
+			// the ids of the codecs depend on the order of registration, so the codec is stored by name.
+			b = appendSnapshotString(b, codecName(c.Size))
+		} else {
+			b = binary.AppendUvarint(b, uint64(c.Size))
+		}
+	}
+	b = binary.AppendUvarint(b, codeIDs[codeSet.NoescapeKeyCode])
+	b = binary.AppendUvarint(b, codeIDs[codeSet.EscapeKeyCode])
+	return b, nil
+}
+
+func appendSnapshotString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendSnapshotBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// snapshotReader reads a snapshot, keeping the first error.
+// It reads a string, so that the strings of the opcodes share its memory instead of being allocated one by one.
+type snapshotReader struct {
+	data string
+	err  error
+}
+
+func (r *snapshotReader) fail() {
+	if r.err == nil {
+		r.err = fmt.Errorf("json: malformed snapshot")
+	}
+	r.data = ""
+}
+
+func (r *snapshotReader) uvarint() uint64 {
+	var v uint64
+	for i := 0; i < len(r.data) && i < binary.MaxVarintLen64; i++ {
+		c := r.data[i]
+		if c < 0x80 {
+			r.data = r.data[i+1:]
+			return v | uint64(c)<<(7*i)
+		}
+		v |= uint64(c&0x7f) << (7 * i)
+	}
+	r.fail()
+	return 0
+}
+
+func (r *snapshotReader) uint32() uint32 {
+	v := r.uvarint()
+	if v > 1<<32-1 {
+		r.fail()
+	}
+	return uint32(v)
+}
+
+func (r *snapshotReader) string() string {
+	return r.next(r.uvarint())
+}
+
+func (r *snapshotReader) next(n uint64) string {
+	if n > uint64(len(r.data)) {
+		r.fail()
+		return ""
+	}
+	s := r.data[:n]
+	r.data = r.data[n:]
+	return s
+}
+
+func (r *snapshotReader) byte() byte {
+	s := r.next(1)
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+func (r *snapshotReader) uint64() uint64 {
+	s := r.next(8)
+	if s == "" {
+		return 0
+	}
+	return binary.LittleEndian.Uint64([]byte(s))
+}
+
+// LoadSnapshot loads the opcodes of types from snapshot into the cache of the compiled types.
+// The types whose graph changed since the snapshot was made, and the types compiled already, are skipped.
+// It returns the number of types loaded.
+func LoadSnapshot(snapshot []byte, types []*runtime.Type) (int, error) {
+	r := &snapshotReader{data: string(snapshot)}
+	if r.next(uint64(len(snapshotMagic))) != snapshotMagic {
+		return 0, fmt.Errorf("json: not a snapshot")
+	}
+	if r.uint64() != snapshotFingerprint {
+		return 0, fmt.Errorf("json: snapshot made by another version of the encoder or for another platform")
+	}
+	type entry struct {
+		hash uint64
+		body string
+	}
+	entries := map[string][]entry{}
+	for n := r.uvarint(); n > 0 && r.err == nil; n-- {
+		name := r.string()
+		hash := r.uint64()
+		body := r.string()
+		entries[name] = append(entries[name], entry{hash: hash, body: body})
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	initEncoder()
+	loaded := 0
+	for _, typ := range types {
+		t := runtime.RType2Type(typ)
+		candidates := entries[snapshotName(t)]
+		if len(candidates) == 0 {
+			continue
+		}
+		g := newTypeGraph(t)
+		hash := g.hash()
+		for _, e := range candidates {
+			if e.hash != hash {
+				continue
+			}
+			codeSet, err := loadSnapshotBody(&snapshotReader{data: e.body}, g, typ)
+			if err != nil {
+				return loaded, err
+			}
+			if codeSet != nil && storeCodeSet(uintptr(unsafe.Pointer(typ)), codeSet) {
+				loaded++
+			}
+			break
+		}
+	}
+	return loaded, nil
+}
+
+// loadSnapshotBody returns the OpcodeSet of typ read from a body written by appendSnapshotBody.
+// It returns nil if a codec of the snapshot is not registered for the same type anymore.
+func loadSnapshotBody(r *snapshotReader, g *typeGraph, typ *runtime.Type) (*OpcodeSet, error) {
+	jmpNum := r.uvarint()
+	if jmpNum > uint64(len(r.data)) {
+		return nil, fmt.Errorf("json: malformed snapshot")
+	}
+	jmps := make([]CompiledCode, jmpNum)
+	jmpCodes := make([]uint64, jmpNum)
+	for i := range jmps {
+		jmpCodes[i] = r.uvarint()
+		jmps[i].Linked = r.byte() != 0
+		jmps[i].CurLen = uintptr(r.uvarint())
+		jmps[i].NextLen = uintptr(r.uvarint())
+	}
+	codeNum := r.uvarint()
+	if codeNum == 0 || codeNum > uint64(len(r.data)) {
+		return nil, fmt.Errorf("json: malformed snapshot")
+	}
+	codes := make([]Opcode, codeNum)
+	codeOf := func(id uint64) *Opcode {
+		if id == 0 {
+			return nil
+		}
+		if id > codeNum {
+			r.fail()
+			return nil
+		}
+		return &codes[id-1]
+	}
+	for i := range codes {
+		if r.err != nil {
+			return nil, r.err
+		}
+		c := &codes[i]
+		c.Op = OpType(r.uvarint())
+		c.Idx = r.uint32()
+		c.Next = codeOf(r.uvarint())
+		c.End = codeOf(r.uvarint())
+		c.NextField = codeOf(r.uvarint())
+		c.Key = r.string()
+		c.Offset = r.uint32()
+		c.PtrNum = r.byte()
+		c.NumBitSize = r.byte()
+		codeType, ok := g.typeOf(r.uvarint())
+		if !ok {
+			r.fail()
+		}
+		c.Type = codeType
+		if jmp := r.uvarint(); jmp > jmpNum {
+			r.fail()
+		} else if jmp != 0 {
+			c.Jmp = &jmps[jmp-1]
+		}
+		c.ElemIdx = r.uint32()
+		c.Length = r.uint32()
+		c.Indent = r.uint32()
+		c.DisplayIdx = r.uint32()
+		c.Flags = OpFlags(r.uint32())
+		c.DisplayKey = r.string()
+		if (c.Flags & CodecFlags) != 0 {
+			id, err := codecID(r.string(), c.Type)
+			if err != nil {
+				return nil, nil
+			}
+			c.Size = id
+		} else {
+			c.Size = r.uint32()
+		}
+	}
+	for i := range jmps {
+		jmps[i].Code = codeOf(jmpCodes[i])
+	}
+	noescapeKeyCode := codeOf(r.uvarint())
+	escapeKeyCode := codeOf(r.uvarint())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if noescapeKeyCode == nil || escapeKeyCode == nil || len(r.data) != 0 {
+		return nil, fmt.Errorf("json: malformed snapshot")
+	}
+	codeLength, err := validateSnapshotCodes(codes)
+	if err != nil {
+		return nil, err
+	}
+	if n := noescapeKeyCode.TotalLength(); codeLength < n {
+		codeLength = n
+	}
+	interfaceNoescapeKeyCode := copyToInterfaceOpcode(noescapeKeyCode)
+	interfaceEscapeKeyCode := copyToInterfaceOpcode(escapeKeyCode)
+	return &OpcodeSet{
+		Type:                     typ,
+		NoescapeKeyCode:          noescapeKeyCode,
+		EscapeKeyCode:            escapeKeyCode,
+		InterfaceNoescapeKeyCode: interfaceNoescapeKeyCode,
+		InterfaceEscapeKeyCode:   interfaceEscapeKeyCode,
+		CodeLength:               codeLength,
+		EndCode:                  ToEndCode(interfaceNoescapeKeyCode),
+		QueryCache:               map[string]*OpcodeSet{},
+		VariantCache:             map[OptionFlag]*OpcodeSet{},
+		NoMethodsCache:           map[*runtime.TypeSet]*OpcodeSet{},
+		TagConfigCache:           map[runtime.TagConfig]*OpcodeSet{},
+	}, nil
+}
+
+// validateSnapshotCodes checks the opcodes read from a snapshot, so that a corrupted snapshot is an error
+// rather than a VM running unknown opcodes, indexing past its pointers or looping forever.
+// It returns the number of pointers the opcodes use.
+// The offsets of the fields are not checked against their types, which is why a snapshot must come from a trusted source.
+func validateSnapshotCodes(codes []Opcode) (int, error) {
+	// the compiler assigns at most a few pointers to each opcode.
+	maxIdx := uint64(4*len(codes)) * uintptrSize
+	codeLength := 0
+	for i := range codes {
+		c := &codes[i]
+		if int(c.Op) >= len(opTypeStrings) {
+			return 0, fmt.Errorf("json: malformed snapshot: unknown opcode %d", c.Op)
+		}
+		for _, idx := range []uint32{c.Idx, c.ElemIdx} {
+			if idx%uintptrSize != 0 || uint64(idx) > maxIdx {
+				return 0, fmt.Errorf("json: malformed snapshot: invalid index %d of %s", idx, c.Op)
+			}
+			if n := int(idx/uintptrSize) + 1; codeLength < n {
+				codeLength = n
+			}
+		}
+	}
+
+	// the sequences of opcodes must end: the elements loop back through Next, which IterNext skips,
+	// and the recursions go through Jmp.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]uint8, len(codes))
+	indexOf := func(c *Opcode) int {
+		return int((uintptr(unsafe.Pointer(c)) - uintptr(unsafe.Pointer(&codes[0]))) / unsafe.Sizeof(Opcode{}))
+	}
+	var path []int
+	for i := range codes {
+		path = path[:0]
+		for j := i; state[j] == unvisited; {
+			state[j] = visiting
+			path = append(path, j)
+			next := codes[j].IterNext()
+			if next == nil {
+				break
+			}
+			j = indexOf(next)
+			if state[j] == visiting {
+				return 0, fmt.Errorf("json: malformed snapshot: opcode %d loops", j)
+			}
+		}
+		for _, j := range path {
+			state[j] = visited
+		}
+	}
+	return codeLength, nil
+}
+
+// codecName returns the name of the codec of id.
+func codecName(id uint32) string {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	for name, codecID := range codecIDs {
+		if codecID == id {
+			return name
+		}
+	}
+	return ""
+}
+
+// storeCodeSet stores codeSet as the compiled OpcodeSet of the type of typeptr, unless it is compiled already.
+// It reports whether codeSet is stored.
+func storeCodeSet(typeptr uintptr, codeSet *OpcodeSet) bool {
+	if typeptr > typeAddr.MaxTypeAddr || typeptr < typeAddr.BaseTypeAddr {
+		return opcodeMapShardOf(typeptr).store(typeptr, codeSet) == codeSet
+	}
+	index := (typeptr - typeAddr.BaseTypeAddr) >> typeAddr.AddrShift
+	return atomic.CompareAndSwapPointer(&cachedOpcodeSets[index], nil, unsafe.Pointer(codeSet))
+}
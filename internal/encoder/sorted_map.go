@@ -0,0 +1,171 @@
+package encoder
+
+import (
+	"encoding"
+	"reflect"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
+)
+
+// MapEntry is a pair of pointers to a key and a value stored in a map.
+// keyStart and keyEnd are the range of the encoded key in MapContext.Buf.
+type MapEntry struct {
+	Key      unsafe.Pointer
+	Value    unsafe.Pointer
+	keyStart int
+	keyEnd   int
+}
+
+type mapEntrySorter struct {
+	entries []MapEntry
+	buf     []byte
+}
+
+func (s *mapEntrySorter) Len() int {
+	return len(s.entries)
+}
+
+func (s *mapEntrySorter) Less(i, j int) bool {
+	ei, ej := s.entries[i], s.entries[j]
+	return string(s.buf[ei.keyStart:ei.keyEnd]) < string(s.buf[ej.keyStart:ej.keyEnd])
+}
+
+func (s *mapEntrySorter) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+}
+
+// CanPresortMap reports whether the entries of a map of mapType can be sorted by PresortEntries.
+func CanPresortMap(mapType *runtime.Type) bool {
+	keyType := mapType.Key()
+	if keyType.Implements(marshalTextType) || runtime.PtrTo(keyType).Implements(marshalTextType) {
+		return keyType.Kind() != reflect.Ptr
+	}
+	switch keyType.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// PresortEntries collects the entries of the map m and sorts them by their encoded keys,
+// so that the VM can encode them in order directly instead of buffering every encoded key and value.
+// Only the encoded keys are kept during the encoding of the map.
+// c must be created by NewMapContext for an unordered map.
+func (c *MapContext) PresortEntries(ctx *RuntimeContext, mapType *runtime.Type, m unsafe.Pointer) error {
+	keyType := mapType.Key()
+	if cap(c.Entries) < c.Len {
+		c.Entries = make([]MapEntry, 0, c.Len)
+	}
+	entries := c.Entries[:0]
+	buf := c.Buf[:0]
+	var iter mapIter
+	MapIterInit(mapType, m, &iter)
+	for i := 0; i < c.Len; i++ {
+		key := MapIterKey(&iter)
+		start := len(buf)
+		b, err := appendMapSortKey(ctx, buf, keyType, key)
+		if err != nil {
+			return err
+		}
+		buf = b
+		entries = append(entries, MapEntry{
+			Key:      key,
+			Value:    MapIterValue(&iter),
+			keyStart: start,
+			keyEnd:   len(buf),
+		})
+		MapIterNext(&iter)
+	}
+	sort.Sort(&mapEntrySorter{entries: entries, buf: buf})
+	c.Entries = entries
+	c.Buf = buf
+	c.Presorted = true
+	return nil
+}
+
+// IterKey returns the key of the current entry.
+func (c *MapContext) IterKey() unsafe.Pointer {
+	if c.Presorted {
+		return c.Entries[c.Idx].Key
+	}
+	return MapIterKey(&c.Iter)
+}
+
+// IterValue returns the value of the current entry.
+func (c *MapContext) IterValue() unsafe.Pointer {
+	if c.Presorted {
+		return c.Entries[c.Idx].Value
+	}
+	return MapIterValue(&c.Iter)
+}
+
+// IterNext advances the map iterator. Presorted entries are indexed by Idx instead.
+func (c *MapContext) IterNext() {
+	if !c.Presorted {
+		MapIterNext(&c.Iter)
+	}
+}
+
+// appendMapSortKey appends the key pointed to by p in the same form as the key opcodes write it,
+// so that presorted entries are in the same order as sorted by the encoded keys.
+func appendMapSortKey(ctx *RuntimeContext, b []byte, typ *runtime.Type, p unsafe.Pointer) ([]byte, error) {
+	if typ.Implements(marshalTextType) || runtime.PtrTo(typ).Implements(marshalTextType) {
+		v := reflect.NewAt(runtime.RType2Type(typ), p).Elem().Interface()
+		if !typ.Implements(marshalTextType) {
+			// copy the key, since the method with pointer receiver must not modify the key stored in the map.
+			rv := reflect.New(runtime.RType2Type(typ))
+			rv.Elem().Set(reflect.ValueOf(v))
+			v = rv.Interface()
+		}
+		text, err := v.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, errors.ErrMarshaler(reflect.TypeOf(v), err, "MarshalText")
+		}
+		return AppendString(ctx, b, string(text)), nil
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return AppendString(ctx, b, *(*string)(p)), nil
+	case reflect.Int:
+		return appendQuotedInt(b, int64(*(*int)(p))), nil
+	case reflect.Int8:
+		return appendQuotedInt(b, int64(*(*int8)(p))), nil
+	case reflect.Int16:
+		return appendQuotedInt(b, int64(*(*int16)(p))), nil
+	case reflect.Int32:
+		return appendQuotedInt(b, int64(*(*int32)(p))), nil
+	case reflect.Int64:
+		return appendQuotedInt(b, *(*int64)(p)), nil
+	case reflect.Uint:
+		return appendQuotedUint(b, uint64(*(*uint)(p))), nil
+	case reflect.Uint8:
+		return appendQuotedUint(b, uint64(*(*uint8)(p))), nil
+	case reflect.Uint16:
+		return appendQuotedUint(b, uint64(*(*uint16)(p))), nil
+	case reflect.Uint32:
+		return appendQuotedUint(b, uint64(*(*uint32)(p))), nil
+	case reflect.Uint64:
+		return appendQuotedUint(b, *(*uint64)(p)), nil
+	case reflect.Uintptr:
+		return appendQuotedUint(b, uint64(*(*uintptr)(p))), nil
+	}
+	return nil, &errors.UnsupportedTypeError{Type: runtime.RType2Type(typ)}
+}
+
+func appendQuotedInt(b []byte, v int64) []byte {
+	b = append(b, '"')
+	b = strconv.AppendInt(b, v, 10)
+	return append(b, '"')
+}
+
+func appendQuotedUint(b []byte, v uint64) []byte {
+	b = append(b, '"')
+	b = strconv.AppendUint(b, v, 10)
+	return append(b, '"')
+}
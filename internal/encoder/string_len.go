@@ -0,0 +1,52 @@
+package encoder
+
+// EscapedLen returns the length of s encoded as a JSON string by AppendString with flag, including the quotes,
+// and whether any character of s is escaped or replaced. It does not allocate.
+func EscapedLen(flag OptionFlag, s string) (int, bool) {
+	var table *[256]bool
+	normalize := flag&NormalizeUTF8Option != 0
+	switch {
+	case flag&HTMLEscapeOption != 0 && normalize:
+		table = &needEscapeHTMLNormalizeUTF8
+	case flag&HTMLEscapeOption != 0:
+		table = &needEscapeHTML
+	case normalize:
+		table = &needEscapeNormalizeUTF8
+	default:
+		table = &needEscape
+	}
+	n := len(s) + 2
+	escaped := false
+	for j := 0; j < len(s); {
+		c := s[j]
+		if !table[c] {
+			j++
+			continue
+		}
+		if c < 0x80 {
+			escaped = true
+			switch c {
+			case '\\', '"', '\n', '\r', '\t':
+				n++ // \c
+			default:
+				n += 5 // \u00XX
+			}
+			j++
+			continue
+		}
+		state, size := decodeRuneInString(s[j:])
+		switch state {
+		case runeErrorState:
+			escaped = true
+			n += 5 // \ufffd replaces the invalid byte.
+			j++
+		case lineSepState, paragraphSepState:
+			escaped = true
+			n += 3 // \u2028 and \u2029 replace 3 bytes.
+			j += 3
+		default:
+			j += size
+		}
+	}
+	return n, escaped
+}
@@ -0,0 +1,40 @@
+package encoder
+
+import (
+	"reflect"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// isTupleType reports whether typ is encoded as an array with the tuple tag option.
+func isTupleType(typ *runtime.Type) bool {
+	return toElemType(typ).Kind() == reflect.Struct
+}
+
+// marshalTuple returns the array of the fields of the struct v, or null if v is a nil pointer.
+// Each field is encoded with Marshal. tagKey is the key of the struct tags of the fields, or "" for json.
+func marshalTuple(v interface{}, tagKey string) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return []byte("null"), nil
+		}
+		rv = rv.Elem()
+	}
+	typ := rv.Type()
+	b := []byte{'['}
+	for i := 0; i < typ.NumField(); i++ {
+		if !runtime.IsTupleElemField(typ.Field(i), tagKey) {
+			continue
+		}
+		elem, err := Marshal(rv.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 1 {
+			b = append(b, ',')
+		}
+		b = append(b, elem...)
+	}
+	return append(b, ']'), nil
+}
@@ -0,0 +1,287 @@
+package encoder
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/going/json/internal/errors"
+	"github.com/going/json/internal/runtime"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// ErrWithUnsupportedPath returns err with the location in v of the type or value that caused it,
+// if err is an *errors.UnsupportedTypeError or an *errors.UnsupportedValueError.
+// The location is searched only after the encode failed, so that it costs nothing otherwise.
+func ErrWithUnsupportedPath(ctx *RuntimeContext, v interface{}, err error) error {
+	switch e := err.(type) {
+	case *errors.UnsupportedTypeError:
+		if e.Path != "" {
+			return err
+		}
+		l := &unsupportedLocator{seenTypes: map[reflect.Type]struct{}{}, tagConfig: ctx.Option.tagConfig()}
+		if !l.findType(reflect.TypeOf(v), e.Type) || len(l.parents) == 0 {
+			return err
+		}
+		located := *e
+		located.Path, located.Parents = l.path.String(), l.parents
+		return &located
+	case *errors.UnsupportedValueError:
+		if e.Path != "" {
+			return err
+		}
+		l := &unsupportedLocator{seenPtrs: map[uintptr]struct{}{}, tagConfig: ctx.Option.tagConfig()}
+		if strings.HasPrefix(e.Str, "encountered a cycle") {
+			l.isCycle = true
+		}
+		if !l.findValue(reflect.ValueOf(v)) || len(l.parents) == 0 {
+			return err
+		}
+		located := *e
+		located.Path, located.Parents = l.path.String(), l.parents
+		return &located
+	}
+	return err
+}
+
+// unsupportedLocator searches the unsupported type or value in the order it is encoded.
+type unsupportedLocator struct {
+	path    pathBuilder
+	parents []reflect.Type
+	// seenTypes are the types being searched, to stop at recursive types.
+	seenTypes map[reflect.Type]struct{}
+	// seenPtrs are the pointers, maps and slices being searched, to detect cycles.
+	seenPtrs map[uintptr]struct{}
+	// isCycle searches a cycle instead of an unsupported float.
+	isCycle bool
+	// tagConfig is how the struct tags of the fields are read.
+	tagConfig runtime.TagConfig
+}
+
+// enter adds the path element of a child of a value of typ. It returns a function to remove them.
+func (l *unsupportedLocator) enter(typ reflect.Type, elem func(*pathBuilder)) func() {
+	pathLen, parentsLen := l.path.Len(), len(l.parents)
+	l.parents = append(l.parents, typ)
+	elem(&l.path)
+	return func() {
+		l.path.Truncate(pathLen)
+		l.parents = l.parents[:parentsLen]
+	}
+}
+
+func isMarshalerType(typ reflect.Type) bool {
+	return typ.Implements(jsonMarshalerType) || typ.Implements(textMarshalerType) ||
+		(typ.Kind() != reflect.Ptr && reflect.PtrTo(typ).Implements(jsonMarshalerType))
+}
+
+func (l *unsupportedLocator) findType(typ, target reflect.Type) bool {
+	if typ == nil {
+		return false
+	}
+	if typ == target {
+		return true
+	}
+	if _, exists := l.seenTypes[typ]; exists || isMarshalerType(typ) {
+		return false
+	}
+	l.seenTypes[typ] = struct{}{}
+	defer delete(l.seenTypes, typ)
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return l.findType(typ.Elem(), target)
+	case reflect.Slice, reflect.Array:
+		leave := l.enter(typ, func(p *pathBuilder) { p.WriteString("[*]") })
+		if l.findType(typ.Elem(), target) {
+			return true
+		}
+		leave()
+	case reflect.Map:
+		if typ.Key() == target {
+			l.parents = append(l.parents, typ)
+			return true
+		}
+		leave := l.enter(typ, func(p *pathBuilder) { p.WriteString(".*") })
+		if l.findType(typ.Elem(), target) {
+			return true
+		}
+		leave()
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if runtime.IsIgnoredStructField(field, l.tagConfig.Key) {
+				continue
+			}
+			tag := runtime.StructTagFromField(field, l.tagConfig)
+			leave := l.enter(typ, func(p *pathBuilder) {
+				if !isEmbeddedField(field, tag) {
+					p.WriteKey(tag.Key)
+				}
+			})
+			if l.findType(field.Type, target) {
+				return true
+			}
+			leave()
+		}
+	}
+	return false
+}
+
+func (l *unsupportedLocator) findValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return !l.isCycle && (math.IsNaN(f) || math.IsInf(f, 0))
+	case reflect.Interface:
+		return l.findValue(v.Elem())
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return false
+		}
+		ptr := v.Pointer()
+		if _, exists := l.seenPtrs[ptr]; exists && v.Kind() != reflect.Slice {
+			return l.isCycle
+		}
+		l.seenPtrs[ptr] = struct{}{}
+		defer delete(l.seenPtrs, ptr)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return l.findValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			leave := l.enter(v.Type(), func(p *pathBuilder) { p.WriteIndex(i) })
+			if l.findValue(v.Index(i)) {
+				return true
+			}
+			leave()
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = mapKeyName(key)
+		}
+		sort.Sort(mapKeysByName{keys: keys, names: names})
+		for i, key := range keys {
+			leave := l.enter(v.Type(), func(p *pathBuilder) { p.WriteKey(names[i]) })
+			if l.findValue(v.MapIndex(key)) {
+				return true
+			}
+			leave()
+		}
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if runtime.IsIgnoredStructField(field, l.tagConfig.Key) {
+				continue
+			}
+			tag := runtime.StructTagFromField(field, l.tagConfig)
+			leave := l.enter(typ, func(p *pathBuilder) {
+				if !isEmbeddedField(field, tag) {
+					p.WriteKey(tag.Key)
+				}
+			})
+			if l.findValue(v.Field(i)) {
+				return true
+			}
+			leave()
+		}
+	}
+	return false
+}
+
+// isEmbeddedField reports whether the fields of field are encoded as the fields of the struct containing it.
+func isEmbeddedField(field reflect.StructField, tag *runtime.StructTag) bool {
+	if !tag.IsFlattened() {
+		return false
+	}
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct
+}
+
+func mapKeyName(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if key.CanInterface() {
+		if m, ok := key.Interface().(encoding.TextMarshaler); ok {
+			if text, err := m.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10)
+	}
+	return ""
+}
+
+type mapKeysByName struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (m mapKeysByName) Len() int           { return len(m.keys) }
+func (m mapKeysByName) Less(i, j int) bool { return m.names[i] < m.names[j] }
+func (m mapKeysByName) Swap(i, j int) {
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+	m.names[i], m.names[j] = m.names[j], m.names[i]
+}
+
+// pathBuilder builds a location of the form of $.a[0].b, the same as Path.
+type pathBuilder struct {
+	b []byte
+}
+
+func (p *pathBuilder) Len() int { return len(p.b) }
+
+func (p *pathBuilder) Truncate(n int) { p.b = p.b[:n] }
+
+func (p *pathBuilder) WriteString(s string) { p.b = append(p.b, s...) }
+
+func (p *pathBuilder) WriteIndex(i int) { p.b = AppendPathIndex(p.b, i) }
+
+func (p *pathBuilder) WriteKey(key string) { p.b = AppendPathKey(p.b, key) }
+
+func (p *pathBuilder) String() string {
+	return "$" + string(p.b)
+}
+
+// AppendPathIndex appends the path element of the index i of an array, such as [0], to b.
+func AppendPathIndex(b []byte, i int) []byte {
+	return append(strconv.AppendInt(append(b, '['), int64(i), 10), ']')
+}
+
+// AppendPathKey appends the path element of the key of an object to b, such as .a,
+// or ['a.b'] for a key that has characters of the syntax of a path.
+func AppendPathKey(b []byte, key string) []byte {
+	if strings.ContainsAny(key, ".[]$*'\"") {
+		return append(append(append(b, "['"...), key...), "']"...)
+	}
+	return append(append(b, '.'), key...)
+}
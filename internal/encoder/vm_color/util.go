@@ -205,7 +205,14 @@ func appendMapEnd(_ *encoder.RuntimeContext, _ *encoder.Opcode, b []byte) []byte
 }
 
 func appendMarshalJSON(ctx *encoder.RuntimeContext, code *encoder.Opcode, b []byte, v interface{}) ([]byte, error) {
-	return encoder.AppendMarshalJSON(ctx, code, b, v)
+	start := len(b)
+	bb, err := encoder.AppendMarshalJSON(ctx, code, b, v)
+	if err != nil {
+		return nil, err
+	}
+	// colorize the output of MarshalJSON like the surrounding values. MarshalBuf is free to reuse after the call.
+	ctx.MarshalBuf = append(ctx.MarshalBuf[:0], bb[start:]...)
+	return encoder.AppendColorized(bb[:start], ctx.MarshalBuf, ctx.Option.ColorScheme), nil
 }
 
 func appendMarshalText(ctx *encoder.RuntimeContext, code *encoder.Opcode, b []byte, v interface{}) ([]byte, error) {
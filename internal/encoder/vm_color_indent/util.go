@@ -236,7 +236,14 @@ func appendObjectEnd(ctx *encoder.RuntimeContext, code *encoder.Opcode, b []byte
 }
 
 func appendMarshalJSON(ctx *encoder.RuntimeContext, code *encoder.Opcode, b []byte, v interface{}) ([]byte, error) {
-	return encoder.AppendMarshalJSONIndent(ctx, code, b, v)
+	start := len(b)
+	bb, err := encoder.AppendMarshalJSONIndent(ctx, code, b, v)
+	if err != nil {
+		return nil, err
+	}
+	// colorize the output of MarshalJSON like the surrounding values. MarshalBuf is free to reuse after the call.
+	ctx.MarshalBuf = append(ctx.MarshalBuf[:0], bb[start:]...)
+	return encoder.AppendColorized(bb[:start], ctx.MarshalBuf, ctx.Option.ColorScheme), nil
 }
 
 func appendMarshalText(ctx *encoder.RuntimeContext, code *encoder.Opcode, b []byte, v interface{}) ([]byte, error) {
@@ -293,5 +300,5 @@ func appendArrayElemIndent(ctx *encoder.RuntimeContext, code *encoder.Opcode, b
 }
 
 func appendMapKeyIndent(ctx *encoder.RuntimeContext, code *encoder.Opcode, b []byte) []byte {
-	return appendIndent(ctx, b, code.Indent)
+	return appendIndent(ctx, b, code.Indent+1)
 }
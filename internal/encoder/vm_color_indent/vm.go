@@ -195,6 +195,11 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 				typ = iface.typ
 			}
 			if ifacePtr == nil {
+				if typ != nil && typ.Kind() == reflect.Map && (ctx.Option.Flag&encoder.NilMapAsEmptyOption) != 0 {
+					b = appendEmptyObject(ctx, b)
+					code = code.Next
+					break
+				}
 				isDirectedNil := typ != nil && typ.Kind() == reflect.Struct && !runtime.IfaceIndir(typ)
 				if !isDirectedNil {
 					b = appendNullComma(ctx, b)
@@ -313,7 +318,11 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			p := load(ctxptr, code.Idx)
 			slice := ptrToSlice(p)
 			if p == 0 || slice.Data == nil {
-				b = appendNullComma(ctx, b)
+				if p != 0 && (ctx.Option.Flag&encoder.NilSliceAsEmptyOption) != 0 {
+					b = appendEmptyArray(ctx, b)
+				} else {
+					b = appendNullComma(ctx, b)
+				}
 				code = code.End.Next
 				break
 			}
@@ -329,6 +338,13 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 				code = code.End.Next
 			}
 		case encoder.OpSliceElem:
+			if ctx.FlushWriter != nil {
+				flushed, err := ctx.FlushBuffer(b)
+				if err != nil {
+					return nil, err
+				}
+				b = flushed
+			}
 			idx := load(ctxptr, code.ElemIdx)
 			length := load(ctxptr, code.Length)
 			idx++
@@ -369,6 +385,13 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 				code = code.End.Next
 			}
 		case encoder.OpArrayElem:
+			if ctx.FlushWriter != nil {
+				flushed, err := ctx.FlushBuffer(b)
+				if err != nil {
+					return nil, err
+				}
+				b = flushed
+			}
 			idx := load(ctxptr, code.ElemIdx)
 			idx++
 			if idx < uintptr(code.Length) {
@@ -385,7 +408,12 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 		case encoder.OpMapPtr:
 			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
 			if p == 0 {
-				b = appendNullComma(ctx, b)
+				if (ctx.Option.Flag&encoder.NilMapAsEmptyOption) != 0 && code.PtrNum > 0 && loadNPtr(ctxptr, code.Idx, code.PtrNum-1) != 0 {
+					// a pointer to a nil map, such as the address of an element of a slice.
+					b = appendEmptyObject(ctx, b)
+				} else {
+					b = appendNullComma(ctx, b)
+				}
 				code = code.End.Next
 				break
 			}
@@ -394,7 +422,11 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 		case encoder.OpMap:
 			p := load(ctxptr, code.Idx)
 			if p == 0 {
-				b = appendNullComma(ctx, b)
+				if (ctx.Option.Flag & encoder.NilMapAsEmptyOption) != 0 {
+					b = appendEmptyObject(ctx, b)
+				} else {
+					b = appendNullComma(ctx, b)
+				}
 				code = code.End.Next
 				break
 			}
@@ -407,28 +439,43 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			}
 			b = appendStructHead(ctx, b)
 			unorderedMap := (ctx.Option.Flag & encoder.UnorderedMapOption) != 0
-			mapCtx := encoder.NewMapContext(mlen, unorderedMap)
-			mapiterinit(code.Type, uptr, &mapCtx.Iter)
+			presortMap := !unorderedMap && (ctx.Option.Flag&encoder.PresortMapOption) != 0 && encoder.CanPresortMap(code.Type)
+			mapCtx := encoder.NewMapContext(mlen, unorderedMap || presortMap)
+			if presortMap {
+				if err := mapCtx.PresortEntries(ctx, code.Type, uptr); err != nil {
+					return nil, err
+				}
+			} else {
+				mapiterinit(code.Type, uptr, &mapCtx.Iter)
+			}
 			store(ctxptr, code.Idx, uintptr(unsafe.Pointer(mapCtx)))
 			ctx.KeepRefs = append(ctx.KeepRefs, unsafe.Pointer(mapCtx))
-			if unorderedMap {
+			if unorderedMap || presortMap {
 				b = appendMapKeyIndent(ctx, code.Next, b)
 			} else {
+				ctx.SortedMaps++
 				mapCtx.Start = len(b)
 				mapCtx.First = len(b)
 			}
-			key := mapiterkey(&mapCtx.Iter)
+			key := mapCtx.IterKey()
 			store(ctxptr, code.Next.Idx, uintptr(key))
 			code = code.Next
 		case encoder.OpMapKey:
+			if ctx.FlushWriter != nil {
+				flushed, err := ctx.FlushBuffer(b)
+				if err != nil {
+					return nil, err
+				}
+				b = flushed
+			}
 			mapCtx := (*encoder.MapContext)(ptrToUnsafePtr(load(ctxptr, code.Idx)))
 			idx := mapCtx.Idx
 			idx++
-			if (ctx.Option.Flag & encoder.UnorderedMapOption) != 0 {
+			if (ctx.Option.Flag&encoder.UnorderedMapOption) != 0 || mapCtx.Presorted {
 				if idx < mapCtx.Len {
 					b = appendMapKeyIndent(ctx, code, b)
 					mapCtx.Idx = int(idx)
-					key := mapiterkey(&mapCtx.Iter)
+					key := mapCtx.IterKey()
 					store(ctxptr, code.Next.Idx, uintptr(key))
 					code = code.Next
 				} else {
@@ -441,7 +488,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 				if idx < mapCtx.Len {
 					mapCtx.Idx = int(idx)
 					mapCtx.Start = len(b)
-					key := mapiterkey(&mapCtx.Iter)
+					key := mapCtx.IterKey()
 					store(ctxptr, code.Next.Idx, uintptr(key))
 					code = code.Next
 				} else {
@@ -450,15 +497,15 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			}
 		case encoder.OpMapValue:
 			mapCtx := (*encoder.MapContext)(ptrToUnsafePtr(load(ctxptr, code.Idx)))
-			if (ctx.Option.Flag & encoder.UnorderedMapOption) != 0 {
+			if (ctx.Option.Flag&encoder.UnorderedMapOption) != 0 || mapCtx.Presorted {
 				b = appendColon(ctx, b)
 			} else {
 				mapCtx.Slice.Items[mapCtx.Idx].Key = b[mapCtx.Start:len(b)]
 				mapCtx.Start = len(b)
 			}
-			value := mapitervalue(&mapCtx.Iter)
+			value := mapCtx.IterValue()
 			store(ctxptr, code.Next.Idx, uintptr(value))
-			mapiternext(&mapCtx.Iter)
+			mapCtx.IterNext()
 			code = code.Next
 		case encoder.OpMapEnd:
 			// this operation only used by sorted map.
@@ -473,6 +520,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			b = append(b, buf...)
 			mapCtx.Buf = buf
 			encoder.ReleaseMapContext(mapCtx)
+			ctx.SortedMaps--
 			code = code.Next
 		case encoder.OpRecursivePtr:
 			p := load(ctxptr, code.Idx)
@@ -581,9 +629,15 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			if code.Flags&encoder.AnonymousHeadFlags == 0 {
 				b = appendStructHead(ctx, b)
 			}
+			if p != 0 && (code.Flags&encoder.IndirectFlags) == 0 && (code.Flags&encoder.ZeroCheckerFlags) != 0 {
+				// the struct is the pointer or the map of its only field, whose address is the one p is loaded from.
+				p = ctxptr + uintptr(code.Idx)
+			}
 			p += uintptr(code.Offset)
 			if p == 0 || (ptrToPtr(p) == 0 && (code.Flags&encoder.IsNextOpPtrTypeFlags) != 0) {
 				code = code.NextField
+			} else if (code.Flags&encoder.ZeroCheckerFlags) != 0 && encoder.IsZeroByChecker(code, p) {
+				code = code.NextField
 			} else {
 				b = appendStructKey(ctx, code, b)
 				code = code.Next
@@ -3269,6 +3323,8 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			p += uintptr(code.Offset)
 			if ptrToPtr(p) == 0 && (code.Flags&encoder.IsNextOpPtrTypeFlags) != 0 {
 				code = code.NextField
+			} else if (code.Flags&encoder.ZeroCheckerFlags) != 0 && encoder.IsZeroByChecker(code, p) {
+				code = code.NextField
 			} else {
 				b = appendStructKey(ctx, code, b)
 				code = code.Next
@@ -4118,9 +4174,12 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet) ([]b
 			b = appendStructKey(ctx, code, b)
 			p := load(ctxptr, code.Idx)
 			p = ptrToPtr(p + uintptr(code.Offset))
-			if p != 0 {
-				p = ptrToNPtr(p, code.PtrNum)
+			if p == 0 {
+				b = appendNullComma(ctx, b)
+				code = code.NextField
+				break
 			}
+			p = ptrToNPtr(p, code.PtrNum)
 			code = code.Next
 			store(ctxptr, code.Idx, p)
 		case encoder.OpStructFieldOmitEmptyMapPtr:
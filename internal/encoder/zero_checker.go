@@ -0,0 +1,114 @@
+package encoder
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/going/json/internal/runtime"
+)
+
+// ZeroChecker reports whether the value that p points to is empty.
+type ZeroChecker func(p unsafe.Pointer) bool
+
+var (
+	zeroCheckerMu sync.Mutex
+	// zeroCheckers holds map[*runtime.Type]ZeroChecker, which is replaced by a copy on each registration
+	// so that the encoder can read it without locking.
+	zeroCheckers atomic.Value
+)
+
+func RegisterZeroChecker(typ *runtime.Type, checker ZeroChecker) {
+	zeroCheckerMu.Lock()
+	defer zeroCheckerMu.Unlock()
+
+	old, _ := zeroCheckers.Load().(map[*runtime.Type]ZeroChecker)
+	m := make(map[*runtime.Type]ZeroChecker, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[typ] = checker
+	zeroCheckers.Store(m)
+}
+
+func hasZeroChecker(typ *runtime.Type) bool {
+	m, _ := zeroCheckers.Load().(map[*runtime.Type]ZeroChecker)
+	_, exists := m[typ]
+	return exists
+}
+
+// IsZeroByChecker reports whether the field of code, whose value p points to, is omitted
+// by the omitzero tag option, or by the checker registered for its type with the omitempty or omitzero tag options.
+func IsZeroByChecker(code *Opcode, p uintptr) bool {
+	ptr := *(*unsafe.Pointer)(unsafe.Pointer(&p))
+	if (code.Flags & OmitZeroFlags) != 0 {
+		if isZeroValue(code.Type, ptr) {
+			return true
+		}
+		if (code.Flags&OmitEmptyFlags) != 0 && !hasZeroChecker(code.Type) {
+			return isEmptyValue(reflect.NewAt(runtime.RType2Type(code.Type), ptr).Elem())
+		}
+	}
+	m, _ := zeroCheckers.Load().(map[*runtime.Type]ZeroChecker)
+	checker, exists := m[code.Type]
+	if !exists {
+		return false
+	}
+	return checker(ptr)
+}
+
+// isZeroer is the interface of the types that define their zero values for the omitzero tag option, such as time.Time.
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// isZeroImpliedByEmpty reports whether every zero value of typ is also empty, so that omitzero
+// omits nothing more than omitempty does.
+func isZeroImpliedByEmpty(typ *runtime.Type) bool {
+	t := runtime.RType2Type(typ)
+	if t.Implements(isZeroerType) || reflect.PtrTo(t).Implements(isZeroerType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Array:
+		return false
+	}
+	return true
+}
+
+// isZeroValue reports whether the value of typ that p points to is zero for the omitzero tag option.
+func isZeroValue(typ *runtime.Type, p unsafe.Pointer) bool {
+	v := reflect.NewAt(runtime.RType2Type(typ), p).Elem()
+	switch {
+	case v.Type().Implements(isZeroerType):
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return true
+		}
+		return v.Interface().(isZeroer).IsZero()
+	case reflect.PtrTo(v.Type()).Implements(isZeroerType):
+		return v.Addr().Interface().(isZeroer).IsZero()
+	}
+	return v.IsZero()
+}
+
+// isEmptyValue reports whether v is empty for the omitempty tag option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type InvalidUTF8Error struct {
@@ -31,8 +34,12 @@ func (e *InvalidUnmarshalError) Error() string {
 
 // A MarshalerError represents an error from calling a MarshalJSON or MarshalText method.
 type MarshalerError struct {
-	Type       reflect.Type
-	Err        error
+	Type reflect.Type
+	Err  error
+	// Path is the location of the value in the document being encoded ( e.g. $.a[0].b ),
+	// recorded when the method fails or returns invalid JSON, unless the head of the document has been flushed.
+	// The offset of the syntax error in the returned JSON is reported by Err.
+	Path       string
 	sourceFunc string
 }
 
@@ -41,20 +48,119 @@ func (e *MarshalerError) Error() string {
 	if srcFunc == "" {
 		srcFunc = "MarshalJSON"
 	}
+	if e.Path != "" {
+		if serr, ok := e.Err.(*SyntaxError); ok {
+			return fmt.Sprintf(
+				"json: error calling %s for type %s at %s: %s ( offset %d of the output )",
+				srcFunc, e.Type, e.Path, serr.Error(), serr.Offset,
+			)
+		}
+		if e.Path != "$" {
+			// the root is reported by the type alone, as the error of encoding/json.
+			return fmt.Sprintf("json: error calling %s for type %s at %s: %s", srcFunc, e.Type, e.Path, e.Err.Error())
+		}
+	}
 	return fmt.Sprintf("json: error calling %s for type %s: %s", srcFunc, e.Type, e.Err.Error())
 }
 
 // Unwrap returns the underlying error.
 func (e *MarshalerError) Unwrap() error { return e.Err }
 
+// The classes of decode errors, which are matched by errors.Is.
+var (
+	ErrTrailingData  = stderrors.New("json: invalid data after top-level value")
+	ErrDepthExceeded = stderrors.New("json: exceeded max depth")
+	ErrDuplicateKey  = stderrors.New("json: duplicate key")
+	ErrStringTooLong = stderrors.New("json: string too long")
+	ErrUnknownField  = stderrors.New("json: unknown field")
+	ErrMissingField  = stderrors.New("json: missing required field")
+)
+
 // A SyntaxError is a description of a JSON syntax error.
 type SyntaxError struct {
 	msg    string // description of error
 	Offset int64  // error occurred after reading Offset bytes
+	class  error  // class of the error, if any
 }
 
 func (e *SyntaxError) Error() string { return e.msg }
 
+// Unwrap returns the class of the error, such as ErrDepthExceeded, or nil.
+func (e *SyntaxError) Unwrap() error { return e.class }
+
+// An UnknownFieldError describes an object key that matches no field of the struct it is decoded into,
+// with a Decoder that disallows unknown fields. It matches ErrUnknownField.
+type UnknownFieldError struct {
+	Field string // the object key
+	Path  string // the keys and indexes from the root to the key, separated by dots
+	// Suggestion is the key of the struct that the object key is likely a misspelling of, or "".
+	Suggestion string
+}
+
+func (e *UnknownFieldError) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("json: unknown field %q; did you mean %q?", e.Field, e.Suggestion)
+	}
+	return fmt.Sprintf("json: unknown field %q", e.Field)
+}
+
+// Unwrap returns ErrUnknownField.
+func (e *UnknownFieldError) Unwrap() error { return ErrUnknownField }
+
+// A DuplicateKeyError describes an object key that appears more than once in the object.
+type DuplicateKeyError struct {
+	Key    string // the object key, unescaped
+	Path   string // the keys and indexes from the root to the key, separated by dots
+	Offset int64  // error occurred after reading Offset bytes
+	syntax *SyntaxError
+}
+
+func (e *DuplicateKeyError) Error() string { return e.syntax.Error() }
+
+// Unwrap returns the *SyntaxError of the duplicate key, which matches ErrDuplicateKey.
+func (e *DuplicateKeyError) Unwrap() error { return e.syntax }
+
+// A MissingFieldError describes the fields of a struct with the required tag option
+// whose keys are absent from the object it is decoded from. It matches ErrMissingField.
+type MissingFieldError struct {
+	Type   reflect.Type // the struct type
+	Fields []string     // the keys of the absent fields, in the order of the fields
+	Offset int64        // error occurred after reading Offset bytes
+}
+
+func (e *MissingFieldError) Error() string {
+	keys := make([]string, len(e.Fields))
+	for i, key := range e.Fields {
+		keys[i] = strconv.Quote(key)
+	}
+	if len(keys) == 1 {
+		return fmt.Sprintf("json: missing required field %s of Go struct %s", keys[0], e.Type)
+	}
+	return fmt.Sprintf("json: missing required fields %s of Go struct %s", strings.Join(keys, ", "), e.Type)
+}
+
+// Unwrap returns ErrMissingField.
+func (e *MissingFieldError) Unwrap() error { return ErrMissingField }
+
+// An UnknownFieldsError describes all the unknown fields of a value, if there are more than one.
+type UnknownFieldsError struct {
+	Fields []*UnknownFieldError // in the order of the input
+}
+
+func (e *UnknownFieldsError) Error() string {
+	paths := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		paths[i] = strconv.Quote(f.Path)
+		if f.Suggestion != "" {
+			paths[i] += fmt.Sprintf(" (did you mean %q?)", f.Suggestion)
+		}
+	}
+	return "json: unknown fields " + strings.Join(paths, ", ")
+}
+
+// Unwrap returns the first unknown field.
+func (e *UnknownFieldsError) Unwrap() error { return e.Fields[0] }
+
 // An UnmarshalFieldError describes a JSON object key that
 // led to an unexported (and therefore unwritable) struct field.
 //
@@ -94,21 +200,45 @@ func (e *UnmarshalTypeError) Error() string {
 // to encode an unsupported value type.
 type UnsupportedTypeError struct {
 	Type reflect.Type
+	// Path is the location of the values of Type in the value being encoded ( e.g. $.a[*].b ),
+	// where [*] and .* stand for the elements of arrays and maps, recorded if they are nested in it.
+	Path string
+	// Parents are the types of the values containing the values of Type, from the value being encoded.
+	Parents []reflect.Type
 }
 
 func (e *UnsupportedTypeError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("json: unsupported type: %s at %s ( %s )", e.Type, e.Path, typeChain(e.Parents))
+	}
 	return fmt.Sprintf("json: unsupported type: %s", e.Type)
 }
 
 type UnsupportedValueError struct {
 	Value reflect.Value
 	Str   string
+	// Path is the location of Value in the value being encoded ( e.g. $.a[0].b ), recorded if it is nested in it.
+	Path string
+	// Parents are the types of the values containing Value, from the value being encoded.
+	Parents []reflect.Type
 }
 
 func (e *UnsupportedValueError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("json: unsupported value: %s at %s ( %s )", e.Str, e.Path, typeChain(e.Parents))
+	}
 	return fmt.Sprintf("json: unsupported value: %s", e.Str)
 }
 
+// typeChain returns the types joined with " > ".
+func typeChain(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, typ := range types {
+		names[i] = typ.String()
+	}
+	return strings.Join(names, " > ")
+}
+
 func ErrSyntax(msg string, offset int64) *SyntaxError {
 	return &SyntaxError{msg: msg, Offset: offset}
 }
@@ -125,9 +255,47 @@ func ErrExceededMaxDepth(c byte, cursor int64) *SyntaxError {
 	return &SyntaxError{
 		msg:    fmt.Sprintf(`invalid character "%c" exceeded max depth`, c),
 		Offset: cursor,
+		class:  ErrDepthExceeded,
+	}
+}
+
+func ErrAfterTopLevelValue(c byte, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("invalid character '%c' after top-level value", c),
+		Offset: cursor,
+		class:  ErrTrailingData,
+	}
+}
+
+func ErrDuplicateObjectKey(key, path string, cursor int64) *DuplicateKeyError {
+	return &DuplicateKeyError{
+		Key:    key,
+		Path:   path,
+		Offset: cursor,
+		syntax: &SyntaxError{
+			msg:    fmt.Sprintf("json: duplicate key %q", key),
+			Offset: cursor,
+			class:  ErrDuplicateKey,
+		},
+	}
+}
+
+func ErrStringLimit(limit int, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("json: string longer than %d bytes", limit),
+		Offset: cursor,
+		class:  ErrStringTooLong,
 	}
 }
 
+func ErrUnknownStructField(key, path, suggestion string) *UnknownFieldError {
+	return &UnknownFieldError{Field: key, Path: path, Suggestion: suggestion}
+}
+
+func ErrMissingRequiredFields(typ reflect.Type, keys []string, cursor int64) *MissingFieldError {
+	return &MissingFieldError{Type: typ, Fields: keys, Offset: cursor}
+}
+
 func ErrNotAtBeginningOfValue(cursor int64) *SyntaxError {
 	return &SyntaxError{msg: "not at beginning of value", Offset: cursor}
 }
@@ -181,3 +349,102 @@ func ErrInvalidPath(msg string, args ...interface{}) *PathError {
 func ErrEmptyPath() *PathError {
 	return &PathError{msg: "path is empty"}
 }
+
+type ExprError struct {
+	msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("json: %s", e.msg)
+}
+
+func ErrInvalidExpr(msg string, args ...interface{}) *ExprError {
+	if len(args) != 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return &ExprError{msg: "invalid expression: " + msg}
+}
+
+func ErrEvalExpr(msg string, args ...interface{}) *ExprError {
+	if len(args) != 0 {
+		return &ExprError{msg: fmt.Sprintf(msg, args...)}
+	}
+	return &ExprError{msg: msg}
+}
+
+type RefError struct {
+	Ref string // the value of the $ref member
+	msg string
+}
+
+func (e *RefError) Error() string {
+	return fmt.Sprintf("json: cannot resolve $ref %q: %s", e.Ref, e.msg)
+}
+
+func ErrRef(ref, msg string, args ...interface{}) *RefError {
+	if len(args) != 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return &RefError{Ref: ref, msg: msg}
+}
+
+type KeyLimitError struct {
+	Limit    int   // the limit that is exceeded
+	Document bool  // whether the limit is on the keys of the whole document rather than of one object
+	Offset   int64 // error occurred after reading Offset bytes
+}
+
+func (e *KeyLimitError) Error() string {
+	if e.Document {
+		return fmt.Sprintf("json: document has more than %d object keys", e.Limit)
+	}
+	return fmt.Sprintf("json: object has more than %d keys", e.Limit)
+}
+
+func ErrObjectKeyLimit(limit int, offset int64) *KeyLimitError {
+	return &KeyLimitError{Limit: limit, Offset: offset}
+}
+
+func ErrDocumentKeyLimit(limit int, offset int64) *KeyLimitError {
+	return &KeyLimitError{Limit: limit, Document: true, Offset: offset}
+}
+
+// PositionError wraps a decode error with the line and column of the position in the input where it occurred.
+type PositionError struct {
+	Line   int   // line number, starting at 1
+	Column int   // byte offset in the line, starting at 1
+	Err    error // the underlying error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("json: line %d, column %d: %s", e.Line, e.Column, strings.TrimPrefix(e.Err.Error(), "json: "))
+}
+
+// Unwrap returns the underlying error.
+func (e *PositionError) Unwrap() error { return e.Err }
+
+// ErrWithPosition wraps err with its position in the input if it has an offset.
+// newlines are the offsets of the line feeds of the input, in ascending order.
+func ErrWithPosition(err error, newlines []int64) error {
+	var offset int64
+	switch e := err.(type) {
+	case *SyntaxError:
+		offset = e.Offset
+	case *UnmarshalTypeError:
+		offset = e.Offset
+	case *KeyLimitError:
+		offset = e.Offset
+	case *DuplicateKeyError:
+		offset = e.Offset
+	case *MissingFieldError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	line := sort.Search(len(newlines), func(i int) bool { return newlines[i] >= offset })
+	lineStart := int64(0)
+	if line > 0 {
+		lineStart = newlines[line-1] + 1
+	}
+	return &PositionError{Line: line + 1, Column: int(offset-lineStart) + 1, Err: err}
+}
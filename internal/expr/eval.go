@@ -0,0 +1,609 @@
+package expr
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/going/json/internal/errors"
+)
+
+// Program is a compiled expression.
+// It is safe for concurrent use by multiple goroutines.
+type Program struct {
+	src  string
+	root node
+}
+
+// Compile parses src into a program.
+func Compile(src string) (*Program, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, errors.ErrInvalidExpr("expression is empty")
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().typ != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// Run evaluates the program against v and returns all of its outputs.
+// v must be made of the types that decoding into interface{} produces.
+func (p *Program) Run(v interface{}) ([]interface{}, error) {
+	return p.root.eval(v, nil)
+}
+
+// String returns the source of the program.
+func (p *Program) String() string {
+	return p.src
+}
+
+// node evaluates a part of an expression against its input and appends its outputs to out.
+// On error, it returns out with the outputs produced before the error.
+type node interface {
+	eval(v interface{}, out []interface{}) ([]interface{}, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	return append(out, v), nil
+}
+
+type recurseNode struct{}
+
+func (recurseNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	out = append(out, v)
+	switch v := v.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			out, _ = recurseNode{}.eval(elem, out)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			out, _ = recurseNode{}.eval(v[k], out)
+		}
+	}
+	return out, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(_ interface{}, out []interface{}) ([]interface{}, error) {
+	return append(out, n.value), nil
+}
+
+type pipeNode struct {
+	lhs, rhs node
+}
+
+func (n *pipeNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	values, lhsErr := n.lhs.eval(v, nil)
+	for _, value := range values {
+		var err error
+		if out, err = n.rhs.eval(value, out); err != nil {
+			return out, err
+		}
+	}
+	return out, lhsErr
+}
+
+type commaNode struct {
+	lhs, rhs node
+}
+
+func (n *commaNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	out, err := n.lhs.eval(v, out)
+	if err != nil {
+		return out, err
+	}
+	return n.rhs.eval(v, out)
+}
+
+// indexNode evaluates `target[key]`. key is evaluated against the input of the node, not against target.
+type indexNode struct {
+	target, key node
+}
+
+func (n *indexNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	targets, targetErr := n.target.eval(v, nil)
+	keys, err := n.key.eval(v, nil)
+	if err != nil {
+		return out, err
+	}
+	for _, target := range targets {
+		for _, key := range keys {
+			value, err := index(target, key)
+			if err != nil {
+				return out, err
+			}
+			out = append(out, value)
+		}
+	}
+	return out, targetErr
+}
+
+func index(target, key interface{}) (interface{}, error) {
+	switch target := target.(type) {
+	case nil:
+		switch key.(type) {
+		case string:
+			return nil, nil
+		}
+		if _, ok := toNumber(key); ok {
+			return nil, nil
+		}
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			return target[k], nil
+		}
+	case []interface{}:
+		if f, ok := toNumber(key); ok {
+			i := int(math.Floor(f))
+			if i < 0 {
+				i += len(target)
+			}
+			if i < 0 || i >= len(target) {
+				return nil, nil
+			}
+			return target[i], nil
+		}
+	}
+	return nil, errors.ErrEvalExpr("cannot index %s with %s", typeName(target), typeName(key))
+}
+
+type iterateNode struct {
+	target node
+}
+
+func (n *iterateNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	targets, targetErr := n.target.eval(v, nil)
+	for _, target := range targets {
+		switch target := target.(type) {
+		case []interface{}:
+			out = append(out, target...)
+		case map[string]interface{}:
+			for _, k := range sortedKeys(target) {
+				out = append(out, target[k])
+			}
+		default:
+			return out, errors.ErrEvalExpr("cannot iterate over %s", typeName(target))
+		}
+	}
+	return out, targetErr
+}
+
+// sliceNode evaluates `target[from:to]`. Either bound may be nil.
+type sliceNode struct {
+	target, from, to node
+}
+
+func (n *sliceNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(v, nil)
+	if err != nil {
+		return out, err
+	}
+	froms, err := n.bound(v, n.from)
+	if err != nil {
+		return out, err
+	}
+	tos, err := n.bound(v, n.to)
+	if err != nil {
+		return out, err
+	}
+	for _, target := range targets {
+		for _, from := range froms {
+			for _, to := range tos {
+				value, err := slice(target, from, to)
+				if err != nil {
+					return out, err
+				}
+				out = append(out, value)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (n *sliceNode) bound(v interface{}, bound node) ([]interface{}, error) {
+	if bound == nil {
+		return []interface{}{nil}, nil
+	}
+	return bound.eval(v, nil)
+}
+
+func slice(target, from, to interface{}) (interface{}, error) {
+	var length int
+	switch target := target.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		length = len(target)
+	case string:
+		length = utf8.RuneCountInString(target)
+	default:
+		return nil, errors.ErrEvalExpr("cannot slice %s", typeName(target))
+	}
+	start, err := sliceBound(from, 0, length)
+	if err != nil {
+		return nil, err
+	}
+	end, err := sliceBound(to, length, length)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		end = start
+	}
+	if s, ok := target.(string); ok {
+		runes := []rune(s)
+		return string(runes[start:end]), nil
+	}
+	return target.([]interface{})[start:end:end], nil
+}
+
+func sliceBound(bound interface{}, def, length int) (int, error) {
+	if bound == nil {
+		return def, nil
+	}
+	f, ok := toNumber(bound)
+	if !ok {
+		return 0, errors.ErrEvalExpr("cannot slice with %s", typeName(bound))
+	}
+	i := int(math.Floor(f))
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0, nil
+	}
+	if i > length {
+		return length, nil
+	}
+	return i, nil
+}
+
+type collectNode struct {
+	body node
+}
+
+func (n *collectNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	values := []interface{}{}
+	if n.body != nil {
+		var err error
+		if values, err = n.body.eval(v, values); err != nil {
+			return out, err
+		}
+	}
+	return append(out, values), nil
+}
+
+// tryNode evaluates `body?`, which stops at the first error of body and keeps the outputs produced before it.
+type tryNode struct {
+	body node
+}
+
+func (n *tryNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	out, _ = n.body.eval(v, out)
+	return out, nil
+}
+
+type logicalNode struct {
+	and      bool
+	lhs, rhs node
+}
+
+func (n *logicalNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	lhs, err := n.lhs.eval(v, nil)
+	if err != nil {
+		return out, err
+	}
+	for _, l := range lhs {
+		if isTruthy(l) != n.and {
+			out = append(out, !n.and)
+			continue
+		}
+		rhs, err := n.rhs.eval(v, nil)
+		if err != nil {
+			return out, err
+		}
+		for _, r := range rhs {
+			out = append(out, isTruthy(r))
+		}
+	}
+	return out, nil
+}
+
+type compareNode struct {
+	op       string
+	lhs, rhs node
+}
+
+func (n *compareNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	lhs, err := n.lhs.eval(v, nil)
+	if err != nil {
+		return out, err
+	}
+	rhs, err := n.rhs.eval(v, nil)
+	if err != nil {
+		return out, err
+	}
+	for _, l := range lhs {
+		for _, r := range rhs {
+			c := compare(l, r)
+			var result bool
+			switch n.op {
+			case "==":
+				result = c == 0
+			case "!=":
+				result = c != 0
+			case "<":
+				result = c < 0
+			case "<=":
+				result = c <= 0
+			case ">":
+				result = c > 0
+			case ">=":
+				result = c >= 0
+			}
+			out = append(out, result)
+		}
+	}
+	return out, nil
+}
+
+type callNode struct {
+	name string
+	arg  node
+}
+
+func (n *callNode) eval(v interface{}, out []interface{}) ([]interface{}, error) {
+	switch n.name {
+	case "empty":
+		return out, nil
+	case "not":
+		return append(out, !isTruthy(v)), nil
+	case "type":
+		return append(out, typeName(v)), nil
+	case "length":
+		switch v := v.(type) {
+		case nil:
+			return append(out, float64(0)), nil
+		case string:
+			return append(out, float64(utf8.RuneCountInString(v))), nil
+		case []interface{}:
+			return append(out, float64(len(v))), nil
+		case map[string]interface{}:
+			return append(out, float64(len(v))), nil
+		}
+		if f, ok := toNumber(v); ok {
+			return append(out, math.Abs(f)), nil
+		}
+		return out, errors.ErrEvalExpr("%s has no length", typeName(v))
+	case "keys":
+		switch v := v.(type) {
+		case map[string]interface{}:
+			keys := make([]interface{}, 0, len(v))
+			for _, k := range sortedKeys(v) {
+				keys = append(keys, k)
+			}
+			return append(out, keys), nil
+		case []interface{}:
+			keys := make([]interface{}, len(v))
+			for i := range v {
+				keys[i] = float64(i)
+			}
+			return append(out, keys), nil
+		}
+		return out, errors.ErrEvalExpr("%s has no keys", typeName(v))
+	case "has":
+		keys, err := n.arg.eval(v, nil)
+		if err != nil {
+			return out, err
+		}
+		for _, key := range keys {
+			switch target := v.(type) {
+			case map[string]interface{}:
+				if k, ok := key.(string); ok {
+					_, exists := target[k]
+					out = append(out, exists)
+					continue
+				}
+			case []interface{}:
+				if f, ok := toNumber(key); ok {
+					out = append(out, f >= 0 && f < float64(len(target)))
+					continue
+				}
+			}
+			return out, errors.ErrEvalExpr("cannot check whether %s has a %s key", typeName(v), typeName(key))
+		}
+		return out, nil
+	case "select":
+		conds, err := n.arg.eval(v, nil)
+		if err != nil {
+			return out, err
+		}
+		for _, cond := range conds {
+			if isTruthy(cond) {
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	case "map":
+		values, err := (&iterateNode{target: identityNode{}}).eval(v, nil)
+		if err != nil {
+			return out, err
+		}
+		mapped := []interface{}{}
+		for _, value := range values {
+			if mapped, err = n.arg.eval(value, mapped); err != nil {
+				return out, err
+			}
+		}
+		return append(out, mapped), nil
+	}
+	return out, errors.ErrEvalExpr("unknown function %s", n.name)
+}
+
+func isTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	}
+	return true
+}
+
+// toNumber returns the value of a number decoded as float64 or Number, or passed as any Go number type.
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case interface{ Float64() (float64, error) }:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// typeOrder returns the rank of the type of v in the order null < boolean < number < string < array < object.
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case string:
+		return 3
+	case []interface{}:
+		return 4
+	case map[string]interface{}:
+		return 5
+	}
+	if _, ok := toNumber(v); ok {
+		return 2
+	}
+	return 6
+}
+
+func typeName(v interface{}) string {
+	return [...]string{"null", "boolean", "number", "string", "array", "object", "unknown"}[typeOrder(v)]
+}
+
+// compare returns -1, 0 or +1 depending on whether a sorts before, equal to or after b.
+// Values of different types are ordered by type, arrays are compared element by element,
+// and objects are compared by their sorted keys first and then by the values of each key.
+func compare(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return compareInt(ta, tb)
+	}
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		return compareInt(boolToInt(a), boolToInt(b.(bool)))
+	case string:
+		b := b.(string)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case []interface{}:
+		b := b.([]interface{})
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInt(len(a), len(b))
+	case map[string]interface{}:
+		b := b.(map[string]interface{})
+		ka, kb := sortedKeys(a), sortedKeys(b)
+		for i := 0; i < len(ka) && i < len(kb); i++ {
+			if ka[i] != kb[i] {
+				return compare(ka[i], kb[i])
+			}
+		}
+		if len(ka) != len(kb) {
+			return compareInt(len(ka), len(kb))
+		}
+		for _, k := range ka {
+			if c := compare(a[k], b[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	fa, _ := toNumber(a)
+	fb, _ := toNumber(b)
+	switch {
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,487 @@
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/going/json/internal/errors"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenDot
+	tokenRecurse
+	tokenField
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenPunct
+	tokenOp
+)
+
+type token struct {
+	typ    tokenType
+	text   string
+	num    float64
+	offset int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokenEOF:
+		return "end of expression"
+	case tokenField:
+		return strconv.Quote("." + t.text)
+	case tokenString:
+		return strconv.Quote(t.text) + " string"
+	}
+	return strconv.Quote(t.text)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || ('0' <= c && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '.':
+			i++
+			switch {
+			case i < len(src) && src[i] == '.':
+				i++
+				tokens = append(tokens, token{typ: tokenRecurse, text: "..", offset: start})
+			case i < len(src) && isIdentStart(src[i]):
+				for i < len(src) && isIdentChar(src[i]) {
+					i++
+				}
+				tokens = append(tokens, token{typ: tokenField, text: src[start+1 : i], offset: start})
+			default:
+				tokens = append(tokens, token{typ: tokenDot, text: ".", offset: start})
+			}
+		case c == '"':
+			s, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{typ: tokenString, text: s, offset: start})
+		case isDigit(c):
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				j := i + 1
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				if j < len(src) && isDigit(src[j]) {
+					i = j
+					for i < len(src) && isDigit(src[i]) {
+						i++
+					}
+				}
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, errors.ErrInvalidExpr("invalid number %s at offset %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{typ: tokenNumber, text: src[start:i], num: num, offset: start})
+		case isIdentStart(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{typ: tokenIdent, text: src[start:i], offset: start})
+		case strings.IndexByte("[]()|,:?", c) >= 0:
+			i++
+			tokens = append(tokens, token{typ: tokenPunct, text: src[start:i], offset: start})
+		case c == '=' || c == '!':
+			if i+1 >= len(src) || src[i+1] != '=' {
+				return nil, errors.ErrInvalidExpr("unexpected character %q at offset %d", c, start)
+			}
+			i += 2
+			tokens = append(tokens, token{typ: tokenOp, text: src[start:i], offset: start})
+		case c == '<' || c == '>':
+			i++
+			if i < len(src) && src[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, token{typ: tokenOp, text: src[start:i], offset: start})
+		case c == '-':
+			i++
+			tokens = append(tokens, token{typ: tokenOp, text: "-", offset: start})
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, errors.ErrInvalidExpr("unexpected character %q at offset %d", r, start)
+		}
+	}
+	return append(tokens, token{typ: tokenEOF, offset: len(src)}), nil
+}
+
+// lexString reads the JSON string literal that begins at src[start] and returns it unescaped with the offset after it.
+func lexString(src string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\\':
+			if i+1 >= len(src) {
+				return "", 0, errors.ErrInvalidExpr("unterminated string at offset %d", start)
+			}
+			switch src[i+1] {
+			case '"', '\\', '/':
+				b.WriteByte(src[i+1])
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				r, ok := lexHex(src, i+2)
+				if !ok {
+					return "", 0, errors.ErrInvalidExpr("invalid escape sequence at offset %d", i)
+				}
+				i += 6
+				if utf16.IsSurrogate(r) && strings.HasPrefix(src[i:], `\u`) {
+					if r2, ok := lexHex(src, i+2); ok {
+						if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+							r = dec
+							i += 6
+						}
+					}
+				}
+				b.WriteRune(r)
+				continue
+			default:
+				return "", 0, errors.ErrInvalidExpr("invalid escape sequence at offset %d", i)
+			}
+			i += 2
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, errors.ErrInvalidExpr("unterminated string at offset %d", start)
+}
+
+func lexHex(src string, start int) (rune, bool) {
+	if start+4 > len(src) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(src[start:start+4], 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+// funcArity is the number of arguments of each builtin function.
+var funcArity = map[string]int{
+	"empty":  0,
+	"has":    1,
+	"keys":   0,
+	"length": 0,
+	"map":    1,
+	"not":    0,
+	"select": 1,
+	"type":   0,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(typ tokenType, text string) bool {
+	t := p.peek()
+	if t.typ == typ && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if p.accept(tokenPunct, text) {
+		return nil
+	}
+	return p.unexpected()
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	return errors.ErrInvalidExpr("unexpected %s at offset %d", t, t.offset)
+}
+
+// parsePipe parses `a | b`, which has the lowest precedence and is right-associative.
+func (p *parser) parsePipe() (node, error) {
+	lhs, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(tokenPunct, "|") {
+		return lhs, nil
+	}
+	rhs, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	return &pipeNode{lhs: lhs, rhs: rhs}, nil
+}
+
+func (p *parser) parseComma() (node, error) {
+	lhs, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenPunct, ",") {
+		rhs, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &commaNode{lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenIdent, "or") {
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &logicalNode{and: false, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	lhs, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenIdent, "and") {
+		rhs, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &logicalNode{and: true, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	lhs, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.typ != tokenOp || t.text == "-" {
+		return lhs, nil
+	}
+	p.next()
+	rhs, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: t.text, lhs: lhs, rhs: rhs}, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.typ == tokenField:
+			p.next()
+			n = &indexNode{target: n, key: &literalNode{value: t.text}}
+		case t.typ == tokenDot && p.tokens[p.pos+1].typ == tokenString:
+			p.next()
+			n = &indexNode{target: n, key: &literalNode{value: p.next().text}}
+		case t.typ == tokenDot && p.tokens[p.pos+1].typ == tokenPunct && p.tokens[p.pos+1].text == "[":
+			p.next()
+			p.next()
+			if n, err = p.parseBracket(n); err != nil {
+				return nil, err
+			}
+		case t.typ == tokenPunct && t.text == "[":
+			p.next()
+			if n, err = p.parseBracket(n); err != nil {
+				return nil, err
+			}
+		case t.typ == tokenPunct && t.text == "?":
+			p.next()
+			n = &tryNode{body: n}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// parseBracket parses the suffixes `[]`, `[e]` and `[from:to]` of target after the opening bracket.
+func (p *parser) parseBracket(target node) (node, error) {
+	if p.accept(tokenPunct, "]") {
+		return &iterateNode{target: target}, nil
+	}
+	var (
+		from node
+		err  error
+	)
+	if !p.accept(tokenPunct, ":") {
+		if from, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+		if p.accept(tokenPunct, "]") {
+			return &indexNode{target: target, key: from}, nil
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+	}
+	var to node
+	if !p.accept(tokenPunct, "]") {
+		if to, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if from == nil {
+		return nil, errors.ErrInvalidExpr("slice without bounds at offset %d", p.tokens[p.pos-1].offset)
+	}
+	return &sliceNode{target: target, from: from, to: to}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenDot:
+		p.next()
+		if p.peek().typ == tokenString {
+			return &indexNode{target: identityNode{}, key: &literalNode{value: p.next().text}}, nil
+		}
+		return identityNode{}, nil
+	case tokenRecurse:
+		p.next()
+		return recurseNode{}, nil
+	case tokenField:
+		p.next()
+		return &indexNode{target: identityNode{}, key: &literalNode{value: t.text}}, nil
+	case tokenString:
+		p.next()
+		return &literalNode{value: t.text}, nil
+	case tokenNumber:
+		p.next()
+		return &literalNode{value: t.num}, nil
+	case tokenOp:
+		if t.text == "-" && p.tokens[p.pos+1].typ == tokenNumber {
+			p.next()
+			return &literalNode{value: -p.next().num}, nil
+		}
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			p.next()
+			n, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			p.next()
+			if p.accept(tokenPunct, "]") {
+				return &collectNode{}, nil
+			}
+			n, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return &collectNode{body: n}, nil
+		}
+	case tokenIdent:
+		p.next()
+		switch t.text {
+		case "null":
+			return &literalNode{value: nil}, nil
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		arity, exists := funcArity[t.text]
+		if !exists {
+			return nil, errors.ErrInvalidExpr("unknown function %s at offset %d", t.text, t.offset)
+		}
+		if arity == 0 {
+			return &callNode{name: t.text}, nil
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		arg, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &callNode{name: t.text, arg: arg}, nil
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"strings"
+)
+
+// AuditKind is the kind of the data lost or defaulted across encoding or decoding.
+type AuditKind int
+
+const (
+	// AuditOmitted is a struct field omitted from the output by the encoder, such as an empty omitempty field.
+	AuditOmitted AuditKind = iota + 1
+	// AuditUnknownKey is a key of the input ignored by the decoder, since no struct field matches it.
+	AuditUnknownKey
+	// AuditDefaulted is a struct field whose key is missing from the input, which keeps its value.
+	AuditDefaulted
+)
+
+func (k AuditKind) String() string {
+	switch k {
+	case AuditOmitted:
+		return "omitted"
+	case AuditUnknownKey:
+		return "unknown key"
+	case AuditDefaulted:
+		return "defaulted"
+	}
+	return "unknown audit kind"
+}
+
+// AuditEvent is a field or a key reported with the audit options.
+type AuditEvent struct {
+	Kind AuditKind
+	// Path is the JSON Pointer of the field or the key, made of the keys of the JSON document.
+	Path string
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// JSONPointer returns the JSON Pointer of the reference tokens.
+func JSONPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(pointerEscaper.Replace(token))
+	}
+	return b.String()
+}
@@ -6,7 +6,11 @@ import (
 	"unicode"
 )
 
-func getTag(field reflect.StructField) string {
+// getTag returns the tag of field under tagKey, or under json and then db if tagKey is "".
+func getTag(field reflect.StructField, tagKey string) string {
+	if tagKey != "" {
+		return field.Tag.Get(tagKey)
+	}
 	if val := field.Tag.Get("json"); val != "" {
 		return val
 	}
@@ -16,7 +20,8 @@ func getTag(field reflect.StructField) string {
 	return ""
 }
 
-func IsIgnoredStructField(field reflect.StructField) bool {
+// IsIgnoredStructField reports whether field is neither encoded nor decoded, with the struct tags of tagKey.
+func IsIgnoredStructField(field reflect.StructField, tagKey string) bool {
 	if field.PkgPath != "" {
 		if field.Anonymous {
 			t := field.Type
@@ -31,16 +36,42 @@ func IsIgnoredStructField(field reflect.StructField) bool {
 			return true
 		}
 	}
-	tag := getTag(field)
+	tag := getTag(field, tagKey)
 	return tag == "-"
 }
 
+// IsTupleElemField reports whether field is an element of the array that a struct is encoded as with the tuple tag option.
+// The elements are the exported fields in the order of declaration, except the ones tagged with "-".
+func IsTupleElemField(field reflect.StructField, tagKey string) bool {
+	return field.PkgPath == "" && getTag(field, tagKey) != "-"
+}
+
 type StructTag struct {
-	Key         string
-	IsTaggedKey bool
-	IsOmitEmpty bool
-	IsString    bool
-	Field       reflect.StructField
+	Key             string
+	IsTaggedKey     bool
+	IsOmitEmpty     bool
+	IsOmitZero      bool
+	IsOmitNil       bool
+	IsString        bool
+	IsBigString     bool
+	IsSealed        bool
+	IsNest          bool
+	IsSquash        bool
+	IsScalarOrArray bool
+	IsTuple         bool
+	IsRequired      bool
+	Codec           string
+	Field           reflect.StructField
+}
+
+// IsFlattened reports whether the fields of the field are encoded as the fields of the struct containing it,
+// which is the case of an embedded field without a key name or the nest option, and of a field with the squash option.
+// The field must also be a struct or a pointer to a struct to be flattened.
+func (t *StructTag) IsFlattened() bool {
+	if t.IsSquash {
+		return true
+	}
+	return t.Field.Anonymous && !t.IsTaggedKey && !t.IsNest
 }
 
 type StructTags []*StructTag
@@ -71,9 +102,87 @@ func isValidTag(s string) bool {
 	return true
 }
 
-func StructTagFromField(field reflect.StructField) *StructTag {
-	keyName := field.Name
-	tag := getTag(field)
+// FieldNaming is the case of the keys of the struct fields that are not named by their tag.
+type FieldNaming int
+
+const (
+	// FieldNameAsIs names the fields as they are named in Go.
+	FieldNameAsIs FieldNaming = iota
+	// SnakeCase names the fields in snake_case, e.g. UserID as user_id.
+	SnakeCase
+	// CamelCase names the fields in camelCase, e.g. UserID as userID.
+	CamelCase
+	// KebabCase names the fields in kebab-case, e.g. UserID as user-id.
+	KebabCase
+)
+
+// Key returns the key of the field name in the case of n.
+func (n FieldNaming) Key(name string) string {
+	switch n {
+	case SnakeCase:
+		return joinWords(name, '_')
+	case KebabCase:
+		return joinWords(name, '-')
+	case CamelCase:
+		return lowerFirstWord(name)
+	}
+	return name
+}
+
+// wordStarts returns the indexes where the words of a Go name start after the first one.
+// A word starts at an upper case letter following a lower case letter or a digit,
+// and at the last upper case letter of an acronym followed by a lower case letter, as in HTTPServer.
+func wordStarts(name string) []int {
+	runes := []rune(name)
+	var starts []int
+	offset := 0
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				starts = append(starts, offset)
+			}
+		}
+		offset += len(string(r))
+	}
+	return starts
+}
+
+func joinWords(name string, sep byte) string {
+	var b strings.Builder
+	start := 0
+	for _, end := range wordStarts(name) {
+		b.WriteString(strings.ToLower(name[start:end]))
+		b.WriteByte(sep)
+		start = end
+	}
+	b.WriteString(strings.ToLower(name[start:]))
+	return b.String()
+}
+
+func lowerFirstWord(name string) string {
+	starts := wordStarts(name)
+	if len(starts) == 0 {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name[:starts[0]]) + name[starts[0]:]
+}
+
+// TagConfig is how the keys and the options of the struct fields are read from their struct tags.
+// The zero value reads the json tags, falling back to db, and names the fields not named by their tag as in Go.
+type TagConfig struct {
+	// Key is the key of the struct tags, or "" for json and db.
+	Key string
+	// Naming is the case of the keys of the fields not named by their tag.
+	Naming FieldNaming
+}
+
+// StructTagFromField returns the options of field in its struct tag under config.Key.
+// The tag key is json, falling back to db, if config.Key is "".
+func StructTagFromField(field reflect.StructField, config TagConfig) *StructTag {
+	keyName := config.Naming.Key(field.Name)
+	tag := getTag(field, config.Key)
 	st := &StructTag{Field: field}
 	opts := strings.Split(tag, ",")
 	if len(opts) > 0 {
@@ -88,8 +197,30 @@ func StructTagFromField(field reflect.StructField) *StructTag {
 			switch opt {
 			case "omitempty":
 				st.IsOmitEmpty = true
+			case "omitzero":
+				st.IsOmitZero = true
+			case "omitnil":
+				st.IsOmitNil = true
 			case "string":
 				st.IsString = true
+			case "bigstring":
+				st.IsBigString = true
+			case "sealed":
+				st.IsSealed = true
+			case "nest":
+				st.IsNest = true
+			case "squash":
+				st.IsSquash = true
+			case "scalarOrArray":
+				st.IsScalarOrArray = true
+			case "tuple":
+				st.IsTuple = true
+			case "required":
+				st.IsRequired = true
+			default:
+				if name := strings.TrimPrefix(opt, "codec="); name != opt {
+					st.Codec = name
+				}
 			}
 		}
 	}
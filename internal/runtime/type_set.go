@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// TypeSet is an immutable set of types.
+// Equal sets made by NewTypeSet are the same pointer, so that a TypeSet can be the key of a cache.
+type TypeSet struct {
+	types map[*Type]struct{}
+}
+
+var typeSets sync.Map // map[string]*TypeSet keyed by the addresses of the sorted types
+
+// NewTypeSet returns the set of the types of values. A pointer stands for the type it points to.
+func NewTypeSet(values []interface{}) *TypeSet {
+	types := make(map[*Type]struct{}, len(values))
+	for _, v := range values {
+		typ := reflect.TypeOf(v)
+		if typ == nil {
+			continue
+		}
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		types[Type2RType(typ)] = struct{}{}
+	}
+	addrs := make([]uintptr, 0, len(types))
+	for typ := range types {
+		addrs = append(addrs, uintptr(unsafe.Pointer(typ)))
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	key := make([]byte, 0, len(addrs)*int(unsafe.Sizeof(uintptr(0))))
+	for _, addr := range addrs {
+		for i := uintptr(0); i < unsafe.Sizeof(addr); i++ {
+			key = append(key, byte(addr>>(8*i)))
+		}
+	}
+	set, _ := typeSets.LoadOrStore(string(key), &TypeSet{types: types})
+	return set.(*TypeSet)
+}
+
+// Has reports whether typ, or the type typ points to, is in the set.
+func (s *TypeSet) Has(typ *Type) bool {
+	if s == nil {
+		return false
+	}
+	if _, exists := s.types[typ]; exists {
+		return true
+	}
+	if typ.Kind() == reflect.Ptr {
+		_, exists := s.types[typ.Elem()]
+		return exists
+	}
+	return false
+}
+
+// Types returns the types of the set.
+func (s *TypeSet) Types() []*Type {
+	types := make([]*Type, 0, len(s.types))
+	for typ := range s.types {
+		types = append(types, typ)
+	}
+	return types
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 
+	"github.com/going/json/internal/decoder"
 	"github.com/going/json/internal/encoder"
 )
 
@@ -71,10 +73,12 @@ type UnmarshalerContext interface {
 // Struct values encode as JSON objects.
 // Each exported struct field becomes a member of the object, using the
 // field name as the object key, unless the field is omitted for one of the
-// reasons given below.
+// reasons given below. The FieldNamingStrategy option converts the field
+// name to another case, such as snake_case.
 //
 // The encoding of each struct field can be customized by the format string
-// stored under the "json" key in the struct field's tag.
+// stored under the "json" key in the struct field's tag, or under another key
+// with the WithTagKey option.
 // The format string gives the name of the field, possibly followed by a
 // comma-separated list of options. The name may be empty in order to
 // specify options without overriding the default field name.
@@ -84,6 +88,13 @@ type UnmarshalerContext interface {
 // false, 0, a nil pointer, a nil interface value, and any empty array,
 // slice, map, or string.
 //
+// The "omitzero" option specifies that the field should be omitted
+// from the encoding if the field has a zero value, according to the
+// IsZero() bool method of its type if it has one, and to reflect.Value.IsZero
+// otherwise. Unlike omitempty, it omits zero structs such as time.Time{}.
+// If both omitempty and omitzero are given, the field is omitted if its value
+// is either empty or zero.
+//
 // As a special case, if the field tag is "-", the field is always omitted.
 // Note that a field with name "-" can still be generated using the tag "-,".
 //
@@ -102,6 +113,10 @@ type UnmarshalerContext interface {
 //	// Note the leading comma.
 //	Field int `json:",omitempty"`
 //
+//	// Field appears in JSON as key "Field", but
+//	// the field is skipped if it is the zero time.
+//	Field time.Time `json:",omitzero"`
+//
 //	// Field is ignored by this package.
 //	Field int `json:"-"`
 //
@@ -115,6 +130,42 @@ type UnmarshalerContext interface {
 //
 //	Int64String int64 `json:",string"`
 //
+// The "bigstring" option encodes an integer field as a JSON string only if its
+// magnitude exceeds 2^53-1, beyond which JavaScript numbers lose precision.
+// On decode, the field accepts both the string and the number form:
+//
+//	ID uint64 `json:"id,bigstring"`
+//
+// The "sealed" option encrypts the JSON encoding of a field with the Sealer
+// registered by RegisterSealer, and writes the ciphertext as a base64 string.
+// On decode, the string is decrypted and decoded into the field:
+//
+//	SSN string `json:"ssn,sealed"`
+//
+// The "scalarOrArray" option makes a slice field accept a single value on decode,
+// as if it were the array of the value, for the APIs that write a list of one element
+// without brackets. It has no effect on encode:
+//
+//	Tags []string `json:"tags,scalarOrArray"` // accepts both "a" and ["a", "b"]
+//
+// The "tuple" option encodes a field of struct type as the array of the exported fields
+// of the struct in the order of declaration, and decodes the array back by position,
+// for compact formats such as ["GET", "/path", 200]. Missing elements decode as
+// the zero value, and extra elements are ignored:
+//
+//	Request Request `json:"req,tuple"`
+//
+// The "required" option makes decode fail with a *MissingFieldError, which lists all
+// the required fields of the struct, if the key of the field is absent from the object.
+// A key with a null value is present. It has no effect on encode:
+//
+//	Email string `json:"email,required"`
+//
+// The "codec=name" option encodes and decodes a field with the codec registered
+// as name by RegisterCodec, so that a type is written differently in different structs:
+//
+//	Price Money `json:"price,codec=money"`
+//
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, and ASCII punctuation except quotation
 // marks, backslash, and comma.
@@ -126,6 +177,14 @@ type UnmarshalerContext interface {
 // having that name, rather than being anonymous.
 // An anonymous struct field of interface type is treated the same as having
 // that type as its name, rather than being anonymous.
+// The "nest" option treats an anonymous struct field as having its type name as its name,
+// without giving the name in the tag, and the "squash" option treats a named struct field
+// as being anonymous, as the squash option of mapstructure does:
+//
+//	type Config struct {
+//		Base   `json:",nest"`          // appears in JSON as key "Base"
+//		Server Server `json:",squash"` // the fields of Server appear in the object of Config
+//	}
 //
 // The Go visibility rules for struct fields are amended for JSON when
 // deciding which field to marshal or unmarshal. If there are
@@ -166,6 +225,8 @@ type UnmarshalerContext interface {
 // JSON cannot represent cyclic data structures and Marshal does not
 // handle them. Passing cyclic structures to Marshal will result in
 // an infinite recursion.
+//
+// Use MarshalWithOption to configure the encoding with EncodeOption arguments.
 func Marshal(v interface{}) ([]byte, error) {
 	return MarshalWithOption(v)
 }
@@ -185,9 +246,23 @@ func MarshalWithOption(v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, err
 	return marshal(v, optFuncs...)
 }
 
+// MarshalAppend appends the JSON encoding of v with EncodeOption to dst and returns the extended buffer,
+// so that a caller reusing its buffers encodes without allocating one:
+//
+//	buf = buf[:0]
+//	buf, err = json.MarshalAppend(buf, v)
+//
+// It encodes directly into the spare capacity of dst, growing it as append does.
+// On error, dst is returned with its length unchanged.
+func MarshalAppend(dst []byte, v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	return marshalAppend(dst, v, optFuncs...)
+}
+
 // MarshalIndent is like Marshal but applies Indent to format the output.
 // Each JSON element in the output will begin on a new line beginning with prefix
 // followed by one or more copies of indent according to the indentation nesting.
+// Building with the json_noindentvm tag drops the dedicated indent VMs from the binary;
+// the output is then indented after encoding, which is slower.
 func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 	return MarshalIndentWithOption(v, prefix, indent)
 }
@@ -197,6 +272,30 @@ func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...E
 	return marshalIndent(v, prefix, indent, optFuncs...)
 }
 
+// MarshalAll returns the JSON encodings of values, each followed by a newline character,
+// in the same form as writing them by Encoder.Encode ( newline-delimited JSON ).
+func MarshalAll[T any](values []T, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	return marshalAll(values, optFuncs...)
+}
+
+// DecodeAll decodes the sequence of JSON values read from r until the end of the input.
+// The values may be separated by whitespace, as written by Encoder.Encode or MarshalAll.
+// If an error occurs, it returns the values decoded before the error along with the error.
+func DecodeAll[T any](r io.Reader, optFuncs ...DecodeOptionFunc) ([]T, error) {
+	dec := NewDecoder(r)
+	var values []T
+	for {
+		var v T
+		if err := dec.DecodeWithOption(&v, optFuncs...); err != nil {
+			if err == io.EOF {
+				return values, nil
+			}
+			return values, err
+		}
+		values = append(values, v)
+	}
+}
+
 // Unmarshal parses the JSON-encoded data and stores the result
 // in the value pointed to by v. If v is nil or not a pointer,
 // Unmarshal returns an InvalidUnmarshalError.
@@ -270,6 +369,8 @@ func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...E
 // invalid UTF-16 surrogate pairs are not treated as an error.
 // Instead, they are replaced by the Unicode replacement
 // character U+FFFD.
+//
+// Use UnmarshalWithOption to configure the decoding with DecodeOption arguments.
 func Unmarshal(data []byte, v interface{}) error {
 	return unmarshal(data, v)
 }
@@ -278,7 +379,7 @@ func Unmarshal(data []byte, v interface{}) error {
 // in the value pointed to by v. If you implement the UnmarshalerContext interface,
 // call it with ctx as an argument.
 func UnmarshalContext(ctx context.Context, data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
-	return unmarshalContext(ctx, data, v)
+	return unmarshalContext(ctx, data, v, optFuncs...)
 }
 
 func UnmarshalWithOption(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
@@ -289,6 +390,34 @@ func UnmarshalNoEscape(data []byte, v interface{}, optFuncs ...DecodeOptionFunc)
 	return unmarshalNoEscape(data, v, optFuncs...)
 }
 
+// UnmarshalFirst is like Unmarshal but decodes only the first JSON value in data,
+// which may be followed by other values as in concatenated JSON.
+// It returns the rest of data after the value and the whitespace following it,
+// which is empty when data has no more values.
+func UnmarshalFirst(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) ([]byte, error) {
+	return unmarshalFirst(data, v, optFuncs...)
+}
+
+// ReleaseValue recycles the map[string]interface{} and []interface{} values in v,
+// as produced by decoding into interface{}, for the subsequent decodes into interface{}.
+// It walks v recursively, clears the maps and the slices, and pools them,
+// so that request-scoped generic decodes reuse them instead of allocating new ones.
+//
+// v and all the values in it must not be used after ReleaseValue,
+// and must not be referenced by anything else, since their memory is reused.
+// Values of other types are ignored.
+func ReleaseValue(v interface{}) {
+	decoder.ReleaseValue(v)
+}
+
+// SetMaxRetainedBufferCap sets the maximum capacity of an internal encode buffer that is kept for reuse.
+// Buffers grown beyond n bytes are trimmed when they are returned to the internal pool,
+// so an occasional huge encoding doesn't permanently inflate the steady-state memory.
+// n <= 0 means unlimited, which is the default.
+func SetMaxRetainedBufferCap(n int) {
+	encoder.SetMaxRetainedBufferCap(n)
+}
+
 // A Token holds a value of one of these types:
 //
 //	Delim, for the four JSON delimiters [ ] { }
@@ -302,6 +431,10 @@ type Token = json.Token
 // A Number represents a JSON number literal.
 type Number = json.Number
 
+// A RawNumber represents a JSON number literal as the exact bytes of the input.
+// It avoids the string conversion of Number and is re-emitted unchanged by Marshal.
+type RawNumber = decoder.RawNumber
+
 // RawMessage is a raw encoded JSON value.
 // It implements Marshaler and Unmarshaler and can
 // be used to delay JSON decoding or precompute a JSON encoding.
@@ -6,6 +6,8 @@ import (
 	"math"
 	"math/rand"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/going/json"
@@ -372,3 +374,39 @@ func genMap(n int) map[string]interface{} {
 	}
 	return x
 }
+
+func TestConcurrentCodecCache(t *testing.T) {
+	// types created by reflect.StructOf are out of the range of the static type cache.
+	types := make([]reflect.Type, 32)
+	for i := range types {
+		types[i] = reflect.StructOf([]reflect.StructField{
+			{Name: "F" + strconv.Itoa(i), Type: reflect.TypeOf(0), Tag: `json:"f"`},
+		})
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, typ := range types {
+				v := reflect.New(typ)
+				v.Elem().Field(0).SetInt(int64(i))
+				b, err := json.Marshal(v.Interface())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				dst := reflect.New(typ)
+				if err := json.Unmarshal(b, dst.Interface()); err != nil {
+					t.Error(err)
+					return
+				}
+				if dst.Elem().Field(0).Int() != int64(i) {
+					t.Errorf("unexpected value: %s", b)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
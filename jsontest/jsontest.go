@@ -0,0 +1,105 @@
+// Package jsontest provides helpers for comparing JSON documents in tests.
+package jsontest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+	"github.com/going/json/internal/diff"
+)
+
+// Normalize returns the canonical form of data, suitable for golden files.
+// Object keys are sorted, insignificant whitespace is replaced by a two-space indentation,
+// HTML characters are not escaped and the result ends with a newline.
+// Numbers keep their literal text, so 1 and 1.0 are not normalized to the same form.
+func Normalize(data []byte) ([]byte, error) {
+	dec := json.NewDecoderBytes(data)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(new(interface{})); err != io.EOF {
+		if err == nil {
+			return nil, fmt.Errorf("jsontest: multiple JSON values")
+		}
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RawMessageEqual reports whether x and y are semantically equal JSON values.
+// Invalid values are equal only if they are byte-for-byte identical.
+// It can be used as a go-cmp comparer: cmp.Comparer(jsontest.RawMessageEqual).
+func RawMessageEqual(x, y json.RawMessage) bool {
+	return bytesEqual(x, y)
+}
+
+// BytesEqual is like RawMessageEqual for []byte.
+func BytesEqual(x, y []byte) bool {
+	return bytesEqual(x, y)
+}
+
+func bytesEqual(x, y []byte) bool {
+	nx, err := Normalize(x)
+	if err != nil {
+		return bytes.Equal(x, y)
+	}
+	ny, err := Normalize(y)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(nx, ny)
+}
+
+// Equal reports a test error with a line diff of the normalized documents if want and got are not semantically equal.
+func Equal(t testing.TB, want, got []byte) bool {
+	t.Helper()
+	nw, err := Normalize(want)
+	if err != nil {
+		t.Errorf("jsontest: invalid want: %v", err)
+		return false
+	}
+	ng, err := Normalize(got)
+	if err != nil {
+		t.Errorf("jsontest: invalid got: %v\n%s", err, got)
+		return false
+	}
+	if bytes.Equal(nw, ng) {
+		return true
+	}
+	t.Errorf("JSON mismatch (-want +got):\n%s", Diff(nw, ng))
+	return false
+}
+
+// Diff returns a line diff between a and b, where removed lines start with "-" and added lines start with "+".
+// It is intended for normalized documents.
+func Diff(a, b []byte) string {
+	var sb strings.Builder
+	for _, edit := range diff.Lines(splitLines(a), splitLines(b)) {
+		sb.WriteByte(byte(edit.Op))
+		sb.WriteByte(' ')
+		sb.WriteString(edit.Line)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
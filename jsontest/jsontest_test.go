@@ -0,0 +1,76 @@
+package jsontest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/going/json"
+	"github.com/going/json/jsontest"
+)
+
+func TestNormalize(t *testing.T) {
+	got, err := jsontest.Normalize([]byte(` {"b":[1, 2.50],"a":{"y":null,"x":"<&>"}} `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "a": {
+    "x": "<&>",
+    "y": null
+  },
+  "b": [
+    1,
+    2.50
+  ]
+}
+`
+	if string(got) != want {
+		t.Fatalf("unexpected result: %s", got)
+	}
+	if _, err := jsontest.Normalize([]byte(`1 2`)); err == nil {
+		t.Fatal("expected error for multiple values")
+	}
+}
+
+func TestRawMessageEqual(t *testing.T) {
+	if !jsontest.RawMessageEqual(json.RawMessage(`{"a":1,"b":2}`), json.RawMessage(`{ "b": 2, "a": 1 }`)) {
+		t.Fatal("expected equal")
+	}
+	if jsontest.RawMessageEqual(json.RawMessage(`[1,2]`), json.RawMessage(`[2,1]`)) {
+		t.Fatal("expected not equal")
+	}
+	if jsontest.RawMessageEqual(json.RawMessage(`{`), json.RawMessage(`{}`)) {
+		t.Fatal("expected not equal")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !jsontest.Equal(t, []byte(`{"a":[1,2]}`), []byte(`{"a": [1, 2]}`)) {
+		t.Fatal("expected equal")
+	}
+	rec := &recorder{TB: t}
+	if jsontest.Equal(rec, []byte(`{"a":1,"b":2}`), []byte(`{"a":1,"b":3}`)) {
+		t.Fatal("expected not equal")
+	}
+	want := `JSON mismatch (-want +got):
+  {
+    "a": 1,
+-   "b": 2
++   "b": 3
+  }
+`
+	if rec.msg != want {
+		t.Fatalf("unexpected message:\n%s", rec.msg)
+	}
+}
+
+type recorder struct {
+	testing.TB
+	msg string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.msg = fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,64 @@
+package json
+
+// Kind is the type of a JSON value.
+type Kind int
+
+const (
+	// KindInvalid is not a JSON value, such as empty input or the end of an object or an array.
+	KindInvalid Kind = iota
+	KindNull
+	KindBool
+	KindNumber
+	KindString
+	KindObject
+	KindArray
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNull:
+		return "null"
+	case KindBool:
+		return "bool"
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindObject:
+		return "object"
+	case KindArray:
+		return "array"
+	}
+	return "invalid"
+}
+
+// KindOf returns the kind of the JSON value in data, skipping leading whitespace.
+// The kind is decided by the first character of the value, so data is not validated.
+func KindOf(data []byte) Kind {
+	for _, c := range data {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return kindOfChar(c)
+	}
+	return KindInvalid
+}
+
+func kindOfChar(c byte) Kind {
+	switch c {
+	case 'n':
+		return KindNull
+	case 't', 'f':
+		return KindBool
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return KindNumber
+	case '"':
+		return KindString
+	case '{':
+		return KindObject
+	case '[':
+		return KindArray
+	}
+	return KindInvalid
+}
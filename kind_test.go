@@ -0,0 +1,30 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		data string
+		want json.Kind
+	}{
+		{data: `null`, want: json.KindNull},
+		{data: ` true`, want: json.KindBool},
+		{data: "\n\tfalse", want: json.KindBool},
+		{data: `-1.5`, want: json.KindNumber},
+		{data: `0`, want: json.KindNumber},
+		{data: `"a"`, want: json.KindString},
+		{data: ` {"a":1}`, want: json.KindObject},
+		{data: `[]`, want: json.KindArray},
+		{data: ``, want: json.KindInvalid},
+		{data: `  `, want: json.KindInvalid},
+		{data: `}`, want: json.KindInvalid},
+	}
+	for _, tc := range tests {
+		assertEq(t, tc.data, tc.want, json.KindOf([]byte(tc.data)))
+	}
+	assertEq(t, "string", "object", json.KindObject.String())
+}
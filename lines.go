@@ -0,0 +1,164 @@
+package json
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// linesBufSize is the size of the buffer the lines are read into. A longer line is read in several parts.
+const linesBufSize = 64 * 1024
+
+// DecodeLines decodes each line of the JSON Lines ( NDJSON ) input read from r with DecodeOption,
+// and calls fn with the value, in order, until the end of the input:
+//
+//	err := json.DecodeLines(r, func(line json.RawMessage) error {
+//		...
+//	})
+//
+// Each line holds a whole value, and the empty lines are skipped. A *SyntaxError reports the offset in the whole input.
+// It stops at the first error, either of a line or returned by fn, and returns it.
+func DecodeLines[T any](r io.Reader, fn func(T) error, optFuncs ...DecodeOptionFunc) error {
+	var err error
+	readErr := readLines(r, func(line []byte, offset int64) bool {
+		var v T
+		if err = decodeLine(line, offset, &v, optFuncs); err == nil {
+			err = fn(v)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return readErr
+}
+
+// DecodeLinesParallel is like DecodeLines but decodes the lines in workers goroutines,
+// for an input whose decoding is the bottleneck, such as a large export.
+// fn is still called with the values in the order of the lines, from the calling goroutine.
+// At most a few lines per worker are read ahead of fn. With workers less than 2, it is DecodeLines.
+func DecodeLinesParallel[T any](r io.Reader, workers int, fn func(T) error, optFuncs ...DecodeOptionFunc) error {
+	if workers < 2 {
+		return DecodeLines(r, fn, optFuncs...)
+	}
+	var (
+		jobs    = make(chan *lineJob[T], workers)
+		ordered = make(chan *lineJob[T], 2*workers)
+		stop    = make(chan struct{})
+		readErr error
+		wg      sync.WaitGroup
+	)
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		readErr = readLines(r, func(line []byte, offset int64) bool {
+			job := &lineJob[T]{line: line, offset: offset, done: make(chan struct{})}
+			// the job is queued in order before it is decoded, so that fn waits for it.
+			select {
+			case ordered <- job:
+			case <-stop:
+				return false
+			}
+			select {
+			case jobs <- job:
+			case <-stop:
+				return false
+			}
+			return true
+		})
+	}()
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.err = decodeLine(job.line, job.offset, &job.value, optFuncs)
+				close(job.done)
+			}
+		}()
+	}
+	for job := range ordered {
+		<-job.done
+		err := job.err
+		if err == nil {
+			err = fn(job.value)
+		}
+		if err != nil {
+			close(stop)
+			for range ordered {
+			}
+			wg.Wait()
+			return err
+		}
+	}
+	wg.Wait()
+	return readErr
+}
+
+// lineJob is a line decoded by a worker of DecodeLinesParallel.
+type lineJob[T any] struct {
+	line   []byte
+	offset int64
+	value  T
+	err    error
+	// done is closed once the line is decoded.
+	done chan struct{}
+}
+
+// readLines calls yield with each line of r that is not empty, terminated with a nul character,
+// and its offset in the input, until the end of the input or until yield returns false.
+func readLines(r io.Reader, yield func(line []byte, offset int64) bool) error {
+	br := bufio.NewReaderSize(r, linesBufSize)
+	var (
+		offset int64
+		long   []byte
+	)
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			long = append(long, chunk...)
+			continue
+		}
+		line := chunk
+		if len(long) > 0 {
+			long = append(long, chunk...)
+			line = long
+		}
+		if !isBlank(line) {
+			// each line has its own buffer, since it is modified while it is decoded.
+			terminated := make([]byte, len(line)+1)
+			copy(terminated, line)
+			if !yield(terminated, offset) {
+				return nil
+			}
+		}
+		offset += int64(len(line))
+		long = long[:0]
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func isBlank(line []byte) bool {
+	for _, c := range line {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// decodeLine decodes the nul-terminated line at offset of the input into v.
+func decodeLine(line []byte, offset int64, v interface{}, optFuncs []DecodeOptionFunc) error {
+	err := unmarshalTerminated(line, v, optFuncs...)
+	if serr, ok := err.(*SyntaxError); ok {
+		serr.Offset += offset
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package json_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestDecodeLines(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	input := "{\"a\": 1}\r\n\n  \n{\"a\": 2, \"b\": \"" + long + "\"}\n{\"a\": 3}"
+	type T struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	t.Run("raw", func(t *testing.T) {
+		var lines []string
+		assertErr(t, json.DecodeLines(strings.NewReader(input), func(line json.RawMessage) error {
+			lines = append(lines, string(line))
+			return nil
+		}))
+		assertEq(t, "lines", 3, len(lines))
+		assertEq(t, "first", `{"a": 1}`, lines[0])
+		assertEq(t, "last", `{"a": 3}`, lines[2])
+	})
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("workers %d", workers), func(t *testing.T) {
+			var values []T
+			assertErr(t, json.DecodeLinesParallel(strings.NewReader(input), workers, func(v T) error {
+				values = append(values, v)
+				return nil
+			}))
+			assertEq(t, "values", 3, len(values))
+			assertEq(t, "long", long, values[1].B)
+			for i, v := range values {
+				assertEq(t, "order", i+1, v.A)
+			}
+		})
+	}
+	t.Run("order", func(t *testing.T) {
+		var b strings.Builder
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(&b, "%d\n", i)
+		}
+		next := 0
+		assertErr(t, json.DecodeLinesParallel(strings.NewReader(b.String()), 8, func(n int) error {
+			if n != next {
+				return fmt.Errorf("got %d instead of %d", n, next)
+			}
+			next++
+			return nil
+		}))
+		assertEq(t, "count", 1000, next)
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, workers := range []int{1, 4} {
+			src := "1\n2\n{\"a\": x}\n4\n"
+			var got []int
+			err := json.DecodeLinesParallel(strings.NewReader(src), workers, func(v interface{}) error {
+				got = append(got, int(v.(float64)))
+				return nil
+			})
+			serr, ok := err.(*json.SyntaxError)
+			if !ok {
+				t.Fatalf("unexpected error %T: %v", err, err)
+			}
+			// the offset is in the whole input.
+			if serr.Offset < 4 || serr.Offset > int64(len(src)) {
+				t.Fatalf("unexpected offset %d", serr.Offset)
+			}
+			assertEq(t, "before the error", "[1 2]", fmt.Sprint(got))
+
+			stop := errors.New("stop")
+			n := 0
+			err = json.DecodeLinesParallel(strings.NewReader(strings.Repeat("1\n", 100)), workers, func(int) error {
+				n++
+				if n == 3 {
+					return stop
+				}
+				return nil
+			})
+			assertEq(t, "fn error", stop, err)
+			assertEq(t, "calls", 3, n)
+		}
+	})
+}
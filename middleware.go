@@ -0,0 +1,178 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/going/json/internal/encoder"
+)
+
+// EncodeMiddleware transforms a value encoded by an Encoder.
+// path is the location of the value in the document, in the form of $.a[0].b as Path,
+// and raw is its compact encoding, which must not be retained.
+// It returns raw itself to leave the value unchanged, or the JSON encoding of the value to write instead.
+type EncodeMiddleware func(path string, raw []byte) ([]byte, error)
+
+// Use adds middleware invoked for every value the Encoder encodes, including the values nested in objects and arrays,
+// so that cross-cutting concerns such as the encryption of specific subtrees are applied without custom marshalers.
+// The nested values are transformed first, and their parent is given the transformed values.
+// The middleware are invoked in the order they are added, each given the output of the previous one.
+//
+// Each value is encoded entirely before the middleware are invoked, so SetFlushSize has no effect,
+// and the Colorize option must not be used.
+func (e *Encoder) Use(middleware ...EncodeMiddleware) {
+	e.middleware = append(e.middleware, middleware...)
+}
+
+// Use is like Encoder.Use. It applies to the values encoded after it returns.
+func (e *SyncEncoder) Use(middleware ...EncodeMiddleware) {
+	e.mu.Lock()
+	e.enc.Use(middleware...)
+	e.mu.Unlock()
+}
+
+// encodeLineWithMiddleware is like encodeLine for an Encoder with middleware, whose options are already applied to ctx.
+func (e *Encoder) encodeLineWithMiddleware(ctx *encoder.RuntimeContext, v interface{}) ([]byte, error) {
+	buf, err := encode(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	buf, err = e.applyMiddleware(buf[:len(buf)-1])
+	if err != nil {
+		return nil, err
+	}
+	if e.enabledIndent {
+		var indented bytes.Buffer
+		if err := encoder.Indent(&indented, buf, e.prefix, e.indentStr); err != nil {
+			return nil, err
+		}
+		buf = indented.Bytes()
+	}
+	return append(buf, '\n'), nil
+}
+
+// applyMiddleware returns the compact encoding src with the middleware of e applied to each value.
+func (e *Encoder) applyMiddleware(src []byte) ([]byte, error) {
+	w := middlewareWalker{
+		middleware: e.middleware,
+		src:        src,
+		out:        make([]byte, 0, len(src)),
+		path:       []byte{'$'},
+		escapeHTML: e.enabledHTMLEscape,
+	}
+	if _, err := w.walk(0); err != nil {
+		return nil, err
+	}
+	return w.out, nil
+}
+
+type middlewareWalker struct {
+	middleware []EncodeMiddleware
+	src        []byte
+	out        []byte
+	path       []byte
+	escapeHTML bool
+}
+
+// walk appends the value of src at cursor to out, transformed by the middleware, and returns the position next to it.
+func (w *middlewareWalker) walk(cursor int) (int, error) {
+	start := len(w.out)
+	pathLen := len(w.path)
+	switch w.src[cursor] {
+	case '{':
+		w.out = append(w.out, '{')
+		cursor++
+		for w.src[cursor] != '}' {
+			if w.src[cursor] == ',' {
+				w.out = append(w.out, ',')
+				cursor++
+			}
+			keyEnd := middlewareStringEnd(w.src, cursor)
+			key := w.src[cursor:keyEnd]
+			w.out = append(w.out, key...)
+			w.out = append(w.out, ':')
+			w.path = appendMiddlewarePathKey(w.path[:pathLen], key)
+			next, err := w.walk(keyEnd + 1) // skip ':'
+			if err != nil {
+				return 0, err
+			}
+			cursor = next
+		}
+		w.out = append(w.out, '}')
+		cursor++
+	case '[':
+		w.out = append(w.out, '[')
+		cursor++
+		for i := 0; w.src[cursor] != ']'; i++ {
+			if w.src[cursor] == ',' {
+				w.out = append(w.out, ',')
+				cursor++
+			}
+			w.path = encoder.AppendPathIndex(w.path[:pathLen], i)
+			next, err := w.walk(cursor)
+			if err != nil {
+				return 0, err
+			}
+			cursor = next
+		}
+		w.out = append(w.out, ']')
+		cursor++
+	case '"':
+		end := middlewareStringEnd(w.src, cursor)
+		w.out = append(w.out, w.src[cursor:end]...)
+		cursor = end
+	default:
+		end := cursor
+		for end < len(w.src) && !isMiddlewareDelimiter(w.src[end]) {
+			end++
+		}
+		w.out = append(w.out, w.src[cursor:end]...)
+		cursor = end
+	}
+	w.path = w.path[:pathLen]
+
+	raw := w.out[start:len(w.out):len(w.out)]
+	path := string(w.path)
+	for _, mw := range w.middleware {
+		transformed, err := mw(path, raw)
+		if err != nil {
+			return 0, err
+		}
+		if len(transformed) == len(raw) && (len(raw) == 0 || &transformed[0] == &raw[0]) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := encoder.Compact(&buf, transformed, w.escapeHTML); err != nil {
+			return 0, fmt.Errorf("json: invalid output of encode middleware at %s: %w", path, err)
+		}
+		raw = buf.Bytes()
+	}
+	w.out = append(w.out[:start], raw...)
+	return cursor, nil
+}
+
+// middlewareStringEnd returns the position next to the closing quote of the string of src at cursor.
+func middlewareStringEnd(src []byte, cursor int) int {
+	for cursor++; src[cursor] != '"'; cursor++ {
+		if src[cursor] == '\\' {
+			cursor++
+		}
+	}
+	return cursor + 1
+}
+
+func isMiddlewareDelimiter(c byte) bool {
+	return c == ',' || c == ']' || c == '}'
+}
+
+// appendMiddlewarePathKey appends the path element of the quoted key to path.
+func appendMiddlewarePathKey(path []byte, quoted []byte) []byte {
+	key := string(quoted[1 : len(quoted)-1])
+	if bytes.IndexByte(quoted, '\\') >= 0 {
+		if unquoted, err := strconv.Unquote(string(quoted)); err == nil {
+			key = unquoted
+		}
+	}
+	return encoder.AppendPathKey(path, key)
+}
@@ -0,0 +1,107 @@
+package json_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestEncoder_Use(t *testing.T) {
+	type Card struct {
+		Number string `json:"number"`
+		Holder string `json:"holder"`
+	}
+	type Order struct {
+		ID    int               `json:"id"`
+		Card  Card              `json:"card"`
+		Items []string          `json:"items"`
+		Meta  map[string]string `json:"meta"`
+	}
+	order := Order{
+		ID:    1,
+		Card:  Card{Number: "4111", Holder: "A"},
+		Items: []string{"x", "a long description"},
+		Meta:  map[string]string{"a.b": "c"},
+	}
+	t.Run("paths", func(t *testing.T) {
+		var paths []string
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.Use(func(path string, raw []byte) ([]byte, error) {
+			paths = append(paths, path+"="+string(raw))
+			return raw, nil
+		})
+		assertErr(t, enc.Encode(order))
+		assertEq(t, "output", `{"id":1,"card":{"number":"4111","holder":"A"},"items":["x","a long description"],"meta":{"a.b":"c"}}`+"\n", buf.String())
+		assertEq(t, "paths", strings.Join([]string{
+			`$.id=1`,
+			`$.card.number="4111"`,
+			`$.card.holder="A"`,
+			`$.card={"number":"4111","holder":"A"}`,
+			`$.items[0]="x"`,
+			`$.items[1]="a long description"`,
+			`$.items=["x","a long description"]`,
+			`$.meta['a.b']="c"`,
+			`$.meta={"a.b":"c"}`,
+			`$={"id":1,"card":{"number":"4111","holder":"A"},"items":["x","a long description"],"meta":{"a.b":"c"}}`,
+		}, "\n"), strings.Join(paths, "\n"))
+	})
+	t.Run("transform", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", " ")
+		enc.Use(func(path string, raw []byte) ([]byte, error) {
+			if path == "$.card" {
+				return []byte(`"` + base64.StdEncoding.EncodeToString(raw) + `"`), nil
+			}
+			return raw, nil
+		}, func(path string, raw []byte) ([]byte, error) {
+			if len(raw) > 10 && raw[0] == '"' && !strings.HasPrefix(path, "$.card") {
+				return []byte(fmt.Sprintf(`{ "truncated" : %s" }`, raw[:6])), nil
+			}
+			return raw, nil
+		})
+		assertErr(t, enc.Encode(order))
+		var decoded struct {
+			Card  []byte          `json:"card"`
+			Items json.RawMessage `json:"items"`
+		}
+		assertErr(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assertEq(t, "card", `{"number":"4111","holder":"A"}`, string(decoded.Card))
+		assertEq(t, "items", "[\n  \"x\",\n  {\n   \"truncated\": \"a lon\"\n  }\n ]", string(decoded.Items))
+	})
+	t.Run("error", func(t *testing.T) {
+		enc := json.NewEncoder(&bytes.Buffer{})
+		errDenied := errors.New("denied")
+		enc.Use(func(path string, raw []byte) ([]byte, error) {
+			if path == "$.card.number" {
+				return nil, errDenied
+			}
+			return raw, nil
+		})
+		assertEq(t, "error", errDenied, enc.Encode(order))
+
+		enc = json.NewEncoder(&bytes.Buffer{})
+		enc.Use(func(path string, raw []byte) ([]byte, error) {
+			return []byte("{"), nil
+		})
+		assertNeq(t, "invalid output", nil, enc.Encode(1))
+	})
+	t.Run("sync", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewSyncEncoder(&buf)
+		enc.Use(func(path string, raw []byte) ([]byte, error) {
+			if path == "$" {
+				return []byte(`"redacted"`), nil
+			}
+			return raw, nil
+		})
+		assertErr(t, enc.Encode(order))
+		assertEq(t, "output", `"redacted"`+"\n", buf.String())
+	})
+}
@@ -2,21 +2,57 @@ package json
 
 import (
 	"io"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/going/json/internal/decoder"
 	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/runtime"
 )
 
 type EncodeOption = encoder.Option
+
+// EncodeOptionFunc is an option of MarshalWithOption, MarshalIndentWithOption, MarshalContext and Encoder.EncodeWithOption.
+// An option is a value that can be built once and passed to any number of encodes.
 type EncodeOptionFunc func(*EncodeOption)
 
+// EncodeOptions bundles optFuncs into a single option, applied in order,
+// so that a set of options is built once and passed everywhere:
+//
+//	var apiOptions = json.EncodeOptions(json.UnorderedMap(), json.DisableHTMLEscape())
+//
+//	b, err := json.MarshalWithOption(v, apiOptions)
+func EncodeOptions(optFuncs ...EncodeOptionFunc) EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		for _, optFunc := range optFuncs {
+			optFunc(opt)
+		}
+	}
+}
+
 // UnorderedMap doesn't sort when encoding map type.
+// The entries of a map are written in its iteration order, without buffering them to sort their keys,
+// so the output of the same map may differ between encodes. See also Encoder.SetUnorderedMap.
 func UnorderedMap() EncodeOptionFunc {
 	return func(opt *EncodeOption) {
 		opt.Flag |= encoder.UnorderedMapOption
 	}
 }
 
+// StreamSortedMap changes how a map is sorted when encoding map type.
+// By default, every encoded key and value of a map is buffered and then sorted by the key.
+// With this option, the keys are sorted first and each value is encoded in order directly,
+// so that only the encoded keys are buffered. The output is the same.
+// It is useful with Encoder.SetFlushSize to encode huge maps deterministically with bounded memory.
+// Maps whose key type is a pointer type implementing encoding.TextMarshaler are sorted in the default way.
+// This option is ignored if UnorderedMap is specified.
+func StreamSortedMap() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.PresortMapOption
+	}
+}
+
 // DisableHTMLEscape disables escaping of HTML characters ( '&', '<', '>' ) when encoding string.
 func DisableHTMLEscape() EncodeOptionFunc {
 	return func(opt *EncodeOption) {
@@ -34,6 +70,252 @@ func DisableNormalizeUTF8() EncodeOptionFunc {
 	}
 }
 
+// ForceIncludeEmpty encodes every struct field as if it had no omitempty tag option.
+// It is useful for debug dumps that must show all fields.
+// The first encoding of each type with this option compiles a separate opcode sequence.
+func ForceIncludeEmpty() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag &= ^encoder.ForceOmitEmptyOption
+		opt.Flag |= encoder.ForceIncludeEmptyOption
+	}
+}
+
+// ForceOmitEmpty encodes every struct field as if it had the omitempty tag option.
+// It is useful for sparse exports.
+// The first encoding of each type with this option compiles a separate opcode sequence.
+func ForceOmitEmpty() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag &= ^encoder.ForceIncludeEmptyOption
+		opt.Flag |= encoder.ForceOmitEmptyOption
+	}
+}
+
+// NilSliceAsEmpty encodes nil slices as an empty array ( [] ) instead of null.
+// A []byte is still encoded as null, since it is encoded as a base64 string otherwise.
+// Nil pointers to slices are encoded as null.
+func NilSliceAsEmpty() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.NilSliceAsEmptyOption
+	}
+}
+
+// NilMapAsEmpty encodes nil maps as an empty object ( {} ) instead of null.
+// Nil pointers to maps are encoded as null.
+func NilMapAsEmpty() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.NilMapAsEmptyOption
+	}
+}
+
+// OmitNilPointers omits struct fields of pointer type when they are nil, instead of encoding them as null.
+// A single field can be configured in the same way by the omitnil tag option ( `json:"name,omitnil"` ).
+// Unlike omitempty, other empty values such as zero numbers, empty strings or nil slices are still encoded.
+// ForceIncludeEmpty takes precedence over this option and over the omitnil tag option.
+func OmitNilPointers() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.OmitNilPointerOption
+	}
+}
+
+// UsePointerReceiverMarshalers makes the encoder call MarshalJSON and MarshalText methods with pointer receiver
+// also on values that are not addressable, such as map values, values stored in interfaces and values passed to Marshal directly.
+// The value is copied to make it addressable before the method is called.
+// By default, such values are encoded as if the methods did not exist, like encoding/json.
+func UsePointerReceiverMarshalers() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.PtrMarshalerOption
+	}
+}
+
+// WithoutCustomMethods encodes the values of the types of values as if they had no MarshalJSON and MarshalText methods,
+// with the default codec of their kind, such as the fields of a struct. It is useful for third-party types whose
+// methods produce the wrong wire format:
+//
+//	b, err := json.MarshalWithOption(v, json.WithoutCustomMethods(thirdparty.Event{}))
+//
+// A pointer in values stands for the type it points to, and the methods of pointers to the types are ignored as well.
+// The keys of maps are encoded with their MarshalText methods as usual.
+// Use DecodeWithoutCustomMethods to decode them.
+// The first encoding of each type with a set of types compiles a separate opcode sequence.
+func WithoutCustomMethods(values ...interface{}) EncodeOptionFunc {
+	types := runtime.NewTypeSet(values)
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.NoMethodsOption
+		opt.NoMethodTypes = types
+	}
+}
+
+// WithTagKey reads the names and the options of the struct fields from the struct tags of key instead of json,
+// so that one struct definition drives several wire formats:
+//
+//	type User struct {
+//		ID string `json:"id" api:"user_id"`
+//	}
+//	b, err := json.MarshalWithOption(u, json.WithTagKey("api")) // {"user_id":"..."}
+//
+// The fields without a tag of key are named after the field, and the json and db tags are not read.
+// Use DecodeWithTagKey to decode them. If key is empty, the json tags are read as usual.
+// The first encoding of each type with a key compiles a separate opcode sequence.
+func WithTagKey(key string) EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag &^= encoder.TagKeyOption
+		if key != "" {
+			opt.Flag |= encoder.TagKeyOption
+			opt.TagKey = key
+		}
+	}
+}
+
+// FieldNaming is the case of the keys of the struct fields that are not named by their tag, see FieldNamingStrategy.
+type FieldNaming = runtime.FieldNaming
+
+const (
+	// FieldNameAsIs names the fields as they are named in Go, which is the default.
+	FieldNameAsIs = runtime.FieldNameAsIs
+	// SnakeCase names the fields in snake_case, e.g. UserID as user_id and HTTPServer as http_server.
+	SnakeCase = runtime.SnakeCase
+	// CamelCase names the fields in camelCase, e.g. UserID as userID and HTTPServer as httpServer.
+	CamelCase = runtime.CamelCase
+	// KebabCase names the fields in kebab-case, e.g. UserID as user-id and HTTPServer as http-server.
+	KebabCase = runtime.KebabCase
+)
+
+// FieldNamingStrategy encodes the struct fields without a name in their tag with keys in the case of naming,
+// instead of their Go name, so that the fields need not all be tagged:
+//
+//	type User struct {
+//		UserID    string
+//		CreatedAt time.Time
+//		Email     string `json:"mail"`
+//	}
+//	b, err := json.MarshalWithOption(u, json.FieldNamingStrategy(json.SnakeCase)) // {"user_id":"...","created_at":"...","mail":"..."}
+//
+// The names of the tags are kept as they are. Use DecodeFieldNamingStrategy to decode them.
+// The first encoding of each type with a naming compiles a separate opcode sequence.
+func FieldNamingStrategy(naming FieldNaming) EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag &^= encoder.FieldNamingOption
+		if naming != FieldNameAsIs {
+			opt.Flag |= encoder.FieldNamingOption
+			opt.FieldNaming = naming
+		}
+	}
+}
+
+// RedactPaths replaces the values at the JSON Pointers of paths with "[REDACTED]" in the output,
+// whatever their type and struct tags, so that a logging policy applies to any value:
+//
+//	b, err := json.MarshalWithOption(v, json.RedactPaths("/user/ssn", "/cards/*/number"))
+//
+// A "*" reference token matches any key of an object or index of an array. The paths are matched
+// with the keys of the output, after WithTagKey and FieldNamingStrategy. The encoded value is rewritten
+// as a whole, so an Encoder with SetFlushSize does not flush it halfway.
+// RedactPaths panics if a path is neither empty nor starts with "/". See also WithRedactPaths for Transcode.
+func RedactPaths(paths ...string) EncodeOptionFunc {
+	redactPaths := mustRedactPaths(paths)
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.RedactOption
+		opt.RedactPaths = redactPaths
+	}
+}
+
+func mustRedactPaths(paths []string) *encoder.RedactPaths {
+	redactPaths, err := encoder.NewRedactPaths(paths)
+	if err != nil {
+		panic(err)
+	}
+	return redactPaths
+}
+
+// AuditEvent is a field or a key reported by Audit or DecodeAudit, with the JSON Pointer of its value as Path.
+type AuditEvent = runtime.AuditEvent
+
+// AuditKind is the kind of an AuditEvent.
+type AuditKind = runtime.AuditKind
+
+const (
+	// AuditOmitted is a struct field omitted from the output by Audit, such as an empty omitempty field.
+	AuditOmitted = runtime.AuditOmitted
+	// AuditUnknownKey is a key of the input ignored by DecodeAudit, since no struct field matches it.
+	AuditUnknownKey = runtime.AuditUnknownKey
+	// AuditDefaulted is a struct field whose key is missing from the input of DecodeAudit, which keeps its value.
+	AuditDefaulted = runtime.AuditDefaulted
+)
+
+// Audit calls collect with every struct field omitted from the output, by the omitempty, omitzero and omitnil
+// tag options, ForceOmitEmpty or OmitNilPointer, so that operators can audit the data lost across a serialization boundary:
+//
+//	var omitted []string
+//	b, err := json.MarshalWithOption(v, json.Audit(func(e json.AuditEvent) {
+//		omitted = append(omitted, e.Path) // "/user/email"
+//	}))
+//
+// The fields are reported in the order they are encoded, once the value has been encoded successfully.
+// Finding them walks the value a second time with reflection, so Audit is meant for audits rather than hot paths.
+// The fields of the values encoded by their MarshalJSON or MarshalText methods are not reported.
+func Audit(collect func(AuditEvent)) EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag &^= encoder.AuditOption
+		if collect != nil {
+			opt.Flag |= encoder.AuditOption
+			opt.Audit = collect
+		}
+	}
+}
+
+// TimesInUTC converts time.Time values to UTC before they are encoded, as if by WithTimeLocation(time.UTC).
+func TimesInUTC() EncodeOptionFunc {
+	return WithTimeLocation(time.UTC)
+}
+
+// WithTimeLocation converts time.Time and *time.Time values to loc before they are encoded,
+// so that their representation has the offset of loc regardless of the location each value was created in.
+// It applies to values, struct fields, map values and map keys whose type is exactly time.Time or *time.Time.
+// Types defined from time.Time and structs embedding it are encoded by their own methods as usual.
+func WithTimeLocation(loc *time.Location) EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.TimeLocationOption
+		opt.TimeLocation = loc
+	}
+}
+
+// Int64AsString encodes 64-bit integers as JSON strings ( e.g. "9007199254740993" ),
+// so that JavaScript consumers, whose numbers represent integers exactly only up to 2^53, do not lose precision.
+// It applies to int64 and uint64, and to int, uint and uintptr on 64-bit platforms.
+// The bigstring tag option does the same for a single struct field, but only for integers whose magnitude exceeds 2^53-1.
+// Use AcceptInt64AsString to decode them.
+func Int64AsString() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.Int64AsStringOption
+	}
+}
+
+// FloatExponentThreshold encodes floats whose absolute value has up to n digits before the decimal point in plain decimal form,
+// and larger floats in exponent form ( e.g. 1e+21 ), for consumers that cannot parse the exponent form.
+// By default, n is 21 as in encoding/json. Floats below 1e-6 are still encoded in exponent form.
+// If n <= 0, all floats are encoded in plain decimal form regardless of their magnitude.
+// Number values are encoded as they are.
+func FloatExponentThreshold(n int) EncodeOptionFunc {
+	threshold := math.Inf(1)
+	if n > 0 {
+		threshold = math.Pow10(n)
+	}
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.FloatExpThresholdOption
+		opt.FloatExpThreshold = threshold
+	}
+}
+
+// CompactMarshalerOutput makes the indented encodings embed the output of MarshalJSON methods, such as of a RawMessage,
+// and of the codecs registered by RegisterCodec compacted on a single line, instead of re-indented to the indentation
+// of the surrounding value. The output is validated either way, and the compact encodings always compact it.
+// It has no effect when the indent VMs are dropped by the json_noindentvm build tag, since the whole output is indented afterwards.
+func CompactMarshalerOutput() EncodeOptionFunc {
+	return func(opt *EncodeOption) {
+		opt.Flag |= encoder.CompactMarshalerOption
+	}
+}
+
 // Debug outputs debug information when panic occurs during encoding.
 func Debug() EncodeOptionFunc {
 	return func(opt *EncodeOption) {
@@ -56,6 +338,9 @@ func DebugDOT(w io.WriteCloser) EncodeOptionFunc {
 }
 
 // Colorize add an identifier for coloring to the string of the encoded result.
+// Building with the json_nocolor tag drops the dedicated color VMs from the binary;
+// the result is then colorized after encoding, which is slower, and the keys of maps are colored as ObjectKey
+// and the []byte values as String, since the types of the values are not known anymore.
 func Colorize(scheme *ColorScheme) EncodeOptionFunc {
 	return func(opt *EncodeOption) {
 		opt.Flag |= encoder.ColorizeOption
@@ -64,8 +349,41 @@ func Colorize(scheme *ColorScheme) EncodeOptionFunc {
 }
 
 type DecodeOption = decoder.Option
+
+// DecodeOptionFunc is an option of UnmarshalWithOption, UnmarshalContext and Decoder.DecodeWithOption.
+// An option is a value that can be built once and passed to any number of decodes.
 type DecodeOptionFunc func(*DecodeOption)
 
+// DecodeOptions bundles optFuncs into a single option, applied in order. See EncodeOptions.
+func DecodeOptions(optFuncs ...DecodeOptionFunc) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		for _, optFunc := range optFuncs {
+			optFunc(opt)
+		}
+	}
+}
+
+// DisallowUnknownFields makes the decode fail with an *UnknownFieldError if the input contains object keys
+// which do not match any non-ignored, exported fields of the destination struct, as Decoder.DisallowUnknownFields.
+// The value is decoded entirely before the error is returned: if there are more than one unknown fields,
+// the error is an *UnknownFieldsError listing them. LenientPaths still skips the unknown fields of its subtrees.
+//
+// The context given to an UnmarshalerContext implementation carries the option, so that the values it decodes
+// by UnmarshalContext or Decoder.DecodeContext with this context disallow the unknown fields too:
+//
+//	func (v *Wrapper) UnmarshalJSON(ctx context.Context, b []byte) error {
+//		return json.UnmarshalContext(ctx, b, &v.inner) // strict if the caller is
+//	}
+//
+// Only an UnmarshalerContext implementation is reached: an Unmarshaler implementation has no context,
+// so the values it decodes by Unmarshal are not strict unless it passes DisallowUnknownFields to UnmarshalWithOption itself.
+func DisallowUnknownFields() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		// the paths of the unknown fields are tracked as with StrictPaths.
+		opt.Flags |= decoder.DisallowUnknownOption | decoder.PathModeOption
+	}
+}
+
 // DecodeFieldPriorityFirstWin
 // in the default behavior, go-json, like encoding/json,
 // will reflect the result of the last evaluation when a field with the same name exists.
@@ -77,3 +395,353 @@ func DecodeFieldPriorityFirstWin() DecodeOptionFunc {
 		opt.Flags |= decoder.FirstWinOption
 	}
 }
+
+// TrustedInput skips validation that is redundant for input this process produced itself
+// or has already validated, such as UTF-8 validation of strings and syntax checks of numbers.
+//
+// This option must not be used for untrusted input.
+// Invalid UTF-8 sequences are passed through as-is instead of being replaced with U+FFFD,
+// and malformed numbers may be accepted or decoded into unexpected values.
+func TrustedInput() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.TrustedInputOption
+	}
+}
+
+// AliasRawMessages makes the RawMessage values, such as those of a map[string]RawMessage, share memory
+// with the input instead of being copied one by one, for the routing layers that only pass them on.
+// Unmarshal copies data once as usual, and the values are slices of that copy, so they stay valid after it returns.
+// With File.Unmarshal, the values refer to the file contents and must not be used after File.Close.
+// A Decoder reading from an io.Reader still copies the values, since its buffer is reused for the next ones.
+// The values have no spare capacity, so appending to one never overwrites the others.
+func AliasRawMessages() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.RawAliasOption
+	}
+}
+
+// AcceptSpecialFloats accepts the strings "NaN", "Infinity" and "-Infinity" as the values of float fields,
+// as some producers write the float values that JSON cannot represent.
+// The bare literals NaN, Infinity and -Infinity, as written by JSON5 and Python, are accepted as well.
+// The values are accepted only where a float32 or float64 is expected; elsewhere they are decoded as usual.
+func AcceptSpecialFloats() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.SpecialFloatOption
+	}
+}
+
+// AcceptInt64AsString accepts 64-bit integers quoted as JSON strings as well as numbers,
+// so that the output of Int64AsString is decoded transparently.
+// It applies to int64 and uint64, and to int, uint and uintptr on 64-bit platforms.
+// Struct fields with the bigstring tag option accept both forms without it.
+func AcceptInt64AsString() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.Int64StringOption
+	}
+}
+
+// MaxObjectKeys limits the number of keys of any single object to perObject,
+// and the number of object keys in the whole document to perDocument,
+// so that an input with huge objects cannot make the decoder build huge maps.
+// The keys of objects decoded into maps, structs and interface{} are counted, including duplicate keys,
+// but not those of values skipped without being decoded. A limit <= 0 means no limit.
+// If a limit is exceeded, the decode returns a *KeyLimitError.
+// For a Decoder, the document is each value decoded.
+func MaxObjectKeys(perObject, perDocument int) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.KeyLimitOption
+		opt.MaxObjectKeys = perObject
+		opt.MaxDocumentKeys = perDocument
+	}
+}
+
+// MaxStringLength limits the length of the strings, including the object keys, to n bytes,
+// counted in the input between the quotes, before the escape sequences are decoded.
+// If a string is longer, the decode fails with a *SyntaxError matching ErrStringTooLong,
+// before the destination is modified. n <= 0 means no limit.
+// The value is validated in a separate pass before it is decoded, as with StrictRFC8259.
+func MaxStringLength(n int) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		if n <= 0 {
+			opt.Flags &^= decoder.StringLimitOption
+			return
+		}
+		opt.Flags |= decoder.StringLimitOption
+		opt.MaxStringLength = n
+	}
+}
+
+// DecodeWithoutCustomMethods decodes the values of the types of values as if they had no UnmarshalJSON and UnmarshalText methods,
+// with the default codec of their kind, such as the fields of a struct. See WithoutCustomMethods.
+// The decode functions of a DecodeRegistry still take precedence.
+func DecodeWithoutCustomMethods(values ...interface{}) DecodeOptionFunc {
+	types := runtime.NewTypeSet(values)
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.NoMethodsOption
+		opt.NoMethodTypes = types
+	}
+}
+
+// DecodeWithTagKey matches the keys of the objects with the struct fields named by the struct tags of key
+// instead of json. See WithTagKey.
+// The decode functions of a DecodeRegistry still take precedence.
+func DecodeWithTagKey(key string) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags &^= decoder.TagKeyOption
+		if key != "" {
+			opt.Flags |= decoder.TagKeyOption
+			opt.TagKey = key
+		}
+	}
+}
+
+// DecodeFieldNamingStrategy matches the keys of the objects with the struct fields without a name in their tag
+// by their name in the case of naming, instead of their Go name. See FieldNamingStrategy.
+// The decode functions of a DecodeRegistry still take precedence.
+func DecodeFieldNamingStrategy(naming FieldNaming) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags &^= decoder.FieldNamingOption
+		if naming != FieldNameAsIs {
+			opt.Flags |= decoder.FieldNamingOption
+			opt.FieldNaming = naming
+		}
+	}
+}
+
+// DecodeAudit calls collect with every key of the input ignored since no struct field matches it,
+// and every struct field whose key is missing from its object, which keeps its value, as AuditDefaulted.
+// See Audit for the encoding. The events are reported while decoding, so a decode that fails may report some.
+// DisallowUnknownFields and StrictPaths still make the unknown keys errors instead.
+func DecodeAudit(collect func(AuditEvent)) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags &^= decoder.AuditOption
+		if collect == nil {
+			return
+		}
+		if opt.Flags&decoder.PathModeOption == 0 {
+			opt.PathModes = nil
+		}
+		// the paths of the keys are tracked as with StrictPaths.
+		opt.Flags |= decoder.AuditOption | decoder.PathModeOption
+		opt.Audit = collect
+	}
+}
+
+// RejectDuplicateKeys makes the decode fail with a *DuplicateKeyError, before the destination is modified,
+// if an object of the value has the same key more than once, compared after unescaping,
+// instead of keeping the last value. It applies to the objects decoded into structs, maps and interface{} alike:
+//
+//	err := json.UnmarshalWithOption([]byte(`{"role":"user","role":"admin"}`), &req, json.RejectDuplicateKeys())
+//	// err matches json.ErrDuplicateKey
+//
+// The value is validated in a separate pass before it is decoded, as with StrictRFC8259.
+func RejectDuplicateKeys() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.DuplicateKeyOption
+	}
+}
+
+// StrictMaxDepth is the maximum nesting depth of the values decoded with StrictRFC8259.
+const StrictMaxDepth = 512
+
+// StrictRFC8259 enables a strict posture for untrusted input with a single option.
+// The decode fails with a *SyntaxError, before the destination is modified, if the value
+//   - has strings with invalid UTF-8 or unescaped control characters, instead of replacing them with U+FFFD or accepting them,
+//   - has objects with duplicate keys, compared after unescaping, instead of keeping the last value,
+//     as with RejectDuplicateKeys ( the error is a *DuplicateKeyError wrapping the *SyntaxError ),
+//   - is nested deeper than StrictMaxDepth ( ErrDepthExceeded ).
+//
+// It disables TrustedInput and AcceptSpecialFloats given before it, so that NaN and Infinity are rejected.
+// Trailing data after the value is always rejected by Unmarshal; a Decoder reads the values of a stream one by one.
+// The value is validated in a separate pass before it is decoded.
+func StrictRFC8259() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags &^= decoder.TrustedInputOption | decoder.SpecialFloatOption
+		opt.Flags |= decoder.StrictOption
+		opt.MaxDepth = StrictMaxDepth
+	}
+}
+
+// ConfigFiles accepts the extensions of human-authored configuration files, such as .jsonc files, with a single option:
+// "//" and "/* */" comments, trailing commas in objects and arrays, and a leading UTF-8 byte order mark.
+// Decode errors with an offset are wrapped in a *PositionError with the line and column where they occurred.
+//
+// The extensions are rewritten into whitespace in the decoder's copy of the input before it is decoded,
+// so a Decoder reads the rest of its input entirely at the first Decode with this option.
+// The lines a Decoder has read before are not counted.
+func ConfigFiles() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.RelaxedOption
+	}
+}
+
+// StrictPaths makes the unknown fields of the structs in the subtrees at the paths matching patterns errors,
+// even with Unmarshal or a Decoder that allows them.
+// A pattern is the keys of the objects and the indexes of the arrays from the root separated by dots,
+// where "*" matches any key or index, such as "config.*" or "items.*.price". It matches the subtree below it.
+// Where patterns of StrictPaths and LenientPaths overlap, the longest one wins.
+// The unknown fields are reported together after the value is decoded ( see UnknownFieldsError ).
+func StrictPaths(patterns ...string) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		addPathModes(opt, patterns, true)
+	}
+}
+
+// LenientPaths tolerates the unknown fields and the type errors in the subtrees at the paths matching patterns,
+// so that sloppy sections supplied by others do not fail the whole decode. See StrictPaths for the patterns.
+// A value with a type error is skipped, leaving the destination as decoded so far, as encoding/json does,
+// so the element of a map is stored with the zero value.
+func LenientPaths(patterns ...string) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		addPathModes(opt, patterns, false)
+	}
+}
+
+func addPathModes(opt *DecodeOption, patterns []string, strict bool) {
+	if opt.Flags&decoder.PathModeOption == 0 {
+		opt.PathModes = nil
+	}
+	opt.Flags |= decoder.PathModeOption
+NEXT:
+	for _, pattern := range patterns {
+		var keys []string
+		if pattern != "" {
+			keys = strings.Split(pattern, ".")
+		}
+		for _, m := range opt.PathModes {
+			if m.Strict == strict && strings.Join(m.Pattern, ".") == pattern {
+				continue NEXT
+			}
+		}
+		opt.PathModes = append(opt.PathModes, decoder.PathMode{Pattern: keys, Strict: strict})
+	}
+}
+
+// WithSliceCapacityHint allocates room for n elements to decode the slice that is the decoded value itself,
+// such as a []Record of about n records, instead of growing the buffer of the elements as they are decoded.
+// It does not apply to the slices nested in the value, so that they are not oversized.
+// The decoded slice has the capacity of its length as usual.
+func WithSliceCapacityHint(n int) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		resetSizeHints(opt)
+		opt.SliceCapacityHint = n
+	}
+}
+
+// WithMapSizeHint allocates the map that is the decoded value itself for n elements,
+// such as a map[string]Record of about n records, instead of growing it as the keys are decoded.
+// It does not apply to the maps nested in the value, nor to an existing map which is decoded into.
+func WithMapSizeHint(n int) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		resetSizeHints(opt)
+		opt.MapSizeHint = n
+	}
+}
+
+func resetSizeHints(opt *DecodeOption) {
+	if opt.Flags&decoder.SizeHintOption == 0 {
+		opt.SliceCapacityHint, opt.MapSizeHint = 0, 0
+	}
+	opt.Flags |= decoder.SizeHintOption
+}
+
+// PresizeContainers counts the elements of every array and object before decoding it into a slice or a map,
+// so that they are allocated with their final size, such as for large homogeneous arrays.
+// The count is an extra pass over the input at each nesting level, which pays off for large containers of small values.
+// It takes precedence over WithSliceCapacityHint and WithMapSizeHint.
+// A Decoder reading from an io.Reader does not count the elements, since they may not have been read yet.
+func PresizeContainers() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.PrescanOption
+	}
+}
+
+// CacheShapes caches the keys of the objects decoded into interface{} during the decode.
+// When many objects have the same keys in the same order, as records of telemetry usually do,
+// their maps are allocated with the final size and share the key strings, which cuts the allocations.
+// The decoded values are the same as without it.
+// Use Decoder.CacheShapes to keep the cache across the values of a stream.
+func CacheShapes() DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.ShapeCacheOption
+		opt.Shapes = decoder.NewShapeCache()
+	}
+}
+
+// Backend is the way the input of Unmarshal is read.
+type Backend uint8
+
+const (
+	// BackendScanner reads the input incrementally, scanning every value to decode or to skip it. This is the default,
+	// and the only backend of a Decoder.
+	BackendScanner Backend = iota
+	// BackendStructuralIndex reads the input in two stages, as simdjson does: a first pass indexes the brackets
+	// of the whole input 64 bytes at a time, then the values are decoded as usual, but the objects and arrays
+	// that are skipped rather than decoded are jumped over by looking up their end in the index.
+	// The first pass costs about as much as scanning the input once, so it pays off only when the decode skips
+	// large parts of the input more than once, such as with PresizeContainers, or with RawMessage and Unmarshaler values,
+	// LenientPaths and unknown fields inside of them.
+	// The errors and the decoded values are the same as with BackendScanner.
+	BackendStructuralIndex
+)
+
+// DecodeBackend sets the way the input of Unmarshal is read.
+func DecodeBackend(backend Backend) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags &^= decoder.IndexOption
+		if backend == BackendStructuralIndex {
+			opt.Flags |= decoder.IndexOption
+		}
+	}
+}
+
+// SlicePolicy controls how decoding into a non-nil slice treats its backing array.
+type SlicePolicy uint8
+
+const (
+	// SliceReuse truncates the slice and decodes into its backing array if it is large enough.
+	// Elements that already exist are decoded into as encoding/json does. This is the default.
+	SliceReuse SlicePolicy = iota
+	// SliceReuseZeroTail is like SliceReuse but also zeroes the elements between the new length and the capacity,
+	// so that the backing array keeps no stale values or references. It is useful for slices reused from a pool.
+	SliceReuseZeroTail
+	// SliceRealloc always allocates a new backing array,
+	// so that the decoded slice never shares memory with the previous one.
+	SliceRealloc
+)
+
+// DecodeSlicePolicy sets how decoding into a non-nil slice treats its backing array.
+func DecodeSlicePolicy(policy SlicePolicy) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags &^= decoder.SliceZeroTailOption | decoder.SliceReallocOption
+		switch policy {
+		case SliceReuseZeroTail:
+			opt.Flags |= decoder.SliceZeroTailOption
+		case SliceRealloc:
+			opt.Flags |= decoder.SliceReallocOption
+		}
+	}
+}
+
+// MapPolicy controls how decoding into a non-nil map treats its existing entries.
+type MapPolicy uint8
+
+const (
+	// MapMerge keeps the existing entries and stores decoded keys into the map. This is the default.
+	MapMerge MapPolicy = iota
+	// MapReplace clears the map before storing decoded keys,
+	// so that the map contains only the keys of the input afterwards.
+	// The map itself is reused, so other references to it observe the new contents.
+	MapReplace
+)
+
+// DecodeMapPolicy sets how decoding into a non-nil map treats its existing entries.
+func DecodeMapPolicy(policy MapPolicy) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		if policy == MapReplace {
+			opt.Flags |= decoder.MapClearOption
+		} else {
+			opt.Flags &^= decoder.MapClearOption
+		}
+	}
+}
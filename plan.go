@@ -0,0 +1,48 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/going/json/internal/decoder"
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/runtime"
+)
+
+// Plan returns a textual description of how values of the type of v are encoded and decoded without options:
+// the opcodes of the encoder, as DebugOption prints them, followed by the tree of the decoders.
+// It is meant for golden tests that catch changes of the encoding of a type between releases:
+//
+//	func TestOrderPlan(t *testing.T) {
+//		got := json.Plan(Order{})
+//		want, _ := os.ReadFile("testdata/order.plan")
+//		if got != string(want) {
+//			t.Errorf("plan of Order changed:\n%s", got)
+//		}
+//	}
+//
+// The plan is stable across runs of the same version of this package on the same architecture,
+// since the encoder opcodes hold the offsets of the struct fields. It is not a stable format
+// between versions: a changed plan is a change to review, not necessarily a regression.
+// A type that cannot be encoded or decoded has its error in place of the plan.
+func Plan(v interface{}) string {
+	var b strings.Builder
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		return ""
+	}
+	rtyp := runtime.Type2RType(typ)
+	b.WriteString("encode:\n")
+	if plan, err := encoder.Plan(rtyp); err != nil {
+		b.WriteString("error: " + err.Error() + "\n")
+	} else {
+		b.WriteString(plan)
+	}
+	b.WriteString("decode:\n")
+	if plan, err := decoder.Plan(rtyp); err != nil {
+		b.WriteString("error: " + err.Error() + "\n")
+	} else {
+		b.WriteString(plan)
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package json_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+type planLine struct {
+	SKU      string
+	Quantity int64 `json:"qty,omitempty"`
+}
+
+type planOrder struct {
+	ID     int64             `json:"id"`
+	Lines  []planLine        `json:"lines"`
+	Labels map[string]string `json:"labels"`
+	Parent *planOrder        `json:"parent"`
+}
+
+func TestPlan(t *testing.T) {
+	t.Run("golden", func(t *testing.T) {
+		if strconv.IntSize != 64 {
+			t.Skip("the offsets of the fields are those of 64-bit architectures")
+		}
+		expected := `encode:
+[000]-StructHeadInt ([idx:0][key:id][offset:0])
+[001]-StructFieldSlice ([idx:0][key:lines][offset:8])
+[002]-Slice ([idx:2][elemIdx:3][length:4])
+[003]---StructHeadString ([idx:5][key:SKU][offset:0])
+[004]---StructEndOmitEmptyInt ([idx:5][key:qty][offset:16])
+[005]-SliceElem ([idx:2][elemIdx:3][length:4][size:24])
+[006]-SliceEnd ([idx:8])
+[007]-StructFieldMap ([idx:0][key:labels][offset:32])
+[008]-Map ([idx:10])
+[009]-String ([idx:12])
+[010]-MapValue ([idx:10])
+[011]--String ([idx:14])
+[012]-MapKey ([idx:10])
+[013]-MapEnd ([idx:10])
+[014]-StructField ([idx:0][key:parent][offset:40])
+[015]-RecursivePtr ([idx:18])
+[016]-StructEnd ([idx:19][key:][offset:0])
+decode:
+struct json_test.planOrder
+  field "id" tagged
+    int int64
+  field "labels" tagged
+    map map[string]string
+      string
+      string
+  field "lines" tagged
+    slice json_test.planLine
+      struct json_test.planLine
+        field "SKU"
+          string
+        field "qty" tagged
+          int int64
+  field "parent" tagged
+    ptr *json_test.planOrder
+      recursive struct json_test.planOrder
+`
+		assertEq(t, "plan", expected, json.Plan(planOrder{}))
+		assertEq(t, "stable", expected, json.Plan(planOrder{}))
+	})
+	t.Run("changed tag", func(t *testing.T) {
+		type a struct {
+			N int `json:"n"`
+		}
+		type b struct {
+			N int `json:"n,string"`
+		}
+		if json.Plan(a{}) == json.Plan(b{}) {
+			t.Fatal("expected the plans to differ")
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		plan := json.Plan(make(chan int))
+		if !strings.HasPrefix(plan, "encode:\nerror: ") {
+			t.Fatalf("unexpected plan %q", plan)
+		}
+	})
+	assertEq(t, "nil", "", json.Plan(nil))
+}
@@ -0,0 +1,43 @@
+package json
+
+import (
+	"bytes"
+)
+
+// Present wraps a struct field value and records whether its key was present in the decoded input.
+// It lets PATCH style handlers distinguish an absent key from an explicit zero value or null
+// without making every field a pointer.
+//
+//	var req struct {
+//		Name json.Present[string] `json:"name"`
+//	}
+//	if req.Name.Set {
+//		// "name" was sent, req.Name.Value holds it (zero value if req.Name.Null).
+//	}
+type Present[T any] struct {
+	Value T
+	// Set reports whether the key was present in the input.
+	Set bool
+	// Null reports whether the key was present with a null value.
+	Null bool
+}
+
+// UnmarshalJSON implements Unmarshaler.
+func (p *Present[T]) UnmarshalJSON(b []byte) error {
+	var zero T
+	p.Value = zero
+	p.Set = true
+	p.Null = bytes.Equal(bytes.TrimSpace(b), []byte("null"))
+	if p.Null {
+		return nil
+	}
+	return Unmarshal(b, &p.Value)
+}
+
+// MarshalJSON implements Marshaler. It encodes null if p is absent or null, otherwise the wrapped value.
+func (p Present[T]) MarshalJSON() ([]byte, error) {
+	if !p.Set || p.Null {
+		return []byte("null"), nil
+	}
+	return Marshal(p.Value)
+}
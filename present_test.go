@@ -0,0 +1,33 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestPresent(t *testing.T) {
+	type patch struct {
+		Name  json.Present[string] `json:"name"`
+		Age   json.Present[int]    `json:"age"`
+		Email json.Present[string] `json:"email"`
+	}
+	var v patch
+	assertErr(t, json.Unmarshal([]byte(`{"name":"alice","age":0,"email":null}`), &v))
+	assertEq(t, "name set", true, v.Name.Set)
+	assertEq(t, "name value", "alice", v.Name.Value)
+	assertEq(t, "age set", true, v.Age.Set)
+	assertEq(t, "age null", false, v.Age.Null)
+	assertEq(t, "age value", 0, v.Age.Value)
+	assertEq(t, "email set", true, v.Email.Set)
+	assertEq(t, "email null", true, v.Email.Null)
+
+	var absent patch
+	assertErr(t, json.Unmarshal([]byte(`{"name":"bob"}`), &absent))
+	assertEq(t, "age absent", false, absent.Age.Set)
+	assertEq(t, "email absent", false, absent.Email.Set)
+
+	b, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "marshal", `{"name":"alice","age":0,"email":null}`, string(b))
+}
@@ -0,0 +1,64 @@
+package json
+
+import (
+	"bytes"
+)
+
+// Preserve holds a value decoded from JSON together with its original encoding,
+// for proxies that must pass the untouched parts of a document through unchanged.
+//
+// On Marshal, the original encoding is written as long as Value encodes the same as when it was decoded,
+// so the order of the keys, the unknown fields and the spelling of the numbers and strings are kept.
+// Once Value is modified, it is encoded as usual.
+// As the output of any Marshaler, the original encoding is compacted, and HTML-escaped if enabled.
+type Preserve[T any] struct {
+	Value T
+
+	raw []byte
+	// decoded is the encoding of Value when it was decoded, to detect its modification.
+	decoded []byte
+}
+
+// UnmarshalJSON decodes data into Value and keeps a copy of data.
+func (p *Preserve[T]) UnmarshalJSON(data []byte) error {
+	var v T
+	if err := Unmarshal(data, &v); err != nil {
+		return err
+	}
+	decoded, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.Value = v
+	p.raw = append(p.raw[:0], data...)
+	p.decoded = decoded
+	return nil
+}
+
+// MarshalJSON returns the original encoding if Value is not modified since it was decoded,
+// and the encoding of Value otherwise.
+func (p Preserve[T]) MarshalJSON() ([]byte, error) {
+	b, err := Marshal(p.Value)
+	if err != nil {
+		return nil, err
+	}
+	if p.raw != nil && bytes.Equal(b, p.decoded) {
+		return p.raw, nil
+	}
+	return b, nil
+}
+
+// Raw returns the original encoding of Value, or nil if it was not decoded.
+func (p *Preserve[T]) Raw() RawMessage {
+	return p.raw
+}
+
+// Modified reports whether Value is modified since it was decoded, in that it encodes differently.
+// It returns true if Value was not decoded.
+func (p *Preserve[T]) Modified() bool {
+	if p.raw == nil {
+		return true
+	}
+	b, err := Marshal(p.Value)
+	return err != nil || !bytes.Equal(b, p.decoded)
+}
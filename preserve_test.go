@@ -0,0 +1,49 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestPreserve(t *testing.T) {
+	type User struct {
+		Name  string  `json:"name"`
+		Score float64 `json:"score"`
+	}
+	type Envelope struct {
+		ID   int                 `json:"id"`
+		User json.Preserve[User] `json:"user"`
+	}
+	const input = `{"id":1,"user":{ "extra" : [1, 2], "score" : 1.50e1, "name" : "A" }}`
+
+	var v Envelope
+	assertErr(t, json.Unmarshal([]byte(input), &v))
+	assertEq(t, "name", "A", v.User.Value.Name)
+	assertEq(t, "score", 15.0, v.User.Value.Score)
+	assertEq(t, "raw", `{ "extra" : [1, 2], "score" : 1.50e1, "name" : "A" }`, string(v.User.Raw()))
+	assertEq(t, "modified", false, v.User.Modified())
+
+	b, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "untouched", `{"id":1,"user":{"extra":[1,2],"score":1.50e1,"name":"A"}}`, string(b))
+
+	v.ID = 2
+	b, err = json.Marshal(&v)
+	assertErr(t, err)
+	assertEq(t, "sibling modified", `{"id":2,"user":{"extra":[1,2],"score":1.50e1,"name":"A"}}`, string(b))
+
+	v.User.Value.Name = "B"
+	assertEq(t, "modified", true, v.User.Modified())
+	b, err = json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "modified", `{"id":2,"user":{"name":"B","score":15}}`, string(b))
+
+	var zero json.Preserve[User]
+	assertEq(t, "zero modified", true, zero.Modified())
+	b, err = json.Marshal(zero)
+	assertErr(t, err)
+	assertEq(t, "zero", `{"name":"","score":0}`, string(b))
+
+	assertNeq(t, "error", nil, json.Unmarshal([]byte(`{"id":1,"user":{"score":"x"}}`), &v))
+}
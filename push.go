@@ -0,0 +1,196 @@
+package json
+
+import (
+	"github.com/going/json/internal/errors"
+)
+
+// PushParser is an incremental parser that is fed with arbitrary byte chunks
+// instead of pulling data from an io.Reader.
+// Each completed top-level value becomes available from Next as soon as its last byte has been written.
+// It is intended for event-loop style network stacks where no io.Reader exists.
+type PushParser struct {
+	buf      []byte
+	values   []RawMessage
+	cursor   int   // position of the next byte to scan
+	start    int   // start position of the value being scanned, -1 if none
+	depth    int   // nesting depth of the value being scanned
+	inString bool  // cursor is inside a string literal
+	escaped  bool  // previous byte was a backslash inside a string literal
+	offset   int64 // number of bytes dropped from the head of buf
+	err      error // error returned by Write, which stops the parser
+}
+
+// NewPushParser returns a new PushParser.
+func NewPushParser() *PushParser {
+	return &PushParser{start: -1}
+}
+
+// Write feeds p to the parser. It always consumes all of p unless an error is returned.
+// A *SyntaxError is returned when a completed value is not valid JSON.
+// The error is sticky: the input cannot be resynchronized after it, so every later Write and Close
+// returns the same error without consuming anything. The values completed before it can still be read by Next.
+func (p *PushParser) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	p.buf = append(p.buf, b...)
+	if err := p.scan(false); err != nil {
+		p.err = err
+		// the bytes after the error are not parsed.
+		p.buf = nil
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close flushes a pending top-level scalar (e.g. a number which is terminated only by the end of input).
+// It returns an error if an incomplete value remains.
+func (p *PushParser) Close() error {
+	if p.err != nil {
+		return p.err
+	}
+	if err := p.scan(true); err != nil {
+		return err
+	}
+	if p.start >= 0 {
+		return errors.ErrUnexpectedEndOfJSON("value", p.offset+int64(len(p.buf)))
+	}
+	return nil
+}
+
+// Buffered returns the number of completed values that can be read by Next.
+func (p *PushParser) Buffered() int {
+	return len(p.values)
+}
+
+// Next returns the next completed top-level value.
+// It returns false if no completed value is available yet.
+func (p *PushParser) Next() (RawMessage, bool) {
+	if len(p.values) == 0 {
+		return nil, false
+	}
+	v := p.values[0]
+	p.values[0] = nil
+	p.values = p.values[1:]
+	return v, true
+}
+
+// Decode stores the next completed top-level value in the value pointed to by v.
+// It returns false if no completed value is available yet.
+func (p *PushParser) Decode(v interface{}, optFuncs ...DecodeOptionFunc) (bool, error) {
+	raw, ok := p.Next()
+	if !ok {
+		return false, nil
+	}
+	if err := UnmarshalWithOption(raw, v, optFuncs...); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func (p *PushParser) scan(eof bool) error {
+	buf := p.buf
+	for ; p.cursor < len(buf); p.cursor++ {
+		c := buf[p.cursor]
+		if p.start < 0 {
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				continue
+			case '{', '[':
+				p.depth = 1
+			case '"':
+				p.inString = true
+			case ']', '}', ',', ':':
+				return errors.ErrInvalidBeginningOfValue(c, p.offset+int64(p.cursor))
+			}
+			p.start = p.cursor
+			continue
+		}
+		if p.inString {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case c == '\\':
+				p.escaped = true
+			case c == '"':
+				p.inString = false
+				if p.depth == 0 {
+					if err := p.complete(p.cursor + 1); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if p.depth == 0 {
+			// top-level scalar such as number, true, false or null
+			switch c {
+			case ' ', '\t', '\r', '\n', '{', '[', ']', '}', ',', ':', '"':
+				if err := p.complete(p.cursor); err != nil {
+					return err
+				}
+				p.cursor--
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			p.inString = true
+		case '{', '[':
+			p.depth++
+			if p.depth > maxNestingDepth {
+				return errors.ErrExceededMaxDepth(c, p.offset+int64(p.cursor))
+			}
+		case '}', ']':
+			p.depth--
+			if p.depth == 0 {
+				if err := p.complete(p.cursor + 1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if eof && p.start >= 0 && p.depth == 0 && !p.inString {
+		if err := p.complete(p.cursor); err != nil {
+			return err
+		}
+	}
+	p.compact()
+	return nil
+}
+
+func (p *PushParser) complete(end int) error {
+	src := p.buf[p.start:end]
+	if !Valid(src) {
+		var v interface{}
+		if err := Unmarshal(src, &v); err != nil {
+			if serr, ok := err.(*SyntaxError); ok {
+				serr.Offset += p.offset + int64(p.start)
+			}
+			return err
+		}
+		return errors.ErrSyntax("invalid JSON value", p.offset+int64(p.start))
+	}
+	value := make(RawMessage, len(src))
+	copy(value, src)
+	p.values = append(p.values, value)
+	p.start = -1
+	return nil
+}
+
+func (p *PushParser) compact() {
+	head := p.cursor
+	if p.start >= 0 {
+		head = p.start
+	}
+	if head == 0 {
+		return
+	}
+	n := copy(p.buf, p.buf[head:])
+	p.buf = p.buf[:n]
+	p.offset += int64(head)
+	p.cursor -= head
+	if p.start >= 0 {
+		p.start -= head
+	}
+}
@@ -0,0 +1,80 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestPushParser(t *testing.T) {
+	src := `{"a": [1, "x\"}"]} 12 true "str" [ ]` + "\n" + `-3.5`
+	p := json.NewPushParser()
+	var got []string
+	for i := 0; i < len(src); i++ {
+		if _, err := p.Write([]byte{src[i]}); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			v, ok := p.Next()
+			if !ok {
+				break
+			}
+			got = append(got, string(v))
+		}
+	}
+	assertEq(t, "before close", 5, len(got))
+	assertErr(t, p.Close())
+	v, ok := p.Next()
+	if !ok {
+		t.Fatal("expected trailing number")
+	}
+	got = append(got, string(v))
+	expected := []string{`{"a": [1, "x\"}"]}`, `12`, `true`, `"str"`, `[ ]`, `-3.5`}
+	assertEq(t, "length", len(expected), len(got))
+	for i := range expected {
+		assertEq(t, "value", expected[i], got[i])
+	}
+}
+
+func TestPushParserDecode(t *testing.T) {
+	p := json.NewPushParser()
+	if _, err := p.Write([]byte(`{"n": 1`)); err != nil {
+		t.Fatal(err)
+	}
+	var v struct{ N int }
+	ok, err := p.Decode(&v)
+	assertErr(t, err)
+	assertEq(t, "incomplete", false, ok)
+	if _, err := p.Write([]byte(`0}{"n":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, "buffered", 2, p.Buffered())
+	ok, err = p.Decode(&v)
+	assertErr(t, err)
+	assertEq(t, "decoded", true, ok)
+	assertEq(t, "value", 10, v.N)
+}
+
+func TestPushParserError(t *testing.T) {
+	p := json.NewPushParser()
+	if _, err := p.Write([]byte(`1 {"a" 1}`)); err == nil {
+		t.Fatal("expected error")
+	}
+	// the error is sticky.
+	n, err := p.Write([]byte(` 2 `))
+	if err == nil {
+		t.Fatal("expected sticky error")
+	}
+	assertEq(t, "written", 0, n)
+	if err := p.Close(); err == nil {
+		t.Fatal("expected sticky error on close")
+	}
+	assertEq(t, "values before the error", 1, p.Buffered())
+	p = json.NewPushParser()
+	if _, err := p.Write([]byte(`[1, 2`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("expected error for incomplete value")
+	}
+}
@@ -0,0 +1,205 @@
+package json
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/going/json/internal/decoder"
+	"github.com/going/json/internal/errors"
+)
+
+// maxRefDepth is the maximum number of references ResolveRefs expands within each other.
+const maxRefDepth = 100
+
+// maxRefExpansion is the maximum number of bytes by which ResolveRefs makes a document grow,
+// so that a small document with references expanding each other many times cannot exhaust the memory.
+const maxRefExpansion = 16 << 20
+
+// ResolveRefs returns a copy of data in which every JSON Reference object within the document,
+// such as {"$ref":"#/definitions/foo"}, is replaced with the value its JSON Pointer fragment refers to.
+// References in the referred value are resolved as well, so that the result has no local references.
+// As specified by JSON Reference, other members of an object with a $ref member are ignored.
+// References to other documents, whose value does not begin with '#', are left unchanged.
+//
+// It returns a RefError if a reference does not refer to a value in data, refers to a value that contains itself,
+// if more than 100 references are expanded within each other, or if the result is more than 16 MiB
+// larger than data. The result is compacted.
+func ResolveRefs(data []byte) ([]byte, error) {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+	start := int64(len(data) - len(bytes.TrimLeft(data, " \t\r\n")))
+	end, err := decoder.ScanValue(src, start)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateEndBuf(src, end); err != nil {
+		return nil, err
+	}
+	r := &refResolver{src: src, root: start, active: map[int64]struct{}{}, maxLen: len(data) + maxRefExpansion}
+	return r.appendValue(make([]byte, 0, len(data)), start, end)
+}
+
+type refResolver struct {
+	src    []byte
+	root   int64
+	active map[int64]struct{} // the values being expanded by a reference
+	depth  int
+	maxLen int // the length of the result beyond which the expansion fails
+}
+
+func (r *refResolver) appendValue(b []byte, start, end int64) ([]byte, error) {
+	switch r.src[start] {
+	case '{':
+		return r.appendObject(b, start)
+	case '[':
+		var err error
+		b = append(b, '[')
+		first := true
+		if _, scanErr := decoder.ScanArray(r.src, start, func(start, end int64) bool {
+			if !first {
+				b = append(b, ',')
+			}
+			first = false
+			b, err = r.appendValue(b, start, end)
+			return err == nil
+		}); scanErr != nil {
+			return nil, scanErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return append(b, ']'), nil
+	}
+	return append(b, r.src[start:end]...), nil
+}
+
+func (r *refResolver) appendObject(b []byte, start int64) ([]byte, error) {
+	var (
+		ref   string
+		isRef bool
+		err   error
+	)
+	if _, scanErr := decoder.ScanObject(r.src, start, func(keyStart, keyEnd, start, end int64) bool {
+		if r.src[start] != '"' || !keyEquals(r.src[keyStart:keyEnd], "$ref") {
+			return true
+		}
+		isRef = true
+		err = Unmarshal(r.src[start:end], &ref)
+		return false
+	}); scanErr != nil {
+		return nil, scanErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isRef && strings.HasPrefix(ref, "#") {
+		return r.appendRef(b, ref)
+	}
+	b = append(b, '{')
+	first := true
+	if _, scanErr := decoder.ScanObject(r.src, start, func(keyStart, keyEnd, start, end int64) bool {
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = append(b, r.src[keyStart:keyEnd]...)
+		b = append(b, ':')
+		b, err = r.appendValue(b, start, end)
+		return err == nil
+	}); scanErr != nil {
+		return nil, scanErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '}'), nil
+}
+
+func (r *refResolver) appendRef(b []byte, ref string) ([]byte, error) {
+	if r.depth >= maxRefDepth {
+		return nil, errors.ErrRef(ref, "exceeded max depth of %d references", maxRefDepth)
+	}
+	start, end, err := r.lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := r.active[start]; exists {
+		return nil, errors.ErrRef(ref, "reference cycle")
+	}
+	r.active[start] = struct{}{}
+	r.depth++
+	b, err = r.appendValue(b, start, end)
+	r.depth--
+	delete(r.active, start)
+	if err == nil && len(b) > r.maxLen {
+		return nil, errors.ErrRef(ref, "exceeded max expansion of %d bytes", maxRefExpansion)
+	}
+	return b, err
+}
+
+// lookup returns the offsets of the value that the JSON Pointer in the fragment of ref refers to.
+func (r *refResolver) lookup(ref string) (int64, int64, error) {
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return 0, 0, errors.ErrRef(ref, "invalid fragment")
+	}
+	if pointer != "" && pointer[0] != '/' {
+		return 0, 0, errors.ErrRef(ref, "JSON Pointer must begin with '/'")
+	}
+	start := r.root
+	end, err := decoder.ScanValue(r.src, start)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pointer == "" {
+		return start, end, nil
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		found := false
+		switch r.src[start] {
+		case '{':
+			_, err = decoder.ScanObject(r.src, start, func(keyStart, keyEnd, valueStart, valueEnd int64) bool {
+				if !keyEquals(r.src[keyStart:keyEnd], token) {
+					return true
+				}
+				start, end, found = valueStart, valueEnd, true
+				return false
+			})
+		case '[':
+			idx, convErr := strconv.Atoi(token)
+			if convErr != nil || idx < 0 || (len(token) > 1 && token[0] == '0') {
+				return 0, 0, errors.ErrRef(ref, "invalid array index %q", token)
+			}
+			_, err = decoder.ScanArray(r.src, start, func(valueStart, valueEnd int64) bool {
+				if idx > 0 {
+					idx--
+					return true
+				}
+				start, end, found = valueStart, valueEnd, true
+				return false
+			})
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if !found {
+			return 0, 0, errors.ErrRef(ref, "%q not found", token)
+		}
+	}
+	return start, end, nil
+}
+
+// keyEquals reports whether the quoted JSON string raw is equal to s.
+func keyEquals(raw []byte, s string) bool {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1:len(raw)-1]) == s
+	}
+	var key string
+	if err := Unmarshal(raw, &key); err != nil {
+		return false
+	}
+	return key == s
+}
@@ -0,0 +1,87 @@
+package json_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestResolveRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "definitions",
+			data: `{
+  "definitions": {"id": {"type": "integer", "minimum": 1.50}},
+  "properties": {"a": {"$ref": "#/definitions/id"}, "b": [{"$ref": "#/definitions/id/type"}]}
+}`,
+			want: `{"definitions":{"id":{"type":"integer","minimum":1.50}},"properties":{"a":{"type":"integer","minimum":1.50},"b":["integer"]}}`,
+		},
+		{
+			name: "nested",
+			data: `{"a":{"$ref":"#/b"},"b":{"c":{"$ref":"#/d/1"}},"d":[0,{"e":true}]}`,
+			want: `{"a":{"c":{"e":true}},"b":{"c":{"e":true}},"d":[0,{"e":true}]}`,
+		},
+		{
+			name: "escaped pointer",
+			data: `{"a/b":{"c~d":1},"%":2,"x":[{"$ref":"#/a~1b/c~0d"},{"$ref":"#/%25"}]}`,
+			want: `{"a/b":{"c~d":1},"%":2,"x":[1,2]}`,
+		},
+		{
+			name: "siblings ignored",
+			data: `{"a":1,"b":{"description":"x","$ref":"#/a"}}`,
+			want: `{"a":1,"b":1}`,
+		},
+		{
+			name: "external",
+			data: `{"a":{"$ref":"other.json#/a"},"b":{"$ref":1}}`,
+			want: `{"a":{"$ref":"other.json#/a"},"b":{"$ref":1}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.ResolveRefs([]byte(tc.data))
+			assertErr(t, err)
+			assertEq(t, "resolved", tc.want, string(got))
+		})
+	}
+	t.Run("error", func(t *testing.T) {
+		for _, data := range []string{
+			`{"a":{"$ref":"#/b"}}`,
+			`{"a":[{"$ref":"#/a/1"}]}`,
+			`{"a":{"$ref":"#/a"}}`,
+			`{"a":{"b":{"$ref":"#"}}}`,
+			`{"a":{"$ref":"#a"}}`,
+			`{"a":[1],"b":{"$ref":"#/a/01"}}`,
+		} {
+			_, err := json.ResolveRefs([]byte(data))
+			if _, ok := err.(*json.RefError); !ok {
+				t.Errorf("expected RefError for %s but got %v", data, err)
+			}
+		}
+		var chain []byte
+		for i := 0; i <= 100; i++ {
+			chain = append(chain, fmt.Sprintf(`{"$ref":"#/%d"},`, i+1)...)
+		}
+		chain = append(append([]byte{'['}, chain...), "0]"...)
+		if _, err := json.ResolveRefs(chain); err == nil {
+			t.Error("expected error for too deep references")
+		}
+		laughs := []byte(`{"0":"` + strings.Repeat("x", 1<<14) + `"`)
+		for i := 1; i <= 40; i++ {
+			laughs = append(laughs, fmt.Sprintf(`,"%d":[{"$ref":"#/%d"},{"$ref":"#/%d"}]`, i, i-1, i-1)...)
+		}
+		laughs = append(laughs, '}')
+		if _, err := json.ResolveRefs(laughs); err == nil || !strings.Contains(err.Error(), "expansion") {
+			t.Errorf("expected error for too large expansion but got %v", err)
+		}
+		if _, err := json.ResolveRefs([]byte(`{"a":`)); err == nil {
+			t.Error("expected error for invalid data")
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/going/json/internal/decoder"
+	"github.com/going/json/internal/runtime"
+)
+
+// DecodeRegistry holds decode functions that override the decoding of their types
+// only for the decodes that use it with WithDecodeRegistry,
+// so that a library can customize decoding without changing the behavior of the rest of the program.
+// Type adapters, enums and unions are expressed as decode functions of the types they apply to.
+// A DecodeRegistry is safe for concurrent use.
+type DecodeRegistry struct {
+	reg *decoder.Registry
+}
+
+// NewDecodeRegistry returns a new DecodeRegistry with no decode functions.
+func NewDecodeRegistry() *DecodeRegistry {
+	return &DecodeRegistry{reg: decoder.NewRegistry()}
+}
+
+// RegisterDecodeFunc registers fn as the decode function of T in r.
+// fn is called with the raw JSON value, including null, wherever a value of T is decoded:
+// at the top level, in struct fields, and in slice, array and map elements.
+// It takes precedence over the Unmarshaler and encoding.TextUnmarshaler implementations of T.
+// An existing decode function of T is replaced.
+func RegisterDecodeFunc[T any](r *DecodeRegistry, fn func(data []byte, v *T) error) {
+	typ := runtime.Type2RType(reflect.TypeOf((*T)(nil)).Elem())
+	r.reg.Register(typ, func(data []byte, p unsafe.Pointer) error {
+		return fn(data, (*T)(p))
+	})
+}
+
+// WithDecodeRegistry decodes with the decode functions registered in r.
+func WithDecodeRegistry(r *DecodeRegistry) DecodeOptionFunc {
+	return func(opt *DecodeOption) {
+		opt.Flags |= decoder.RegistryOption
+		opt.Registry = r.reg
+	}
+}
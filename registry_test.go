@@ -0,0 +1,159 @@
+package json_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+type registryColor int
+
+type registryShape interface {
+	Area() float64
+}
+
+type registrySquare struct {
+	Side float64 `json:"side"`
+}
+
+func (s registrySquare) Area() float64 { return s.Side * s.Side }
+
+type registryCircle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c registryCircle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type registryUpper string
+
+func (u *registryUpper) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*u = registryUpper(strings.ToUpper(s))
+	return nil
+}
+
+func newTestDecodeRegistry() *json.DecodeRegistry {
+	r := json.NewDecodeRegistry()
+	json.RegisterDecodeFunc(r, func(data []byte, v *registryColor) error {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		switch s {
+		case "red":
+			*v = 1
+		case "green":
+			*v = 2
+		default:
+			return fmt.Errorf("unknown color %q", s)
+		}
+		return nil
+	})
+	json.RegisterDecodeFunc(r, func(data []byte, v *registryShape) error {
+		var kind struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(data, &kind); err != nil {
+			return err
+		}
+		switch kind.Kind {
+		case "square":
+			var s registrySquare
+			if err := json.Unmarshal(data, &s); err != nil {
+				return err
+			}
+			*v = s
+		case "circle":
+			var c registryCircle
+			if err := json.Unmarshal(data, &c); err != nil {
+				return err
+			}
+			*v = c
+		default:
+			return fmt.Errorf("unknown shape %q", kind.Kind)
+		}
+		return nil
+	})
+	json.RegisterDecodeFunc(r, func(data []byte, v *registryUpper) error {
+		return json.Unmarshal(data, (*string)(v))
+	})
+	return r
+}
+
+func TestDecodeRegistry(t *testing.T) {
+	type T struct {
+		Color   registryColor            `json:"color"`
+		Colors  []registryColor          `json:"colors"`
+		ByName  map[string]registryColor `json:"byName"`
+		Ptr     *registryColor           `json:"ptr"`
+		Shapes  []registryShape          `json:"shapes"`
+		Upper   registryUpper            `json:"upper"`
+		Integer int                      `json:"integer"`
+	}
+	r := newTestDecodeRegistry()
+	data := `{"color":"red","colors":["green","red"],"byName":{"a":"green"},"ptr":"green",` +
+		`"shapes":[{"kind":"square","side":2},{"kind":"circle","radius":1}],"upper":"abc","integer":3}`
+
+	t.Run("unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption([]byte(data), &v, json.WithDecodeRegistry(r)))
+		assertEq(t, "color", registryColor(1), v.Color)
+		assertEq(t, "colors", "[2 1]", fmt.Sprint(v.Colors))
+		assertEq(t, "byName", registryColor(2), v.ByName["a"])
+		assertEq(t, "ptr", registryColor(2), *v.Ptr)
+		assertEq(t, "shapes", "[{2} {1}]", fmt.Sprint(v.Shapes))
+		assertEq(t, "area", 4.0, v.Shapes[0].Area())
+		assertEq(t, "upper", registryUpper("abc"), v.Upper)
+		assertEq(t, "integer", 3, v.Integer)
+	})
+	t.Run("top level", func(t *testing.T) {
+		var c registryColor
+		assertErr(t, json.UnmarshalWithOption([]byte(` "green" `), &c, json.WithDecodeRegistry(r)))
+		assertEq(t, "color", registryColor(2), c)
+	})
+	t.Run("stream", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(data + "\n" + data))
+		dec.UseRegistry(r)
+		for i := 0; i < 2; i++ {
+			var v T
+			assertErr(t, dec.Decode(&v))
+			assertEq(t, "colors", "[2 1]", fmt.Sprint(v.Colors))
+			assertEq(t, "shapes", "[{2} {1}]", fmt.Sprint(v.Shapes))
+			assertEq(t, "upper", registryUpper("abc"), v.Upper)
+		}
+	})
+	t.Run("interface", func(t *testing.T) {
+		var c registryColor
+		var v interface{} = &c
+		assertErr(t, json.UnmarshalWithOption([]byte(`"red"`), &v, json.WithDecodeRegistry(r)))
+		assertEq(t, "color", registryColor(1), c)
+	})
+	t.Run("error", func(t *testing.T) {
+		var v T
+		err := json.UnmarshalWithOption([]byte(`{"colors":["red","blue"]}`), &v, json.WithDecodeRegistry(r))
+		assertNeq(t, "error", nil, err)
+		assertEq(t, "message", `unknown color "blue"`, err.Error())
+
+		err = json.NewDecoder(bytes.NewBufferString(`{"shapes":[{"kind":"line"}]}`)).
+			DecodeWithOption(&v, json.WithDecodeRegistry(r))
+		assertNeq(t, "error", nil, err)
+	})
+	t.Run("global state unaffected", func(t *testing.T) {
+		var v T
+		err := json.Unmarshal([]byte(`{"color":"red"}`), &v)
+		assertNeq(t, "error", nil, err)
+		assertErr(t, json.Unmarshal([]byte(`{"color":1,"upper":"abc"}`), &v))
+		assertEq(t, "color", registryColor(1), v.Color)
+		assertEq(t, "upper", registryUpper("ABC"), v.Upper)
+
+		var c registryColor
+		assertErr(t, json.NewDecoder(strings.NewReader(`2`)).Decode(&c))
+		assertEq(t, "color", registryColor(2), c)
+	})
+}
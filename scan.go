@@ -0,0 +1,96 @@
+package json
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/going/json/internal/decoder"
+	"github.com/going/json/internal/errors"
+)
+
+// ArrayLen returns the number of elements of the JSON array in data without decoding them.
+// It returns an error if data is not a JSON array.
+// The elements are only scanned for their boundaries, so they are not fully validated.
+func ArrayLen(data []byte) (int, error) {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+	n, cursor, err := decoder.ArrayLen(src, 0)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateEndBuf(src, cursor); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ObjectKeys returns the keys of the JSON object in data in order of appearance without decoding the values.
+// Duplicate keys are returned as many times as they appear.
+// It returns an error if data is not a JSON object.
+// The values are only scanned for their boundaries, so they are not fully validated.
+func ObjectKeys(data []byte) ([]string, error) {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+	keys, cursor, err := decoder.ObjectKeys(src, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateEndBuf(src, cursor); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Exists reports whether data has a value at path, which is written in the syntax of CreatePath.
+// Values outside the path are skipped without being decoded.
+func Exists(data []byte, path string) (bool, error) {
+	p, err := CreatePath(path)
+	if err != nil {
+		return false, err
+	}
+	values, err := p.Extract(data)
+	if err != nil {
+		return false, err
+	}
+	return len(values) > 0, nil
+}
+
+// ForEach calls fn for each member of the JSON object or each element of the JSON array in data, in order,
+// until fn returns false. Values are not decoded.
+// For an object, keyOrIndex is the key as a quoted JSON string; for an array, it is the index as a JSON number.
+// Keys and values are sub-slices of data, so they must not be modified,
+// but they may be used after ForEach returns and passed to other goroutines.
+// It returns an error if data is neither an object nor an array, or if it is malformed
+// up to the point where fn stopped the iteration.
+func ForEach(data []byte, fn func(keyOrIndex, value RawMessage) bool) error {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+	var (
+		cursor  int64
+		err     error
+		stopped bool
+	)
+	switch KindOf(data) {
+	case KindObject:
+		cursor, err = decoder.ScanObject(src, 0, func(keyStart, keyEnd, start, end int64) bool {
+			stopped = !fn(data[keyStart:keyEnd:keyEnd], data[start:end:end])
+			return !stopped
+		})
+	case KindArray:
+		var idx int64
+		cursor, err = decoder.ScanArray(src, 0, func(start, end int64) bool {
+			stopped = !fn(strconv.AppendInt(nil, idx, 10), data[start:end:end])
+			idx++
+			return !stopped
+		})
+	default:
+		return errors.ErrExpected("object or array", int64(len(data)-len(bytes.TrimLeft(data, " \t\r\n"))))
+	}
+	if err != nil {
+		return err
+	}
+	if stopped {
+		return nil
+	}
+	return validateEndBuf(src, cursor)
+}
@@ -0,0 +1,123 @@
+package json_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestArrayLen(t *testing.T) {
+	tests := []struct {
+		data string
+		want int
+	}{
+		{data: `[]`, want: 0},
+		{data: ` [ ] `, want: 0},
+		{data: `[1, "a,]", {"b":[1,2]}, null]`, want: 4},
+		{data: `[[1,2],[3]]`, want: 2},
+	}
+	for _, tc := range tests {
+		n, err := json.ArrayLen([]byte(tc.data))
+		assertErr(t, err)
+		assertEq(t, tc.data, tc.want, n)
+	}
+	for _, data := range []string{`{}`, `1`, `[1,]`, `[1 2]`, `[1`, `[1] 2`} {
+		if _, err := json.ArrayLen([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestObjectKeys(t *testing.T) {
+	tests := []struct {
+		data string
+		want []string
+	}{
+		{data: `{}`, want: nil},
+		{data: `{"b":1,"a":{"c":2},"b":[3]}`, want: []string{"b", "a", "b"}},
+		{data: ` { "a\"b" : "x" , "é" : null } `, want: []string{`a"b`, "é"}},
+	}
+	for _, tc := range tests {
+		keys, err := json.ObjectKeys([]byte(tc.data))
+		assertErr(t, err)
+		assertEq(t, tc.data, fmt.Sprint(tc.want), fmt.Sprint(keys))
+		assertEq(t, tc.data+" length", len(tc.want), len(keys))
+	}
+	for _, data := range []string{`[]`, `"a"`, `{"a" 1}`, `{"a":1,}`, `{1:1}`, `{"a":1`} {
+		if _, err := json.ObjectKeys([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	data := []byte(`{"a":{"b":[1,{"c":null}]},"x":0}`)
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: `$.a.b[1].c`, want: true},
+		{path: `$.a.b[2]`, want: false},
+		{path: `$.a.b[*].c`, want: true},
+		{path: `$.x`, want: true},
+		{path: `$.y`, want: false},
+	}
+	for _, tc := range tests {
+		ok, err := json.Exists(data, tc.path)
+		assertErr(t, err)
+		assertEq(t, tc.path, tc.want, ok)
+	}
+	if _, err := json.Exists(data, `a`); err == nil {
+		t.Fatal("expected error for invalid path")
+	}
+	if _, err := json.Exists([]byte(`{"a":`), `$.a`); err == nil {
+		t.Fatal("expected error for invalid data")
+	}
+}
+
+func TestForEach(t *testing.T) {
+	t.Run("object", func(t *testing.T) {
+		data := []byte(` {"a": 1, "b\"c" : {"d":[1,2]} ,"e":null} `)
+		var got []string
+		assertErr(t, json.ForEach(data, func(key, value json.RawMessage) bool {
+			got = append(got, string(key)+"="+string(value))
+			return true
+		}))
+		assertEq(t, "members", `["a"=1 "b\"c"={"d":[1,2]} "e"=null]`, fmt.Sprint(got))
+	})
+	t.Run("array", func(t *testing.T) {
+		data := []byte(`[true, "x", [1], {}]`)
+		var got []string
+		assertErr(t, json.ForEach(data, func(idx, value json.RawMessage) bool {
+			got = append(got, string(idx)+"="+string(value))
+			return true
+		}))
+		assertEq(t, "elements", `[0=true 1="x" 2=[1] 3={}]`, fmt.Sprint(got))
+	})
+	t.Run("stop", func(t *testing.T) {
+		var n int
+		assertErr(t, json.ForEach([]byte(`[1,2,3,`), func(_, _ json.RawMessage) bool {
+			n++
+			return n < 2
+		}))
+		assertEq(t, "calls", 2, n)
+	})
+	t.Run("zero copy", func(t *testing.T) {
+		data := []byte(`{"a":[1]}`)
+		var value json.RawMessage
+		assertErr(t, json.ForEach(data, func(_, v json.RawMessage) bool {
+			value = v
+			return true
+		}))
+		data[6] = '2'
+		assertEq(t, "value", `[2]`, string(value))
+	})
+	t.Run("error", func(t *testing.T) {
+		for _, data := range []string{``, `1`, `"a"`, `[1,]`, `{"a":1} {}`} {
+			if err := json.ForEach([]byte(data), func(_, _ json.RawMessage) bool { return true }); err == nil {
+				t.Errorf("expected error for %q", data)
+			}
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package json
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/going/json/internal/decoder"
+)
+
+// Schema describes the values observed at one position of a sequence of JSON documents.
+// It is built by SchemaInferrer or InferSchema.
+type Schema struct {
+	// Count is the number of values observed.
+	Count int
+	// Kinds is the number of values observed for each kind.
+	Kinds map[Kind]int
+	// Integers is the number of numbers written without a fraction or an exponent.
+	Integers int
+	// Formats is the number of strings observed for each recognized format,
+	// one of "date-time", "date", "uuid", "email", "uri", "ipv4" and "ipv6".
+	Formats map[string]int
+	// Fields describes the values of the object members observed for each key.
+	// A field is optional if it is missing from some of the objects observed, as reported by Optional.
+	Fields map[string]*Schema
+	// Items describes the elements of all the arrays observed.
+	Items *Schema
+	// MinItems and MaxItems are the lowest and highest number of elements of the arrays observed.
+	MinItems, MaxItems int
+
+	// objects is the number of objects in which the field is observed, which is less than Count
+	// if an object repeats its key.
+	objects int
+	// lastObject is the number of the last object in which the field is observed.
+	lastObject int
+}
+
+// Optional reports whether the field is missing from some of the objects observed.
+func (s *Schema) Optional(field string) bool {
+	f, exists := s.Fields[field]
+	return !exists || f.objects < s.Kinds[KindObject]
+}
+
+// JSONSchema returns the schema as a JSON Schema document.
+// A number is an "integer" if all the numbers observed are integers,
+// and a string has a "format" if all the strings observed have the same format.
+func (s *Schema) JSONSchema() ([]byte, error) {
+	return Marshal(s.jsonSchema())
+}
+
+func (s *Schema) jsonSchema() map[string]interface{} {
+	v := map[string]interface{}{}
+	var types []string
+	for kind := KindNull; kind <= KindArray; kind++ {
+		n := s.Kinds[kind]
+		if n == 0 {
+			continue
+		}
+		switch kind {
+		case KindNull:
+			types = append(types, "null")
+		case KindBool:
+			types = append(types, "boolean")
+		case KindNumber:
+			if s.Integers == n {
+				types = append(types, "integer")
+			} else {
+				types = append(types, "number")
+			}
+		case KindString:
+			types = append(types, "string")
+			for format, count := range s.Formats {
+				if count == n {
+					v["format"] = format
+				}
+			}
+		case KindObject:
+			types = append(types, "object")
+			properties := map[string]interface{}{}
+			required := []string{}
+			for key, field := range s.Fields {
+				properties[key] = field.jsonSchema()
+				if !s.Optional(key) {
+					required = append(required, key)
+				}
+			}
+			sort.Strings(required)
+			v["properties"] = properties
+			if len(required) > 0 {
+				v["required"] = required
+			}
+		case KindArray:
+			types = append(types, "array")
+			if s.Items != nil {
+				v["items"] = s.Items.jsonSchema()
+			}
+			v["minItems"] = s.MinItems
+			v["maxItems"] = s.MaxItems
+		}
+	}
+	if len(types) == 1 {
+		v["type"] = types[0]
+	} else if len(types) > 1 {
+		v["type"] = types
+	}
+	return v
+}
+
+// SchemaInferrer infers a Schema from a sequence of JSON documents.
+// The documents are scanned without being decoded into Go values.
+type SchemaInferrer struct {
+	schema Schema
+}
+
+// NewSchemaInferrer returns a new SchemaInferrer that has observed no documents.
+func NewSchemaInferrer() *SchemaInferrer {
+	return &SchemaInferrer{}
+}
+
+// Add adds the JSON document in data to the observed documents.
+// If data is not valid JSON, it returns an error and the schema is not changed.
+func (si *SchemaInferrer) Add(data []byte) error {
+	if !Valid(data) {
+		var v interface{}
+		return Unmarshal(data, &v)
+	}
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+	start := int64(len(data) - len(bytes.TrimLeft(data, " \t\r\n")))
+	end, err := decoder.ScanValue(src, start)
+	if err != nil {
+		return err
+	}
+	return si.schema.observe(src, start, end)
+}
+
+// Schema returns the schema of the documents added so far.
+// It is updated by subsequent calls to Add.
+func (si *SchemaInferrer) Schema() *Schema {
+	return &si.schema
+}
+
+// InferSchema reads a stream of JSON documents from r until EOF and returns the inferred schema.
+func InferSchema(r io.Reader) (*Schema, error) {
+	si := NewSchemaInferrer()
+	dec := NewDecoder(r)
+	for {
+		var doc RawMessage
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return si.Schema(), nil
+			}
+			return nil, err
+		}
+		if err := si.Add(doc); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Schema) observe(src []byte, start, end int64) error {
+	kind := kindOfChar(src[start])
+	if s.Kinds == nil {
+		s.Kinds = map[Kind]int{}
+	}
+	s.Count++
+	s.Kinds[kind]++
+	switch kind {
+	case KindNumber:
+		if bytes.IndexAny(src[start:end], ".eE") < 0 {
+			s.Integers++
+		}
+	case KindString:
+		if format := stringFormat(unquote(src[start:end])); format != "" {
+			if s.Formats == nil {
+				s.Formats = map[string]int{}
+			}
+			s.Formats[format]++
+		}
+	case KindObject:
+		if s.Fields == nil {
+			s.Fields = map[string]*Schema{}
+		}
+		var err error
+		if _, scanErr := decoder.ScanObject(src, start, func(keyStart, keyEnd, start, end int64) bool {
+			key := unquote(src[keyStart:keyEnd])
+			field, exists := s.Fields[key]
+			if !exists {
+				field = &Schema{}
+				s.Fields[key] = field
+			}
+			if field.lastObject != s.Kinds[KindObject] {
+				field.lastObject = s.Kinds[KindObject]
+				field.objects++
+			}
+			err = field.observe(src, start, end)
+			return err == nil
+		}); scanErr != nil {
+			return scanErr
+		}
+		return err
+	case KindArray:
+		var (
+			n   int
+			err error
+		)
+		if _, scanErr := decoder.ScanArray(src, start, func(start, end int64) bool {
+			if s.Items == nil {
+				s.Items = &Schema{}
+			}
+			n++
+			err = s.Items.observe(src, start, end)
+			return err == nil
+		}); scanErr != nil {
+			return scanErr
+		}
+		if s.Kinds[KindArray] == 1 || n < s.MinItems {
+			s.MinItems = n
+		}
+		if n > s.MaxItems {
+			s.MaxItems = n
+		}
+		return err
+	}
+	return nil
+}
+
+// unquote returns the value of the valid JSON string raw.
+func unquote(raw []byte) string {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1 : len(raw)-1])
+	}
+	var s string
+	_ = Unmarshal(raw, &s)
+	return s
+}
+
+// stringFormat returns the JSON Schema format of s, or an empty string if it has no recognized format.
+func stringFormat(s string) string {
+	switch {
+	case s == "":
+		return ""
+	case isDateTime(s):
+		return "date-time"
+	case isDate(s):
+		return "date"
+	case isUUID(s):
+		return "uuid"
+	case isEmail(s):
+		return "email"
+	case isURI(s):
+		return "uri"
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		if strings.IndexByte(s, ':') < 0 {
+			return "ipv4"
+		}
+		return "ipv6"
+	}
+	return ""
+}
+
+func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !(('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isEmail(s string) bool {
+	at := strings.LastIndexByte(s, '@')
+	return at > 0 && at < len(s)-1 && strings.IndexAny(s, " \t\r\n") < 0 &&
+		strings.IndexByte(s[at+1:], '.') > 0
+}
+
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
@@ -0,0 +1,90 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestInferSchema(t *testing.T) {
+	t.Run("optional fields", func(t *testing.T) {
+		schema, err := json.InferSchema(strings.NewReader(`{"id":1,"name":"a"} {"id":2} {"id":3,"name":null}`))
+		assertErr(t, err)
+		assertEq(t, "count", 3, schema.Count)
+		assertEq(t, "objects", 3, schema.Kinds[json.KindObject])
+		assertEq(t, "id required", false, schema.Optional("id"))
+		assertEq(t, "name optional", true, schema.Optional("name"))
+		assertEq(t, "unknown optional", true, schema.Optional("unknown"))
+		assertEq(t, "name count", 2, schema.Fields["name"].Count)
+	})
+	t.Run("repeated key", func(t *testing.T) {
+		schema, err := json.InferSchema(strings.NewReader(`{"a":1,"a":2} {"b":1}`))
+		assertErr(t, err)
+		assertEq(t, "values", 2, schema.Fields["a"].Count)
+		assertEq(t, "a optional", true, schema.Optional("a"))
+		assertEq(t, "b optional", true, schema.Optional("b"))
+
+		schema, err = json.InferSchema(strings.NewReader(`{"a":1,"a":2} {"a":3}`))
+		assertErr(t, err)
+		assertEq(t, "a required", false, schema.Optional("a"))
+	})
+	t.Run("mixed kinds", func(t *testing.T) {
+		schema, err := json.InferSchema(strings.NewReader(`1 2.5 "s" null true [] {}`))
+		assertErr(t, err)
+		assertEq(t, "count", 7, schema.Count)
+		assertEq(t, "numbers", 2, schema.Kinds[json.KindNumber])
+		assertEq(t, "integers", 1, schema.Integers)
+		for _, kind := range []json.Kind{json.KindString, json.KindNull, json.KindBool, json.KindArray, json.KindObject} {
+			assertEq(t, kind.String(), 1, schema.Kinds[kind])
+		}
+		b, err := schema.JSONSchema()
+		assertErr(t, err)
+		var v struct {
+			Type []string `json:"type"`
+		}
+		assertErr(t, json.Unmarshal(b, &v))
+		assertEq(t, "types", "null boolean number string object array", strings.Join(v.Type, " "))
+	})
+	t.Run("formats", func(t *testing.T) {
+		schema, err := json.InferSchema(strings.NewReader(`
+			"2024-01-02T03:04:05Z" "2024-01-02" "123e4567-e89b-12d3-a456-426614174000"
+			"user@example.com" "https://example.com/a" "192.168.0.1" "::1" "text"`))
+		assertErr(t, err)
+		for _, format := range []string{"date-time", "date", "uuid", "email", "uri", "ipv4", "ipv6"} {
+			assertEq(t, format, 1, schema.Formats[format])
+		}
+		assertEq(t, "formats", 7, len(schema.Formats))
+
+		schema, err = json.InferSchema(strings.NewReader(`{"at":"2024-01-02"} {"at":"2024-01-03"}`))
+		assertErr(t, err)
+		b, err := schema.JSONSchema()
+		assertErr(t, err)
+		assertEq(t, "json schema",
+			`{"properties":{"at":{"format":"date","type":"string"}},"required":["at"],"type":"object"}`,
+			string(b))
+	})
+	t.Run("array cardinality", func(t *testing.T) {
+		schema, err := json.InferSchema(strings.NewReader(`[1,2,3] [] [4]`))
+		assertErr(t, err)
+		assertEq(t, "min items", 0, schema.MinItems)
+		assertEq(t, "max items", 3, schema.MaxItems)
+		assertEq(t, "items", 4, schema.Items.Count)
+
+		schema, err = json.InferSchema(strings.NewReader(`[1,2] [1,2,3,4]`))
+		assertErr(t, err)
+		assertEq(t, "min items", 2, schema.MinItems)
+		assertEq(t, "max items", 4, schema.MaxItems)
+		b, err := schema.JSONSchema()
+		assertErr(t, err)
+		assertEq(t, "json schema", `{"items":{"type":"integer"},"maxItems":4,"minItems":2,"type":"array"}`, string(b))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		si := json.NewSchemaInferrer()
+		assertErr(t, si.Add([]byte(`{"a":1}`)))
+		if err := si.Add([]byte(`{"a":`)); err == nil {
+			t.Fatal("expected error")
+		}
+		assertEq(t, "unchanged", 1, si.Schema().Count)
+	})
+}
@@ -0,0 +1,25 @@
+package json
+
+import (
+	"github.com/going/json/internal/decoder"
+	"github.com/going/json/internal/encoder"
+)
+
+// Sealer encrypts and decrypts the values of the struct fields with the sealed tag option
+// ( e.g. `json:"ssn,sealed"` ), such as with AES-GCM or by envelope encryption with a key management service.
+type Sealer interface {
+	// Seal returns the ciphertext of plaintext, the JSON encoding of the field value.
+	Seal(plaintext []byte) ([]byte, error)
+	// Open returns the plaintext of ciphertext returned by Seal.
+	Open(ciphertext []byte) ([]byte, error)
+}
+
+// RegisterSealer registers s as the Sealer of the struct fields with the sealed tag option.
+// On Marshal, the field value is encoded with the default options, sealed by s, and written as a base64 string.
+// On Unmarshal, the string is opened by s and the plaintext is decoded into the field.
+// Nil pointers, maps and slices are written as null, which is not sealed. Without a registered Sealer, encoding and decoding a sealed field fail.
+// It must be called before anything is encoded or decoded, typically from an init function.
+func RegisterSealer(s Sealer) {
+	encoder.Seal = s.Seal
+	decoder.Open = s.Open
+}
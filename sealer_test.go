@@ -0,0 +1,93 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+// xorSealer is a toy Sealer for the tests, which must not be used for real data.
+type xorSealer struct{}
+
+func (xorSealer) Seal(plaintext []byte) ([]byte, error) {
+	sealed := append([]byte("v1:"), plaintext...)
+	for i := 3; i < len(sealed); i++ {
+		sealed[i] ^= 0x5a
+	}
+	return sealed, nil
+}
+
+func (xorSealer) Open(ciphertext []byte) ([]byte, error) {
+	if !bytes.HasPrefix(ciphertext, []byte("v1:")) {
+		return nil, errors.New("unknown key version")
+	}
+	plaintext := append([]byte{}, ciphertext[3:]...)
+	for i := range plaintext {
+		plaintext[i] ^= 0x5a
+	}
+	return plaintext, nil
+}
+
+func TestSealedField(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+	}
+	type Person struct {
+		Name    string            `json:"name"`
+		SSN     string            `json:"ssn,sealed"`
+		Salary  int               `json:"salary,sealed"`
+		Address *Address          `json:"address,sealed"`
+		Notes   []string          `json:"notes,sealed"`
+		Extra   map[string]string `json:"extra,sealed,omitempty"`
+	}
+	json.RegisterSealer(xorSealer{})
+
+	p := Person{Name: "a", SSN: "123-45-6789", Salary: 100, Address: &Address{City: "b"}, Notes: []string{"x"}}
+	b, err := json.Marshal(p)
+	assertErr(t, err)
+	assertEq(t, "plaintext leaked", false, strings.Contains(string(b), "6789"))
+	assertEq(t, "city leaked", false, strings.Contains(string(b), `"b"`))
+
+	var raw map[string]interface{}
+	assertErr(t, json.Unmarshal(b, &raw))
+	assertEq(t, "name", "a", raw["name"])
+	_, isString := raw["ssn"].(string)
+	assertEq(t, "ssn is a string", true, isString)
+	_, exists := raw["extra"]
+	assertEq(t, "omitempty", false, exists)
+
+	var decoded Person
+	assertErr(t, json.Unmarshal(b, &decoded))
+	assertEq(t, "ssn", p.SSN, decoded.SSN)
+	assertEq(t, "salary", p.Salary, decoded.Salary)
+	assertEq(t, "city", "b", decoded.Address.City)
+	assertEq(t, "notes", "x", decoded.Notes[0])
+
+	var streamed Person
+	assertErr(t, json.NewDecoder(bytes.NewReader(b)).Decode(&streamed))
+	assertEq(t, "stream ssn", p.SSN, streamed.SSN)
+
+	indented, err := json.MarshalIndent(p, "", "  ")
+	assertErr(t, err)
+	var fromIndented Person
+	assertErr(t, json.Unmarshal(indented, &fromIndented))
+	assertEq(t, "indented ssn", p.SSN, fromIndented.SSN)
+
+	t.Run("null", func(t *testing.T) {
+		b, err := json.Marshal(Person{})
+		assertErr(t, err)
+		assertEq(t, "null address", true, strings.Contains(string(b), `"address":null`))
+		decoded := Person{Address: &Address{}}
+		assertErr(t, json.Unmarshal(b, &decoded))
+		assertEq(t, "address", true, decoded.Address == nil)
+	})
+	t.Run("open error", func(t *testing.T) {
+		var v Person
+		err := json.Unmarshal([]byte(`{"ssn":"eDE6"}`), &v)
+		assertNeq(t, "error", nil, err)
+		assertEq(t, "message", "json: cannot open sealed field Person.SSN: unknown key version", err.Error())
+	})
+}
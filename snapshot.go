@@ -0,0 +1,53 @@
+package json
+
+import (
+	"reflect"
+
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/runtime"
+)
+
+// SnapshotCodecs compiles the encoders of the types of values and of the pointers to them, and returns them
+// in a form LoadCodecSnapshot loads at the start of another run of the program, so that the first requests
+// of a service don't pay the compile of the types they encode. The snapshot is typically made by a test or
+// a go:generate command, written to a file and embedded into the binary:
+//
+//	//go:embed codecs.snapshot
+//	var codecSnapshot []byte
+//
+//	func init() {
+//		json.LoadCodecSnapshot(codecSnapshot, Order{}, Invoice{})
+//	}
+//
+// Loading the encoder of a type takes about half the time of compiling it, and allocates far less.
+// Decoders are not part of the snapshot, and are still compiled on first use.
+// Encoders that depend on the options of a call, such as field queries, are built from the loaded encoders on first use.
+func SnapshotCodecs(values ...interface{}) ([]byte, error) {
+	return encoder.AppendSnapshot(nil, snapshotTypes(values))
+}
+
+// LoadCodecSnapshot loads the encoders of the types of values, and of the pointers to them, from a snapshot made
+// by SnapshotCodecs. It returns the number of types loaded.
+// A type is loaded only if its fields, struct tags and methods, and those of the types it contains, are the same
+// as when the snapshot was made, and if it is not encoded yet. The others are compiled on first use as usual,
+// so a stale snapshot is never wrong, only slower.
+// It returns an error if snapshot is malformed, or was made by another version of this package or for another platform.
+//
+// The snapshot must come from a trusted source, such as a file embedded into the binary: the opcodes are checked
+// when they are loaded, but the offsets of the fields they read are not, so a crafted snapshot can make the encoder
+// read memory outside of the values it encodes.
+func LoadCodecSnapshot(snapshot []byte, values ...interface{}) (int, error) {
+	return encoder.LoadSnapshot(snapshot, snapshotTypes(values))
+}
+
+func snapshotTypes(values []interface{}) []*runtime.Type {
+	types := make([]*runtime.Type, 0, 2*len(values))
+	for _, v := range values {
+		typ := reflect.TypeOf(v)
+		if typ == nil {
+			continue
+		}
+		types = append(types, runtime.Type2RType(typ), runtime.Type2RType(reflect.PtrTo(typ)))
+	}
+	return types
+}
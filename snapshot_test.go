@@ -0,0 +1,129 @@
+package json_test
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+type snapshotCategory struct {
+	Name     string              `json:"name"`
+	Children []*snapshotCategory `json:"children,omitempty"`
+}
+
+type snapshotOrder struct {
+	ID       int64              `json:"id,string"`
+	Items    []snapshotItem     `json:"items"`
+	Labels   map[string]string  `json:"labels,omitempty"`
+	Category *snapshotCategory  `json:"category"`
+	Extra    interface{}        `json:"extra"`
+	Price    codecMoney         `json:"price,codec=money-cents"`
+	Note     *string            `json:"note,omitempty"`
+	Counts   [2]uint8           `json:"counts"`
+	Raw      json.RawMessage    `json:"raw"`
+	Nested   struct{ A, B int } `json:"nested"`
+}
+
+type snapshotItem struct {
+	SKU   string  `json:"sku"`
+	Price float64 `json:"price"`
+}
+
+var snapshotOrderLoaded bool
+
+func TestCodecSnapshot(t *testing.T) {
+	snapshot, err := json.SnapshotCodecs(snapshotOrder{})
+	assertErr(t, err)
+
+	n, err := json.LoadCodecSnapshot(snapshot, snapshotOrder{})
+	assertErr(t, err)
+	// the types stay loaded when the test runs again, as with -count.
+	if !snapshotOrderLoaded {
+		assertEq(t, "loaded", 2, n)
+		snapshotOrderLoaded = true
+	}
+
+	note := "<fragile>"
+	v := snapshotOrder{
+		ID:       42,
+		Items:    []snapshotItem{{SKU: "a", Price: 1.5}, {SKU: "b", Price: 2}},
+		Labels:   map[string]string{"x": "y"},
+		Category: &snapshotCategory{Name: "root", Children: []*snapshotCategory{{Name: "leaf"}}},
+		Extra:    []interface{}{"e", 1},
+		Price:    1234,
+		Note:     &note,
+		Counts:   [2]uint8{1, 2},
+		Raw:      json.RawMessage(`{"r":true}`),
+	}
+	expected := `{"id":"42","items":[{"sku":"a","price":1.5},{"sku":"b","price":2}],"labels":{"x":"y"},` +
+		`"category":{"name":"root","children":[{"name":"leaf"}]},"extra":["e",1],"price":1234,` +
+		`"note":"\u003cfragile\u003e","counts":[1,2],"raw":{"r":true},"nested":{"A":0,"B":0}}`
+	t.Run("value", func(t *testing.T) {
+		got, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "json", expected, string(got))
+	})
+	t.Run("pointer", func(t *testing.T) {
+		got, err := json.Marshal(&v)
+		assertErr(t, err)
+		assertEq(t, "json", expected, string(got))
+	})
+	t.Run("interface", func(t *testing.T) {
+		got, err := json.Marshal([]interface{}{v})
+		assertErr(t, err)
+		assertEq(t, "json", "["+expected+"]", string(got))
+	})
+	t.Run("indent", func(t *testing.T) {
+		got, err := json.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		v.Price = 0
+		stdExpected, err := stdjson.MarshalIndent(v, "", "  ")
+		v.Price = 1234
+		assertErr(t, err)
+		// encoding/json has no codecs.
+		assertEq(t, "json", string(stdExpected), strings.Replace(string(got), `"price": 1234,`, `"price": 0,`, 1))
+	})
+	t.Run("field query", func(t *testing.T) {
+		query, err := json.BuildFieldQuery("id", json.BuildSubFieldQuery("category").Fields("name"))
+		assertErr(t, err)
+		got, err := json.MarshalContext(json.SetFieldQueryToContext(context.Background(), query), v)
+		assertErr(t, err)
+		assertEq(t, "json", `{"id":"42","category":{"name":"root"}}`, string(got))
+	})
+
+	t.Run("loaded already", func(t *testing.T) {
+		n, err := json.LoadCodecSnapshot(snapshot, snapshotOrder{})
+		assertErr(t, err)
+		assertEq(t, "loaded", 0, n)
+	})
+	t.Run("changed type", func(t *testing.T) {
+		snapshot := func() []byte {
+			type changed struct {
+				A int `json:"a"`
+			}
+			snapshot, err := json.SnapshotCodecs(changed{})
+			assertErr(t, err)
+			return snapshot
+		}()
+		type changed struct {
+			A int `json:"b"`
+		}
+		n, err := json.LoadCodecSnapshot(snapshot, changed{})
+		assertErr(t, err)
+		assertEq(t, "loaded", 0, n)
+		got, err := json.Marshal(changed{A: 1})
+		assertErr(t, err)
+		assertEq(t, "json", `{"b":1}`, string(got))
+	})
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := json.LoadCodecSnapshot([]byte("{}"), snapshotOrder{}); err == nil {
+			t.Fatal("expected error")
+		}
+		if _, err := json.LoadCodecSnapshot(snapshot[:len(snapshot)-1], snapshotOrder{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
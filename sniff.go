@@ -0,0 +1,186 @@
+package json
+
+import (
+	"bufio"
+	"io"
+)
+
+// Format is the layout of the JSON values in a stream, as detected by SniffFormat.
+type Format int
+
+const (
+	// FormatInvalid is an empty stream or one that does not start with a JSON value.
+	FormatInvalid Format = iota
+	// FormatValue is a single JSON value other than an array.
+	FormatValue
+	// FormatConcatenated is JSON values concatenated on the same line, such as `{"a":1}{"a":2}`.
+	FormatConcatenated
+	// FormatNDJSON is JSON values separated by newlines, as in NDJSON and JSON Lines.
+	FormatNDJSON
+	// FormatArray is a single top-level array whose elements are the values of the stream.
+	FormatArray
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatValue:
+		return "value"
+	case FormatConcatenated:
+		return "concatenated"
+	case FormatNDJSON:
+		return "ndjson"
+	case FormatArray:
+		return "array"
+	}
+	return "invalid"
+}
+
+// sniffSize is the largest number of bytes SniffFormat reads ahead.
+const sniffSize = 64 * 1024
+
+// SniffFormat detects the layout of the JSON values in r, and returns its Format and a Decoder reading r,
+// so that the values are read the same way whatever the client sends:
+//
+//	format, dec := json.SniffFormat(r)
+//	for dec.More() {
+//		var v Record
+//		if err := dec.Decode(&v); err != nil { ... }
+//	}
+//
+// With FormatArray, the Decoder is in ArrayMode, so that Decode reads the elements of the array.
+//
+// SniffFormat reads r up to the first character after the first value, so it returns as soon as the second
+// record of a stream arrives. A first value larger than 64KB is taken as FormatArray if it is an array,
+// and as FormatValue otherwise. Read errors are returned by the Decoder.
+func SniffFormat(r io.Reader) (Format, *Decoder) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	format := (&sniffer{r: br}).sniff()
+	dec := NewDecoder(br)
+	if format == FormatArray {
+		dec.ArrayMode()
+	}
+	return format, dec
+}
+
+// sniffer looks ahead in a bufio.Reader, reading no more than needed.
+type sniffer struct {
+	r   *bufio.Reader
+	buf []byte
+	err error
+}
+
+// at returns the byte at i of the input, or false at the end of the input or beyond sniffSize.
+func (s *sniffer) at(i int) (byte, bool) {
+	for i >= len(s.buf) {
+		if s.err != nil || len(s.buf) >= s.r.Size() {
+			return 0, false
+		}
+		s.buf, s.err = s.r.Peek(len(s.buf) + 1)
+	}
+	return s.buf[i], true
+}
+
+func (s *sniffer) sniff() Format {
+	cursor := s.skipWhiteSpace(0)
+	c, ok := s.at(cursor)
+	if !ok || kindOfChar(c) == KindInvalid {
+		return FormatInvalid
+	}
+	isArray := c == '['
+	end, ok := s.valueEnd(cursor)
+	if !ok {
+		if isArray {
+			return FormatArray
+		}
+		return FormatValue
+	}
+	next := s.skipWhiteSpace(end)
+	if _, ok := s.at(next); !ok {
+		if isArray {
+			return FormatArray
+		}
+		return FormatValue
+	}
+	for _, c := range s.buf[end:next] {
+		if c == '\n' {
+			return FormatNDJSON
+		}
+	}
+	return FormatConcatenated
+}
+
+func (s *sniffer) skipWhiteSpace(cursor int) int {
+	for {
+		c, ok := s.at(cursor)
+		if !ok {
+			return cursor
+		}
+		switch c {
+		case ' ', '\n', '\t', '\r':
+			cursor++
+			continue
+		}
+		return cursor
+	}
+}
+
+// valueEnd returns the position next to the value starting at cursor, or false if it does not end in the lookahead.
+func (s *sniffer) valueEnd(cursor int) (int, bool) {
+	c, _ := s.at(cursor)
+	switch c {
+	case '"':
+		return s.stringEnd(cursor)
+	case '{', '[':
+		depth := 0
+		for {
+			c, ok := s.at(cursor)
+			if !ok {
+				return 0, false
+			}
+			switch c {
+			case '"':
+				end, ok := s.stringEnd(cursor)
+				if !ok {
+					return 0, false
+				}
+				cursor = end
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return cursor + 1, true
+				}
+			}
+			cursor++
+		}
+	}
+	// numbers and literals end at the end of the input.
+	for cursor++; ; cursor++ {
+		c, ok := s.at(cursor)
+		if !ok {
+			return cursor, true
+		}
+		switch c {
+		case ' ', '\n', '\t', '\r', ',', ':', '{', '}', '[', ']', '"':
+			return cursor, true
+		}
+	}
+}
+
+// stringEnd returns the position next to the closing quote of the string starting at cursor.
+func (s *sniffer) stringEnd(cursor int) (int, bool) {
+	for cursor++; ; cursor++ {
+		c, ok := s.at(cursor)
+		if !ok {
+			return 0, false
+		}
+		switch c {
+		case '\\':
+			cursor++
+		case '"':
+			return cursor + 1, true
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package json_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/going/json"
+)
+
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		input  string
+		format json.Format
+		values []interface{}
+	}{
+		{input: ``, format: json.FormatInvalid},
+		{input: " \n ", format: json.FormatInvalid},
+		{input: `<xml/>`, format: json.FormatInvalid},
+		{input: `{"a":"}\n{"}`, format: json.FormatValue, values: []interface{}{map[string]interface{}{"a": "}\n{"}}},
+		{input: " 1 \n", format: json.FormatValue, values: []interface{}{float64(1)}},
+		{input: `"a" "b"`, format: json.FormatConcatenated, values: []interface{}{"a", "b"}},
+		{input: `{"a":1}{"a":2}`, format: json.FormatConcatenated, values: []interface{}{map[string]interface{}{"a": float64(1)}, map[string]interface{}{"a": float64(2)}}},
+		{input: "true\r\nfalse\r\nnull", format: json.FormatNDJSON, values: []interface{}{true, false, nil}},
+		{input: "[1]\n[2]\n", format: json.FormatNDJSON, values: []interface{}{[]interface{}{float64(1)}, []interface{}{float64(2)}}},
+		{input: ` [ {"a":"]"}, 2 ] `, format: json.FormatArray, values: []interface{}{map[string]interface{}{"a": "]"}, float64(2)}},
+		{input: `[]`, format: json.FormatArray},
+		{input: `[` + strings.Repeat(`"xxxxxxxx",`, 10000) + `1]`, format: json.FormatArray},
+	}
+	for _, test := range tests {
+		for _, oneByte := range []bool{false, true} {
+			var r io.Reader = strings.NewReader(test.input)
+			if oneByte {
+				r = iotest.OneByteReader(r)
+			}
+			format, dec := json.SniffFormat(r)
+			assertEq(t, "format of "+test.input, test.format, format)
+			if test.format == json.FormatInvalid || len(test.input) > 1000 {
+				continue
+			}
+			var values []interface{}
+			for dec.More() {
+				var v interface{}
+				assertErr(t, dec.Decode(&v))
+				values = append(values, v)
+			}
+			assertEq(t, "values of "+test.input, len(test.values), len(values))
+			for i := range values {
+				assertEq(t, "value of "+test.input, true, reflect.DeepEqual(test.values[i], values[i]))
+			}
+		}
+	}
+}
+
+func TestSniffFormat_Streaming(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("{\"id\":1}\n{"))
+	}()
+	format, dec := json.SniffFormat(pr)
+	assertEq(t, "format", json.FormatNDJSON, format)
+	var v map[string]int
+	assertErr(t, dec.Decode(&v))
+	assertEq(t, "id", 1, v["id"])
+	pw.Close()
+}
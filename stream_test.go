@@ -7,6 +7,9 @@ package json_test
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,7 +19,9 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/going/json"
 )
@@ -94,6 +99,306 @@ func TestEncoderIndent(t *testing.T) {
 	}
 }
 
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestEncoderWriteComment(t *testing.T) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.WriteComment("generated"); err == nil {
+		t.Fatal("expected error without relaxed mode")
+	}
+	enc.SetRelaxed(true)
+	enc.SetIndent(">", "  ")
+	assertErr(t, enc.WriteComment("generated 2024-05-01\n\nby test"))
+	assertErr(t, enc.Encode(map[string]int{"a": 1}))
+	assertErr(t, enc.WriteComment("second"))
+	assertErr(t, enc.Encode(1))
+	want := `>// generated 2024-05-01
+>//
+>// by test
+{
+>  "a": 1
+>}
+>// second
+1
+`
+	assertEq(t, "output", want, buf.String())
+}
+
+func TestSyncEncoder(t *testing.T) {
+	type record struct {
+		Goroutine int    `json:"g"`
+		Seq       int    `json:"seq"`
+		Msg       string `json:"msg"`
+	}
+	var w writeCounter
+	enc := json.NewSyncEncoder(&w)
+	enc.SetEscapeHTML(false)
+	const goroutines, records = 8, 200
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < records; i++ {
+				if err := enc.Encode(record{Goroutine: g, Seq: i, Msg: strings.Repeat("<>", i%50)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assertEq(t, "writes", goroutines*records, w.writes)
+	out := w.String()
+	if strings.Contains(out, `\u003c`) {
+		t.Fatal("expected HTML characters not to be escaped")
+	}
+	next := make([]int, goroutines)
+	dec := json.NewDecoder(strings.NewReader(out))
+	for dec.More() {
+		var r record
+		assertErr(t, dec.Decode(&r))
+		assertEq(t, "sequence", next[r.Goroutine], r.Seq)
+		next[r.Goroutine]++
+	}
+	for g := range next {
+		assertEq(t, "records", records, next[g])
+	}
+}
+
+func TestEncoderStats(t *testing.T) {
+	var w writeCounter
+	enc := json.NewEncoder(&w)
+	assertErr(t, enc.Encode([]int{1, 2, 3}))
+	assertErr(t, enc.Encode("a"))
+	stats := enc.Stats()
+	assertEq(t, "values", int64(2), stats.Values)
+	assertEq(t, "bytes", int64(w.Len()), stats.Bytes)
+	assertEq(t, "max write", len("[1,2,3]\n"), stats.MaxWrite)
+
+	if err := enc.Encode(func() {}); err == nil {
+		t.Fatal("expected error")
+	}
+	assertEq(t, "values after error", int64(2), enc.Stats().Values)
+
+	w = writeCounter{}
+	enc = json.NewEncoder(&w)
+	enc.SetFlushSize(1024)
+	assertErr(t, enc.Encode(make([]int, 10000)))
+	stats = enc.Stats()
+	assertEq(t, "flushed bytes", int64(w.Len()), stats.Bytes)
+	if stats.MaxWrite > 2048 {
+		t.Fatalf("expected writes bounded by the flush size but got %d", stats.MaxWrite)
+	}
+}
+
+func TestEncoderSetHash(t *testing.T) {
+	var buf bytes.Buffer
+	h := sha256.New()
+	enc := json.NewEncoder(&buf)
+	enc.SetXSSIPrefix(json.XSSIPrefix)
+	enc.SetFlushSize(64)
+	enc.SetHash(h)
+	assertErr(t, enc.Encode(make([]int, 1000)))
+	assertErr(t, enc.Encode(map[string]string{"a": "<b>"}))
+	expected := sha256.Sum256(buf.Bytes())
+	assertEq(t, "digest", hex.EncodeToString(expected[:]), hex.EncodeToString(h.Sum(nil)))
+
+	enc.SetHash(nil)
+	assertErr(t, enc.Encode(1))
+	assertEq(t, "detached", hex.EncodeToString(expected[:]), hex.EncodeToString(h.Sum(nil)))
+
+	var sbuf bytes.Buffer
+	sh := sha256.New()
+	senc := json.NewSyncEncoder(&sbuf)
+	senc.SetHash(sh)
+	assertErr(t, senc.Encode([]string{"x", "y"}))
+	sexpected := sha256.Sum256(sbuf.Bytes())
+	assertEq(t, "sync digest", hex.EncodeToString(sexpected[:]), hex.EncodeToString(sh.Sum(nil)))
+}
+
+func TestEncoderSetUnorderedMap(t *testing.T) {
+	m := map[string]int{}
+	for i := 0; i < 20; i++ {
+		m[fmt.Sprintf("k%02d", i)] = i
+	}
+	sorted, err := json.Marshal(m)
+	assertErr(t, err)
+
+	var buf bytes.Buffer
+	enc := json.NewSyncEncoder(&buf)
+	enc.SetUnorderedMap(true)
+	unordered := false
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		assertErr(t, enc.Encode(m))
+		var decoded map[string]int
+		assertErr(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assertEq(t, "entries", true, reflect.DeepEqual(m, decoded))
+		if buf.String() != string(sorted)+"\n" {
+			unordered = true
+		}
+	}
+	assertEq(t, "unordered", true, unordered)
+
+	enc.SetUnorderedMap(false)
+	buf.Reset()
+	assertErr(t, enc.Encode(m))
+	assertEq(t, "sorted", string(sorted)+"\n", buf.String())
+}
+
+func TestEncoderSetBufferStatsFunc(t *testing.T) {
+	var (
+		buf   bytes.Buffer
+		stats []json.BufferStats
+	)
+	enc := json.NewEncoder(&buf)
+	enc.SetBufferStatsFunc(func(s json.BufferStats) { stats = append(stats, s) })
+	assertErr(t, enc.Encode(strings.Repeat("x", 100000)))
+	assertErr(t, enc.Encode(1))
+	assertEq(t, "reports", 2, len(stats))
+	// the encode buffers are pooled, so whether they grow depends on the values encoded before.
+	assertEq(t, "len", 100003, stats[0].Len)
+	assertEq(t, "small len", 2, stats[1].Len)
+	for _, s := range stats {
+		if s.FinalCap < s.Len || s.FinalCap < s.InitialCap || (s.Regrows == 0) != (s.FinalCap == s.InitialCap) {
+			t.Fatalf("unexpected stats %+v", s)
+		}
+	}
+
+	b := make([]byte, 0, 8)
+	initialCap := cap(b)
+	b = append(b, "12345678"...)
+	assertEq(t, "full", json.BufferStats{InitialCap: 8, FinalCap: 8, Len: 8}, json.NewBufferStats(initialCap, b))
+	for i := 0; i < 100000; i++ {
+		b = append(b, 'x')
+	}
+	large := json.NewBufferStats(initialCap, b)
+	assertEq(t, "grown len", 100008, large.Len)
+	if large.FinalCap != cap(b) || large.Regrows == 0 {
+		t.Fatalf("expected the buffer to grow but got %+v", large)
+	}
+
+	enc.SetBufferStatsFunc(nil)
+	assertErr(t, enc.Encode(1))
+	assertEq(t, "disabled", 2, len(stats))
+
+	var sbuf bytes.Buffer
+	senc := json.NewSyncEncoder(&sbuf)
+	reports := 0
+	senc.SetBufferStatsFunc(func(json.BufferStats) { reports++ })
+	assertErr(t, senc.Encode([]int{1, 2, 3}))
+	assertEq(t, "sync reports", 1, reports)
+}
+
+func TestDecoderStats(t *testing.T) {
+	input := `{"a":1} [1,2,3] "` + strings.Repeat("x", 2000) + `"`
+	dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	var v interface{}
+	assertErr(t, dec.Decode(&v))
+	assertErr(t, dec.Decode(&v))
+	stats := dec.Stats()
+	assertEq(t, "values", int64(2), stats.Values)
+	if stats.Bytes < int64(len(`{"a":1} [1,2,3]`)) || stats.Bytes > int64(len(input)) {
+		t.Fatalf("unexpected bytes %d", stats.Bytes)
+	}
+	assertErr(t, dec.Decode(&v))
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Fatalf("expected EOF but got %v", err)
+	}
+	stats = dec.Stats()
+	assertEq(t, "values", int64(3), stats.Values)
+	assertEq(t, "bytes", int64(len(input)), stats.Bytes)
+	if stats.BufferSize < 2000 {
+		t.Fatalf("expected the buffer to grow for the long string but got %d", stats.BufferSize)
+	}
+
+	dec = json.NewDecoderBytes([]byte(input))
+	for dec.More() {
+		assertErr(t, dec.Decode(&v))
+	}
+	stats = dec.Stats()
+	assertEq(t, "bytes values", int64(3), stats.Values)
+	assertEq(t, "bytes bytes", int64(len(input)), stats.Bytes)
+}
+
+func TestEncoderSetFlushSize(t *testing.T) {
+	type item struct {
+		ID    int              `json:"id"`
+		Name  string           `json:"name"`
+		Tags  []string         `json:"tags"`
+		Attrs map[string]int   `json:"attrs"`
+		Any   interface{}      `json:"any"`
+		Arr   [3]int           `json:"arr"`
+		Group map[string][]int `json:"group,omitempty"`
+	}
+	items := make([]item, 500)
+	for i := range items {
+		items[i] = item{
+			ID:    i,
+			Name:  strings.Repeat("x", i%17),
+			Tags:  []string{"a", "b"},
+			Attrs: map[string]int{"z": i, "a": -i},
+			Any:   []interface{}{i, "s", map[string]interface{}{"k": []int{1, 2, 3}}},
+			Arr:   [3]int{i, i, i},
+		}
+	}
+	items[10].Group = map[string][]int{"b": make([]int, 2000), "a": make([]int, 2000)}
+	// indent and color are not flushed halfway when their VMs are compiled out by build tags.
+	for _, tc := range []struct {
+		name      string
+		opts      []json.EncodeOptionFunc
+		set       func(*json.Encoder)
+		unordered bool
+		flushes   bool
+	}{
+		{name: "compact", flushes: true},
+		{name: "unordered", opts: []json.EncodeOptionFunc{json.UnorderedMap()}, unordered: true, flushes: true},
+		{name: "stream sorted map", opts: []json.EncodeOptionFunc{json.StreamSortedMap()}, flushes: true},
+		{name: "indent", set: func(enc *json.Encoder) { enc.SetIndent("", "  ") }},
+		{name: "color", opts: []json.EncodeOptionFunc{json.Colorize(json.DefaultColorScheme)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var want bytes.Buffer
+			enc := json.NewEncoder(&want)
+			if tc.set != nil {
+				tc.set(enc)
+			}
+			assertErr(t, enc.EncodeWithOption(items, tc.opts...))
+
+			var got writeCounter
+			enc = json.NewEncoder(&got)
+			if tc.set != nil {
+				tc.set(enc)
+			}
+			enc.SetFlushSize(4096)
+			assertErr(t, enc.EncodeWithOption(items, tc.opts...))
+			if tc.unordered {
+				var gotV, wantV interface{}
+				assertErr(t, json.Unmarshal(got.Bytes(), &gotV))
+				assertErr(t, json.Unmarshal(want.Bytes(), &wantV))
+				if !reflect.DeepEqual(gotV, wantV) {
+					t.Fatal("flushed output mismatch")
+				}
+			} else if got.String() != want.String() {
+				t.Fatal("flushed output mismatch")
+			}
+			if tc.flushes && got.writes < 2 {
+				t.Fatalf("expected multiple writes, got %d", got.writes)
+			}
+		})
+	}
+}
+
 type strMarshaler string
 
 func (s strMarshaler) MarshalJSON() ([]byte, error) {
@@ -441,6 +746,16 @@ func TestDecodeStreamUseNumber(t *testing.T) {
 	assertEq(t, "json.Number", "json.Number", fmt.Sprintf("%T", v))
 }
 
+func TestDecodeStreamUseRawNumber(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`3.140`))
+	dec.UseRawNumber()
+	v, err := dec.Token()
+	if err != nil {
+		t.Errorf("unexpected error: %#v", err)
+	}
+	assertEq(t, "json.RawNumber", "3.140", string(v.(json.RawNumber)))
+}
+
 // Test from golang.org/issue/11893
 func TestHTTPDecoding(t *testing.T) {
 	const raw = `{ "foo": "bar" }`
@@ -538,3 +853,328 @@ func TestIssue278(t *testing.T) {
 		t.Errorf("string %q; want = %q", got, want)
 	}
 }
+
+func TestDecoderPeekKind(t *testing.T) {
+	input := ` {"a": [1, "b"]} null`
+	dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	next := func(want json.Kind) {
+		t.Helper()
+		kind, err := dec.PeekKind()
+		assertErr(t, err)
+		assertEq(t, "kind", want, kind)
+		// peeking again must return the same kind.
+		kind, err = dec.PeekKind()
+		assertErr(t, err)
+		assertEq(t, "kind", want, kind)
+	}
+	next(json.KindObject)
+	_, err := dec.Token()
+	assertErr(t, err)
+	next(json.KindString)
+	_, err = dec.Token()
+	assertErr(t, err)
+	next(json.KindArray)
+	var v []interface{}
+	assertErr(t, dec.Decode(&v))
+	assertEq(t, "value", "[1 b]", fmt.Sprint(v))
+	next(json.KindInvalid)
+	_, err = dec.Token()
+	assertErr(t, err)
+	next(json.KindNull)
+	assertErr(t, dec.Decode(&v))
+	_, err = dec.PeekKind()
+	assertEq(t, "end", io.EOF, err)
+}
+
+func TestDecoderPeek(t *testing.T) {
+	input := `{"a\n\u00e9": [1.5, true, null, "x\"y"]}`
+	for name, dec := range map[string]*json.Decoder{
+		"reader": json.NewDecoder(iotest.OneByteReader(strings.NewReader(input))),
+		"bytes":  json.NewDecoderBytes([]byte(input)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var tokens []json.Token
+			for {
+				peeked, err := dec.Peek()
+				if err == io.EOF {
+					break
+				}
+				assertErr(t, err)
+				tok, err := dec.Token()
+				assertErr(t, err)
+				assertEq(t, "token", fmt.Sprint(tok), fmt.Sprint(peeked))
+				tokens = append(tokens, tok)
+			}
+			assertEq(t, "tokens", fmt.Sprint([]json.Token{
+				json.Delim('{'), "a\né", json.Delim('['), 1.5, true, nil, `x"y`, json.Delim(']'), json.Delim('}'),
+			}), fmt.Sprint(tokens))
+		})
+	}
+	t.Run("decode", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`"a\tb" {"k": 1}`))
+		tok, err := dec.Peek()
+		assertErr(t, err)
+		assertEq(t, "peek", "a\tb", tok)
+		var s string
+		assertErr(t, dec.Decode(&s))
+		assertEq(t, "decode", "a\tb", s)
+
+		tok, err = dec.Peek()
+		assertErr(t, err)
+		assertEq(t, "peek", json.Delim('{'), tok)
+		var m map[string]int
+		assertErr(t, dec.Decode(&m))
+		assertEq(t, "decode", 1, m["k"])
+	})
+}
+
+func TestDecoderSkip(t *testing.T) {
+	input := `{"a": [1, {"b": "}\\"}], "c": "x"} "skipped" [true] 3`
+	for name, dec := range map[string]*json.Decoder{
+		"reader": json.NewDecoder(iotest.OneByteReader(strings.NewReader(input))),
+		"bytes":  json.NewDecoderBytes([]byte(input)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assertErr(t, dec.Skip())
+			assertErr(t, dec.Skip())
+			var v []bool
+			assertErr(t, dec.Decode(&v))
+			assertEq(t, "decode", true, v[0])
+			assertErr(t, dec.Skip())
+			assertEq(t, "end", io.EOF, dec.Skip())
+		})
+	}
+	t.Run("tokens", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a": {"x": [1, 2]}, "b": 2, "c": []}`))
+		var keys []string
+		_, err := dec.Token()
+		assertErr(t, err)
+		for dec.More() {
+			key, err := dec.Token()
+			assertErr(t, err)
+			keys = append(keys, key.(string))
+			if key == "b" {
+				var n int
+				assertErr(t, dec.Decode(&n))
+				assertEq(t, "b", 2, n)
+				continue
+			}
+			assertErr(t, dec.Skip())
+		}
+		tok, err := dec.Token()
+		assertErr(t, err)
+		assertEq(t, "delim", json.Delim('}'), tok)
+		assertEq(t, "keys", "[a b c]", fmt.Sprint(keys))
+	})
+	t.Run("array mode", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[{"a": 1}, 2, [3]]`))
+		dec.ArrayMode()
+		assertErr(t, dec.Skip())
+		var n int
+		assertErr(t, dec.Decode(&n))
+		assertEq(t, "element", 2, n)
+		assertErr(t, dec.Skip())
+		assertEq(t, "end", io.EOF, dec.Skip())
+	})
+	t.Run("errors", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[]`))
+		_, err := dec.Token()
+		assertErr(t, err)
+		// the end of the array is not a value.
+		assertNeq(t, "end of array", nil, dec.Skip())
+		assertNeq(t, "truncated", nil, json.NewDecoder(strings.NewReader(`{"a": [1`)).Skip())
+	})
+	t.Run("allocs", func(t *testing.T) {
+		data := []byte(`{"a": [1, 2, {"b": "c"}], "d": "` + strings.Repeat("e", 1000) + `"}`)
+		allocs := testing.AllocsPerRun(100, func() {
+			dec := json.NewDecoderBytes(data)
+			if err := dec.Skip(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		decodeAllocs := testing.AllocsPerRun(100, func() {
+			dec := json.NewDecoderBytes(data)
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs >= decodeAllocs {
+			t.Fatalf("Skip allocates %v times, not fewer than Decode (%v)", allocs, decodeAllocs)
+		}
+	})
+}
+
+func TestDecoderSkipInvalidRecords(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+	}
+	input := "{\"a\": 1}\n{\"a\": 1\n\n  {\"a\": 2}  \r\n{\"a\": 3} x\n{\"a\": \"s\"}\n{\"a\": 4}\n{\"a"
+	for name, dec := range map[string]*json.Decoder{
+		"reader": json.NewDecoder(iotest.OneByteReader(strings.NewReader(input))),
+		"bytes":  json.NewDecoderBytes([]byte(input)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				records []string
+				offsets []int64
+			)
+			dec.SkipInvalidRecords(func(record []byte, err error) {
+				records = append(records, string(record))
+				serr, ok := err.(*json.SyntaxError)
+				if !ok {
+					t.Fatalf("unexpected error %T: %v", err, err)
+				}
+				offsets = append(offsets, serr.Offset)
+			})
+			var got []int
+			for {
+				var v T
+				err := dec.Decode(&v)
+				if err == io.EOF {
+					break
+				}
+				if _, ok := err.(*json.UnmarshalTypeError); ok {
+					// the decoder continues with the next record.
+					continue
+				}
+				assertErr(t, err)
+				got = append(got, v.A)
+			}
+			assertEq(t, "values", "[1 2 4]", fmt.Sprint(got))
+			assertEq(t, "records", fmt.Sprint([]string{`{"a": 1`, `{"a": 3} x`, `{"a`}), fmt.Sprint(records))
+			// the offsets are in the whole input.
+			assertEq(t, "offsets", "[16 41 66]", fmt.Sprint(offsets))
+			assertEq(t, "values", int64(3), dec.Stats().Values)
+		})
+	}
+}
+
+func TestDecoderSkipInvalidRecordsNul(t *testing.T) {
+	const input = "{}\n\x00bad\n{}\n"
+	for name, dec := range map[string]*json.Decoder{
+		"reader":    json.NewDecoder(strings.NewReader(input)),
+		"one byte":  json.NewDecoder(iotest.OneByteReader(strings.NewReader(input))),
+		"bytes":     json.NewDecoderBytes([]byte(input)),
+		"nul first": json.NewDecoder(iotest.OneByteReader(strings.NewReader("\x00\n{}\n"))),
+	} {
+		var records []string
+		dec.SkipInvalidRecords(func(record []byte, err error) {
+			records = append(records, string(record))
+			if _, ok := err.(*json.SyntaxError); !ok {
+				t.Fatalf("%s: unexpected error %T: %v", name, err, err)
+			}
+		})
+		n := 0
+		for {
+			var v struct{}
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			assertErr(t, err)
+			n++
+		}
+		if name == "nul first" {
+			assertEq(t, name+" values", 1, n)
+			assertEq(t, name+" records", fmt.Sprint([]string{"\x00"}), fmt.Sprint(records))
+			continue
+		}
+		assertEq(t, name+" values", 2, n)
+		assertEq(t, name+" records", fmt.Sprint([]string{"\x00bad"}), fmt.Sprint(records))
+	}
+}
+
+func TestDecoderBytes(t *testing.T) {
+	data := []byte(`{"a": "x\ty", "b": [1, 2]} {"a": "z", "b": []}
+[10, 20]`)
+	dec := json.NewDecoderBytes(data)
+	type T struct {
+		A string
+		B []int
+	}
+	var v1, v2 T
+	assertErr(t, dec.Decode(&v1))
+	assertErr(t, dec.Decode(&v2))
+	assertEq(t, "escaped string of data", `"x\ty"`, string(data[6:12]))
+	data[7] = 'X' // decoder must not alias caller's data
+	assertEq(t, "first", "x\ty", v1.A)
+	assertEq(t, "first length", 2, len(v1.B))
+	assertEq(t, "second", "z", v2.A)
+
+	tok, err := dec.Token()
+	assertErr(t, err)
+	assertEq(t, "delim", json.Delim('['), tok)
+	for dec.More() {
+		var n int
+		assertErr(t, dec.Decode(&n))
+	}
+	tok, err = dec.Token()
+	assertErr(t, err)
+	assertEq(t, "delim", json.Delim(']'), tok)
+	if err := dec.Decode(&v1); err != io.EOF {
+		t.Fatalf("expected io.EOF but got %v", err)
+	}
+
+	dec = json.NewDecoderBytes([]byte(`{"n": 1.5}`))
+	dec.UseNumber()
+	var m map[string]interface{}
+	assertErr(t, dec.Decode(&m))
+	assertEq(t, "json.Number", json.Number("1.5"), m["n"])
+}
+
+func TestDecoderBytesPaths(t *testing.T) {
+	type T struct {
+		A  int `json:"a"`
+		In struct {
+			B int `json:"b"`
+		} `json:"in"`
+	}
+	const src = `{"a":1,"x":2,"in":{"b":1,"y":3}}`
+	for name, opt := range map[string]json.DecodeOptionFunc{
+		"DisallowUnknownFields": json.DisallowUnknownFields(),
+		"StrictPaths":           json.StrictPaths("in"),
+	} {
+		for kind, dec := range map[string]*json.Decoder{
+			"reader": json.NewDecoder(strings.NewReader(src)),
+			"bytes":  json.NewDecoderBytes([]byte(src)),
+		} {
+			var v T
+			err := dec.DecodeWithOption(&v, opt)
+			if !errors.Is(err, json.ErrUnknownField) {
+				t.Errorf("%s with %s: unexpected error: %v", kind, name, err)
+			}
+		}
+	}
+	dec := json.NewDecoderBytes([]byte(src))
+	dec.DisallowUnknownFields()
+	var v T
+	if err := dec.Decode(&v); !errors.Is(err, json.ErrUnknownField) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMarshalAllDecodeAll(t *testing.T) {
+	type T struct {
+		A int    `json:"a"`
+		B string `json:"b,omitempty"`
+	}
+	values := []T{{A: 1, B: "<x>"}, {A: 2}, {A: 3, B: "z"}}
+	b, err := json.MarshalAll(values)
+	assertErr(t, err)
+	assertEq(t, "marshal", "{\"a\":1,\"b\":\"\\u003cx\\u003e\"}\n{\"a\":2}\n{\"a\":3,\"b\":\"z\"}\n", string(b))
+
+	b, err = json.MarshalAll([]interface{}{1, "a", nil}, json.DisableHTMLEscape())
+	assertErr(t, err)
+	assertEq(t, "marshal interfaces", "1\n\"a\"\nnull\n", string(b))
+
+	b, err = json.MarshalAll(values)
+	assertErr(t, err)
+	got, err := json.DecodeAll[T](bytes.NewReader(b))
+	assertErr(t, err)
+	assertEq(t, "decode", fmt.Sprint(values), fmt.Sprint(got))
+
+	got, err = json.DecodeAll[T](strings.NewReader(`{"a":1} {"a":`))
+	assertNeq(t, "error", nil, err)
+	assertEq(t, "decoded before error", 1, len(got))
+}
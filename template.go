@@ -0,0 +1,195 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Template is a compiled output template, a JSON document that defines the shape of the encoding of a value.
+// It can be used any number of times and is safe for concurrent use by multiple goroutines.
+type Template struct {
+	src  []byte
+	root *templateNode
+}
+
+// templateNode is a value of a template: a literal, an object, an array or a placeholder.
+type templateNode struct {
+	// raw is the encoding of a literal.
+	raw []byte
+	// keys are the encoded keys of an object, followed by ':'.
+	keys     [][]byte
+	elems    []*templateNode
+	isObject bool
+	isArray  bool
+	path     *Path
+}
+
+// CompileTemplate compiles an output template, a JSON document whose strings of the form "{{ path }}" are placeholders
+// for the values of the encoding selected by the JSON Path, as given to CreatePath:
+//
+//	tmpl, err := json.CompileTemplate([]byte(`{
+//		"id": "{{ $.ID }}",
+//		"profile": {"name": "{{ $.Name }}", "city": "{{ $.Address.City }}"},
+//		"tags": "{{ $.Tags[*].Name }}",
+//		"version": 2
+//	}`))
+//
+// The objects, arrays and other values of the template are kept as they are, in the order of the template,
+// so that the shape of a response is configured rather than coded in a type.
+// A placeholder must be the whole string. The paths refer to the keys of the encoding, as set by the json tags.
+func CompileTemplate(tmpl []byte) (*Template, error) {
+	// the tokens of Decoder are not checked to be separated by the right commas and colons.
+	if !Valid(tmpl) {
+		var v interface{}
+		if err := Unmarshal(tmpl, &v); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("json: template is not valid JSON")
+	}
+	dec := NewDecoder(bytes.NewReader(tmpl))
+	dec.UseNumber()
+	root, err := compileTemplateNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("json: template has data after its value")
+		}
+		return nil, err
+	}
+	return &Template{src: append([]byte(nil), tmpl...), root: root}, nil
+}
+
+// MustCompileTemplate is like CompileTemplate but panics if the template cannot be compiled.
+func MustCompileTemplate(tmpl []byte) *Template {
+	t, err := CompileTemplate(tmpl)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func compileTemplateNode(dec *Decoder) (*templateNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("json: template ends before a value")
+		}
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case Delim:
+		node := &templateNode{isObject: tok == '{', isArray: tok == '['}
+		for dec.More() {
+			if node.isObject {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := keyTok.(string); !ok {
+					return nil, fmt.Errorf("json: template has an object key that is not a string: %v", keyTok)
+				}
+				key, err := Marshal(keyTok)
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, append(key, ':'))
+			}
+			elem, err := compileTemplateNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.elems = append(node.elems, elem)
+		}
+		end, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if (node.isObject && end != Delim('}')) || (node.isArray && end != Delim(']')) {
+			return nil, fmt.Errorf("json: template has %v closing %v", end, tok)
+		}
+		return node, nil
+	case string:
+		if strings.HasPrefix(tok, "{{") && strings.HasSuffix(tok, "}}") && len(tok) >= 4 {
+			p := strings.TrimSpace(tok[2 : len(tok)-2])
+			path, err := CreatePath(p)
+			if err != nil {
+				return nil, fmt.Errorf("json: template placeholder %q: %w", tok, err)
+			}
+			return &templateNode{path: path}, nil
+		}
+	}
+	raw, err := Marshal(tok)
+	if err != nil {
+		return nil, err
+	}
+	return &templateNode{raw: raw}, nil
+}
+
+// Marshal returns the template with its placeholders replaced by the values selected from the encoding of v
+// with EncodeOption.
+// A placeholder whose path selects no value is null, and one whose path may select several values,
+// with [*] or recursive descent, is the array of the values.
+// The result is compact, and the Colorize option must not be used.
+func (t *Template) Marshal(v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	data, err := MarshalWithOption(v, optFuncs...)
+	if err != nil {
+		return nil, err
+	}
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+	return t.root.appendTo(make([]byte, 0, len(t.src)+len(data)), src)
+}
+
+// String returns the source of the template.
+func (t *Template) String() string {
+	return string(t.src)
+}
+
+// appendTo appends the value of n to b, with the values of the placeholders selected from the nul-terminated src.
+func (n *templateNode) appendTo(b, src []byte) ([]byte, error) {
+	switch {
+	case n.path != nil:
+		values, err := extractFromPathTerminated(n.path, src)
+		if err != nil {
+			return nil, err
+		}
+		if n.path.path.MultipleValues {
+			b = append(b, '[')
+			for i, value := range values {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = append(b, value...)
+			}
+			return append(b, ']'), nil
+		}
+		if len(values) == 0 {
+			return append(b, "null"...), nil
+		}
+		return append(b, values[0]...), nil
+	case n.isObject || n.isArray:
+		open, end := byte('['), byte(']')
+		if n.isObject {
+			open, end = '{', '}'
+		}
+		b = append(b, open)
+		for i, elem := range n.elems {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if n.isObject {
+				b = append(b, n.keys[i]...)
+			}
+			var err error
+			if b, err = elem.appendTo(b, src); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, end), nil
+	}
+	return append(b, n.raw...), nil
+}
@@ -0,0 +1,93 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestTemplate(t *testing.T) {
+	type tag struct {
+		Name string `json:"name"`
+	}
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		ID      int      `json:"id"`
+		Name    string   `json:"name"`
+		Address *address `json:"address"`
+		Tags    []tag    `json:"tags"`
+		Secret  string   `json:"secret"`
+	}
+	u := user{
+		ID:      1,
+		Name:    "<alice>",
+		Address: &address{City: "Paris"},
+		Tags:    []tag{{Name: "a"}},
+		Secret:  "s",
+	}
+	tmpl, err := json.CompileTemplate([]byte(`{
+		"user": {"id": "{{ $.id }}", "name": "{{$.name}}"},
+		"city": "{{ $.address.city }}",
+		"tags": "{{ $.tags[*].name }}",
+		"first": "{{ $.tags[0] }}",
+		"missing": "{{ $.nothing }}",
+		"version": 2,
+		"list": [1.50, "text", true, null, {}]
+	}`))
+	assertErr(t, err)
+
+	t.Run("marshal", func(t *testing.T) {
+		got, err := tmpl.Marshal(u)
+		assertErr(t, err)
+		assertEq(t, "encoding",
+			`{"user":{"id":1,"name":"\u003calice\u003e"},"city":"Paris","tags":["a"],"first":{"name":"a"},"missing":null,"version":2,"list":[1.50,"text",true,null,{}]}`,
+			string(got))
+	})
+	t.Run("no values", func(t *testing.T) {
+		got, err := tmpl.Marshal(user{Tags: []tag{}})
+		assertErr(t, err)
+		assertEq(t, "encoding",
+			`{"user":{"id":0,"name":""},"city":null,"tags":[],"first":null,"missing":null,"version":2,"list":[1.50,"text",true,null,{}]}`,
+			string(got))
+	})
+	t.Run("option", func(t *testing.T) {
+		got, err := tmpl.Marshal(u, json.DisableHTMLEscape())
+		assertErr(t, err)
+		if !strings.Contains(string(got), `"name":"<alice>"`) {
+			t.Fatalf("unexpected encoding %s", got)
+		}
+	})
+	t.Run("root", func(t *testing.T) {
+		got, err := json.MustCompileTemplate([]byte(`["{{$}}", "{{ not a placeholder"]`)).Marshal(tag{Name: "a"})
+		assertErr(t, err)
+		assertEq(t, "encoding", `[{"name":"a"},"{{ not a placeholder"]`, string(got))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, src := range []string{
+			``,
+			`{"a":`,
+			`{"a": "{{ a }}"}`,
+			`[1] 2`,
+			`{"a" "{{ $.x }}"]`,
+			`[1 2}`,
+			`{"a",1}`,
+			`{1:2}`,
+			`[1,,2]`,
+			`[1,]`,
+			`{"a":1,}`,
+			`{"a"::1}`,
+		} {
+			if _, err := json.CompileTemplate([]byte(src)); err == nil {
+				t.Errorf("expected error for %q", src)
+			}
+		}
+	})
+	t.Run("unsupported value", func(t *testing.T) {
+		if _, err := tmpl.Marshal(func() {}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
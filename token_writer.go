@@ -0,0 +1,264 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/going/json/internal/encoder"
+)
+
+// tokenFlushSize is the size of the buffered tokens at which they are written to the underlying writer.
+const tokenFlushSize = 4096
+
+var errInsideTokens = errors.New("json: Encode called inside a value being written by WriteToken ( use WriteValue )")
+
+// tokenWriter is the state of the value being written by Encoder.WriteToken.
+type tokenWriter struct {
+	buf    []byte
+	scopes []tokenScope
+}
+
+// tokenScope is an object or an array being written by Encoder.WriteToken.
+type tokenScope struct {
+	object bool
+	// n is the number of keys and values written in the scope.
+	n int
+}
+
+// WriteToken writes the JSON token t, so that a document is emitted incrementally without building it as a Go value:
+//
+//	enc.WriteToken(json.Delim('{'))
+//	enc.WriteToken("items") // a string where a key is expected is the key
+//	enc.WriteToken(json.Delim('['))
+//	for rows.Next() {
+//		enc.WriteValue(row)
+//	}
+//	enc.WriteToken(json.Delim(']'))
+//	enc.WriteToken(json.Delim('}'))
+//
+// t is a Delim, a string, a float64, a Number, a RawNumber, a bool or nil, as returned by Decoder.Token.
+// The tokens are escaped and indented as Encode does, with the settings of the encoder.
+// They are buffered and written to the underlying writer every few kilobytes, or SetFlushSize bytes if it is larger,
+// and once the top-level value is complete, followed by a newline character, as after Encode.
+// A token that is not valid at its position is an error, which leaves the value being written unchanged.
+// Encode must not be called until the top-level value is complete.
+func (e *Encoder) WriteToken(t Token) error {
+	ctx := encoder.TakeRuntimeContext()
+	ctx.Option.Flag = 0
+	e.applyOption(ctx, nil)
+	err := e.writeToken(ctx, t)
+	encoder.ReleaseRuntimeContext(ctx)
+	return err
+}
+
+// BeginObject writes the start of an object, as WriteToken(Delim('{')) does.
+func (e *Encoder) BeginObject() error {
+	return e.WriteToken(Delim('{'))
+}
+
+// EndObject writes the end of the current object, as WriteToken(Delim('}')) does.
+func (e *Encoder) EndObject() error {
+	return e.WriteToken(Delim('}'))
+}
+
+// BeginArray writes the start of an array, as WriteToken(Delim('[')) does.
+func (e *Encoder) BeginArray() error {
+	return e.WriteToken(Delim('['))
+}
+
+// EndArray writes the end of the current array, as WriteToken(Delim(']')) does.
+func (e *Encoder) EndArray() error {
+	return e.WriteToken(Delim(']'))
+}
+
+// WriteKey writes key as the key of the next member of the current object.
+func (e *Encoder) WriteKey(key string) error {
+	if t := e.tokens; t == nil || len(t.scopes) == 0 || !t.expectsKey() {
+		return errors.New("json: WriteKey called where no object key is expected")
+	}
+	return e.WriteToken(key)
+}
+
+// WriteValue writes the JSON encoding of v with EncodeOption as the next value, where WriteToken would write one,
+// or as a whole top-level value followed by a newline character, like EncodeWithOption.
+func (e *Encoder) WriteValue(v interface{}, optFuncs ...EncodeOptionFunc) error {
+	t := e.tokenWriter()
+	if t.expectsKey() {
+		return errors.New("json: WriteValue called where an object key is expected")
+	}
+	ctx := encoder.TakeRuntimeContext()
+	ctx.Option.Flag = 0
+	e.applyOption(ctx, optFuncs)
+	var (
+		buf []byte
+		err error
+	)
+	if e.enabledIndent {
+		// the lines of the value are indented by the depth at which it is written.
+		prefix := e.prefix + strings.Repeat(e.indentStr, len(t.scopes))
+		buf, err = encodeIndent(ctx, v, prefix, e.indentStr)
+		if err == nil {
+			buf = buf[:len(buf)-2]
+		}
+	} else {
+		buf, err = encode(ctx, v)
+		if err == nil {
+			buf = buf[:len(buf)-1]
+		}
+	}
+	if err == nil {
+		t.beginValue(e)
+		t.buf = append(t.buf, buf...)
+		err = e.endTokenValue()
+	}
+	encoder.ReleaseRuntimeContext(ctx)
+	return err
+}
+
+func (e *Encoder) tokenWriter() *tokenWriter {
+	if e.tokens == nil {
+		e.tokens = &tokenWriter{}
+	}
+	return e.tokens
+}
+
+func (e *Encoder) writeToken(ctx *encoder.RuntimeContext, tok Token) error {
+	t := e.tokenWriter()
+	if t.expectsKey() {
+		switch tok := tok.(type) {
+		case string:
+			t.beginValue(e)
+			t.buf = encoder.AppendString(ctx, t.buf, tok)
+			t.buf = append(t.buf, ':')
+			if e.enabledIndent {
+				t.buf = append(t.buf, ' ')
+			}
+			return nil
+		case Delim:
+			if tok == '}' {
+				return e.endTokenScope(true)
+			}
+		}
+		return fmt.Errorf("json: invalid token %v where an object key is expected", tok)
+	}
+	switch tok := tok.(type) {
+	case Delim:
+		switch tok {
+		case '{', '[':
+			t.beginValue(e)
+			t.buf = append(t.buf, byte(tok))
+			t.scopes = append(t.scopes, tokenScope{object: tok == '{'})
+			return nil
+		case ']':
+			return e.endTokenScope(false)
+		}
+		return fmt.Errorf("json: invalid delimiter %v where a value is expected", tok)
+	case string:
+		t.beginValue(e)
+		t.buf = encoder.AppendString(ctx, t.buf, tok)
+	case float64:
+		if math.IsNaN(tok) || math.IsInf(tok, 0) {
+			return encoder.ErrUnsupportedFloat(tok)
+		}
+		t.beginValue(e)
+		t.buf = encoder.AppendFloat64(ctx, t.buf, tok)
+	case Number:
+		b, err := encoder.AppendNumber(ctx, nil, tok)
+		if err != nil {
+			return err
+		}
+		t.beginValue(e)
+		t.buf = append(t.buf, b...)
+	case RawNumber:
+		b, err := encoder.AppendNumber(ctx, nil, Number(tok))
+		if err != nil {
+			return err
+		}
+		t.beginValue(e)
+		t.buf = append(t.buf, b...)
+	case bool:
+		t.beginValue(e)
+		t.buf = encoder.AppendBool(ctx, t.buf, tok)
+	case nil:
+		t.beginValue(e)
+		t.buf = encoder.AppendNull(ctx, t.buf)
+	default:
+		return fmt.Errorf("json: invalid token of type %T ( use WriteValue )", tok)
+	}
+	return e.endTokenValue()
+}
+
+// expectsKey reports whether the next token is a key of the current object.
+func (t *tokenWriter) expectsKey() bool {
+	if len(t.scopes) == 0 {
+		return false
+	}
+	top := t.scopes[len(t.scopes)-1]
+	return top.object && top.n%2 == 0
+}
+
+// beginValue writes the separator and the indentation before the next key or value of the current scope.
+func (t *tokenWriter) beginValue(e *Encoder) {
+	if len(t.scopes) == 0 {
+		return
+	}
+	top := &t.scopes[len(t.scopes)-1]
+	top.n++
+	if top.object && top.n%2 == 0 {
+		// the value of a key follows it on the same line.
+		return
+	}
+	if top.n > 1 {
+		t.buf = append(t.buf, ',')
+	}
+	if e.enabledIndent {
+		t.appendIndent(e, len(t.scopes))
+	}
+}
+
+func (t *tokenWriter) appendIndent(e *Encoder, depth int) {
+	t.buf = append(t.buf, '\n')
+	t.buf = append(t.buf, e.prefix...)
+	for i := 0; i < depth; i++ {
+		t.buf = append(t.buf, e.indentStr...)
+	}
+}
+
+// endTokenScope writes the end of the current object or array.
+func (e *Encoder) endTokenScope(object bool) error {
+	t := e.tokens
+	if len(t.scopes) == 0 || t.scopes[len(t.scopes)-1].object != object {
+		if object {
+			return errors.New("json: invalid delimiter } outside of an object")
+		}
+		return errors.New("json: invalid delimiter ] outside of an array")
+	}
+	top := t.scopes[len(t.scopes)-1]
+	t.scopes = t.scopes[:len(t.scopes)-1]
+	if e.enabledIndent && top.n > 0 {
+		t.appendIndent(e, len(t.scopes))
+	}
+	if object {
+		t.buf = append(t.buf, '}')
+	} else {
+		t.buf = append(t.buf, ']')
+	}
+	return e.endTokenValue()
+}
+
+// endTokenValue writes the buffered tokens to the underlying writer once the top-level value is complete,
+// or once they have grown to the flush size.
+func (e *Encoder) endTokenValue() error {
+	t := e.tokens
+	if len(t.scopes) == 0 {
+		t.buf = append(t.buf, '\n')
+		e.stats.Values++
+	} else if len(t.buf) < tokenFlushSize || len(t.buf) < e.flushSize {
+		return nil
+	}
+	_, err := e.write(t.buf)
+	t.buf = t.buf[:0]
+	return err
+}
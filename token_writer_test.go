@@ -0,0 +1,96 @@
+package json_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/going/json"
+)
+
+func TestEncoderWriteToken(t *testing.T) {
+	type row struct {
+		ID   int      `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	write := func(enc *json.Encoder) {
+		assertErr(t, enc.BeginObject())
+		assertErr(t, enc.WriteKey("name"))
+		assertErr(t, enc.WriteToken("<a>"))
+		assertErr(t, enc.WriteToken("rows"))
+		assertErr(t, enc.BeginArray())
+		for i := 1; i <= 2; i++ {
+			assertErr(t, enc.WriteValue(row{ID: i, Tags: []string{"x"}}))
+		}
+		assertErr(t, enc.EndArray())
+		assertErr(t, enc.WriteKey("empty"))
+		assertErr(t, enc.BeginObject())
+		assertErr(t, enc.EndObject())
+		assertErr(t, enc.WriteKey("n"))
+		assertErr(t, enc.WriteToken(json.Number("1.5")))
+		assertErr(t, enc.WriteKey("ok"))
+		assertErr(t, enc.WriteToken(true))
+		assertErr(t, enc.WriteKey("nil"))
+		assertErr(t, enc.WriteToken(nil))
+		assertErr(t, enc.EndObject())
+	}
+	t.Run("compact", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		write(enc)
+		assertErr(t, enc.WriteToken(float64(2)))
+		assertEq(t, "output",
+			`{"name":"\u003ca\u003e","rows":[{"id":1,"tags":["x"]},{"id":2,"tags":["x"]}],"empty":{},"n":1.5,"ok":true,"nil":null}`+"\n2\n",
+			buf.String())
+		assertEq(t, "values", int64(2), enc.Stats().Values)
+	})
+	t.Run("indent", func(t *testing.T) {
+		var tokens, values bytes.Buffer
+		enc := json.NewEncoder(&tokens)
+		enc.SetIndent(">", "  ")
+		enc.SetEscapeHTML(false)
+		write(enc)
+		// the tokens are indented as the encoding of the same value.
+		enc = json.NewEncoder(&values)
+		enc.SetIndent(">", "  ")
+		enc.SetEscapeHTML(false)
+		assertErr(t, enc.Encode(struct {
+			Name  string      `json:"name"`
+			Rows  []row       `json:"rows"`
+			Empty struct{}    `json:"empty"`
+			N     json.Number `json:"n"`
+			OK    bool        `json:"ok"`
+			Nil   *int        `json:"nil"`
+		}{Name: "<a>", Rows: []row{{ID: 1, Tags: []string{"x"}}, {ID: 2, Tags: []string{"x"}}}, N: "1.5", OK: true}))
+		assertEq(t, "output", values.String(), tokens.String())
+	})
+	t.Run("flush", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		assertErr(t, enc.BeginArray())
+		for i := 0; i < 2000; i++ {
+			assertErr(t, enc.WriteToken("value"))
+		}
+		if buf.Len() == 0 {
+			t.Fatal("tokens are not flushed before the end of the value")
+		}
+		assertErr(t, enc.EndArray())
+		assertEq(t, "output", "["+strings.TrimSuffix(strings.Repeat(`"value",`, 2000), ",")+"]\n", buf.String())
+	})
+	t.Run("errors", func(t *testing.T) {
+		enc := json.NewEncoder(&bytes.Buffer{})
+		assertNeq(t, "end without begin", nil, enc.EndObject())
+		assertNeq(t, "key outside of an object", nil, enc.WriteKey("a"))
+		assertErr(t, enc.BeginObject())
+		assertNeq(t, "value for a key", nil, enc.WriteToken(float64(1)))
+		assertNeq(t, "value for a key", nil, enc.WriteValue(1))
+		assertNeq(t, "end of an array", nil, enc.EndArray())
+		assertErr(t, enc.WriteKey("a"))
+		assertNeq(t, "end without value", nil, enc.EndObject())
+		assertNeq(t, "unsupported token", nil, enc.WriteToken(1))
+		assertNeq(t, "encode inside tokens", nil, enc.Encode(1))
+		assertErr(t, enc.WriteToken(float64(1)))
+		assertErr(t, enc.EndObject())
+		assertErr(t, enc.Encode(1))
+	})
+}
@@ -0,0 +1,320 @@
+package json
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/going/json/internal/encoder"
+	"github.com/going/json/internal/errors"
+)
+
+// transcodeFlushSize is the size of the buffered output at which Transcode writes it to the encoder.
+const transcodeFlushSize = 4096
+
+// pointerEscaper escapes the keys of a JSON Pointer.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// TranscodeOption holds the options of Transcode.
+type TranscodeOption struct {
+	keyMapper   func(path, key string) string
+	redactPaths *encoder.RedactPaths
+}
+
+// TranscodeOptionFunc configures Transcode.
+type TranscodeOptionFunc func(*TranscodeOption)
+
+// WithKeyMapper rewrites every object key with fn while transcoding.
+// path is the JSON Pointer of the object that holds the key, made of the keys of the input, and "" for the top-level object.
+//
+//	json.Transcode(enc, dec, json.WithKeyMapper(func(path, key string) string {
+//		return toSnakeCase(key)
+//	}))
+func WithKeyMapper(fn func(path, key string) string) TranscodeOptionFunc {
+	return func(opt *TranscodeOption) {
+		opt.keyMapper = fn
+	}
+}
+
+// WithRedactPaths replaces the values at the JSON Pointers of paths with "[REDACTED]" while transcoding,
+// as RedactPaths does when encoding. The paths are matched with the keys of the input, before WithKeyMapper.
+// WithRedactPaths panics if a path is neither empty nor starts with "/".
+func WithRedactPaths(paths ...string) TranscodeOptionFunc {
+	redactPaths := mustRedactPaths(paths)
+	return func(opt *TranscodeOption) {
+		opt.redactPaths = redactPaths
+	}
+}
+
+// Transcode copies the next value from dec to enc token by token, without decoding it into Go values,
+// so that documents of any size are rewritten with a bounded amount of memory.
+// The value is written compactly, followed by a newline, like Encoder.Encode without SetIndent.
+// Numbers are copied as they are in the input.
+// Transcode returns io.EOF if dec has no more values.
+func Transcode(enc *Encoder, dec *Decoder, optFuncs ...TranscodeOptionFunc) error {
+	var opt TranscodeOption
+	for _, optFunc := range optFuncs {
+		optFunc(&opt)
+	}
+	useRawNumber := dec.s.UseRawNumber
+	dec.s.UseRawNumber = true
+	defer func() { dec.s.UseRawNumber = useRawNumber }()
+
+	t := transcoder{opt: &opt, enc: enc, dec: dec, ctx: encoder.TakeRuntimeContext()}
+	defer encoder.ReleaseRuntimeContext(t.ctx)
+	t.ctx.Option.Flag = encoder.NormalizeUTF8Option
+	if enc.enabledHTMLEscape {
+		t.ctx.Option.Flag |= encoder.HTMLEscapeOption
+	}
+	if err := t.run(); err != nil {
+		return err
+	}
+	t.buf = append(t.buf, '\n')
+	if err := t.flush(); err != nil {
+		return err
+	}
+	enc.stats.Values++
+	return nil
+}
+
+// transcodeScope is an object or an array that is being transcoded.
+type transcodeScope struct {
+	object bool
+	// n is the number of keys and values written in the scope.
+	n int
+	// path is the JSON Pointer of the scope.
+	path string
+	// redacting are the redacted paths that match the path of the scope.
+	redacting []int
+}
+
+type transcoder struct {
+	opt    *TranscodeOption
+	enc    *Encoder
+	dec    *Decoder
+	ctx    *encoder.RuntimeContext
+	buf    []byte
+	scopes []transcodeScope
+	// key is the key of the value being transcoded in the current object.
+	key string
+}
+
+func (t *transcoder) run() error {
+	for {
+		if err := t.separator(); err != nil {
+			return err
+		}
+		tok, err := t.dec.Token()
+		if err == io.EOF && len(t.scopes) > 0 {
+			return errors.ErrUnexpectedEndOfJSON("value", t.dec.InputOffset())
+		}
+		if err != nil {
+			return err
+		}
+		if len(t.scopes) > 0 {
+			top := &t.scopes[len(t.scopes)-1]
+			if top.object && top.n%2 == 0 {
+				if tok == Delim('}') {
+					t.buf = append(t.buf, '}')
+					if t.pop() {
+						return nil
+					}
+					continue
+				}
+				key, ok := tok.(string)
+				if !ok {
+					return errors.ErrExpected("object key", t.dec.InputOffset())
+				}
+				if top.n > 0 {
+					t.buf = append(t.buf, ',')
+				}
+				top.n++
+				t.key = key
+				if t.opt.keyMapper != nil {
+					key = t.opt.keyMapper(top.path, key)
+				}
+				t.buf = encoder.AppendString(t.ctx, t.buf, key)
+				t.buf = append(t.buf, ':')
+				continue
+			}
+			if !top.object && tok == Delim(']') {
+				t.buf = append(t.buf, ']')
+				if t.pop() {
+					return nil
+				}
+				continue
+			}
+			if top.object {
+				top.n++
+			} else {
+				if top.n > 0 {
+					t.buf = append(t.buf, ',')
+				}
+				t.key = strconv.Itoa(top.n)
+				top.n++
+			}
+		}
+		redacting, redacted := t.redact()
+		if redacted {
+			if err := t.skip(tok); err != nil {
+				return err
+			}
+			t.buf = append(t.buf, encoder.RedactedValue...)
+		} else {
+			switch v := tok.(type) {
+			case Delim:
+				switch v {
+				case '{', '[':
+					t.push(v == '{', redacting)
+					t.buf = append(t.buf, byte(v))
+					continue
+				default:
+					return errors.ErrInvalidCharacter(byte(v), "value", t.dec.InputOffset())
+				}
+			case string:
+				t.buf = encoder.AppendString(t.ctx, t.buf, v)
+			case RawNumber:
+				t.buf = append(t.buf, v...)
+			case bool:
+				t.buf = strconv.AppendBool(t.buf, v)
+			case nil:
+				t.buf = append(t.buf, "null"...)
+			}
+		}
+		if len(t.scopes) == 0 {
+			return nil
+		}
+		if len(t.buf) >= transcodeFlushSize {
+			if err := t.flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// redact returns the redacted paths that match the path of the value being transcoded,
+// and whether one of them ends at it.
+func (t *transcoder) redact() ([]int, bool) {
+	paths := t.opt.redactPaths
+	if paths == nil {
+		return nil, false
+	}
+	if len(t.scopes) == 0 {
+		redacting := paths.Alive()
+		return redacting, paths.Matches(redacting, 0)
+	}
+	depth := len(t.scopes)
+	redacting := paths.Next(t.scopes[depth-1].redacting, depth-1, t.key)
+	return redacting, paths.Matches(redacting, depth)
+}
+
+// separator consumes the comma or the colon expected before the next token of the current scope,
+// since Decoder.Token skips them without checking them.
+func (t *transcoder) separator() error {
+	s := t.dec.s
+	c, err := s.NextChar()
+	if err != nil {
+		if err == io.EOF && len(t.scopes) > 0 {
+			return errors.ErrUnexpectedEndOfJSON("value", t.dec.InputOffset())
+		}
+		// Token returns the error again.
+		return nil
+	}
+	if len(t.scopes) > 0 {
+		top := t.scopes[len(t.scopes)-1]
+		closing := byte(']')
+		if top.object {
+			closing = '}'
+		}
+		var sep byte
+		switch {
+		case top.object && top.n%2 == 1:
+			sep = ':'
+		case c == closing:
+			return nil
+		case top.n > 0:
+			sep = ','
+		}
+		if sep != 0 {
+			if c != sep {
+				return errors.ErrInvalidCharacter(c, "separator", t.dec.InputOffset())
+			}
+			s.SkipPrefix(string(sep))
+			if c, err = s.NextChar(); err == io.EOF {
+				return errors.ErrUnexpectedEndOfJSON("value", t.dec.InputOffset())
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	switch c {
+	case ',', ':', ']', '}':
+		return errors.ErrInvalidCharacter(c, "value", t.dec.InputOffset())
+	}
+	return nil
+}
+
+// skip reads the tokens of the value that starts with tok, checking them as run does.
+func (t *transcoder) skip(tok Token) error {
+	if tok != Delim('{') && tok != Delim('[') {
+		return nil
+	}
+	base := len(t.scopes)
+	t.scopes = append(t.scopes, transcodeScope{object: tok == Delim('{')})
+	for len(t.scopes) > base {
+		if err := t.separator(); err != nil {
+			return err
+		}
+		tok, err := t.dec.Token()
+		if err == io.EOF {
+			return errors.ErrUnexpectedEndOfJSON("value", t.dec.InputOffset())
+		}
+		if err != nil {
+			return err
+		}
+		top := &t.scopes[len(t.scopes)-1]
+		switch {
+		case (top.object && tok == Delim('}')) || (!top.object && tok == Delim(']')):
+			t.scopes = t.scopes[:len(t.scopes)-1]
+			continue
+		case top.object && top.n%2 == 0:
+			if _, ok := tok.(string); !ok {
+				return errors.ErrExpected("object key", t.dec.InputOffset())
+			}
+		case tok == Delim('{') || tok == Delim('['):
+			top.n++
+			t.scopes = append(t.scopes, transcodeScope{object: tok == Delim('{')})
+			continue
+		}
+		if delim, ok := tok.(Delim); ok {
+			return errors.ErrInvalidCharacter(byte(delim), "value", t.dec.InputOffset())
+		}
+		top.n++
+	}
+	return nil
+}
+
+// push enters an object or an array that is the value of t.key in the current scope.
+// redacting are the redacted paths that match its path.
+func (t *transcoder) push(object bool, redacting []int) {
+	var path string
+	if len(t.scopes) > 0 {
+		key := pointerEscaper.Replace(t.key)
+		path = t.scopes[len(t.scopes)-1].path + "/" + key
+	}
+	t.scopes = append(t.scopes, transcodeScope{object: object, path: path, redacting: redacting})
+}
+
+// pop leaves the current scope, and reports whether it was the top-level value.
+func (t *transcoder) pop() bool {
+	t.scopes = t.scopes[:len(t.scopes)-1]
+	return len(t.scopes) == 0
+}
+
+func (t *transcoder) flush() error {
+	if _, err := t.enc.write(t.buf); err != nil {
+		return err
+	}
+	t.buf = t.buf[:0]
+	return nil
+}
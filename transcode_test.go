@@ -0,0 +1,109 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/going/json"
+)
+
+func TestTranscode(t *testing.T) {
+	snake := func(s string) string {
+		var b strings.Builder
+		for i, r := range s {
+			if r >= 'A' && r <= 'Z' {
+				if i > 0 {
+					b.WriteByte('_')
+				}
+				r += 'a' - 'A'
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	t.Run("key mapper", func(t *testing.T) {
+		src := `{"userId": 1, "userName": "a<b", "lastLogin": {"unixTime": 12345678901234567890, "isValid": true},
+			"tagList": [{"tagName": null}, 1.50, "x"], "emptyObj": {}, "emptyArr": []}`
+		var buf bytes.Buffer
+		var paths []string
+		err := json.Transcode(json.NewEncoder(&buf), json.NewDecoder(iotest.OneByteReader(strings.NewReader(src))),
+			json.WithKeyMapper(func(path, key string) string {
+				paths = append(paths, path)
+				return snake(key)
+			}))
+		assertErr(t, err)
+		assertEq(t, "output",
+			`{"user_id":1,"user_name":"a\u003cb","last_login":{"unix_time":12345678901234567890,"is_valid":true},`+
+				`"tag_list":[{"tag_name":null},1.50,"x"],"empty_obj":{},"empty_arr":[]}`+"\n",
+			buf.String())
+		assertEq(t, "paths", ",,,/lastLogin,/lastLogin,,/tagList/0,,", strings.Join(paths, ","))
+	})
+	t.Run("stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		dec := json.NewDecoder(strings.NewReader(`{"a":1} [2] "s" 3`))
+		for {
+			err := json.Transcode(enc, dec)
+			if err == io.EOF {
+				break
+			}
+			assertErr(t, err)
+		}
+		assertEq(t, "output", "{\"a\":1}\n[2]\n\"s\"\n3\n", buf.String())
+		assertEq(t, "values", int64(4), enc.Stats().Values)
+	})
+	t.Run("pointer escape", func(t *testing.T) {
+		var path string
+		err := json.Transcode(json.NewEncoder(io.Discard), json.NewDecoder(strings.NewReader(`{"a/b~":{"c":1}}`)),
+			json.WithKeyMapper(func(p, key string) string {
+				if key == "c" {
+					path = p
+				}
+				return key
+			}))
+		assertErr(t, err)
+		assertEq(t, "path", "/a~1b~0", path)
+	})
+	t.Run("redact paths", func(t *testing.T) {
+		src := `{"user": {"ssn": {"area": 123}, "name": "a"}, "cards": [{"number": [4, 1]}, {"number": "5500"}], "ssn": 1}`
+		var buf bytes.Buffer
+		err := json.Transcode(json.NewEncoder(&buf), json.NewDecoder(strings.NewReader(src)),
+			json.WithRedactPaths("/user/ssn", "/cards/*/number"), json.WithKeyMapper(func(path, key string) string {
+				return strings.ToUpper(key)
+			}))
+		assertErr(t, err)
+		assertEq(t, "output",
+			`{"USER":{"SSN":"[REDACTED]","NAME":"a"},"CARDS":[{"NUMBER":"[REDACTED]"},{"NUMBER":"[REDACTED]"}],"SSN":1}`+"\n",
+			buf.String())
+	})
+	t.Run("error", func(t *testing.T) {
+		for _, src := range []string{
+			`{"a":1`, `[1}`, `{1:2}`,
+			`[1 2 3]`, `{"a" 1}`, `[,,1]`, `[1,]`, `{"a":1,}`, `{"a":1 "b":2}`, `{"a"::1}`, `{,"a":1}`, `[1:2]`, `,1`,
+		} {
+			for name, opt := range map[string]json.TranscodeOptionFunc{
+				"plain":        json.WithKeyMapper(func(path, key string) string { return key }),
+				"redact paths": json.WithRedactPaths("/a", "/0"),
+			} {
+				var buf bytes.Buffer
+				err := json.Transcode(json.NewEncoder(&buf), json.NewDecoder(strings.NewReader(src)), opt)
+				var syntaxErr *json.SyntaxError
+				if !errors.As(err, &syntaxErr) {
+					t.Errorf("%s with %s: unexpected error %v, output %q", src, name, err, buf.String())
+				}
+			}
+		}
+		// the redacted values are checked too.
+		for _, src := range []string{`{"a":[1 2]}`, `{"a":{"b" 1}}`, `{"a":[1,]}`, `{"a":{"b":1]}`} {
+			err := json.Transcode(json.NewEncoder(io.Discard), json.NewDecoder(strings.NewReader(src)), json.WithRedactPaths("/a"))
+			var syntaxErr *json.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Errorf("%s: unexpected error %v", src, err)
+			}
+		}
+	})
+}
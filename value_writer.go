@@ -0,0 +1,79 @@
+package json
+
+import (
+	"io"
+
+	"github.com/going/json/internal/encoder"
+)
+
+// ValueWriter is the JSON encoding of a value, encoded when it is read,
+// so that it is passed to the APIs that take an io.Reader without marshaling it beforehand:
+//
+//	req, err := http.NewRequest("POST", url, json.NewValueWriter(payload))
+//
+// It is also an io.WriterTo, which io.Copy uses to encode the value directly into the destination,
+// without holding a copy of the encoding.
+// The encoding is the one of MarshalWithOption, without a trailing newline character.
+// An error of the encoding is returned by the first Read or WriteTo call.
+type ValueWriter struct {
+	v        interface{}
+	optFuncs []EncodeOptionFunc
+	// buf is the encoding of v read by Read, from off.
+	buf     []byte
+	off     int
+	encoded bool
+	err     error
+}
+
+// NewValueWriter returns a ValueWriter encoding v with EncodeOption.
+func NewValueWriter(v interface{}, optFuncs ...EncodeOptionFunc) *ValueWriter {
+	return &ValueWriter{v: v, optFuncs: optFuncs}
+}
+
+// Read reads the next bytes of the encoding of the value, which is encoded by the first call.
+// It returns io.EOF after the last byte.
+func (w *ValueWriter) Read(p []byte) (int, error) {
+	if !w.encoded {
+		w.buf, w.err = marshal(w.v, w.optFuncs...)
+		w.encoded = true
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.off >= len(w.buf) {
+		return 0, io.EOF
+	}
+	n := copy(p, w.buf[w.off:])
+	w.off += n
+	return n, nil
+}
+
+// WriteTo writes the encoding of the value to dst, or the rest of it if it has already been partly read.
+func (w *ValueWriter) WriteTo(dst io.Writer) (int64, error) {
+	if w.encoded {
+		if w.err != nil {
+			return 0, w.err
+		}
+		n, err := dst.Write(w.buf[w.off:])
+		w.off += n
+		return int64(n), err
+	}
+	w.encoded = true
+
+	ctx := encoder.TakeRuntimeContext()
+	ctx.Option.Flag = 0
+	ctx.Option.Flag |= (encoder.HTMLEscapeOption | encoder.NormalizeUTF8Option)
+	for _, optFunc := range w.optFuncs {
+		optFunc(ctx.Option)
+	}
+	buf, err := encode(ctx, w.v)
+	if err != nil {
+		encoder.ReleaseRuntimeContext(ctx)
+		w.err = err
+		return 0, err
+	}
+	// the encoding is written from the buffer of the context, so that it is not copied.
+	n, err := dst.Write(buf[:len(buf)-1])
+	encoder.ReleaseRuntimeContext(ctx)
+	return int64(n), err
+}
@@ -0,0 +1,61 @@
+package json_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/going/json"
+)
+
+func TestValueWriter(t *testing.T) {
+	v := map[string]interface{}{"a": []int{1, 2}, "b": "<x>"}
+	expected, err := json.Marshal(v)
+	assertErr(t, err)
+
+	t.Run("read", func(t *testing.T) {
+		got, err := io.ReadAll(iotest.OneByteReader(json.NewValueWriter(v)))
+		assertErr(t, err)
+		assertEq(t, "encoding", string(expected), string(got))
+	})
+	t.Run("write to", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := json.NewValueWriter(v).WriteTo(&buf)
+		assertErr(t, err)
+		assertEq(t, "encoding", string(expected), buf.String())
+		assertEq(t, "n", int64(len(expected)), n)
+	})
+	t.Run("partly read", func(t *testing.T) {
+		w := json.NewValueWriter(v)
+		head := make([]byte, 3)
+		_, err := io.ReadFull(w, head)
+		assertErr(t, err)
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, w)
+		assertErr(t, err)
+		assertEq(t, "encoding", string(expected), string(head)+buf.String())
+		n, err := w.Read(head)
+		assertEq(t, "n", 0, n)
+		assertEq(t, "end", io.EOF, err)
+	})
+	t.Run("option", func(t *testing.T) {
+		got, err := io.ReadAll(json.NewValueWriter(v, json.UnorderedMap(), json.DisableHTMLEscape()))
+		assertErr(t, err)
+		var m map[string]interface{}
+		assertErr(t, json.Unmarshal(got, &m))
+		assertEq(t, "b", "<x>", m["b"])
+		if !bytes.Contains(got, []byte("<x>")) {
+			t.Fatalf("unexpected encoding %s", got)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		w := json.NewValueWriter(func() {})
+		if _, err := io.ReadAll(w); err == nil {
+			t.Fatal("expected error")
+		}
+		if _, err := w.WriteTo(io.Discard); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
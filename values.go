@@ -0,0 +1,44 @@
+package json
+
+import (
+	"net/url"
+	"reflect"
+
+	"github.com/going/json/internal/decoder"
+)
+
+// UnmarshalValues decodes the URL query parameters or the form values into the value pointed to by v
+// with DecodeOption, with the same struct tags as Unmarshal, so that a struct describes the query and the body alike:
+//
+//	var filter struct {
+//		Status []string `json:"status"`
+//		Page   struct {
+//			Size int `json:"size"`
+//		} `json:"page"`
+//	}
+//	err := json.UnmarshalValues(r.URL.Query(), &filter) // ?status=open&status=closed&page.size=20
+//
+// The values are decoded as if they were a JSON object:
+//   - the dotted keys are nested objects, such as page.size above, or the elements of an array by their indexes, as in items.0.id.
+//   - the repeated keys are the elements of an array, or only the first value is decoded for a field that is not a slice or an array.
+//   - the values are strings, or numbers and booleans for the fields of these kinds: an empty value leaves them unchanged
+//     or is no element of their slice,
+//     and a checkbox sending "on" is true. A value that is not of the type of its field is an *UnmarshalTypeError.
+//   - a type implementing Unmarshaler or encoding.TextUnmarshaler decodes the value as a JSON string.
+//
+// The keys match the fields as they do in Unmarshal, case-insensitively, and DisallowUnknownFields rejects the unknown keys.
+func UnmarshalValues(values url.Values, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Kind() != reflect.Ptr || reflect.ValueOf(v).IsNil() {
+		return &InvalidUnmarshalError{Type: typ}
+	}
+	opt := &decoder.Option{}
+	for _, optFunc := range optFuncs {
+		optFunc(opt)
+	}
+	data, err := decoder.ValuesToJSON(values, typ.Elem(), opt)
+	if err != nil {
+		return err
+	}
+	return unmarshalTerminated(append(data, nul), v, optFuncs...)
+}
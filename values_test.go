@@ -0,0 +1,110 @@
+package json_test
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/going/json"
+)
+
+func TestUnmarshalValues(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type Page struct {
+		Size   int  `json:"size"`
+		Cursor *int `json:"cursor"`
+	}
+	type Embedded struct {
+		Sort string `json:"sort"`
+	}
+	type Filter struct {
+		Embedded
+		Query   string            `json:"q"`
+		Status  []string          `json:"status"`
+		IDs     []int64           `json:"ids"`
+		Active  bool              `json:"active"`
+		Limit   int               `json:"limit"`
+		Count   int64             `json:"count,string"`
+		Ratio   float64           `json:"ratio"`
+		Page    Page              `json:"page"`
+		Items   []Item            `json:"items"`
+		Labels  map[string]string `json:"labels"`
+		Since   time.Time         `json:"since"`
+		Extra   interface{}       `json:"extra"`
+		Ignored string            `json:"-"`
+	}
+	values, err := url.ParseQuery("q=a+%22b%22&status=open&status=closed&ids=1&ids=2&active=on&limit=&count=7&ratio=0.5" +
+		"&page.size=20&page.cursor=3&items.1.id=2&items.0.id=1&items.0.name=x&labels.env=prod&since=2020-01-02T03:04:05Z" +
+		"&extra=1&extra=2&SORT=name&Ignored=x&unknown=1")
+	assertErr(t, err)
+	v := Filter{Limit: 10}
+	assertErr(t, json.UnmarshalValues(values, &v))
+	cursor := 3
+	expected := Filter{
+		Embedded: Embedded{Sort: "name"},
+		Query:    `a "b"`,
+		Status:   []string{"open", "closed"},
+		IDs:      []int64{1, 2},
+		Active:   true,
+		Limit:    10,
+		Count:    7,
+		Ratio:    0.5,
+		Page:     Page{Size: 20, Cursor: &cursor},
+		Items:    []Item{{ID: 1, Name: "x"}, {ID: 2}},
+		Labels:   map[string]string{"env": "prod"},
+		Since:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:    []interface{}{"1", "2"},
+	}
+	if !reflect.DeepEqual(expected, v) {
+		t.Fatalf("unexpected value:\n%+v\n%+v", expected, v)
+	}
+
+	t.Run("empty values", func(t *testing.T) {
+		var v Filter
+		assertErr(t, json.UnmarshalValues(url.Values{"ids": {""}, "status": {""}}, &v))
+		assertEq(t, "ids", 0, len(v.IDs))
+		assertEq(t, "status", `[""]`, fmt.Sprintf("%q", v.Status))
+
+		v.IDs = []int64{3}
+		assertErr(t, json.UnmarshalValues(url.Values{"ids": {"", ""}}, &v))
+		assertEq(t, "emptied", 0, len(v.IDs))
+
+		assertErr(t, json.UnmarshalValues(url.Values{"ids": {"", "1", "", "2"}}, &v))
+		assertEq(t, "skipped", "[1 2]", fmt.Sprint(v.IDs))
+	})
+	t.Run("errors", func(t *testing.T) {
+		var v Filter
+		var typeErr *json.UnmarshalTypeError
+		if err := json.UnmarshalValues(url.Values{"limit": {"x"}}, &v); !errors.As(err, &typeErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.UnmarshalValues(url.Values{"unknown": {"1"}}, &v, json.DisallowUnknownFields()); !errors.Is(err, json.ErrUnknownField) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.UnmarshalValues(url.Values{"page": {"1"}, "page.size": {"1"}}, &v); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := json.UnmarshalValues(url.Values{"items.x.id": {"1"}}, &v); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := json.UnmarshalValues(url.Values{"limit.x": {"1"}}, &v); err == nil {
+			t.Fatal("expected error")
+		}
+		var invalidErr *json.InvalidUnmarshalError
+		if err := json.UnmarshalValues(url.Values{}, v); !errors.As(err, &invalidErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		var m map[string]interface{}
+		assertErr(t, json.UnmarshalValues(url.Values{"a": {"1"}, "b.c": {"x", "y"}}, &m))
+		assertEq(t, "a", "1", m["a"])
+		assertEq(t, "b", true, reflect.DeepEqual(map[string]interface{}{"c": []interface{}{"x", "y"}}, m["b"]))
+	})
+}
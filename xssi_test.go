@@ -0,0 +1,98 @@
+package json_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/going/json"
+)
+
+func TestEncoder_SetXSSIPrefix(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetXSSIPrefix(json.XSSIPrefix)
+		assertErr(t, enc.Encode(map[string]int{"a": 1}))
+		assertErr(t, enc.Encode([]int{2}))
+		assertEq(t, "output", ")]}'\n{\"a\":1}\n[2]\n", buf.String())
+	})
+	t.Run("indent", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetXSSIPrefix(json.XSSIPrefix)
+		enc.SetIndent("", "  ")
+		assertErr(t, enc.Encode([]int{1}))
+		assertEq(t, "output", ")]}'\n[\n  1\n]\n", buf.String())
+	})
+	t.Run("disabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetXSSIPrefix(json.XSSIPrefix)
+		enc.SetXSSIPrefix("")
+		assertErr(t, enc.Encode(1))
+		assertEq(t, "output", "1\n", buf.String())
+	})
+	t.Run("sync", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewSyncEncoder(&buf)
+		enc.SetXSSIPrefix(json.XSSIPrefix)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assertErr(t, enc.Encode(1))
+			}()
+		}
+		wg.Wait()
+		assertEq(t, "output", ")]}'\n1\n1\n1\n1\n", buf.String())
+	})
+}
+
+func TestDecoder_StripXSSIPrefix(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+	}
+	t.Run("values", func(t *testing.T) {
+		dec := json.NewDecoder(iotest.OneByteReader(strings.NewReader(")]}'\n{\"a\":1}\n{\"a\":2}")))
+		dec.StripXSSIPrefix(json.XSSIPrefix)
+		for _, want := range []int{1, 2} {
+			var v T
+			assertErr(t, dec.Decode(&v))
+			assertEq(t, "a", want, v.A)
+		}
+	})
+	t.Run("without prefix", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a":1}`))
+		dec.StripXSSIPrefix(json.XSSIPrefix)
+		var v T
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "a", 1, v.A)
+	})
+	t.Run("token", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(")]}'\n[1]"))
+		dec.StripXSSIPrefix(json.XSSIPrefix)
+		tok, err := dec.Token()
+		assertErr(t, err)
+		assertEq(t, "token", json.Delim('['), tok)
+	})
+	t.Run("not stripped", func(t *testing.T) {
+		var v T
+		err := json.NewDecoder(strings.NewReader(")]}'\n{\"a\":1}")).Decode(&v)
+		assertNeq(t, "error", nil, err)
+	})
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetXSSIPrefix(json.XSSIPrefix)
+		assertErr(t, enc.Encode(T{A: 3}))
+		dec := json.NewDecoder(&buf)
+		dec.StripXSSIPrefix(json.XSSIPrefix)
+		var v T
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "a", 3, v.A)
+	})
+}